@@ -3,7 +3,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/muidea/magicCommon/application"
 	"github.com/muidea/magicCommon/foundation/log"
@@ -15,6 +18,9 @@ import (
 var listenPort = "8080"
 var endpointName = "magicAgent"
 var configFile = ""
+var validateOnly = false
+var encryptOnly = false
+var keyFile = config.DefaultKeyFile
 
 func initPprofMonitor(listenPort string) {
 	addr := ":1" + listenPort
@@ -27,22 +33,102 @@ func initPprofMonitor(listenPort string) {
 	}()
 }
 
+// flagOverride 只有显式指定的命令行参数才覆盖配置
+func flagOverride(cfg *config.CfgItem) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ListenPort":
+			cfg.ListenPort = listenPort
+		case "EndpointName":
+			cfg.EndpointName = endpointName
+		}
+	})
+}
+
+// validateConfig 校验配置并输出全部问题，不启动服务
+func validateConfig(cfgFile string) int {
+	cfgName := cfgFile
+	if cfgName == "" {
+		cfgName = "default config"
+	}
+
+	_, cfgErr := config.ParseConfig(cfgFile, flagOverride)
+	if cfgErr == nil {
+		fmt.Printf("%s is valid\n", cfgName)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "%s is invalid:\n", cfgName)
+	validateErr, ok := cfgErr.(*config.ValidateError)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "  %s\n", cfgErr.Error())
+		return 1
+	}
+
+	for _, val := range validateErr.Problems {
+		fmt.Fprintf(os.Stderr, "  %s\n", val)
+	}
+	return 1
+}
+
+// encryptSecret 从标准输入读取明文，输出可写入配置文件的enc:引用
+func encryptSecret() int {
+	byteVal, byteErr := io.ReadAll(os.Stdin)
+	if byteErr != nil {
+		fmt.Fprintf(os.Stderr, "read secret from stdin failed, error:%s\n", byteErr.Error())
+		return 1
+	}
+
+	secretVal, secretErr := config.EncryptSecret(strings.TrimRight(string(byteVal), "\r\n"))
+	if secretErr != nil {
+		fmt.Fprintf(os.Stderr, "encrypt secret failed, error:%s\n", secretErr.Error())
+		return 1
+	}
+
+	fmt.Println(secretVal)
+	return 0
+}
+
 func main() {
 	flag.StringVar(&listenPort, "ListenPort", listenPort, "listen address")
 	flag.StringVar(&endpointName, "EndpointName", endpointName, "endpoint name.")
 	flag.StringVar(&configFile, "Config", configFile, "config file path")
+	flag.BoolVar(&validateOnly, "validate", validateOnly, "validate config file and exit")
+	flag.BoolVar(&encryptOnly, "encrypt", encryptOnly, "encrypt secret read from stdin and exit")
+	flag.StringVar(&keyFile, "KeyFile", keyFile, "secret key file path")
 	flag.Parse()
 
-	initPprofMonitor(listenPort)
+	config.SetKeyFile(keyFile)
+	if encryptOnly {
+		os.Exit(encryptSecret())
+	}
 
-	if configFile != "" {
-		configErr := config.LoadConfig(configFile)
-		if configErr != nil {
-			log.Errorf("load config file failed, error:%s", configErr.Error())
-			return
+	if configFile == "" {
+		if _, statErr := os.Stat(config.DefaultConfigFile); statErr == nil {
+			configFile = config.DefaultConfigFile
 		}
 	}
 
+	if validateOnly {
+		os.Exit(validateConfig(configFile))
+	}
+
+	if configFile == "" {
+		log.Warnf("no config file found, using default config and environment")
+	}
+
+	configErr := config.LoadConfig(configFile, flagOverride)
+	if configErr != nil {
+		fmt.Fprintf(os.Stderr, "load config file %s failed, error:%s\n", configFile, configErr.Error())
+		log.Criticalf("load config file failed, error:%s", configErr.Error())
+		os.Exit(1)
+	}
+	log.Infof("current config:%s", config.Dump())
+
+	listenPort = config.GetListenPort()
+	endpointName = config.GetEndpointName()
+	initPprofMonitor(listenPort)
+
 	fmt.Printf("%s starting!\n", endpointName)
 
 	corePtr, coreErr := core.New(endpointName, listenPort)
@@ -0,0 +1,976 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/client"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// 子命令参数
+var serviceName = ""
+var cmdParam = ""
+var alarmTitle = ""
+var alarmContent = ""
+var historyCount = 20
+var pauseReason = ""
+var runtimeName = ""
+var logTail = 0
+var logSince = ""
+var logFollow = false
+var execEnv = stringList{}
+var execWorkDir = ""
+var execTimeOut = time.Duration(0)
+var jobType = ""
+var jobID = ""
+var jobParam = ""
+var killThread = int64(0)
+var killQuery = false
+var killReason = ""
+
+var jobColumns = []string{"ID", "TYPE", "SERVICE", "STATUS", "PROGRESS", "MESSAGE", "CREATE TIME", "ERROR"}
+
+var containerColumns = []string{"NAME", "RUNTIME", "IMAGE", "STATE", "STATUS", "RESTARTS", "EXIT CODE", "OOM KILLED"}
+
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+var outputLock sync.Mutex
+
+// prefixWriter 多个agent并发输出时按行写入并增加agent前缀
+type prefixWriter struct {
+	writer io.Writer
+	prefix string
+	buffer []byte
+}
+
+func newPrefixWriter(writer io.Writer, agent string) *prefixWriter {
+	ptr := &prefixWriter{writer: writer}
+	if agentCount > 1 {
+		ptr.prefix = "[" + agent + "] "
+	}
+
+	return ptr
+}
+
+func (s *prefixWriter) Write(data []byte) (int, error) {
+	s.buffer = append(s.buffer, data...)
+	idx := bytes.LastIndexByte(s.buffer, '\n')
+	if idx < 0 {
+		return len(data), nil
+	}
+
+	err := s.output(s.buffer[:idx+1])
+	s.buffer = append([]byte{}, s.buffer[idx+1:]...)
+	return len(data), err
+}
+
+// Flush 输出剩余不完整的行
+func (s *prefixWriter) Flush() {
+	if len(s.buffer) > 0 {
+		_ = s.output(append(s.buffer, '\n'))
+		s.buffer = nil
+	}
+}
+
+func (s *prefixWriter) output(lines []byte) error {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+
+	if s.prefix == "" {
+		_, err := s.writer.Write(lines)
+		return err
+	}
+
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := s.writer.Write(append([]byte(s.prefix), line...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+func parseService(name string, required bool) func(args []string) error {
+	return func(args []string) error {
+		flagSet := newFlagSet(name)
+		flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if required && serviceName == "" {
+			return fmt.Errorf("-service is required")
+		}
+
+		return nil
+	}
+}
+
+func serviceRows(value interface{}) [][]string {
+	result, ok := value.(*common.StartServiceResult)
+	if !ok || result == nil {
+		return [][]string{{"", ""}}
+	}
+
+	return [][]string{{strings.TrimSpace(result.StdOut), strings.TrimSpace(result.StdErr)}}
+}
+
+func jobRow(jobPtr *common.JobInfo) []string {
+	errorVal := ""
+	if jobPtr.Error != nil {
+		errorVal = jobPtr.Error.Reason
+	}
+
+	return []string{
+		jobPtr.ID,
+		jobPtr.Type,
+		jobPtr.Service,
+		jobPtr.Status,
+		fmt.Sprintf("%d%%", jobPtr.Progress),
+		jobPtr.Message,
+		jobPtr.CreateTime.Format(time.RFC3339),
+		errorVal,
+	}
+}
+
+func jobRows(value interface{}) [][]string {
+	jobPtr, ok := value.(*common.JobInfo)
+	if !ok || jobPtr == nil {
+		return nil
+	}
+
+	return [][]string{jobRow(jobPtr)}
+}
+
+func parseJobID(name string) func(args []string) error {
+	return func(args []string) error {
+		flagSet := newFlagSet(name)
+		flagSet.StringVar(&jobID, "id", jobID, "job id")
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if jobID == "" {
+			return fmt.Errorf("-id is required")
+		}
+
+		return nil
+	}
+}
+
+func remediationRows(value interface{}) [][]string {
+	statusPtr, ok := value.(*common.RemediationStatus)
+	if !ok || statusPtr == nil {
+		return [][]string{{"", "", ""}}
+	}
+
+	services := []string{}
+	for key, val := range statusPtr.Services {
+		services = append(services, fmt.Sprintf("%s(%s)", key, val))
+	}
+
+	return [][]string{{fmt.Sprintf("%v", statusPtr.Paused), statusPtr.Reason, strings.Join(services, ",")}}
+}
+
+func containerRows(value interface{}) [][]string {
+	var containers []*common.ContainerInfo
+	switch val := value.(type) {
+	case []*common.ContainerInfo:
+		containers = val
+	case *common.ContainerInfo:
+		if val != nil {
+			containers = append(containers, val)
+		}
+	}
+
+	rows := [][]string{}
+	for _, val := range containers {
+		rows = append(rows, []string{
+			val.Name,
+			val.Runtime,
+			val.Image,
+			val.State,
+			val.Status,
+			fmt.Sprintf("%d", val.RestartCount),
+			fmt.Sprintf("%d", val.ExitCode),
+			fmt.Sprintf("%v", val.OOMKilled),
+		})
+	}
+
+	return rows
+}
+
+func commands() []*command {
+	return []*command{
+		{
+			name:    "status",
+			usage:   "query guarded service cluster status, -service name",
+			columns: []string{"STATUS", "NODE SIZE", "NODES"},
+			parse:   parseService("status", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryStatus(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.ClusterStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{{"", "", ""}}
+				}
+
+				return [][]string{{statusPtr.Status, fmt.Sprintf("%d", statusPtr.NodeSize), strings.Join(statusPtr.Nodes, ",")}}
+			},
+		},
+		{
+			name:    "replication",
+			usage:   "query primary/replica replication status, -service name",
+			columns: []string{"ROLE", "MASTER", "IO", "SQL", "LAG", "GTID", "LAST ERROR"},
+			parse:   parseService("replication", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryReplication(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.ReplicationStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{{"", "", "", "", "", "", ""}}
+				}
+				if statusPtr.Role != common.ReplicationReplica {
+					return [][]string{{statusPtr.Role, "", "", "", "", "", ""}}
+				}
+
+				lag := "NULL"
+				if statusPtr.SecondsBehindMaster != nil {
+					lag = strconv.FormatInt(*statusPtr.SecondsBehindMaster, 10)
+				}
+				gtid := statusPtr.GtidIOPos
+				if gtid == "" {
+					gtid = statusPtr.ExecutedGtidSet
+				}
+				lastError := statusPtr.LastSQLError
+				if lastError == "" {
+					lastError = statusPtr.LastIOError
+				}
+
+				return [][]string{{statusPtr.Role, fmt.Sprintf("%s:%d", statusPtr.MasterHost, statusPtr.MasterPort),
+					statusPtr.IORunning, statusPtr.SQLRunning, lag, gtid, lastError}}
+			},
+		},
+		{
+			name:    "redis",
+			usage:   "query redis role, replication, persistence and memory status, -service name",
+			columns: []string{"ROLE", "LOADING", "MASTER", "LINK", "PERSISTENCE", "MEMORY", "SENTINEL MASTERS"},
+			parse:   parseService("redis", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryRedisStatus(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.RedisStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{{"", "", "", "", "", "", ""}}
+				}
+
+				master := ""
+				if statusPtr.MasterHost != "" {
+					master = fmt.Sprintf("%s:%d", statusPtr.MasterHost, statusPtr.MasterPort)
+				}
+				persistence := statusPtr.PersistenceError()
+				if persistence == "" && statusPtr.Role != common.RedisSentinel {
+					persistence = "ok"
+				}
+				memory := strconv.FormatInt(statusPtr.UsedMemory, 10)
+				if statusPtr.MaxMemory > 0 {
+					memory += "/" + strconv.FormatInt(statusPtr.MaxMemory, 10)
+				}
+				masters := []string{}
+				for _, val := range statusPtr.SentinelMasters {
+					masters = append(masters, fmt.Sprintf("%s(%s %s)", val.Name, val.Address, val.Status))
+				}
+
+				return [][]string{{statusPtr.Role, fmt.Sprintf("%v", statusPtr.Loading), master, statusPtr.MasterLinkStatus,
+					persistence, memory, strings.Join(masters, ",")}}
+			},
+		},
+		{
+			name:    "postgres",
+			usage:   "query postgres role, streaming replication, slots and connections, -service name",
+			columns: []string{"ROLE", "CONNECTIONS", "RECEIVER", "REPLICAS", "SLOTS"},
+			parse:   parseService("postgres", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryPostgresStatus(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.PostgresStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{{"", "", "", "", ""}}
+				}
+
+				receiver := ""
+				if statusPtr.Receiver != nil {
+					receiver = fmt.Sprintf("%s(%s:%d)", statusPtr.Receiver.Status, statusPtr.Receiver.SenderHost, statusPtr.Receiver.SenderPort)
+				} else if statusPtr.InRecovery {
+					receiver = "stopped"
+				}
+				replicas := []string{}
+				for _, val := range statusPtr.Replicas {
+					replicas = append(replicas, fmt.Sprintf("%s(%s %s %.0fs)", val.ApplicationName, val.State, val.SyncState, val.ReplayLag))
+				}
+				slots := []string{}
+				for _, val := range statusPtr.Slots {
+					slots = append(slots, fmt.Sprintf("%s(active:%v %d)", val.Name, val.Active, val.RetainedBytes))
+				}
+
+				return [][]string{{statusPtr.Role, fmt.Sprintf("%d/%d", statusPtr.Connections, statusPtr.MaxConnections),
+					receiver, strings.Join(replicas, ","), strings.Join(slots, ",")}}
+			},
+		},
+		{
+			name:    "probe",
+			usage:   "run tcp, http, tls and command probes of a probe guard, -service name",
+			columns: []string{"TYPE", "TARGET", "HEALTHY", "LATENCY(ms)", "CERT EXPIRE", "REASON"},
+			parse:   parseService("probe", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryProbeStatus(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.ProbeStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{}
+				}
+
+				rows := [][]string{}
+				for _, val := range statusPtr.Results {
+					certExpire := ""
+					if val.CertExpire != nil {
+						certExpire = val.CertExpire.Format(time.RFC3339)
+						if val.CertExpiring {
+							certExpire += "(expiring)"
+						}
+					}
+					rows = append(rows, []string{val.Type, val.Target, fmt.Sprintf("%v", val.Healthy),
+						strconv.FormatInt(val.Latency, 10), certExpire, val.Reason})
+				}
+				return rows
+			},
+		},
+		{
+			name:    "host",
+			usage:   "query host disk, inode, memory, swap and load average",
+			columns: []string{"RESOURCE", "USAGE", "DETAIL"},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryHostStatus(ctx)
+			},
+			rows: func(value interface{}) [][]string {
+				statusPtr, ok := value.(*common.HostStatus)
+				if !ok || statusPtr == nil {
+					return [][]string{}
+				}
+
+				rows := [][]string{}
+				for _, val := range statusPtr.Mounts {
+					if val.Unavailable != "" {
+						rows = append(rows, []string{val.Path, "", val.Unavailable})
+						continue
+					}
+					rows = append(rows, []string{val.Path, fmt.Sprintf("%.1f%%", val.Usage),
+						fmt.Sprintf("%d bytes available, inode %.1f%%", val.Available, val.InodeUsage)})
+				}
+				rows = append(rows,
+					[]string{"memory", fmt.Sprintf("%.1f%%", statusPtr.MemoryUsage), fmt.Sprintf("%d of %d bytes available", statusPtr.MemoryAvailable, statusPtr.MemoryTotal)},
+					[]string{"swap", fmt.Sprintf("%.1f%%", statusPtr.SwapUsage), fmt.Sprintf("%d of %d bytes free", statusPtr.SwapFree, statusPtr.SwapTotal)},
+					[]string{"load", fmt.Sprintf("%.2f %.2f %.2f", statusPtr.Load1, statusPtr.Load5, statusPtr.Load15), fmt.Sprintf("%d cpus", statusPtr.CPUCount)},
+				)
+				for _, val := range statusPtr.Warnings {
+					rows = append(rows, []string{"warning", "", val})
+				}
+				return rows
+			},
+		},
+		{
+			name:    "volume",
+			usage:   "query data volume usage of a guard, restart is blocked when it is full, -service name",
+			columns: []string{"PATH", "USAGE", "AVAILABLE", "INODE USAGE", "FULL"},
+			parse:   parseService("volume", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryDataVolume(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				volumePtr, ok := value.(*common.DataVolumeStatus)
+				if !ok || volumePtr == nil || volumePtr.Usage == nil {
+					return [][]string{{"", "", "", "", ""}}
+				}
+
+				return [][]string{{volumePtr.Path, fmt.Sprintf("%.1f%%", volumePtr.Usage.Usage), strconv.FormatUint(volumePtr.Usage.Available, 10),
+					fmt.Sprintf("%.1f%%", volumePtr.Usage.InodeUsage), fmt.Sprintf("%v", volumePtr.Full)}}
+			},
+		},
+		{
+			name:    "failover",
+			usage:   "promote the most up-to-date replica when primary is down, -service name, may need a longer -timeout",
+			columns: []string{"HOST", "ROLE", "MASTER", "IO", "ERROR"},
+			parse:   parseService("failover", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.Failover(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				decisionPtr, ok := value.(*common.FailoverDecision)
+				if !ok || decisionPtr == nil {
+					return [][]string{}
+				}
+
+				rows := [][]string{}
+				for _, val := range decisionPtr.Views {
+					if val.Status == nil {
+						rows = append(rows, []string{val.Host, "", "", "", val.Error})
+						continue
+					}
+					rows = append(rows, []string{val.Host, val.Status.Role,
+						fmt.Sprintf("%s:%d", val.Status.MasterHost, val.Status.MasterPort), val.Status.IORunning, ""})
+				}
+				decision := "decision: " + decisionPtr.Action
+				if decisionPtr.Candidate != "" {
+					decision += " on " + decisionPtr.Candidate
+				}
+				rows = append(rows, []string{decision, "", "", "", decisionPtr.Reason})
+
+				return rows
+			},
+		},
+		{
+			name:    "process",
+			usage:   "list slow queries and long transactions over thresholds, -service name",
+			columns: []string{"TYPE", "ID", "THREAD", "USER", "DB", "TIME", "STATE", "DIGEST", "QUERY"},
+			parse:   parseService("process", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryProcess(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				reportPtr, ok := value.(*common.ProcessReport)
+				if !ok || reportPtr == nil {
+					return [][]string{}
+				}
+
+				rows := [][]string{}
+				for _, val := range reportPtr.SlowQueries {
+					rows = append(rows, []string{"query", "", strconv.FormatInt(val.ID, 10), val.User + "@" + val.Host, val.DB,
+						strconv.FormatInt(val.Time, 10), val.State, val.Digest, val.DigestText})
+				}
+				for _, val := range reportPtr.LongTransactions {
+					rows = append(rows, []string{"transaction", val.ID, strconv.FormatInt(val.ThreadID, 10), val.User + "@" + val.Host, val.DB,
+						strconv.FormatInt(val.Time, 10), val.State, val.Digest, val.DigestText})
+				}
+
+				return rows
+			},
+		},
+		{
+			name:    "kill",
+			usage:   "kill thread on mariadb, -service name -thread id [-query] -reason reason",
+			columns: []string{"RESULT"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("kill")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+				flagSet.Int64Var(&killThread, "thread", killThread, "thread id")
+				flagSet.BoolVar(&killQuery, "query", killQuery, "kill the current query only, keep the connection")
+				flagSet.StringVar(&killReason, "reason", killReason, "kill reason, recorded in audit log")
+				if err := flagSet.Parse(args); err != nil {
+					return err
+				}
+				if serviceName == "" || killThread <= 0 {
+					return fmt.Errorf("-service and -thread are required")
+				}
+
+				return nil
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				param := &common.KillProcessParam{Service: serviceName, ThreadID: killThread, Query: killQuery, Reason: killReason}
+				return nil, clnt.KillProcess(ctx, param)
+			},
+			rows: func(_ interface{}) [][]string {
+				return [][]string{{"killed"}}
+			},
+		},
+		{
+			name:    "component",
+			usage:   "analyze Non-Primary component from all cluster nodes, -service name",
+			columns: []string{"HOST", "STATUS", "LOCAL STATE", "SIZE", "SEQNO", "ERROR"},
+			parse:   parseService("component", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryComponent(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				decisionPtr, ok := value.(*common.ComponentDecision)
+				if !ok || decisionPtr == nil {
+					return [][]string{}
+				}
+
+				rows := [][]string{}
+				for _, val := range decisionPtr.Views {
+					if val.Status == nil {
+						rows = append(rows, []string{val.Host, "", "", "", "", val.Error})
+						continue
+					}
+					rows = append(rows, []string{val.Host, val.Status.Status, val.Status.LocalState,
+						strconv.Itoa(val.Status.NodeSize), strconv.FormatInt(val.Status.LastCommitted, 10), ""})
+				}
+				decision := "decision: " + decisionPtr.Action
+				if decisionPtr.Candidate != "" {
+					decision += " on " + decisionPtr.Candidate
+				}
+				rows = append(rows, []string{decision, "", "", "", "", decisionPtr.Reason})
+
+				return rows
+			},
+		},
+		{
+			name:    "bootstrap",
+			usage:   "set pc.bootstrap=YES on Non-Primary node, -service name",
+			columns: []string{"RESULT"},
+			parse:   parseService("bootstrap", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return nil, clnt.Bootstrap(ctx, serviceName)
+			},
+			rows: func(_ interface{}) [][]string {
+				return [][]string{{"bootstrapped"}}
+			},
+		},
+		{
+			name:    "start",
+			usage:   "start guarded service, -service name",
+			columns: []string{"STDOUT", "STDERR"},
+			parse:   parseService("start", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.StartService(ctx, serviceName)
+			},
+			rows: serviceRows,
+		},
+		{
+			name:    "stop",
+			usage:   "stop guarded service, -service name",
+			columns: []string{"STDOUT", "STDERR"},
+			parse:   parseService("stop", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				ret, err := clnt.StopService(ctx, serviceName)
+				return (*common.StartServiceResult)(ret), err
+			},
+			rows: serviceRows,
+		},
+		{
+			name:    "restart",
+			usage:   "restart guarded service, -service name",
+			columns: []string{"STDOUT", "STDERR"},
+			parse:   parseService("restart", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				ret, err := clnt.RestartService(ctx, serviceName)
+				return (*common.StartServiceResult)(ret), err
+			},
+			rows: serviceRows,
+		},
+		{
+			name:    "exec",
+			usage:   "execute command in guarded service, -service name -cmd command -env KEY=VALUE -workdir dir -exec-timeout 30s",
+			columns: []string{"EXIT CODE", "STDOUT", "STDERR"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("exec")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+				flagSet.StringVar(&cmdParam, "cmd", cmdParam, "command executed by sh -c")
+				flagSet.Var(&execEnv, "env", "environment variable KEY=VALUE, can be repeated")
+				flagSet.StringVar(&execWorkDir, "workdir", execWorkDir, "working directory of the command")
+				flagSet.DurationVar(&execTimeOut, "exec-timeout", execTimeOut, "command timeout, 0 for agent default")
+				if err := flagSet.Parse(args); err != nil {
+					return err
+				}
+				if serviceName == "" || cmdParam == "" {
+					return fmt.Errorf("-service and -cmd are required")
+				}
+				if execTimeOut < 0 {
+					return fmt.Errorf("-exec-timeout must not be negative")
+				}
+				// 请求超时不能短于命令超时
+				if execTimeOut+5*time.Second > timeOut {
+					timeOut = execTimeOut + 5*time.Second
+				}
+
+				return nil
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				param := &common.ServiceParam{
+					Service:  serviceName,
+					CmdParam: cmdParam,
+					Env:      execEnv,
+					WorkDir:  execWorkDir,
+					TimeOut:  int((execTimeOut + time.Second - 1) / time.Second),
+				}
+				return clnt.ExecuteCommand(ctx, param)
+			},
+			rows: func(value interface{}) [][]string {
+				result, ok := value.(*common.ExecServiceResult)
+				if !ok || result == nil {
+					return nil
+				}
+
+				return [][]string{{fmt.Sprintf("%d", result.ExitCode), strings.TrimSpace(result.StdOut), strings.TrimSpace(result.StdErr)}}
+			},
+		},
+		{
+			name:   "shell",
+			usage:  "open interactive session in guarded service, -service name -tty -env KEY=VALUE -workdir dir [command args...]",
+			stream: true,
+			parse:  parseShell,
+			run:    runShell,
+		},
+		{
+			name:    "audit",
+			usage:   "list interactive session audit records, -count n",
+			columns: []string{"ID", "SERVICE", "COMMAND", "TTY", "REMOTE", "START TIME", "FINISH TIME", "EXIT CODE", "ERROR", "TRANSCRIPT"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("audit")
+				flagSet.IntVar(&historyCount, "count", historyCount, "max record count, 0 for all")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryAudit(ctx, historyCount)
+			},
+			rows: func(value interface{}) [][]string {
+				records, _ := value.([]*common.AuditRecord)
+				rows := [][]string{}
+				for _, val := range records {
+					finishTime, errorVal := "", ""
+					if val.FinishTime != nil {
+						finishTime = val.FinishTime.Format(time.RFC3339)
+					}
+					if val.Error != nil {
+						errorVal = val.Error.Reason
+					}
+					rows = append(rows, []string{
+						val.ID,
+						val.Service,
+						strings.Join(val.Command, " "),
+						strconv.FormatBool(val.TTY),
+						val.RemoteAddr,
+						val.StartTime.Format(time.RFC3339),
+						finishTime,
+						fmt.Sprintf("%d", val.ExitCode),
+						errorVal,
+						val.Transcript,
+					})
+				}
+
+				return rows
+			},
+		},
+		{
+			name:    "container list",
+			usage:   "list containers, -runtime name (runtimes of guards if empty)",
+			columns: containerColumns,
+			parse: func(args []string) error {
+				flagSet := newFlagSet("container list")
+				flagSet.StringVar(&runtimeName, "runtime", runtimeName, "runtime name: docker, podman, nerdctl, systemd or kubernetes")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.ListContainer(ctx, runtimeName)
+			},
+			rows: containerRows,
+		},
+		{
+			name:    "container inspect",
+			usage:   "inspect guarded service container, -service name",
+			columns: containerColumns,
+			parse:   parseService("container inspect", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.InspectContainer(ctx, serviceName)
+			},
+			rows: containerRows,
+		},
+		{
+			name:    "container stats",
+			usage:   "query guarded service resource usage, -service name",
+			columns: []string{"NAME", "CPU %", "MEM USAGE", "MEM LIMIT", "MEM %", "PIDS"},
+			parse:   parseService("container stats", true),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryContainerStats(ctx, serviceName)
+			},
+			rows: func(value interface{}) [][]string {
+				statsPtr, ok := value.(*common.ContainerStats)
+				if !ok || statsPtr == nil {
+					return nil
+				}
+
+				return [][]string{{
+					statsPtr.Name,
+					fmt.Sprintf("%.2f", statsPtr.CPUPercent),
+					fmt.Sprintf("%d", statsPtr.MemoryUsage),
+					fmt.Sprintf("%d", statsPtr.MemoryLimit),
+					fmt.Sprintf("%.2f", statsPtr.MemoryPercent),
+					fmt.Sprintf("%d", statsPtr.PIDs),
+				}}
+			},
+		},
+		{
+			name:    "container events",
+			usage:   "query container events, -count n",
+			columns: []string{"TIME", "NAME", "ACTION", "HEALTH", "EXIT CODE", "RUNTIME"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("container events")
+				flagSet.IntVar(&historyCount, "count", historyCount, "max event count, 0 for all")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryContainerEvents(ctx, historyCount)
+			},
+			rows: func(value interface{}) [][]string {
+				events, _ := value.([]*common.ContainerEvent)
+				rows := [][]string{}
+				for _, val := range events {
+					rows = append(rows, []string{
+						val.TimeStamp.Format(time.RFC3339),
+						val.Name,
+						val.Action,
+						val.Health,
+						fmt.Sprintf("%d", val.ExitCode),
+						val.Runtime,
+					})
+				}
+
+				return rows
+			},
+		},
+		{
+			name:   "logs",
+			usage:  "print guarded service logs, -service name -tail n -since 10m|RFC3339 -follow",
+			stream: true,
+			parse: func(args []string) error {
+				flagSet := newFlagSet("logs")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+				flagSet.IntVar(&logTail, "tail", logTail, "number of lines from the end, 0 for all")
+				flagSet.StringVar(&logSince, "since", logSince, "show logs since RFC3339 time, relative duration or unix timestamp")
+				flagSet.BoolVar(&logFollow, "follow", logFollow, "follow log output")
+				if err := flagSet.Parse(args); err != nil {
+					return err
+				}
+				if serviceName == "" {
+					return fmt.Errorf("-service is required")
+				}
+
+				return nil
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				writer := newPrefixWriter(os.Stdout, clnt.ServerURL())
+				defer writer.Flush()
+
+				return nil, clnt.ContainerLogs(ctx, serviceName, logTail, logSince, logFollow, writer)
+			},
+		},
+		{
+			name:    "job create",
+			usage:   "create asynchronous job, -type restart -service name -param json",
+			columns: jobColumns,
+			parse: func(args []string) error {
+				flagSet := newFlagSet("job create")
+				flagSet.StringVar(&jobType, "type", jobType, "job type")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+				flagSet.StringVar(&jobParam, "param", jobParam, "job param in json")
+				if err := flagSet.Parse(args); err != nil {
+					return err
+				}
+				if jobType == "" {
+					return fmt.Errorf("-type is required")
+				}
+				if jobParam != "" && !json.Valid([]byte(jobParam)) {
+					return fmt.Errorf("-param must be valid json")
+				}
+
+				return nil
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				param := &common.JobParam{Type: jobType, Service: serviceName}
+				if jobParam != "" {
+					param.Param = json.RawMessage(jobParam)
+				}
+				return clnt.CreateJob(ctx, param)
+			},
+			rows: jobRows,
+		},
+		{
+			name:    "job get",
+			usage:   "query job status, progress and output, -id job",
+			columns: append(append([]string{}, jobColumns...), "OUTPUT"),
+			parse:   parseJobID("job get"),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryJob(ctx, jobID)
+			},
+			rows: func(value interface{}) [][]string {
+				jobPtr, ok := value.(*common.JobInfo)
+				if !ok || jobPtr == nil {
+					return nil
+				}
+
+				return [][]string{append(jobRow(jobPtr), strings.TrimSpace(jobPtr.Output))}
+			},
+		},
+		{
+			name:    "job list",
+			usage:   "list jobs, -count n",
+			columns: jobColumns,
+			parse: func(args []string) error {
+				flagSet := newFlagSet("job list")
+				flagSet.IntVar(&historyCount, "count", historyCount, "max job count, 0 for all")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.ListJob(ctx, historyCount)
+			},
+			rows: func(value interface{}) [][]string {
+				jobs, _ := value.([]*common.JobInfo)
+				rows := [][]string{}
+				for _, val := range jobs {
+					rows = append(rows, jobRow(val))
+				}
+
+				return rows
+			},
+		},
+		{
+			name:    "job cancel",
+			usage:   "cancel pending or running job, -id job",
+			columns: jobColumns,
+			parse:   parseJobID("job cancel"),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.CancelJob(ctx, jobID)
+			},
+			rows: jobRows,
+		},
+		{
+			name:    "alarm send",
+			usage:   "send alarm, -title title -content content",
+			columns: []string{"RESULT"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("alarm send")
+				flagSet.StringVar(&alarmTitle, "title", alarmTitle, "alarm title")
+				flagSet.StringVar(&alarmContent, "content", alarmContent, "alarm content")
+				if err := flagSet.Parse(args); err != nil {
+					return err
+				}
+				if alarmTitle == "" {
+					return fmt.Errorf("-title is required")
+				}
+
+				return nil
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				err := clnt.SendAlarm(ctx, &common.AlarmInfo{Title: alarmTitle, Content: alarmContent})
+				return nil, err
+			},
+			rows: func(_ interface{}) [][]string {
+				return [][]string{{"sent"}}
+			},
+		},
+		{
+			name:    "alarm history",
+			usage:   "query alarm history, -count n",
+			columns: []string{"TIME", "HOST", "TITLE", "CONTENT"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("alarm history")
+				flagSet.IntVar(&historyCount, "count", historyCount, "max alarm count, 0 for all")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryAlarmHistory(ctx, historyCount)
+			},
+			rows: func(value interface{}) [][]string {
+				alarms, _ := value.([]*common.AlarmRecord)
+				rows := [][]string{}
+				for _, val := range alarms {
+					rows = append(rows, []string{val.TimeStamp.Format(time.RFC3339), val.Host, val.Title, val.Content})
+				}
+
+				return rows
+			},
+		},
+		{
+			name:    "backup list",
+			usage:   "list backups, -service name (all if empty) -count n",
+			columns: []string{"TIME", "SERVICE", "METHOD", "SIZE", "NAME", "CHECKSUM"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("backup list")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name, all if empty")
+				flagSet.IntVar(&historyCount, "count", historyCount, "max backup count, 0 for all")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.ListBackup(ctx, serviceName, historyCount)
+			},
+			rows: func(value interface{}) [][]string {
+				backups, _ := value.([]*common.BackupRecord)
+				rows := [][]string{}
+				for _, val := range backups {
+					rows = append(rows, []string{val.CreateTime.Format(time.RFC3339), val.Service, val.Method, strconv.FormatInt(val.Size, 10), val.Name, val.Checksum})
+				}
+
+				return rows
+			},
+		},
+		{
+			name:    "pause",
+			usage:   "pause remediation, -service name (all if empty) -reason reason",
+			columns: []string{"PAUSED", "PAUSE REASON", "SERVICES"},
+			parse: func(args []string) error {
+				flagSet := newFlagSet("pause")
+				flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name, all if empty")
+				flagSet.StringVar(&pauseReason, "reason", pauseReason, "pause reason")
+				return flagSet.Parse(args)
+			},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.PauseRemediation(ctx, &common.RemediationParam{Service: serviceName, Reason: pauseReason})
+			},
+			rows: remediationRows,
+		},
+		{
+			name:    "resume",
+			usage:   "resume remediation, -service name (all if empty)",
+			columns: []string{"PAUSED", "PAUSE REASON", "SERVICES"},
+			parse:   parseService("resume", false),
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.ResumeRemediation(ctx, &common.RemediationParam{Service: serviceName})
+			},
+			rows: remediationRows,
+		},
+		{
+			name:    "config",
+			usage:   "show effective agent config",
+			columns: []string{"CONFIG"},
+			run: func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+				return clnt.QueryConfig(ctx)
+			},
+			rows: func(value interface{}) [][]string {
+				rawVal, _ := value.(json.RawMessage)
+				return [][]string{{string(rawVal)}}
+			},
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/client"
+)
+
+// 退出码，多个agent时取第一个失败agent对应的退出码
+const (
+	exitSucceeded        = 0
+	exitFailed           = 1
+	exitWarned           = 2
+	exitIllegalParam     = 3
+	exitInvalidAuthority = 4
+	exitNoExist          = 5
+	exitUsage            = 64
+)
+
+var agentList = ""
+var clusterFlag = false
+var configFile = config.DefaultConfigFile
+var token = os.Getenv("MAGICAGENTCTL_TOKEN")
+var outputFormat = formatTable
+var timeOut = 30 * time.Second
+var retryCount = 2
+
+// agentCount 目标agent数量，多于一个时流式输出的每行增加agent前缀
+var agentCount = 0
+
+// agentResult 单个agent的执行结果
+type agentResult struct {
+	Agent string      `json:"agent"`
+	Value interface{} `json:"value,omitempty"`
+	Error *cd.Result  `json:"error,omitempty"`
+}
+
+// command 子命令，run在每个agent上执行一次
+// stream为true时run直接输出到标准输出，不再按输出格式打印结果，且不限制执行时长
+type command struct {
+	name    string
+	usage   string
+	columns []string
+	stream  bool
+	parse   func(args []string) error
+	run     func(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result)
+	rows    func(value interface{}) [][]string
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: magicagentctl [options] <command> [command options]
+
+Commands:
+`)
+	for _, val := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", val.name, val.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.StringVar(&agentList, "agent", agentList, "agent address list, separated by comma, e.g. 192.168.1.2:8080")
+	flag.BoolVar(&clusterFlag, "cluster", clusterFlag, "target localHost and all clusterHosts from config file")
+	flag.StringVar(&configFile, "Config", configFile, "config file path, used with -cluster")
+	flag.StringVar(&token, "token", token, "bearer token, defaults to $MAGICAGENTCTL_TOKEN")
+	flag.StringVar(&outputFormat, "o", outputFormat, "output format: table, json or yaml")
+	flag.DurationVar(&timeOut, "timeout", timeOut, "request timeout")
+	flag.IntVar(&retryCount, "retry", retryCount, "retry count on connection failure")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	cmdPtr := findCommand(flag.Args())
+	if cmdPtr == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", strings.Join(flag.Args(), " "))
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	cmdArgs := flag.Args()[len(strings.Fields(cmdPtr.name)):]
+	if cmdPtr.parse != nil {
+		if err := cmdPtr.parse(cmdArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", cmdPtr.name, err.Error())
+			os.Exit(exitUsage)
+		}
+	}
+
+	agents, agentErr := targetAgents()
+	if agentErr != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", agentErr.Error())
+		os.Exit(exitUsage)
+	}
+
+	agentCount = len(agents)
+	results := execute(cmdPtr, agents)
+	if cmdPtr.stream {
+		for _, val := range results {
+			if val.Error != nil && !val.Error.Success() {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", val.Agent, val.Error.Reason)
+			}
+		}
+	} else if err := writeResults(os.Stdout, cmdPtr, results); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(exitUsage)
+	}
+
+	os.Exit(exitCode(results))
+}
+
+func findCommand(args []string) *command {
+	for _, val := range commands() {
+		names := strings.Fields(val.name)
+		if len(args) < len(names) {
+			continue
+		}
+
+		matched := true
+		for idx, name := range names {
+			if args[idx] != name {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return val
+		}
+	}
+
+	return nil
+}
+
+// targetAgents 汇总 -agent 指定的地址以及 -cluster 从配置文件读取的地址
+func targetAgents() ([]string, error) {
+	agents := []string{}
+	for _, val := range strings.Split(agentList, ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			agents = append(agents, val)
+		}
+	}
+
+	if clusterFlag {
+		cfg, cfgErr := config.ReadConfig(configFile)
+		if cfgErr != nil {
+			return nil, fmt.Errorf("read config file %s failed, %s", configFile, cfgErr.Error())
+		}
+
+		hosts := append([]string{cfg.LocalHost}, cfg.ClusterHosts...)
+		for _, val := range hosts {
+			if _, _, splitErr := net.SplitHostPort(val); splitErr != nil {
+				val = net.JoinHostPort(val, cfg.ListenPort)
+			}
+			agents = append(agents, val)
+		}
+	}
+
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agent specified, use -agent or -cluster")
+	}
+
+	return agents, nil
+}
+
+// execute 并发在全部agent上执行命令，结果按agent顺序返回
+func execute(cmdPtr *command, agents []string) []*agentResult {
+	results := make([]*agentResult, len(agents))
+	wg := sync.WaitGroup{}
+	for idx, val := range agents {
+		wg.Add(1)
+		go func(idx int, agent string) {
+			defer wg.Done()
+
+			options := []client.Option{client.WithTimeout(timeOut), client.WithRetry(retryCount, 0)}
+			if token != "" {
+				options = append(options, client.WithToken(token))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeOut*time.Duration(retryCount+1))
+			defer cancel()
+			// 流式命令不受超时限制，持续输出直到收到中断信号
+			if cmdPtr.stream {
+				var stop context.CancelFunc
+				ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+			}
+
+			clnt := client.NewClient(agent, options...)
+			value, err := cmdPtr.run(ctx, clnt)
+			results[idx] = &agentResult{Agent: agent, Value: value, Error: err}
+		}(idx, val)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func exitCode(results []*agentResult) int {
+	for _, val := range results {
+		if val.Error == nil || val.Error.Success() {
+			continue
+		}
+
+		switch {
+		case val.Error.ErrorCode == cd.NoExist:
+			return exitNoExist
+		case val.Error.Warn():
+			return exitWarned
+		case val.Error.ErrorCode == cd.IllegalParam:
+			return exitIllegalParam
+		case val.Error.ErrorCode == cd.InvalidAuthority:
+			return exitInvalidAuthority
+		default:
+			return exitFailed
+		}
+	}
+
+	return exitSucceeded
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestFindCommand(t *testing.T) {
+	cases := []struct {
+		args   []string
+		expect string
+	}{
+		{args: []string{"status", "-service", "mariadb001"}, expect: "status"},
+		{args: []string{"alarm", "history", "-count", "10"}, expect: "alarm history"},
+		{args: []string{"alarm"}, expect: ""},
+		{args: []string{"container", "list", "-runtime", "docker"}, expect: "container list"},
+		{args: []string{"job", "cancel", "-id", "1"}, expect: "job cancel"},
+		{args: []string{"container"}, expect: ""},
+		{args: []string{"unknown"}, expect: ""},
+	}
+
+	for _, val := range cases {
+		t.Run(strings.Join(val.args, " "), func(t *testing.T) {
+			cmdPtr := findCommand(val.args)
+			name := ""
+			if cmdPtr != nil {
+				name = cmdPtr.name
+			}
+			if name != val.expect {
+				t.Errorf("command %q, expect %q", name, val.expect)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		name   string
+		errs   []*cd.Result
+		expect int
+	}{
+		{name: "succeeded", errs: []*cd.Result{nil, {ErrorCode: cd.Succeeded}}, expect: exitSucceeded},
+		{name: "no exist", errs: []*cd.Result{cd.NewWarn(cd.NoExist, "")}, expect: exitNoExist},
+		{name: "warned", errs: []*cd.Result{cd.NewWarn(cd.Warned, "")}, expect: exitWarned},
+		{name: "illegal param", errs: []*cd.Result{cd.NewError(cd.IllegalParam, "")}, expect: exitIllegalParam},
+		{name: "invalid authority", errs: []*cd.Result{cd.NewError(cd.InvalidAuthority, "")}, expect: exitInvalidAuthority},
+		{name: "first failure", errs: []*cd.Result{nil, cd.NewError(cd.UnExpected, ""), cd.NewError(cd.IllegalParam, "")}, expect: exitFailed},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			results := []*agentResult{}
+			for _, err := range val.errs {
+				results = append(results, &agentResult{Agent: "127.0.0.1:8080", Error: err})
+			}
+			if ret := exitCode(results); ret != val.expect {
+				t.Errorf("exit code %d, expect %d", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestQuoteYAML(t *testing.T) {
+	cases := map[string]string{
+		"mariadb001": "mariadb001",
+		"":           `""`,
+		"true":       `"true"`,
+		"No":         `"No"`,
+		"3306":       `"3306"`,
+		"a: b":       `"a: b"`,
+		"-x":         `"-x"`,
+		" pad":       `" pad"`,
+		"line\nnext": `"line\nnext"`,
+	}
+
+	for key, val := range cases {
+		if ret := quoteYAML(key); ret != val {
+			t.Errorf("quoteYAML(%q) = %s, expect %s", key, ret, val)
+		}
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	results := []*agentResult{
+		{Agent: "a:8080", Value: map[string]interface{}{"paused": true, "services": map[string]string{}, "list": []int{1, 2}}},
+		{Agent: "b:8080", Error: cd.NewError(cd.UnExpected, "down")},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := writeYAML(buffer, results); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := `- agent: "a:8080"
+  value:
+    list:
+      - 1
+      - 2
+    paused: true
+    services: {}
+- agent: "b:8080"
+  error:
+    errorCode: 500004
+    reason: down
+`
+	if buffer.String() != expect {
+		t.Errorf("got\n%s\nexpect\n%s", buffer.String(), expect)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	cmdPtr := findCommand([]string{"start"})
+	results := []*agentResult{
+		{Agent: "a", Value: &common.StartServiceResult{StdOut: "line1\nline2\n"}},
+		{Agent: "b", Error: cd.NewError(cd.UnExpected, "down")},
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := writeTable(buffer, cmdPtr, results); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected table %q", buffer.String())
+	}
+	if !strings.HasPrefix(lines[0], "AGENT") || !strings.Contains(lines[1], `line1\nline2`) || !strings.Contains(lines[2], "500004") {
+		t.Errorf("unexpected table %q", buffer.String())
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	defer func(count int) { agentCount = count }(agentCount)
+	agentCount = 2
+
+	buffer := &bytes.Buffer{}
+	writer := newPrefixWriter(buffer, "a")
+	_, _ = writer.Write([]byte("one\ntw"))
+	_, _ = writer.Write([]byte("o\nthree"))
+	writer.Flush()
+
+	expect := "[a] one\n[a] two\n[a] three\n"
+	if buffer.String() != expect {
+		t.Errorf("got %q, expect %q", buffer.String(), expect)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+func writeResults(writer io.Writer, cmdPtr *command, results []*agentResult) error {
+	switch outputFormat {
+	case formatTable:
+		return writeTable(writer, cmdPtr, results)
+	case formatJSON:
+		byteVal, byteErr := json.MarshalIndent(results, "", "  ")
+		if byteErr != nil {
+			return byteErr
+		}
+
+		_, err := fmt.Fprintf(writer, "%s\n", byteVal)
+		return err
+	case formatYAML:
+		return writeYAML(writer, results)
+	}
+
+	return fmt.Errorf("illegal output format: %s", outputFormat)
+}
+
+func writeTable(writer io.Writer, cmdPtr *command, results []*agentResult) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	header := append([]string{"AGENT", "ERROR CODE", "REASON"}, cmdPtr.columns...)
+	fmt.Fprintln(tabWriter, strings.Join(header, "\t"))
+
+	for _, val := range results {
+		errorCode, reason := "0", ""
+		if val.Error != nil {
+			errorCode = strconv.Itoa(int(val.Error.ErrorCode))
+			reason = val.Error.Reason
+		}
+
+		rows := [][]string{make([]string, len(cmdPtr.columns))}
+		if val.Error == nil || !val.Error.Fail() {
+			rows = cmdPtr.rows(val.Value)
+			if len(rows) == 0 {
+				rows = [][]string{make([]string, len(cmdPtr.columns))}
+			}
+		}
+
+		for _, row := range rows {
+			items := append([]string{val.Agent, errorCode, reason}, row...)
+			for idx := range items {
+				items[idx] = strings.ReplaceAll(items[idx], "\n", "\\n")
+			}
+			fmt.Fprintln(tabWriter, strings.Join(items, "\t"))
+		}
+	}
+
+	return tabWriter.Flush()
+}
+
+// writeYAML 先转换为json通用结构，再按YAML块格式输出
+func writeYAML(writer io.Writer, results []*agentResult) error {
+	byteVal, byteErr := json.Marshal(results)
+	if byteErr != nil {
+		return byteErr
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(byteVal))
+	decoder.UseNumber()
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return err
+	}
+
+	buffer := &bytes.Buffer{}
+	encodeYAML(buffer, node, 0)
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func encodeYAML(buffer *bytes.Buffer, node interface{}, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	switch val := node.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			buffer.WriteString(prefix + "{}\n")
+			return
+		}
+
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			buffer.WriteString(prefix + quoteYAML(key) + ":")
+			writeYAMLChild(buffer, val[key], indent)
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			buffer.WriteString(prefix + "[]\n")
+			return
+		}
+
+		for _, item := range val {
+			// 映射元素的第一个键与"- "写在同一行
+			if mapVal, ok := item.(map[string]interface{}); ok && len(mapVal) > 0 {
+				itemBuffer := &bytes.Buffer{}
+				encodeYAML(itemBuffer, mapVal, indent+1)
+				buffer.WriteString(prefix + "- ")
+				buffer.Write(itemBuffer.Bytes()[len(prefix)+2:])
+				continue
+			}
+
+			buffer.WriteString(prefix + "-")
+			writeYAMLChild(buffer, item, indent)
+		}
+	default:
+		buffer.WriteString(prefix + scalarYAML(val) + "\n")
+	}
+}
+
+func writeYAMLChild(buffer *bytes.Buffer, node interface{}, indent int) {
+	switch val := node.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			buffer.WriteString(" {}\n")
+			return
+		}
+		buffer.WriteString("\n")
+		encodeYAML(buffer, val, indent+1)
+	case []interface{}:
+		if len(val) == 0 {
+			buffer.WriteString(" []\n")
+			return
+		}
+		buffer.WriteString("\n")
+		encodeYAML(buffer, val, indent+1)
+	default:
+		buffer.WriteString(" " + scalarYAML(val) + "\n")
+	}
+}
+
+func scalarYAML(node interface{}) string {
+	switch val := node.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case json.Number:
+		return val.String()
+	case string:
+		return quoteYAML(val)
+	}
+
+	return quoteYAML(fmt.Sprintf("%v", node))
+}
+
+// quoteYAML 可能被解析为其他类型或包含特殊字符的字符串使用双引号
+func quoteYAML(val string) string {
+	needQuote := val == "" ||
+		strings.TrimSpace(val) != val ||
+		strings.ContainsAny(val, ":#{}[],&*!|>'\"%@`\n\t\\") ||
+		strings.HasPrefix(val, "-") ||
+		strings.HasPrefix(val, "?")
+	if !needQuote {
+		switch strings.ToLower(val) {
+		case "true", "false", "yes", "no", "on", "off", "null", "~":
+			needQuote = true
+		default:
+			_, floatErr := strconv.ParseFloat(val, 64)
+			needQuote = floatErr == nil
+		}
+	}
+
+	if needQuote {
+		return strconv.Quote(val)
+	}
+
+	return val
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/client"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+var shellTTY = false
+var shellCommand = []string{}
+
+func parseShell(args []string) error {
+	shellTTY = isTerminal(int(os.Stdin.Fd()))
+
+	flagSet := newFlagSet("shell")
+	flagSet.StringVar(&serviceName, "service", serviceName, "guarded service name")
+	flagSet.BoolVar(&shellTTY, "tty", shellTTY, "allocate a pseudo terminal, defaults to true when stdin is a terminal")
+	flagSet.Var(&execEnv, "env", "environment variable KEY=VALUE, can be repeated")
+	flagSet.StringVar(&execWorkDir, "workdir", execWorkDir, "working directory of the command")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if serviceName == "" {
+		return fmt.Errorf("-service is required")
+	}
+
+	shellCommand = flagSet.Args()
+	return nil
+}
+
+// runShell 打开交互式会话，TTY时本地终端切换为raw模式并同步窗口大小，命令非零退出时返回错误
+func runShell(ctx context.Context, clnt *client.Client) (interface{}, *cd.Result) {
+	if agentCount > 1 {
+		return nil, cd.NewError(cd.IllegalParam, "shell supports only one agent")
+	}
+
+	stdinFd, stdoutFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	param := &common.TerminalParam{
+		Service: serviceName,
+		Command: shellCommand,
+		Env:     execEnv,
+		WorkDir: execWorkDir,
+		TTY:     shellTTY,
+	}
+	if shellTTY {
+		if rows, cols, sizeErr := terminalSize(stdoutFd); sizeErr == nil {
+			param.Rows, param.Cols = rows, cols
+		}
+	}
+
+	terminalPtr, err := clnt.OpenTerminal(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	if shellTTY && isTerminal(stdinFd) {
+		state, rawErr := makeRaw(stdinFd)
+		if rawErr != nil {
+			terminalPtr.Close()
+			return nil, cd.NewError(cd.UnExpected, fmt.Sprintf("set raw terminal failed, %s", rawErr.Error()))
+		}
+		defer restoreTerminal(stdinFd, state)
+
+		sigChan := make(chan os.Signal, 1)
+		notifyResize(sigChan)
+		defer signal.Stop(sigChan)
+		go func() {
+			for range sigChan {
+				if rows, cols, sizeErr := terminalSize(stdoutFd); sizeErr == nil {
+					_ = terminalPtr.Resize(rows, cols)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		if _, copyErr := io.Copy(terminalPtr, os.Stdin); copyErr == nil {
+			_ = terminalPtr.CloseWrite()
+		}
+	}()
+
+	exitCode, err := terminalPtr.Wait(os.Stdout)
+	if err == nil && exitCode != 0 {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("command exit code %d", exitCode))
+	}
+	return nil, err
+}
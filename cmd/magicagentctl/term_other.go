@@ -0,0 +1,31 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+type terminalState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, fmt.Errorf("raw terminal not supported on %s", runtime.GOOS)
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return nil
+}
+
+func terminalSize(fd int) (rows, cols uint16, err error) {
+	err = fmt.Errorf("terminal size not supported on %s", runtime.GOOS)
+	return
+}
+
+func notifyResize(sigChan chan os.Signal) {
+}
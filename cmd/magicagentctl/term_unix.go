@@ -0,0 +1,75 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// terminalState 进入raw模式前的终端设置
+type terminalState struct {
+	termios syscall.Termios
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	_, _, errNo := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+	if errNo != 0 {
+		return errNo
+	}
+
+	return nil
+}
+
+func isTerminal(fd int) bool {
+	termios := syscall.Termios{}
+	return ioctl(fd, ioctlReadTermios, unsafe.Pointer(&termios)) == nil
+}
+
+// makeRaw 将终端切换为raw模式，输入不回显、不做行缓冲，按键直接发送给远端
+func makeRaw(fd int) (ret *terminalState, err error) {
+	state := &terminalState{}
+	err = ioctl(fd, ioctlReadTermios, unsafe.Pointer(&state.termios))
+	if err != nil {
+		return
+	}
+
+	termios := state.termios
+	termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	termios.Oflag &^= syscall.OPOST
+	termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	termios.Cflag &^= syscall.CSIZE | syscall.PARENB
+	termios.Cflag |= syscall.CS8
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	err = ioctl(fd, ioctlWriteTermios, unsafe.Pointer(&termios))
+	if err != nil {
+		return
+	}
+
+	ret = state
+	return
+}
+
+func restoreTerminal(fd int, state *terminalState) error {
+	return ioctl(fd, ioctlWriteTermios, unsafe.Pointer(&state.termios))
+}
+
+func terminalSize(fd int) (rows, cols uint16, err error) {
+	size := struct {
+		Rows   uint16
+		Cols   uint16
+		Width  uint16
+		Height uint16
+	}{}
+	err = ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	rows, cols = size.Rows, size.Cols
+	return
+}
+
+// notifyResize 终端窗口大小变化时通知
+func notifyResize(sigChan chan os.Signal) {
+	signal.Notify(sigChan, syscall.SIGWINCH)
+}
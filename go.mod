@@ -3,15 +3,16 @@ module github.com/muidea/magicAgent
 go 1.19
 
 require (
+	github.com/go-playground/validator/v10 v10.11.2
 	github.com/muidea/magicCommon v1.3.67
 	github.com/muidea/magicEngine v1.3.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/cihub/seelog v0.0.0-20170130134532-f561c5e57575 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.11.2 // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/leodido/go-urn v1.2.1 // indirect
 	golang.org/x/crypto v0.6.0 // indirect
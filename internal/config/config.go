@@ -2,60 +2,168 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
+	"path"
+	"strings"
 
-	fu "github.com/muidea/magicCommon/foundation/util"
+	"gopkg.in/yaml.v3"
 )
 
+// defaultConfig 默认配置，优先级最低，依次被配置文件、环境变量、命令行参数覆盖
+// 未提供配置文件时守护本机的mariadb001，与早期版本保持一致，密码从环境变量MARIADB_ROOT_PASSWORD读取
 var defaultConfig = `
 {
-    "localHost": "192.168.18.204",
-    "clusterHosts": [
-        "192.168.18.205"
+    "listenPort": "8080",
+    "endpointName": "magicAgent",
+    "localHost": "127.0.0.1",
+    "clusterHosts": [],
+    "guards": [
+        {
+            "name": "mariadb001",
+            "type": "mariadb",
+            "password": "env:MARIADB_ROOT_PASSWORD"
+        }
     ],
-    "guards": "mariadb001",
-    "rayLink": {
-        "serverUrl": "192.168.18.204",
-        "account": "192.168.18.205",
-        "password": "2023-11-15 18:20:00"
-    },
-    "email": {
-        "serverUrl": "192.168.18.204",
-        "account": "192.168.18.205",
-        "password": "2023-11-15 18:20:00"
-    }
+    "timeOut": 30,
+    "alarmLogLines": 100
 }`
 
 var currentWorkPath string
 var configItem *CfgItem
 var enableTrace bool
 
-const cfgPath = "/var/app/config/cfg.json"
+// DefaultConfigFile 默认配置文件路径
+const DefaultConfigFile = "/var/app/config/cfg.json"
+
+// 已知的守护类型
+const (
+	MariadbGuard  = "mariadb"
+	RedisGuard    = "redis"
+	PostgresGuard = "postgres"
+	ProbeGuard    = "probe"
+)
+
+// 探测类型，command在被守护容器内执行
+const (
+	TCPProbe     = "tcp"
+	HTTPProbe    = "http"
+	TLSProbe     = "tls"
+	CommandProbe = "command"
+)
+
+// mariadb守护对象的集群方式，redis守护对象为sentinel时守护Sentinel进程
+const (
+	GaleraMode      = "galera"
+	ReplicationMode = "replication"
+	SentinelMode    = "sentinel"
+)
 
 func init() {
-	err := LoadConfig(cfgPath)
-	if err == nil {
-		return
-	}
+	configItem = newDefaultConfig()
+	currentWorkPath, _ = os.Getwd()
+}
 
+func newDefaultConfig() *CfgItem {
 	cfg := &CfgItem{}
 	_ = json.Unmarshal([]byte(defaultConfig), cfg)
-	configItem = cfg
-	currentWorkPath, _ = os.Getwd()
+	return cfg
 }
 
-func LoadConfig(cfgFile string) (err error) {
-	if cfgFile == "" {
+// Override 用于命令行参数覆盖配置
+type Override func(cfg *CfgItem)
+
+// LoadConfig 加载并校验配置，校验失败时不会替换当前配置
+func LoadConfig(cfgFile string, overrides ...Override) (err error) {
+	cfg, cfgErr := ParseConfig(cfgFile, overrides...)
+	if cfgErr != nil {
+		err = cfgErr
 		return
 	}
 
-	cfg := &CfgItem{}
-	err = fu.LoadConfig(cfgFile, cfg)
+	configItem = cfg
+	return
+}
+
+// ParseConfig 按照 命令行参数 > 环境变量 > 配置文件 > 默认值 的优先级合并配置并校验，
+// 返回的错误会包含全部校验问题，cfgFile为空时不加载配置文件
+func ParseConfig(cfgFile string, overrides ...Override) (ret *CfgItem, err error) {
+	cfg := newDefaultConfig()
+	if cfgFile != "" {
+		err = loadFile(cfgFile, cfg)
+		if err != nil {
+			return
+		}
+	}
+
+	problems := applyEnv(cfg)
+	for _, val := range overrides {
+		val(cfg)
+	}
+
+	problems = append(problems, resolveSecrets(cfg)...)
+	err = Validate(cfg)
+	if len(problems) > 0 {
+		if validateErr, ok := err.(*ValidateError); ok {
+			problems = append(problems, validateErr.Problems...)
+		}
+
+		err = &ValidateError{Problems: problems}
+	}
 	if err != nil {
 		return
 	}
 
-	configItem = cfg
+	ret = cfg
+	return
+}
+
+// ReadConfig 合并默认值、配置文件与环境变量，不解析密文也不校验，
+// 用于命令行工具读取集群地址等信息
+func ReadConfig(cfgFile string) (ret *CfgItem, err error) {
+	cfg := newDefaultConfig()
+	if cfgFile != "" {
+		err = loadFile(cfgFile, cfg)
+		if err != nil {
+			return
+		}
+	}
+
+	problems := applyEnv(cfg)
+	if len(problems) > 0 {
+		err = &ValidateError{Problems: problems}
+		return
+	}
+
+	ret = cfg
+	return
+}
+
+// loadFile 根据文件扩展名选择YAML或JSON格式，无法识别时根据内容判断
+func loadFile(cfgFile string, cfg *CfgItem) (err error) {
+	byteContent, byteErr := os.ReadFile(cfgFile)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	switch strings.ToLower(path.Ext(cfgFile)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(byteContent, cfg)
+	case ".json":
+		err = json.Unmarshal(byteContent, cfg)
+	default:
+		if strings.HasPrefix(strings.TrimSpace(string(byteContent)), "{") {
+			err = json.Unmarshal(byteContent, cfg)
+		} else {
+			err = yaml.Unmarshal(byteContent, cfg)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("parse config file %s failed, %s", cfgFile, err.Error())
+	}
 	return
 }
 
@@ -72,6 +180,14 @@ func EnableTrace() bool {
 	return enableTrace
 }
 
+func GetListenPort() string {
+	return configItem.ListenPort
+}
+
+func GetEndpointName() string {
+	return configItem.EndpointName
+}
+
 func GetLocalHost() string {
 	return configItem.LocalHost
 }
@@ -80,14 +196,54 @@ func GetClusterHosts() []string {
 	return configItem.ClusterHosts
 }
 
-func GetGuards() string {
+// AgentAddress 节点的agent地址，未指定端口时使用本节点的监听端口
+func AgentAddress(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, GetListenPort())
+	}
+
+	return host
+}
+
+func GetGuards() []*GuardItem {
 	return configItem.Guards
 }
 
+// GetGuard 根据名称查找守护对象
+func GetGuard(name string) *GuardItem {
+	for _, val := range configItem.Guards {
+		if val.Name == name {
+			return val
+		}
+	}
+
+	return nil
+}
+
 func GetTimeOut() int {
 	return configItem.TimeOut
 }
 
+// GetAlarmLogLines 重启告警附带的服务日志行数，0表示不附带
+func GetAlarmLogLines() int {
+	return configItem.AlarmLogLines
+}
+
+// GetAdminToken 管理员令牌，为空时禁用需要管理员权限的接口
+func GetAdminToken() string {
+	return configItem.AdminToken
+}
+
+// GetHost 主机资源检查配置，未配置时使用默认阈值检查根分区
+func GetHost() *HostItem {
+	return configItem.Host
+}
+
+// GetBackup 定时备份配置，为空时不执行定时备份，手动备份使用默认参数
+func GetBackup() *BackupItem {
+	return configItem.Backup
+}
+
 func GetRayLinkInfo() *ServerInfo {
 	return configItem.RayLink
 }
@@ -97,17 +253,427 @@ func GetEMailInfo() *ServerInfo {
 }
 
 type ServerInfo struct {
-	ServerUrl string `json:"serverUrl"`
-	Account   string `json:"account"`
-	Password  string `json:"password"`
-	Receiver  string `json:"receiver"`
+	ServerUrl string `json:"serverUrl" yaml:"serverUrl" validate:"required"`
+	Account   string `json:"account" yaml:"account" validate:"required"`
+	Password  string `json:"password" yaml:"password" secret:"true"`
+	Receiver  string `json:"receiver" yaml:"receiver" validate:"required"`
+}
+
+// DefaultBackupPath 默认备份目录，与helm chart挂载的宿主机目录一致
+const DefaultBackupPath = "/backup/HACluster/tmp"
+
+// 备份方式
+const (
+	Mariabackup = "mariabackup"
+	MariadbDump = "mariadb-dump"
+)
+
+// BackupItem 定时备份，Schedule为5段cron表达式(分 时 日 月 周)，Service为空时备份第一个mariadb守护对象，
+// Path为备份目录，KeepCount、KeepDays分别为保留的备份数量和天数，0表示不按该规则清理
+type BackupItem struct {
+	Schedule  string `json:"schedule" yaml:"schedule" validate:"required,cron"`
+	Service   string `json:"service,omitempty" yaml:"service,omitempty"`
+	Method    string `json:"method,omitempty" yaml:"method,omitempty" validate:"omitempty,oneof=mariabackup mariadb-dump"`
+	Path      string `json:"path,omitempty" yaml:"path,omitempty"`
+	KeepCount int    `json:"keepCount,omitempty" yaml:"keepCount,omitempty" validate:"gte=0"`
+	KeepDays  int    `json:"keepDays,omitempty" yaml:"keepDays,omitempty" validate:"gte=0"`
+}
+
+// GetService 未指定时使用第一个mariadb守护对象
+func (s *BackupItem) GetService() string {
+	if s != nil && s.Service != "" {
+		return s.Service
+	}
+
+	for _, val := range GetGuards() {
+		if val.Type == MariadbGuard {
+			return val.Name
+		}
+	}
+
+	return ""
+}
+
+func (s *BackupItem) GetMethod() string {
+	if s != nil && s.Method != "" {
+		return s.Method
+	}
+
+	return Mariabackup
+}
+
+func (s *BackupItem) GetPath() string {
+	if s != nil && s.Path != "" {
+		return s.Path
+	}
+
+	return DefaultBackupPath
+}
+
+// HostItem 主机资源检查，Mounts为需要检查的挂载点，未配置时检查根分区
+// MemoryUsage、SwapUsage为使用率告警阈值(百分比)，LoadRatio为1分钟负载与CPU核数之比的告警阈值
+// FullUsage为数据卷空间或inode使用率达到该值时视为已满，此时不再重启守护对象，只告警
+type HostItem struct {
+	Mounts      []*MountItem `json:"mounts,omitempty" yaml:"mounts,omitempty" validate:"omitempty,dive,required"`
+	MemoryUsage int          `json:"memoryUsage,omitempty" yaml:"memoryUsage,omitempty" validate:"gte=0,lte=100"`
+	SwapUsage   int          `json:"swapUsage,omitempty" yaml:"swapUsage,omitempty" validate:"gte=0,lte=100"`
+	LoadRatio   float64      `json:"loadRatio,omitempty" yaml:"loadRatio,omitempty" validate:"gte=0"`
+	FullUsage   int          `json:"fullUsage,omitempty" yaml:"fullUsage,omitempty" validate:"gte=0,lte=100"`
+}
+
+// MountItem 挂载点，DiskUsage、InodeUsage为空间和inode使用率告警阈值(百分比)
+type MountItem struct {
+	Path       string `json:"path" yaml:"path" validate:"required"`
+	DiskUsage  int    `json:"diskUsage,omitempty" yaml:"diskUsage,omitempty" validate:"gte=0,lte=100"`
+	InodeUsage int    `json:"inodeUsage,omitempty" yaml:"inodeUsage,omitempty" validate:"gte=0,lte=100"`
+}
+
+// 未配置时的主机资源告警阈值
+const (
+	defaultDiskUsage   = 90
+	defaultInodeUsage  = 90
+	defaultMemoryUsage = 95
+	defaultSwapUsage   = 80
+	defaultLoadRatio   = 2.0
+	defaultFullUsage   = 98
+)
+
+// GetMounts 需要检查的挂载点，未配置时为根分区
+func (s *HostItem) GetMounts() []*MountItem {
+	if s == nil || len(s.Mounts) == 0 {
+		return []*MountItem{{Path: "/"}}
+	}
+
+	return s.Mounts
+}
+
+func (s *HostItem) GetMemoryUsage() int {
+	if s == nil || s.MemoryUsage <= 0 {
+		return defaultMemoryUsage
+	}
+
+	return s.MemoryUsage
+}
+
+func (s *HostItem) GetSwapUsage() int {
+	if s == nil || s.SwapUsage <= 0 {
+		return defaultSwapUsage
+	}
+
+	return s.SwapUsage
+}
+
+func (s *HostItem) GetLoadRatio() float64 {
+	if s == nil || s.LoadRatio <= 0 {
+		return defaultLoadRatio
+	}
+
+	return s.LoadRatio
+}
+
+func (s *HostItem) GetFullUsage() int {
+	if s == nil || s.FullUsage <= 0 {
+		return defaultFullUsage
+	}
+
+	return s.FullUsage
+}
+
+func (s *MountItem) GetDiskUsage() int {
+	if s.DiskUsage <= 0 {
+		return defaultDiskUsage
+	}
+
+	return s.DiskUsage
+}
+
+func (s *MountItem) GetInodeUsage() int {
+	if s.InodeUsage <= 0 {
+		return defaultInodeUsage
+	}
+
+	return s.InodeUsage
+}
+
+// GuardItem 守护对象，Name为被守护的服务名，Type为守护类型
+// Account、Password为访问被守护服务的账号信息
+// Runtime为服务所在的运行时，默认docker，Endpoint、Namespace为运行时地址和命名空间
+// Selector为kubernetes运行时下定位pod的标签选择器
+// Mode为mariadb集群方式，默认galera，replication为主从异步复制，MaxReplicaLag为复制延迟告警阈值(秒)
+// AutoFailover为true时主库持续FailoverTimeOut秒不可用后自动切换，FencingHook为切换前隔离原主库的本地命令
+// SlowQueryTime、LongTrxTime为慢查询和长事务告警阈值(秒)，AllowKill为true时允许通过接口结束线程
+// Address为redis服务地址，默认127.0.0.1:6379，Sentinel为127.0.0.1:26379，Account非空时使用ACL用户认证
+// mariadb守护对象的Address为本节点数据库地址，默认127.0.0.1:3306，故障切换后其他从库使用其端口连接新主库
+// postgres守护对象的Address默认127.0.0.1:5432，Database为连接的数据库，默认postgres
+// probe守护对象通过Probes探测没有专用模块的服务，任一探测失败按重启策略计数
+type GuardItem struct {
+	Name      string `json:"name" yaml:"name" validate:"required"`
+	Type      string `json:"type" yaml:"type" validate:"required,oneof=mariadb redis postgres probe"`
+	Account   string `json:"account" yaml:"account"`
+	Password  string `json:"password" yaml:"password" secret:"true"`
+	Runtime   string `json:"runtime,omitempty" yaml:"runtime,omitempty" validate:"omitempty,oneof=docker podman nerdctl systemd kubernetes"`
+	Endpoint  string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Selector  string `json:"selector,omitempty" yaml:"selector,omitempty"`
+	DataDir   string `json:"dataDir,omitempty" yaml:"dataDir,omitempty"`
+	// DataVolume 数据目录在主机上的路径，未配置时根据容器的挂载信息确定
+	DataVolume string `json:"dataVolume,omitempty" yaml:"dataVolume,omitempty"`
+	// AutoBootstrap 集群不存在Primary组件且本节点被选为bootstrap节点时自动执行pc.bootstrap
+	AutoBootstrap   bool         `json:"autoBootstrap,omitempty" yaml:"autoBootstrap,omitempty"`
+	Mode            string       `json:"mode,omitempty" yaml:"mode,omitempty" validate:"omitempty,oneof=galera replication sentinel"`
+	MaxReplicaLag   int          `json:"maxReplicaLag,omitempty" yaml:"maxReplicaLag,omitempty" validate:"gte=0"`
+	AutoFailover    bool         `json:"autoFailover,omitempty" yaml:"autoFailover,omitempty"`
+	FailoverTimeOut int          `json:"failoverTimeOut,omitempty" yaml:"failoverTimeOut,omitempty" validate:"gte=0"`
+	FencingHook     string       `json:"fencingHook,omitempty" yaml:"fencingHook,omitempty"`
+	SlowQueryTime   int          `json:"slowQueryTime,omitempty" yaml:"slowQueryTime,omitempty" validate:"gte=0"`
+	LongTrxTime     int          `json:"longTrxTime,omitempty" yaml:"longTrxTime,omitempty" validate:"gte=0"`
+	AllowKill       bool         `json:"allowKill,omitempty" yaml:"allowKill,omitempty"`
+	Address         string       `json:"address,omitempty" yaml:"address,omitempty" validate:"omitempty,hostname_port"`
+	Database        string       `json:"database,omitempty" yaml:"database,omitempty"`
+	Probes          []*ProbeItem `json:"probes,omitempty" yaml:"probes,omitempty" validate:"omitempty,dive"`
+}
+
+// 未配置账号时使用的mariadb、postgres账号
+const (
+	defaultAccount         = "root"
+	defaultPostgresAccount = "postgres"
+)
+
+// GetAccount 访问被守护服务的账号，未配置时mariadb为root，postgres为postgres
+func (s *GuardItem) GetAccount() string {
+	if s.Account == "" {
+		if s.Type == PostgresGuard {
+			return defaultPostgresAccount
+		}
+		return defaultAccount
+	}
+
+	return s.Account
+}
+
+// 未配置时各守护类型在容器内的数据目录，probe守护对象没有默认数据目录
+var defaultDataDir = map[string]string{
+	MariadbGuard:  "/var/lib/mysql",
+	PostgresGuard: "/var/lib/postgresql/data",
+	RedisGuard:    "/data",
+}
+
+// GetDataDir 被守护服务在容器内的数据目录，未配置时mariadb为/var/lib/mysql
+func (s *GuardItem) GetDataDir() string {
+	if s.DataDir == "" {
+		return defaultDataDir[s.Type]
+	}
+
+	return s.DataDir
+}
+
+// IsReplication 是否为主从异步复制方式
+func (s *GuardItem) IsReplication() bool {
+	return s.Mode == ReplicationMode
+}
+
+// defaultMaxReplicaLag 未配置时的复制延迟告警阈值
+const defaultMaxReplicaLag = 300
+
+// GetMaxReplicaLag 复制延迟告警阈值(秒)，未配置时为300
+func (s *GuardItem) GetMaxReplicaLag() int {
+	if s.MaxReplicaLag <= 0 {
+		return defaultMaxReplicaLag
+	}
+
+	return s.MaxReplicaLag
+}
+
+// defaultFailoverTimeOut 未配置时主库不可用多久后切换(秒)
+const defaultFailoverTimeOut = 60
+
+func (s *GuardItem) GetFailoverTimeOut() int {
+	if s.FailoverTimeOut <= 0 {
+		return defaultFailoverTimeOut
+	}
+
+	return s.FailoverTimeOut
+}
+
+// 未配置时的慢查询、长事务告警阈值(秒)
+const (
+	defaultSlowQueryTime = 60
+	defaultLongTrxTime   = 300
+)
+
+func (s *GuardItem) GetSlowQueryTime() int {
+	if s.SlowQueryTime <= 0 {
+		return defaultSlowQueryTime
+	}
+
+	return s.SlowQueryTime
+}
+
+func (s *GuardItem) GetLongTrxTime() int {
+	if s.LongTrxTime <= 0 {
+		return defaultLongTrxTime
+	}
+
+	return s.LongTrxTime
+}
+
+// IsSentinel redis守护对象是否为Sentinel进程
+func (s *GuardItem) IsSentinel() bool {
+	return s.Type == RedisGuard && s.Mode == SentinelMode
+}
+
+// GetAddress mariadb、redis、postgres服务地址
+func (s *GuardItem) GetAddress() string {
+	if s.Address != "" {
+		return s.Address
+	}
+	if s.Type == MariadbGuard {
+		return "127.0.0.1:3306"
+	}
+	if s.Type == PostgresGuard {
+		return "127.0.0.1:5432"
+	}
+	if s.IsSentinel() {
+		return "127.0.0.1:26379"
+	}
+
+	return "127.0.0.1:6379"
+}
+
+// defaultDatabase 未配置时postgres连接的数据库
+const defaultDatabase = "postgres"
+
+// GetDatabase postgres连接的数据库，未配置时为postgres
+func (s *GuardItem) GetDatabase() string {
+	if s.Database == "" {
+		return defaultDatabase
+	}
+
+	return s.Database
+}
+
+// ProbeItem 探测项，tcp、tls探测Address，http探测URL，command在容器内执行Command，退出码非0视为失败
+// ExpectStatus为http期望的状态码，默认200，BodyRegex非空时响应内容须匹配，Insecure为true时不校验证书
+// ExpireDays为证书剩余有效天数告警阈值，默认14，只告警不重启，TimeOut为单次探测超时(秒)，默认5
+type ProbeItem struct {
+	Type         string `json:"type" yaml:"type" validate:"required,oneof=tcp http tls command"`
+	Address      string `json:"address,omitempty" yaml:"address,omitempty" validate:"omitempty,hostname_port"`
+	URL          string `json:"url,omitempty" yaml:"url,omitempty" validate:"omitempty,url"`
+	ExpectStatus int    `json:"expectStatus,omitempty" yaml:"expectStatus,omitempty" validate:"omitempty,gte=100,lte=599"`
+	BodyRegex    string `json:"bodyRegex,omitempty" yaml:"bodyRegex,omitempty" validate:"omitempty,regexp"`
+	Insecure     bool   `json:"insecure,omitempty" yaml:"insecure,omitempty"`
+	Command      string `json:"command,omitempty" yaml:"command,omitempty"`
+	ExpireDays   int    `json:"expireDays,omitempty" yaml:"expireDays,omitempty" validate:"gte=0"`
+	TimeOut      int    `json:"timeOut,omitempty" yaml:"timeOut,omitempty" validate:"gte=0"`
+}
+
+// 未配置时的探测参数
+const (
+	defaultExpectStatus = 200
+	defaultExpireDays   = 14
+	defaultProbeTimeOut = 5
+)
+
+func (s *ProbeItem) GetExpectStatus() int {
+	if s.ExpectStatus <= 0 {
+		return defaultExpectStatus
+	}
+
+	return s.ExpectStatus
+}
+
+func (s *ProbeItem) GetExpireDays() int {
+	if s.ExpireDays <= 0 {
+		return defaultExpireDays
+	}
+
+	return s.ExpireDays
+}
+
+func (s *ProbeItem) GetTimeOut() int {
+	if s.TimeOut <= 0 {
+		return defaultProbeTimeOut
+	}
+
+	return s.TimeOut
+}
+
+// Target 探测目标，用于展示和告警
+func (s *ProbeItem) Target() string {
+	switch s.Type {
+	case HTTPProbe:
+		return s.URL
+	case CommandProbe:
+		return s.Command
+	}
+
+	return s.Address
+}
+
+// GuardList 守护对象列表
+type GuardList []*GuardItem
+
+// legacyGuards 解析旧格式，旧格式为逗号分隔的mariadb服务名
+func legacyGuards(strVal string) GuardList {
+	guards := GuardList{}
+	for _, val := range strings.Split(strVal, ",") {
+		val = strings.TrimSpace(val)
+		if val == "" {
+			continue
+		}
+
+		guards = append(guards, &GuardItem{Name: val, Type: MariadbGuard})
+	}
+
+	return guards
+}
+
+// UnmarshalJSON 兼容旧格式，旧格式为逗号分隔的mariadb服务名
+func (s *GuardList) UnmarshalJSON(data []byte) error {
+	var strVal string
+	if json.Unmarshal(data, &strVal) == nil {
+		*s = legacyGuards(strVal)
+		return nil
+	}
+
+	var items []*GuardItem
+	err := json.Unmarshal(data, &items)
+	if err != nil {
+		return err
+	}
+
+	*s = items
+	return nil
+}
+
+// UnmarshalYAML 与UnmarshalJSON一致，兼容逗号分隔的旧格式
+func (s *GuardList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = legacyGuards(value.Value)
+		return nil
+	}
+
+	var items []*GuardItem
+	err := value.Decode(&items)
+	if err != nil {
+		return err
+	}
+
+	*s = items
+	return nil
 }
 
 type CfgItem struct {
-	LocalHost    string      `json:"localHost"`
-	ClusterHosts []string    `json:"clusterHosts"`
-	Guards       string      `json:"guards"`
-	TimeOut      int         `json:"timeOut"`
-	RayLink      *ServerInfo `json:"rayLink"`
-	EMail        *ServerInfo `json:"email"`
+	ListenPort    string      `json:"listenPort" yaml:"listenPort" validate:"required,numeric"`
+	EndpointName  string      `json:"endpointName" yaml:"endpointName" validate:"required"`
+	LocalHost     string      `json:"localHost" yaml:"localHost" validate:"required,ip|hostname"`
+	ClusterHosts  []string    `json:"clusterHosts" yaml:"clusterHosts" validate:"dive,ip|hostname|hostname_port"`
+	Guards        GuardList   `json:"guards" yaml:"guards" validate:"required,min=1,dive,required"`
+	TimeOut       int         `json:"timeOut" yaml:"timeOut" validate:"gt=0"`
+	AlarmLogLines int         `json:"alarmLogLines" yaml:"alarmLogLines" validate:"gte=0"`
+	RayLink       *ServerInfo `json:"rayLink" yaml:"rayLink"`
+	EMail         *ServerInfo `json:"email" yaml:"email"`
+	AdminToken    string      `json:"adminToken,omitempty" yaml:"adminToken,omitempty" secret:"true"`
+	Backup        *BackupItem `json:"backup,omitempty" yaml:"backup,omitempty"`
+	Host          *HostItem   `json:"host,omitempty" yaml:"host,omitempty"`
 }
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDefaultConfig(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	cfg, err := ParseConfig("")
+	if err != nil {
+		t.Fatalf("parse default config failed, %v", err)
+	}
+	if len(cfg.Guards) != 1 || cfg.Guards[0].Name != "mariadb001" || cfg.Guards[0].Type != MariadbGuard || cfg.Guards[0].Password != "secret" {
+		t.Fatalf("unexpected default guards %+v", cfg.Guards)
+	}
+	if cfg.ListenPort != "8080" || cfg.TimeOut != 30 {
+		t.Fatalf("unexpected default config %+v", cfg)
+	}
+
+	os.Unsetenv("MARIADB_ROOT_PASSWORD")
+	if _, err = ParseConfig(""); err == nil || !strings.Contains(err.Error(), "environment variable MARIADB_ROOT_PASSWORD not set") {
+		t.Errorf("err %v, expect password environment error", err)
+	}
+}
+
+// TestLegacyGuardsRequirePassword 旧格式的守护对象没有密码，校验失败而不是使用空密码
+func TestLegacyGuardsRequirePassword(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "cfg.json")
+	if err := os.WriteFile(cfgFile, []byte(`{"guards": "mariadb001"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseConfig(cfgFile); err == nil || !strings.Contains(err.Error(), "guards[0].password is required") {
+		t.Errorf("err %v, expect password required", err)
+	}
+}
+
+func TestConfigFileReplacesDefaultGuards(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "cfg.json")
+	content := `{"guards": [{"name": "redis001", "type": "redis"}]}`
+	if err := os.WriteFile(cfgFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseConfig(cfgFile)
+	if err != nil {
+		t.Fatalf("parse config failed, %v", err)
+	}
+	if len(cfg.Guards) != 1 || cfg.Guards[0].Name != "redis001" {
+		t.Fatalf("unexpected guards %+v", cfg.Guards)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		expect  *GuardItem
+		err     string
+	}{
+		{
+			name:    "yaml",
+			file:    "cfg.yaml",
+			content: "# agent\nguards:\n  - name: mariadb001\n    type: mariadb\n    password: 123456 # unquoted\n",
+			expect:  &GuardItem{Name: "mariadb001", Type: MariadbGuard, Password: "123456"},
+		},
+		{
+			name:    "yaml legacy guards",
+			file:    "cfg.yml",
+			content: "guards: mariadb001, mariadb002\n",
+			expect:  &GuardItem{Name: "mariadb001", Type: MariadbGuard},
+		},
+		{
+			name:    "json without extension",
+			file:    "cfg",
+			content: `{"guards": [{"name": "redis001", "type": "redis"}]}`,
+			expect:  &GuardItem{Name: "redis001", Type: RedisGuard},
+		},
+		{
+			name:    "yaml without extension",
+			file:    "cfg",
+			content: "guards:\n- {name: redis001, type: redis}\n",
+			expect:  &GuardItem{Name: "redis001", Type: RedisGuard},
+		},
+		{
+			name:    "bad yaml",
+			file:    "cfg.yaml",
+			content: "guards:\n  - name: a\n   type: b\n",
+			err:     "parse config file",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			cfgFile := filepath.Join(t.TempDir(), val.file)
+			if err := os.WriteFile(cfgFile, []byte(val.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &CfgItem{}
+			err := loadFile(cfgFile, cfg)
+			if val.err != "" {
+				if err == nil || !strings.Contains(err.Error(), val.err) {
+					t.Errorf("err %v, expect %q", err, val.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load config file failed, %v", err)
+			}
+			if len(cfg.Guards) == 0 || cfg.Guards[0].Name != val.expect.Name || cfg.Guards[0].Type != val.expect.Type || cfg.Guards[0].Password != val.expect.Password {
+				t.Errorf("unexpected guards %+v, expect %+v", cfg.Guards, val.expect)
+			}
+		})
+	}
+}
+
+func TestGuardAddress(t *testing.T) {
+	cases := []struct {
+		name   string
+		guard  *GuardItem
+		expect string
+	}{
+		{name: "mariadb", guard: &GuardItem{Type: MariadbGuard}, expect: "127.0.0.1:3306"},
+		{name: "postgres", guard: &GuardItem{Type: PostgresGuard}, expect: "127.0.0.1:5432"},
+		{name: "redis", guard: &GuardItem{Type: RedisGuard}, expect: "127.0.0.1:6379"},
+		{name: "sentinel", guard: &GuardItem{Type: RedisGuard, Mode: SentinelMode}, expect: "127.0.0.1:26379"},
+		{name: "configured", guard: &GuardItem{Type: MariadbGuard, Address: "10.0.0.1:3307"}, expect: "10.0.0.1:3307"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := val.guard.GetAddress(); ret != val.expect {
+				t.Errorf("address %s, expect %s", ret, val.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 环境变量前缀，字段按json路径转换为大写并以下划线连接，例如
+//
+//	MAGICAGENT_LISTENPORT=8080
+//	MAGICAGENT_RAYLINK_PASSWORD=env:RAYLINK_PASSWORD
+//	MAGICAGENT_CLUSTERHOSTS=192.168.1.2,192.168.1.3
+//	MAGICAGENT_GUARDS_0_PASSWORD=file:/run/secrets/mariadb
+const EnvPrefix = "MAGICAGENT_"
+
+// applyEnv 使用环境变量覆盖配置
+func applyEnv(cfg *CfgItem) (problems []string) {
+	envs := map[string]string{}
+	for _, val := range os.Environ() {
+		items := strings.SplitN(val, "=", 2)
+		if len(items) != 2 || !strings.HasPrefix(items[0], EnvPrefix) {
+			continue
+		}
+
+		envs[strings.ToUpper(items[0])] = items[1]
+	}
+	if len(envs) == 0 {
+		return
+	}
+
+	return applyEnvValue(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvPrefix, "_"), envs)
+}
+
+func hasEnvPrefix(envs map[string]string, prefix string) bool {
+	for key := range envs {
+		if key == prefix || strings.HasPrefix(key, prefix+"_") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func applyEnvValue(val reflect.Value, name string, envs map[string]string) (problems []string) {
+	if !hasEnvPrefix(envs, name) {
+		return
+	}
+
+	// 整体赋值，例如 MAGICAGENT_GUARDS=mariadb001 或 MAGICAGENT_RAYLINK={"serverUrl":...}
+	if envVal, envOK := envs[name]; envOK {
+		err := setEnvValue(val, envVal)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s", name, err.Error()))
+			return
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			val.Set(reflect.New(val.Type().Elem()))
+		}
+		problems = append(problems, applyEnvValue(val.Elem(), name, envs)...)
+	case reflect.Struct:
+		valType := val.Type()
+		for idx := 0; idx < val.NumField(); idx++ {
+			fieldType := valType.Field(idx)
+			fieldName := strings.SplitN(fieldType.Tag.Get("json"), ",", 2)[0]
+			if !fieldType.IsExported() || fieldName == "" || fieldName == "-" {
+				continue
+			}
+
+			problems = append(problems, applyEnvValue(val.Field(idx), name+"_"+strings.ToUpper(fieldName), envs)...)
+		}
+	case reflect.Slice:
+		if val.Type().Elem().Kind() != reflect.Ptr && val.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+
+		// 按下标覆盖元素，例如 MAGICAGENT_GUARDS_1_NAME，下标等于长度时追加元素，不允许跳过下标
+		indexSet := map[int]bool{}
+		for key := range envs {
+			if !strings.HasPrefix(key, name+"_") {
+				continue
+			}
+
+			idxVal, idxErr := strconv.Atoi(strings.SplitN(strings.TrimPrefix(key, name+"_"), "_", 2)[0])
+			if idxErr == nil {
+				indexSet[idxVal] = true
+			}
+		}
+		indexes := []int{}
+		for idx := range indexSet {
+			indexes = append(indexes, idx)
+		}
+		sort.Ints(indexes)
+
+		for _, idx := range indexes {
+			if idx < 0 || idx > val.Len() {
+				problems = append(problems, fmt.Sprintf("%s_%d index out of range, current length:%d", name, idx, val.Len()))
+				continue
+			}
+			if idx == val.Len() {
+				val.Set(reflect.Append(val, reflect.Zero(val.Type().Elem())))
+			}
+
+			problems = append(problems, applyEnvValue(val.Index(idx), fmt.Sprintf("%s_%d", name, idx), envs)...)
+		}
+	}
+
+	return
+}
+
+func setEnvValue(val reflect.Value, envVal string) error {
+	if val.CanAddr() {
+		if unmarshaler, ok := val.Addr().Interface().(json.Unmarshaler); ok {
+			byteVal := []byte(envVal)
+			if !json.Valid(byteVal) {
+				byteVal, _ = json.Marshal(envVal)
+			}
+			return unmarshaler.UnmarshalJSON(byteVal)
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.String:
+		val.SetString(envVal)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, intErr := strconv.ParseInt(envVal, 10, 64)
+		if intErr != nil {
+			return fmt.Errorf("must be an integer, current value:%s", envVal)
+		}
+		val.SetInt(intVal)
+	case reflect.Bool:
+		boolVal, boolErr := strconv.ParseBool(envVal)
+		if boolErr != nil {
+			return fmt.Errorf("must be a bool, current value:%s", envVal)
+		}
+		val.SetBool(boolVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, floatErr := strconv.ParseFloat(envVal, 64)
+		if floatErr != nil {
+			return fmt.Errorf("must be a number, current value:%s", envVal)
+		}
+		val.SetFloat(floatVal)
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.String {
+			items := []string{}
+			for _, item := range strings.Split(envVal, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			val.Set(reflect.ValueOf(items).Convert(val.Type()))
+			return nil
+		}
+		fallthrough
+	default:
+		return json.Unmarshal([]byte(envVal), val.Addr().Interface())
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	cases := []struct {
+		name   string
+		envs   map[string]string
+		check  func(cfg *CfgItem) bool
+		expect string
+	}{
+		{
+			name:  "override field",
+			envs:  map[string]string{"MAGICAGENT_LISTENPORT": "9090"},
+			check: func(cfg *CfgItem) bool { return cfg.ListenPort == "9090" },
+		},
+		{
+			name:  "override element",
+			envs:  map[string]string{"MAGICAGENT_GUARDS_0_PASSWORD": "secret"},
+			check: func(cfg *CfgItem) bool { return cfg.Guards[0].Password == "secret" },
+		},
+		{
+			name: "append element",
+			envs: map[string]string{"MAGICAGENT_GUARDS_1_NAME": "mariadb002", "MAGICAGENT_GUARDS_2_NAME": "mariadb003"},
+			check: func(cfg *CfgItem) bool {
+				return len(cfg.Guards) == 3 && cfg.Guards[1].Name == "mariadb002" && cfg.Guards[2].Name == "mariadb003"
+			},
+		},
+		{
+			name:   "skip index",
+			envs:   map[string]string{"MAGICAGENT_GUARDS_5_NAME": "mariadb005"},
+			check:  func(cfg *CfgItem) bool { return len(cfg.Guards) == 1 },
+			expect: "MAGICAGENT_GUARDS_5 index out of range",
+		},
+		{
+			name:   "huge index",
+			envs:   map[string]string{"MAGICAGENT_GUARDS_2000000000_NAME": "x"},
+			check:  func(cfg *CfgItem) bool { return len(cfg.Guards) == 1 },
+			expect: "index out of range",
+		},
+		{
+			name:   "negative index",
+			envs:   map[string]string{"MAGICAGENT_GUARDS_-1_NAME": "x"},
+			check:  func(cfg *CfgItem) bool { return len(cfg.Guards) == 1 },
+			expect: "MAGICAGENT_GUARDS_-1 index out of range",
+		},
+		{
+			name:   "illegal value",
+			envs:   map[string]string{"MAGICAGENT_TIMEOUT": "abc"},
+			check:  func(cfg *CfgItem) bool { return true },
+			expect: "must be an integer",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			for key, envVal := range val.envs {
+				t.Setenv(key, envVal)
+			}
+
+			cfg := newDefaultConfig()
+			problems := strings.Join(applyEnv(cfg), "\n")
+			if val.expect == "" && problems != "" {
+				t.Fatalf("unexpected problems %s", problems)
+			}
+			if !strings.Contains(problems, val.expect) {
+				t.Errorf("problems %q, expect %q", problems, val.expect)
+			}
+			if !val.check(cfg) {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	fu "github.com/muidea/magicCommon/foundation/util"
+)
+
+// 密文引用前缀
+//
+//	enc:xxx        使用密钥文件加密后的内容
+//	env:VAR        从环境变量VAR读取
+//	file:/run/x    从文件读取，去除首尾空白
+const (
+	encryptPrefix = "enc:"
+	envPrefix     = "env:"
+	filePrefix    = "file:"
+)
+
+const redactedValue = "******"
+
+// DefaultKeyFile 默认密钥文件路径
+const DefaultKeyFile = "/var/app/config/secret.key"
+
+var keyFile = DefaultKeyFile
+
+var secretLock sync.RWMutex
+var secretValues = map[string]bool{}
+
+// SetKeyFile 设置密钥文件路径
+func SetKeyFile(filePath string) {
+	if filePath == "" {
+		return
+	}
+
+	keyFile = filePath
+}
+
+func loadKey() (ret string, err error) {
+	byteVal, byteErr := os.ReadFile(keyFile)
+	if byteErr != nil {
+		err = fmt.Errorf("read key file %s failed, %s", keyFile, byteErr.Error())
+		return
+	}
+
+	ret = strings.TrimSpace(string(byteVal))
+	if ret == "" {
+		err = fmt.Errorf("key file %s is empty", keyFile)
+	}
+	return
+}
+
+// EncryptSecret 使用密钥文件加密明文，返回可直接写入配置文件的enc:引用
+func EncryptSecret(plainText string) (ret string, err error) {
+	keyVal, keyErr := loadKey()
+	if keyErr != nil {
+		err = keyErr
+		return
+	}
+
+	cipherText, cipherErr := fu.EncryptByAes(plainText, keyVal)
+	if cipherErr != nil {
+		err = cipherErr
+		return
+	}
+
+	ret = encryptPrefix + cipherText
+	return
+}
+
+// ResolveSecret 解析密文引用，非引用格式的值原样返回
+func ResolveSecret(val string) (ret string, err error) {
+	switch {
+	case strings.HasPrefix(val, encryptPrefix):
+		keyVal, keyErr := loadKey()
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+
+		ret, err = fu.DecryptByAes(strings.TrimPrefix(val, encryptPrefix), keyVal)
+		if err != nil {
+			err = fmt.Errorf("decrypt secret failed, %s", err.Error())
+		}
+	case strings.HasPrefix(val, envPrefix):
+		envName := strings.TrimPrefix(val, envPrefix)
+		envVal, envOK := os.LookupEnv(envName)
+		if !envOK {
+			err = fmt.Errorf("environment variable %s not set", envName)
+			return
+		}
+
+		ret = envVal
+	case strings.HasPrefix(val, filePrefix):
+		filePath := strings.TrimPrefix(val, filePrefix)
+		byteVal, byteErr := os.ReadFile(filePath)
+		if byteErr != nil {
+			err = fmt.Errorf("read secret file %s failed, %s", filePath, byteErr.Error())
+			return
+		}
+
+		ret = strings.TrimSpace(string(byteVal))
+	default:
+		ret = val
+	}
+
+	return
+}
+
+// walkSecrets 遍历配置中标记为secret的字符串字段，path为json路径
+func walkSecrets(val reflect.Value, path string, funcPtr func(path string, ptr *string)) {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !val.IsNil() {
+			walkSecrets(val.Elem(), path, funcPtr)
+		}
+	case reflect.Slice:
+		for idx := 0; idx < val.Len(); idx++ {
+			walkSecrets(val.Index(idx), fmt.Sprintf("%s[%d]", path, idx), funcPtr)
+		}
+	case reflect.Struct:
+		valType := val.Type()
+		for idx := 0; idx < val.NumField(); idx++ {
+			fieldType := valType.Field(idx)
+			if !fieldType.IsExported() {
+				continue
+			}
+
+			fieldPath := strings.SplitN(fieldType.Tag.Get("json"), ",", 2)[0]
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+
+			fieldVal := val.Field(idx)
+			if fieldType.Tag.Get("secret") == "true" && fieldVal.Kind() == reflect.String {
+				funcPtr(fieldPath, fieldVal.Addr().Interface().(*string))
+				continue
+			}
+
+			walkSecrets(fieldVal, fieldPath, funcPtr)
+		}
+	}
+}
+
+// resolveSecrets 解析配置中的全部密文引用，返回解析失败的问题列表
+func resolveSecrets(cfg *CfgItem) (problems []string) {
+	walkSecrets(reflect.ValueOf(cfg), "", func(path string, ptr *string) {
+		if *ptr == "" {
+			return
+		}
+
+		secretVal, secretErr := ResolveSecret(*ptr)
+		if secretErr != nil {
+			problems = append(problems, fmt.Sprintf("%s resolve secret failed, %s", path, secretErr.Error()))
+			return
+		}
+
+		*ptr = secretVal
+		registerSecret(secretVal)
+	})
+
+	return
+}
+
+func registerSecret(val string) {
+	if val == "" {
+		return
+	}
+
+	secretLock.Lock()
+	defer secretLock.Unlock()
+	secretValues[val] = true
+}
+
+// RedactText 将文本中出现的密文值替换为掩码，用于日志输出
+func RedactText(text string) string {
+	secretLock.RLock()
+	defer secretLock.RUnlock()
+
+	for key := range secretValues {
+		text = strings.ReplaceAll(text, key, redactedValue)
+	}
+
+	return text
+}
+
+// Redacted 返回隐藏了密文字段的配置副本
+func (s *CfgItem) Redacted() *CfgItem {
+	byteVal, byteErr := json.Marshal(s)
+	if byteErr != nil {
+		return nil
+	}
+
+	ret := &CfgItem{}
+	byteErr = json.Unmarshal(byteVal, ret)
+	if byteErr != nil {
+		return nil
+	}
+
+	walkSecrets(reflect.ValueOf(ret), "", func(_ string, ptr *string) {
+		if *ptr != "" {
+			*ptr = redactedValue
+		}
+	})
+
+	return ret
+}
+
+// Dump 输出当前配置，密文字段已隐藏
+func Dump() string {
+	byteVal, _ := json.Marshal(configItem.Redacted())
+	return string(byteVal)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyPath, []byte("magic-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secretPath := filepath.Join(dir, "password")
+	if err := os.WriteFile(secretPath, []byte(" from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MAGIC_TEST_SECRET", "from-env")
+
+	oldKeyFile := keyFile
+	SetKeyFile(keyPath)
+	defer func() { keyFile = oldKeyFile }()
+
+	encVal, encErr := EncryptSecret("from-key")
+	if encErr != nil || !strings.HasPrefix(encVal, encryptPrefix) {
+		t.Fatalf("encrypt secret failed, %s, %v", encVal, encErr)
+	}
+
+	cases := []struct {
+		name   string
+		value  string
+		expect string
+		err    string
+	}{
+		{name: "plain", value: "rootkit", expect: "rootkit"},
+		{name: "encrypted", value: encVal, expect: "from-key"},
+		{name: "env", value: "env:MAGIC_TEST_SECRET", expect: "from-env"},
+		{name: "file", value: "file:" + secretPath, expect: "from-file"},
+		{name: "env not set", value: "env:MAGIC_TEST_UNSET", err: "environment variable MAGIC_TEST_UNSET not set"},
+		{name: "file not found", value: "file:" + filepath.Join(dir, "none"), err: "read secret file"},
+		{name: "illegal cipher", value: "enc:abc", err: "decrypt secret failed"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, err := ResolveSecret(val.value)
+			if val.err != "" {
+				if err == nil || !strings.Contains(err.Error(), val.err) {
+					t.Errorf("err %v, expect %q", err, val.err)
+				}
+				return
+			}
+			if err != nil || ret != val.expect {
+				t.Errorf("got %q, %v, expect %q", ret, err, val.expect)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	t.Setenv("MAGIC_TEST_SECRET", "p@ss-word")
+	cfgFile := filepath.Join(t.TempDir(), "cfg.json")
+	content := `{"localHost": "127.0.0.1", "guards": [{"name": "db", "type": "mariadb", "password": "env:MAGIC_TEST_SECRET"}], "timeOut": 30}`
+	if err := os.WriteFile(cfgFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseConfig(cfgFile)
+	if err != nil {
+		t.Fatalf("parse config failed, %v", err)
+	}
+	if cfg.Guards[0].Password != "p@ss-word" {
+		t.Errorf("password %q, expect resolved value", cfg.Guards[0].Password)
+	}
+	if cfg.Redacted().Guards[0].Password != redactedValue {
+		t.Errorf("redacted password %q", cfg.Redacted().Guards[0].Password)
+	}
+	if text := RedactText("mysql MYSQL_PWD=p@ss-word"); strings.Contains(text, "p@ss-word") {
+		t.Errorf("secret not redacted, %s", text)
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	sysValidator "github.com/go-playground/validator/v10"
+
+	"github.com/muidea/magicAgent/internal/cron"
+)
+
+// ValidateError 配置校验错误，Problems记录全部校验问题
+type ValidateError struct {
+	Problems []string
+}
+
+func (s *ValidateError) Error() string {
+	return fmt.Sprintf("invalid config, %s", strings.Join(s.Problems, "; "))
+}
+
+var cfgValidator = newValidator()
+
+func newValidator() *sysValidator.Validate {
+	validate := sysValidator.New()
+	// 使用json名称描述字段，便于与配置文件对照
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+
+		return name
+	})
+	_ = validate.RegisterValidation("cron", func(fl sysValidator.FieldLevel) bool {
+		_, err := cron.Parse(fl.Field().String())
+		return err == nil
+	})
+	_ = validate.RegisterValidation("regexp", func(fl sysValidator.FieldLevel) bool {
+		_, err := regexp.Compile(fl.Field().String())
+		return err == nil
+	})
+	validate.RegisterStructValidation(validateCfgItem, CfgItem{})
+	validate.RegisterStructValidation(validateGuardItem, GuardItem{})
+	validate.RegisterStructValidation(validateProbeItem, ProbeItem{})
+	return validate
+}
+
+// validateCfgItem rayLink为HTTP接口地址，email为SMTP服务地址，两者格式不同需要单独校验
+func validateCfgItem(sl sysValidator.StructLevel) {
+	cfg := sl.Current().Interface().(CfgItem)
+	if cfg.RayLink != nil && cfg.RayLink.ServerUrl != "" {
+		if sl.Validator().Var(cfg.RayLink.ServerUrl, "url") != nil {
+			sl.ReportError(cfg.RayLink.ServerUrl, "rayLink.serverUrl", "ServerUrl", "url", "")
+		}
+	}
+	if cfg.EMail != nil && cfg.EMail.ServerUrl != "" {
+		if sl.Validator().Var(cfg.EMail.ServerUrl, "hostname_port") != nil {
+			sl.ReportError(cfg.EMail.ServerUrl, "email.serverUrl", "ServerUrl", "hostname_port", "")
+		}
+	}
+}
+
+// validateGuardItem mode的取值与守护类型相关，probe守护对象至少需要一个探测项，
+// mariadb守护对象必须配置密码，自动切换必须配置fencingHook以便在原主库agent不可达时隔离原主库
+func validateGuardItem(sl sysValidator.StructLevel) {
+	guard := sl.Current().Interface().(GuardItem)
+	if guard.Type == ProbeGuard && len(guard.Probes) == 0 {
+		sl.ReportError(guard.Probes, "probes", "Probes", "min", "1")
+	}
+	if guard.Type == MariadbGuard && guard.Password == "" {
+		sl.ReportError(guard.Password, "password", "Password", "required", "")
+	}
+	if guard.AutoFailover && guard.FencingHook == "" {
+		sl.ReportError(guard.FencingHook, "fencingHook", "FencingHook", "required_with", "autoFailover")
+	}
+	if guard.Mode == "" {
+		return
+	}
+
+	modes := map[string]string{
+		MariadbGuard:  GaleraMode + " " + ReplicationMode,
+		RedisGuard:    SentinelMode,
+		PostgresGuard: "",
+		ProbeGuard:    "",
+	}
+	allowed, ok := modes[guard.Type]
+	if ok && !strings.Contains(" "+allowed+" ", " "+guard.Mode+" ") {
+		sl.ReportError(guard.Mode, "mode", "Mode", "oneof", allowed)
+	}
+}
+
+// validateProbeItem 探测目标字段与探测类型相关
+func validateProbeItem(sl sysValidator.StructLevel) {
+	probe := sl.Current().Interface().(ProbeItem)
+	switch probe.Type {
+	case TCPProbe, TLSProbe:
+		if probe.Address == "" {
+			sl.ReportError(probe.Address, "address", "Address", "required", "")
+		}
+	case HTTPProbe:
+		if probe.URL == "" {
+			sl.ReportError(probe.URL, "url", "URL", "required", "")
+		}
+	case CommandProbe:
+		if probe.Command == "" {
+			sl.ReportError(probe.Command, "command", "Command", "required", "")
+		}
+	}
+}
+
+// Validate 校验配置，返回的ValidateError包含全部问题
+func Validate(cfg *CfgItem) error {
+	if cfg == nil {
+		return &ValidateError{Problems: []string{"empty config"}}
+	}
+
+	err := cfgValidator.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	validateErrs, ok := err.(sysValidator.ValidationErrors)
+	if !ok {
+		return &ValidateError{Problems: []string{err.Error()}}
+	}
+
+	problems := []string{}
+	for _, val := range validateErrs {
+		problems = append(problems, describeError(val))
+	}
+
+	return &ValidateError{Problems: problems}
+}
+
+func describeError(fieldErr sysValidator.FieldError) string {
+	// 去掉根结构名称，例如 CfgItem.rayLink.serverUrl -> rayLink.serverUrl
+	namespace := fieldErr.Namespace()
+	if idx := strings.Index(namespace, "."); idx >= 0 {
+		namespace = namespace[idx+1:]
+	}
+
+	var reason string
+	switch fieldErr.Tag() {
+	case "required":
+		reason = "is required"
+	case "required_with":
+		reason = fmt.Sprintf("is required when %s is set", fieldErr.Param())
+	case "ip|hostname":
+		reason = "must be an IP address or hostname"
+	case "ip|hostname|hostname_port":
+		reason = "must be an IP address, hostname or host:port"
+	case "hostname_port":
+		reason = "must be host:port"
+	case "numeric":
+		reason = "must be numeric"
+	case "url":
+		reason = "must be a valid URL"
+	case "gt":
+		reason = fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "gte":
+		reason = fmt.Sprintf("must be greater than or equal to %s", fieldErr.Param())
+	case "lte":
+		reason = fmt.Sprintf("must be less than or equal to %s", fieldErr.Param())
+	case "min":
+		reason = fmt.Sprintf("must contain at least %s item(s)", fieldErr.Param())
+	case "regexp":
+		reason = "must be a valid regular expression"
+	case "cron":
+		reason = "must be a cron expression like '0 2 * * *'"
+	case "oneof":
+		reason = fmt.Sprintf("must be one of [%s]", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	default:
+		reason = fmt.Sprintf("failed on '%s' rule", fieldErr.Tag())
+	}
+
+	return fmt.Sprintf("%s %s, current value:%v", namespace, reason, fieldErr.Value())
+}
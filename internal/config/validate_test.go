@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(cfg *CfgItem)
+		expect []string
+	}{
+		{name: "default", modify: func(cfg *CfgItem) {}},
+		{name: "no guard", modify: func(cfg *CfgItem) { cfg.Guards = GuardList{} }, expect: []string{"guards must contain at least 1 item(s)"}},
+		{name: "illegal local host", modify: func(cfg *CfgItem) { cfg.LocalHost = "a b" }, expect: []string{"localHost must be an IP address or hostname"}},
+		{name: "illegal cluster host", modify: func(cfg *CfgItem) { cfg.ClusterHosts = []string{"10.0.0.1", "a b"} }, expect: []string{"clusterHosts[1] must be an IP address, hostname or host:port"}},
+		{name: "mariadb without password", modify: func(cfg *CfgItem) { cfg.Guards[0].Password = "" }, expect: []string{"guards[0].password is required"}},
+		{name: "illegal guard type", modify: func(cfg *CfgItem) { cfg.Guards[0].Type = "oracle" }, expect: []string{"guards[0].type must be one of [mariadb, redis, postgres, probe]"}},
+		{name: "illegal timeout", modify: func(cfg *CfgItem) { cfg.TimeOut = 0 }, expect: []string{"timeOut must be greater than 0"}},
+		{
+			name:   "auto failover without fencing hook",
+			modify: func(cfg *CfgItem) { cfg.Guards[0].Mode, cfg.Guards[0].AutoFailover = ReplicationMode, true },
+			expect: []string{"guards[0].fencingHook is required when autoFailover is set"},
+		},
+		{
+			name: "auto failover with fencing hook",
+			modify: func(cfg *CfgItem) {
+				cfg.Guards[0].Mode, cfg.Guards[0].AutoFailover, cfg.Guards[0].FencingHook = ReplicationMode, true, "/opt/fence.sh"
+			},
+		},
+		{name: "illegal mode", modify: func(cfg *CfgItem) { cfg.Guards[0].Mode = SentinelMode }, expect: []string{"guards[0].mode must be one of [galera, replication]"}},
+		{name: "illegal threshold", modify: func(cfg *CfgItem) { cfg.Host = &HostItem{FullUsage: 101} }, expect: []string{"host.fullUsage must be less than or equal to 100"}},
+		{
+			name: "illegal server url",
+			modify: func(cfg *CfgItem) {
+				cfg.RayLink = &ServerInfo{ServerUrl: "raylink", Account: "a", Receiver: "r"}
+				cfg.EMail = &ServerInfo{ServerUrl: "http://smtp", Account: "a", Receiver: "r"}
+			},
+			expect: []string{"rayLink.serverUrl must be a valid URL", "email.serverUrl must be host:port"},
+		},
+		{
+			name: "probe fields",
+			modify: func(cfg *CfgItem) {
+				cfg.Guards = append(cfg.Guards, &GuardItem{Name: "web", Type: ProbeGuard, Probes: []*ProbeItem{
+					{Type: HTTPProbe},
+					{Type: HTTPProbe, URL: "http://127.0.0.1", BodyRegex: "("},
+				}})
+			},
+			expect: []string{"guards[1].probes[0].url is required", "guards[1].probes[1].bodyRegex must be a valid regular expression"},
+		},
+		{name: "probe without probes", modify: func(cfg *CfgItem) { cfg.Guards[0].Type = ProbeGuard }, expect: []string{"guards[0].probes must contain at least 1 item(s)"}},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			cfg := newDefaultConfig()
+			val.modify(cfg)
+			err := Validate(cfg)
+			if len(val.expect) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+
+			validateErr, ok := err.(*ValidateError)
+			if !ok {
+				t.Fatalf("expect ValidateError, got %v", err)
+			}
+			problems := strings.Join(validateErr.Problems, "\n")
+			for _, item := range val.expect {
+				if !strings.Contains(problems, item) {
+					t.Errorf("expect problem %q, got:\n%s", item, problems)
+				}
+			}
+		})
+	}
+}
+
+// TestParseConfigProblems 一次返回全部校验问题
+func TestParseConfigProblems(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "cfg.json")
+	content := `{"localHost": "127.0.0.1", "guards": [{"name": "db", "password": "p"}, {"type": "mariadb", "password": "p"}], "timeOut": 30}`
+	if err := os.WriteFile(cfgFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseConfig(cfgFile)
+	validateErr, ok := err.(*ValidateError)
+	if !ok {
+		t.Fatalf("expect ValidateError, got %v", err)
+	}
+	if len(validateErr.Problems) != 2 {
+		t.Errorf("problems %v, expect 2", validateErr.Problems)
+	}
+}
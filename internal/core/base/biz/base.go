@@ -1,10 +1,12 @@
 package biz
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"syscall"
 	"time"
 
 	cd "github.com/muidea/magicCommon/def"
@@ -13,6 +15,7 @@ import (
 	"github.com/muidea/magicCommon/task"
 
 	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/runtime"
 )
 
 type Base struct {
@@ -103,35 +106,127 @@ func (s *Base) InnerDestination() string {
 	return s.ID()
 }
 
+// Execute 使用默认参数执行命令
 func (s *Base) Execute(cmdName string, args ...string) (stdout []byte, stderr []byte, err *cd.Result) {
+	resultPtr, resultErr := s.ExecuteContext(context.Background(), nil, cmdName, args...)
+	if resultErr != nil {
+		err = resultErr
+		return
+	}
+
+	stdout = []byte(resultPtr.Stdout)
+	stderr = []byte(resultPtr.Stderr)
+	return
+}
+
+// ExecuteContext 在独立进程组中执行命令，ctx取消或超时时结束整个进程组
+// 命令非零退出时同时返回执行结果和错误，超出上限的输出被丢弃
+func (s *Base) ExecuteContext(ctx context.Context, option *runtime.ExecOption, cmdName string, args ...string) (ret *runtime.ExecResult, err *cd.Result) {
 	defer func() {
 		if errInfo := recover(); errInfo != nil {
 			err = cd.NewError(cd.UnExpected, fmt.Sprintf("%v", errInfo))
-			log.Errorf("Execute unexpected, cmdName:%s, args:%v, error:%v", cmdName, args, errInfo)
+			log.Errorf("Execute unexpected, cmdName:%s, args:%s, error:%v", cmdName, config.RedactText(fmt.Sprintf("%v", args)), errInfo)
 		}
 	}()
 
 	if config.EnableTrace() {
-		log.Infof("Execute, cmdName:%v, args:%v", cmdName, args)
+		log.Infof("Execute, cmdName:%v, args:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)))
+	}
+
+	if option == nil {
+		option = &runtime.ExecOption{}
+	}
+	timeOut := option.TimeOut
+	if timeOut <= 0 {
+		timeOut = time.Duration(config.GetTimeOut()) * time.Second
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeOut)
+	defer cancel()
 
-	output := &bytes.Buffer{}
-	errput := &bytes.Buffer{}
+	output := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
+	errput := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
 
 	cmdPtr := exec.Command(cmdName, args...)
-	cmdPtr.Stdin = os.Stdin
+	cmdPtr.Dir = option.WorkDir
+	if len(option.Env) > 0 {
+		cmdPtr.Env = append(os.Environ(), option.Env...)
+	}
 	cmdPtr.Stdout = output
 	cmdPtr.Stderr = errput
-	byteErr := cmdPtr.Run()
-	if byteErr != nil {
-		err = cd.NewError(cd.UnExpected, byteErr.Error())
+	waitErr := runGroup(ctx, cmdPtr)
+	if cmdPtr.ProcessState == nil {
+		err = cd.NewError(cd.UnExpected, waitErr.Error())
 		if config.EnableTrace() {
-			log.Errorf("Execute failed, cmdName:%s, args:%v, error:%s", cmdName, args, err.Error())
+			log.Errorf("Execute failed, cmdName:%s, args:%s, error:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)), err.Error())
 		}
 		return
 	}
 
-	stdout = output.Bytes()
-	stderr = errput.Bytes()
+	ret = &runtime.ExecResult{
+		Stdout:   output.String(),
+		Stderr:   errput.String(),
+		ExitCode: cmdPtr.ProcessState.ExitCode(),
+	}
+	if output.Truncated || errput.Truncated {
+		log.Warnf("Execute output truncated, cmdName:%s, limit:%d", cmdName, option.GetMaxOutput())
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("command timeout after %v", timeOut))
+	case ctx.Err() != nil:
+		err = cd.NewError(cd.UnExpected, "command canceled")
+	case waitErr != nil:
+		err = cd.NewError(cd.UnExpected, waitErr.Error())
+	}
+	if err != nil && config.EnableTrace() {
+		log.Errorf("Execute failed, cmdName:%s, args:%s, error:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)), err.Error())
+	}
+
+	return
+}
+
+// runGroup 在独立进程组中运行命令并等待结束，ctx取消或超时时结束整个进程组，避免子进程残留
+func runGroup(ctx context.Context, cmdPtr *exec.Cmd) error {
+	cmdPtr.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	startErr := cmdPtr.Start()
+	if startErr != nil {
+		return startErr
+	}
+
+	waitDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = syscall.Kill(-cmdPtr.Process.Pid, syscall.SIGKILL)
+		case <-waitDone:
+		}
+	}()
+	waitErr := cmdPtr.Wait()
+	close(waitDone)
+	return waitErr
+}
+
+// ExecuteStream 在独立进程组中执行命令并将输出实时写入stdout、stderr，ctx取消时结束整个进程组，
+// env为本地命令额外的环境变量
+func (s *Base) ExecuteStream(ctx context.Context, env []string, stdout, stderr io.Writer, cmdName string, args ...string) (err *cd.Result) {
+	if config.EnableTrace() {
+		log.Infof("ExecuteStream, cmdName:%v, args:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)))
+	}
+
+	cmdPtr := exec.Command(cmdName, args...)
+	if len(env) > 0 {
+		cmdPtr.Env = append(os.Environ(), env...)
+	}
+	cmdPtr.Stdout = stdout
+	cmdPtr.Stderr = stderr
+	runErr := runGroup(ctx, cmdPtr)
+	if runErr != nil && ctx.Err() == nil {
+		err = cd.NewError(cd.UnExpected, runErr.Error())
+		if config.EnableTrace() {
+			log.Errorf("ExecuteStream failed, cmdName:%s, args:%s, error:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)), err.Error())
+		}
+	}
+
 	return
 }
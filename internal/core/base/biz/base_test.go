@@ -0,0 +1,94 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+func TestExecuteContext(t *testing.T) {
+	workDir := t.TempDir()
+	cases := []struct {
+		name         string
+		option       *runtime.ExecOption
+		script       string
+		expectStdout string
+		expectStderr string
+		expectCode   int
+		expectErr    string
+	}{
+		{name: "succeeded", script: "echo out; echo err >&2", expectStdout: "out\n", expectStderr: "err\n"},
+		{name: "env and workdir", option: &runtime.ExecOption{Env: []string{"MAGIC_TEST=v1"}, WorkDir: workDir}, script: "echo $MAGIC_TEST; pwd", expectStdout: "v1\n" + workDir + "\n"},
+		{name: "exit code", script: "echo partial; exit 3", expectStdout: "partial\n", expectCode: 3, expectErr: "exit status 3"},
+		{name: "output limit", option: &runtime.ExecOption{MaxOutput: 4}, script: "echo 0123456789", expectStdout: "0123"},
+		{name: "timeout", option: &runtime.ExecOption{TimeOut: 200 * time.Millisecond}, script: "echo begin; sleep 10", expectStdout: "begin\n", expectCode: -1, expectErr: "command timeout"},
+	}
+
+	basePtr := &Base{}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, err := basePtr.ExecuteContext(context.Background(), val.option, "sh", "-c", val.script)
+			if val.expectErr == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if val.expectErr != "" && (err == nil || !strings.Contains(err.Reason, val.expectErr)) {
+				t.Fatalf("error %v, expect %q", err, val.expectErr)
+			}
+			if ret.Stdout != val.expectStdout || ret.Stderr != val.expectStderr || ret.ExitCode != val.expectCode {
+				t.Errorf("result %+v, expect stdout %q, stderr %q, exit code %d", ret, val.expectStdout, val.expectStderr, val.expectCode)
+			}
+		})
+	}
+}
+
+func TestExecuteContextKillGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	// 后台子进程继承了输出管道，只有结束整个进程组命令才能返回
+	startTime := time.Now()
+	ret, err := (&Base{}).ExecuteContext(ctx, nil, "sh", "-c", "sleep 10 & sleep 10")
+	if err == nil || err.Reason != "command canceled" {
+		t.Fatalf("error %v, expect command canceled", err)
+	}
+	if ret.ExitCode != -1 {
+		t.Errorf("exit code %d, expect -1", ret.ExitCode)
+	}
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("command returned after %v", elapsed)
+	}
+}
+
+func TestExecuteStartFailed(t *testing.T) {
+	ret, err := (&Base{}).ExecuteContext(context.Background(), nil, "magic-agent-no-such-command")
+	if err == nil || ret != nil {
+		t.Errorf("result %+v, error %v, expect start failure", ret, err)
+	}
+}
+
+func TestExecuteStreamKillGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	startTime := time.Now()
+	stdout := &bytes.Buffer{}
+	err := (&Base{}).ExecuteStream(ctx, []string{"MAGIC_TEST=v1"}, stdout, io.Discard, "sh", "-c", "echo $MAGIC_TEST; sleep 10 & sleep 10")
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if stdout.String() != "v1\n" {
+		t.Errorf("stdout %q, expect v1", stdout.String())
+	}
+	if elapsed := time.Since(startTime); elapsed > 5*time.Second {
+		t.Errorf("command returned after %v", elapsed)
+	}
+
+	if err = (&Base{}).ExecuteStream(context.Background(), nil, io.Discard, io.Discard, "sh", "-c", "exit 2"); err == nil {
+		t.Errorf("expect exit status error")
+	}
+}
@@ -0,0 +1,40 @@
+package biz
+
+import (
+	"context"
+	"io"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// JobContext 任务执行上下文，任务被取消时Done()关闭，写入的内容作为任务输出
+type JobContext interface {
+	context.Context
+	io.Writer
+	JobID() string
+	Param() *common.JobParam
+	SetProgress(progress int, message string)
+}
+
+// JobHandler 任务处理函数，返回后任务结束，返回错误时任务失败
+type JobHandler func(jobCtx JobContext) *cd.Result
+
+// SubscribeJob 订阅指定类型的任务，同一任务类型只能由一个模块处理
+func (s *Base) SubscribeJob(jobType string, handler JobHandler) {
+	s.SubscribeFunc(common.RunJob+jobType, func(ev event.Event, re event.Result) {
+		jobCtx, jobOK := ev.Data().(JobContext)
+		if !jobOK {
+			log.Warnf("run job %s failed, illegal param", jobType)
+			return
+		}
+
+		err := handler(jobCtx)
+		if re != nil {
+			re.Set(nil, err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package biz
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// openPty 打开伪终端，返回主设备和从设备
+func openPty() (ptyPtr, ttyPtr *os.File, err error) {
+	ptyPtr, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return
+	}
+
+	unlock := int32(0)
+	ptyNo := uint32(0)
+	err = ioctl(ptyPtr, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock)))
+	if err == nil {
+		err = ioctl(ptyPtr, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptyNo)))
+	}
+	if err == nil {
+		ttyPtr, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", ptyNo), os.O_RDWR|syscall.O_NOCTTY, 0)
+	}
+	if err != nil {
+		ptyPtr.Close()
+		ptyPtr = nil
+	}
+	return
+}
+
+// setWindowSize 设置伪终端窗口大小
+func setWindowSize(ptyPtr *os.File, rows, cols uint16) error {
+	size := struct {
+		Rows   uint16
+		Cols   uint16
+		Width  uint16
+		Height uint16
+	}{Rows: rows, Cols: cols}
+
+	return ioctl(ptyPtr, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+}
+
+// ioctl 通过SyscallConn调用，避免Fd()将文件切换为阻塞模式导致Close无法中断Read
+func ioctl(filePtr *os.File, request, arg uintptr) (err error) {
+	rawConn, rawErr := filePtr.SyscallConn()
+	if rawErr != nil {
+		return rawErr
+	}
+
+	ctrlErr := rawConn.Control(func(fd uintptr) {
+		_, _, errNo := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg)
+		if errNo != 0 {
+			err = errNo
+		}
+	})
+	if ctrlErr != nil {
+		err = ctrlErr
+	}
+	return
+}
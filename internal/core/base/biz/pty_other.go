@@ -0,0 +1,18 @@
+//go:build !linux
+
+package biz
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func openPty() (ptyPtr, ttyPtr *os.File, err error) {
+	err = fmt.Errorf("pty not supported on %s", runtime.GOOS)
+	return
+}
+
+func setWindowSize(ptyPtr *os.File, rows, cols uint16) error {
+	return fmt.Errorf("pty not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,34 @@
+package biz
+
+import (
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// PauseRemediation 暂停服务的自动修复，返回恢复函数；已暂停时保持原状态，恢复函数不做处理
+func (s *Base) PauseRemediation(serviceName, reason string) func() {
+	ev := event.NewEvent(common.QueryRemediation, s.ID(), common.BaseModule, nil, nil)
+	statusVal, statusErr := s.SendEvent(ev).Get()
+	if statusErr == nil {
+		if statusPtr, ok := statusVal.(*common.RemediationStatus); ok {
+			if _, paused := statusPtr.Services[serviceName]; statusPtr.Paused || paused {
+				return func() {}
+			}
+		}
+	}
+
+	param := &common.RemediationParam{Service: serviceName, Reason: reason}
+	_, pauseErr := s.SendEvent(event.NewEvent(common.PauseRemediation, s.ID(), common.BaseModule, nil, param)).Get()
+	if pauseErr != nil {
+		log.Warnf("pause remediation failed, service:%s, error:%s", serviceName, pauseErr.Error())
+	}
+
+	return func() {
+		_, resumeErr := s.SendEvent(event.NewEvent(common.ResumeRemediation, s.ID(), common.BaseModule, nil, param)).Get()
+		if resumeErr != nil {
+			log.Warnf("resume remediation failed, service:%s, error:%s", serviceName, resumeErr.Error())
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+// 未指定窗口大小时使用的默认值
+const (
+	defaultTerminalRows = 24
+	defaultTerminalCols = 80
+)
+
+// localTerminal 本地交互式命令，TTY时输入输出均为伪终端主设备
+type localTerminal struct {
+	cmdPtr *exec.Cmd
+	ptyPtr *os.File
+	input  io.WriteCloser
+	output io.ReadCloser
+
+	done     chan struct{}
+	waitOnce sync.Once
+	exitCode int
+	waitErr  *cd.Result
+}
+
+func (s *localTerminal) Read(data []byte) (int, error) {
+	size, err := s.output.Read(data)
+	// 伪终端从设备全部关闭后主设备返回EIO，按EOF处理
+	if err != nil && (errors.Is(err, syscall.EIO) || errors.Is(err, os.ErrClosed)) {
+		err = io.EOF
+	}
+
+	return size, err
+}
+
+func (s *localTerminal) Write(data []byte) (int, error) {
+	return s.input.Write(data)
+}
+
+func (s *localTerminal) Resize(rows, cols uint16) error {
+	if s.ptyPtr == nil || rows == 0 || cols == 0 {
+		return nil
+	}
+
+	return setWindowSize(s.ptyPtr, rows, cols)
+}
+
+func (s *localTerminal) CloseWrite() error {
+	if s.ptyPtr != nil {
+		_, err := s.ptyPtr.Write([]byte{4})
+		return err
+	}
+
+	return s.input.Close()
+}
+
+// Close 结束整个进程组，输出在进程退出后结束
+func (s *localTerminal) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	return syscall.Kill(-s.cmdPtr.Process.Pid, syscall.SIGKILL)
+}
+
+func (s *localTerminal) Wait() (exitCode int, err *cd.Result) {
+	s.waitOnce.Do(func() {
+		waitErr := s.cmdPtr.Wait()
+		close(s.done)
+
+		s.exitCode = s.cmdPtr.ProcessState.ExitCode()
+		if waitErr != nil && s.exitCode < 0 {
+			s.waitErr = cd.NewError(cd.UnExpected, waitErr.Error())
+		}
+
+		_ = s.output.Close()
+		if s.ptyPtr == nil {
+			_ = s.input.Close()
+		}
+	})
+
+	return s.exitCode, s.waitErr
+}
+
+// StartTerminal 在独立会话中启动交互式命令，TTY时分配伪终端并作为控制终端，否则stdout、stderr合并输出
+// ctx取消时结束整个进程组，调用方需在输出结束后调用Wait回收进程
+func (s *Base) StartTerminal(ctx context.Context, option *runtime.TerminalOption, cmdName string, args ...string) (ret runtime.Terminal, err *cd.Result) {
+	if config.EnableTrace() {
+		log.Infof("StartTerminal, cmdName:%v, args:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)))
+	}
+
+	if option == nil {
+		option = &runtime.TerminalOption{}
+	}
+
+	cmdPtr := exec.Command(cmdName, args...)
+	cmdPtr.Dir = option.WorkDir
+	cmdPtr.Env = os.Environ()
+	if option.TTY {
+		cmdPtr.Env = append(cmdPtr.Env, "TERM=xterm")
+	}
+	cmdPtr.Env = append(cmdPtr.Env, option.Env...)
+
+	terminalPtr := &localTerminal{cmdPtr: cmdPtr, done: make(chan struct{})}
+	var childFiles []*os.File
+	if option.TTY {
+		ptyPtr, ttyPtr, ptyErr := openPty()
+		if ptyErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("open pty failed, %s", ptyErr.Error()))
+			return
+		}
+
+		rows, cols := option.Rows, option.Cols
+		if rows == 0 || cols == 0 {
+			rows, cols = defaultTerminalRows, defaultTerminalCols
+		}
+		_ = setWindowSize(ptyPtr, rows, cols)
+
+		cmdPtr.Stdin = ttyPtr
+		cmdPtr.Stdout = ttyPtr
+		cmdPtr.Stderr = ttyPtr
+		cmdPtr.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+
+		terminalPtr.ptyPtr = ptyPtr
+		terminalPtr.input = ptyPtr
+		terminalPtr.output = ptyPtr
+		childFiles = append(childFiles, ttyPtr)
+	} else {
+		inputReader, inputWriter, pipeErr := os.Pipe()
+		if pipeErr != nil {
+			err = cd.NewError(cd.UnExpected, pipeErr.Error())
+			return
+		}
+		outputReader, outputWriter, pipeErr := os.Pipe()
+		if pipeErr != nil {
+			inputReader.Close()
+			inputWriter.Close()
+			err = cd.NewError(cd.UnExpected, pipeErr.Error())
+			return
+		}
+
+		cmdPtr.Stdin = inputReader
+		cmdPtr.Stdout = outputWriter
+		cmdPtr.Stderr = outputWriter
+		cmdPtr.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+		terminalPtr.input = inputWriter
+		terminalPtr.output = outputReader
+		childFiles = append(childFiles, inputReader, outputWriter)
+	}
+
+	startErr := cmdPtr.Start()
+	// 子进程已持有从设备和管道的另一端，父进程关闭后才能在子进程退出时读到EOF
+	for _, val := range childFiles {
+		val.Close()
+	}
+	if startErr != nil {
+		terminalPtr.input.Close()
+		terminalPtr.output.Close()
+		err = cd.NewError(cd.UnExpected, startErr.Error())
+		log.Errorf("StartTerminal failed, cmdName:%s, args:%s, error:%s", cmdName, config.RedactText(fmt.Sprintf("%v", args)), err.Error())
+		return
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = terminalPtr.Close()
+		case <-terminalPtr.done:
+		}
+	}()
+
+	ret = terminalPtr
+	return
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+)
+
+// CheckAdmin 校验请求头 Authorization: Bearer <adminToken>，未配置adminToken时拒绝全部请求
+func CheckAdmin(req *http.Request) *cd.Result {
+	adminToken := config.GetAdminToken()
+	if adminToken == "" {
+		return cd.NewError(cd.InvalidAuthority, "admin token not configured")
+	}
+
+	authVal := req.Header.Get("Authorization")
+	token := strings.TrimSpace(strings.TrimPrefix(authVal, "Bearer "))
+	if authVal == "" || token == authVal || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		return cd.NewError(cd.InvalidAuthority, "admin authority required")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+)
+
+type responseWriterKey struct{}
+
+// WithResponseWriter 在请求上下文中保存原始ResponseWriter
+// 路由处理函数收到的ResponseWriter经过magicEngine包装，不支持Flush和Hijack
+func WithResponseWriter(req *http.Request, res http.ResponseWriter) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), responseWriterKey{}, res))
+}
+
+// RawResponseWriter 获取原始ResponseWriter，只用于Flush和Hijack，响应头和内容仍通过包装后的ResponseWriter写入
+func RawResponseWriter(req *http.Request) http.ResponseWriter {
+	res, _ := req.Context().Value(responseWriterKey{}).(http.ResponseWriter)
+	return res
+}
+
+// Flush 立即发送已写入的内容
+func Flush(req *http.Request) {
+	if flusher, ok := RawResponseWriter(req).(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// hijackWriter 记录连接是否已被Hijack，Hijack后忽略magicEngine补写的响应，避免net/http输出告警
+type hijackWriter struct {
+	http.ResponseWriter
+	hijacked bool
+}
+
+// NewHijackWriter 包装原始ResponseWriter，保留Flush和Hijack能力
+func NewHijackWriter(res http.ResponseWriter) http.ResponseWriter {
+	return &hijackWriter{ResponseWriter: res}
+}
+
+func (s *hijackWriter) WriteHeader(statusCode int) {
+	if s.hijacked {
+		return
+	}
+
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *hijackWriter) Write(data []byte) (int, error) {
+	if s.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *hijackWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok && !s.hijacked {
+		flusher.Flush()
+	}
+}
+
+func (s *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, bufRW, err := hijacker.Hijack()
+	if err == nil {
+		s.hijacked = true
+	}
+	return conn, bufRW, err
+}
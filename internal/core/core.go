@@ -1,6 +1,9 @@
 package core
 
 import (
+	"fmt"
+	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -8,15 +11,23 @@ import (
 
 	cd "github.com/muidea/magicCommon/def"
 	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
 	"github.com/muidea/magicCommon/module"
 	"github.com/muidea/magicCommon/task"
 
+	"github.com/muidea/magicAgent/internal/core/base/service"
 	"github.com/muidea/magicAgent/pkg/common"
 
 	_ "github.com/muidea/magicAgent/internal/core/kernel/base"
 	_ "github.com/muidea/magicAgent/internal/core/module/alarm"
-	_ "github.com/muidea/magicAgent/internal/core/module/docker"
+	_ "github.com/muidea/magicAgent/internal/core/module/backup"
+	_ "github.com/muidea/magicAgent/internal/core/module/host"
+	_ "github.com/muidea/magicAgent/internal/core/module/job"
 	_ "github.com/muidea/magicAgent/internal/core/module/mariadb"
+	_ "github.com/muidea/magicAgent/internal/core/module/postgres"
+	_ "github.com/muidea/magicAgent/internal/core/module/probe"
+	_ "github.com/muidea/magicAgent/internal/core/module/redis"
+	_ "github.com/muidea/magicAgent/internal/core/module/runtime"
 )
 
 type timerCheckTask struct {
@@ -80,7 +91,7 @@ func (s *Core) Run() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		s.httpServer.Run()
+		s.serveHTTP()
 	}()
 
 	wg.Add(1)
@@ -102,6 +113,21 @@ func (s *Core) Run() {
 	wg.Wait()
 }
 
+// serveHTTP 代替httpServer.Run，在请求上下文中保存原始ResponseWriter，供日志流等接口Flush，交互式会话Hijack
+func (s *Core) serveHTTP() {
+	engineHandler := s.httpServer.(http.Handler)
+	handler := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		rawRes := service.NewHijackWriter(res)
+		engineHandler.ServeHTTP(rawRes, service.WithResponseWriter(req, rawRes))
+	})
+
+	listenAddr := fmt.Sprintf(":%s", s.listenPort)
+	log.Infof("listening on %s", listenAddr)
+	err := http.ListenAndServe(listenAddr, handler)
+	log.Criticalf("run httpserver fatal, err:%s", err.Error())
+	os.Exit(1)
+}
+
 // Shutdown 销毁
 func (s *Core) Shutdown() {
 	modules := module.GetModules()
@@ -2,6 +2,7 @@ package biz
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/muidea/magicCommon/event"
@@ -16,9 +17,67 @@ import (
 type Base struct {
 	biz.Base
 
-	checkingFlag  bool
+	// 定时检测与容器事件处理互斥
+	checkLock   sync.Mutex
+	guardStatus map[string]*guardStatus
+
+	// 自动修复暂停状态
+	pauseLock     sync.RWMutex
+	pauseAll      bool
+	pauseReason   string
+	pauseServices map[string]string
+}
+
+// repeatAlarmInterval 问题未变化时重复告警的间隔
+const repeatAlarmInterval = 10 * time.Minute
+
+// issueState 持续性问题的告警状态，issue为当前问题，since为问题出现时间，alarmed表示恢复前已发送过告警，failoverTime为最近一次尝试切换的时间
+type issueState struct {
+	issue        string
+	since        time.Time
+	alarmed      bool
+	alarmTime    time.Time
+	failoverTime time.Time
+}
+
+// update 记录本次检测到的问题，问题持续超过超时时间后需要告警，未变化时按repeatAlarmInterval重复告警，
+// 已告警的问题消失时recovered为true
+func (s *issueState) update(issue string) (alarm, recovered bool) {
+	if issue != s.issue {
+		recovered = issue == "" && s.alarmed
+		if recovered {
+			s.alarmed = false
+		}
+		s.issue = issue
+		s.since = time.Now()
+		s.alarmTime = time.Time{}
+	}
+	if issue == "" || time.Since(s.since) < time.Duration(config.GetTimeOut())*time.Second {
+		return
+	}
+	if !s.alarmTime.IsZero() && time.Since(s.alarmTime) < repeatAlarmInterval {
+		return
+	}
+
+	alarm = true
+	s.alarmed = true
+	s.alarmTime = time.Now()
+	return
+}
+
+// guardStatus 守护对象的异常计数，restartTime为最近一次重启时间，
+// componentKey、componentTime为最近一次Non-Primary处理结论及告警时间，replication为复制告警状态，redis、postgres为对应服务的问题告警状态，probe为证书过期告警状态，blockTime为数据卷已满阻止重启的告警时间
+type guardStatus struct {
 	unexpectCount int
 	unexpectTime  time.Time
+	restartTime   time.Time
+	componentKey  string
+	componentTime time.Time
+	replication   issueState
+	redis         issueState
+	postgres      issueState
+	probe         issueState
+	blockTime     time.Time
 }
 
 func New(
@@ -26,70 +85,286 @@ func New(
 	backgroundRoutine task.BackgroundRoutine,
 ) *Base {
 	ptr := &Base{
-		Base: biz.New(common.BaseModule, eventHub, backgroundRoutine),
+		Base:          biz.New(common.BaseModule, eventHub, backgroundRoutine),
+		guardStatus:   map[string]*guardStatus{},
+		pauseServices: map[string]string{},
 	}
 
 	ptr.SubscribeFunc(common.NotifyTimer, ptr.timerCheck)
+	ptr.SubscribeFunc(common.NotifyContainerEvent, ptr.containerEvent)
+	ptr.SubscribeFunc(common.PauseRemediation, ptr.pauseRemediation)
+	ptr.SubscribeFunc(common.ResumeRemediation, ptr.resumeRemediation)
+	ptr.SubscribeFunc(common.QueryRemediation, ptr.queryRemediation)
 
 	return ptr
 }
 
 func (s *Base) timerCheck(_ event.Event, _ event.Result) {
-	if s.checkingFlag {
+	// 避免并发执行
+	if !s.checkLock.TryLock() {
 		return
 	}
+	defer s.checkLock.Unlock()
 
-	// 避免并发执行
-	s.checkingFlag = true
-	defer func() {
-		s.checkingFlag = false
-	}()
+	for _, val := range config.GetGuards() {
+		s.checkGuard(val)
+	}
+}
+
+func (s *Base) checkGuard(guardPtr *config.GuardItem) {
+	switch guardPtr.Type {
+	case config.MariadbGuard:
+		s.checkMariadb(guardPtr.Name)
+	case config.RedisGuard:
+		s.checkRedis(guardPtr)
+	case config.PostgresGuard:
+		s.checkPostgres(guardPtr)
+	case config.ProbeGuard:
+		s.checkProbe(guardPtr)
+	}
+}
+
+func (s *Base) getGuardStatus(serviceName string) *guardStatus {
+	statusVal, statusOK := s.guardStatus[serviceName]
+	if !statusOK {
+		statusVal = &guardStatus{}
+		s.guardStatus[serviceName] = statusVal
+	}
+
+	return statusVal
+}
+
+func (s *Base) checkMariadb(mariadbService string) {
+	statusVal := s.getGuardStatus(mariadbService)
+
+	// 暂停期间不进行检测，恢复后重新计数
+	if s.isPaused(mariadbService) {
+		statusVal.unexpectCount = 0
+		return
+	}
 
-	mariadbService := config.GetGuards()
 	for {
 		currentTime := time.Now()
-		statusPtr := s.queryMariadbStatus(mariadbService)
-		if statusPtr != nil {
-			unexpectFlag := true
+		var statusPtr *common.ClusterStatus
+		checkedFlag, unexpectFlag := false, true
+		if guardPtr := config.GetGuard(mariadbService); guardPtr != nil && guardPtr.IsReplication() {
+			// 主从复制方式下服务可访问即视为正常，复制问题单独告警，不重启服务
+			if replicationPtr := s.queryReplicationStatus(mariadbService); replicationPtr != nil {
+				checkedFlag, unexpectFlag = true, false
+				s.checkReplication(guardPtr, statusVal, replicationPtr)
+			}
+		} else if statusPtr = s.queryMariadbStatus(mariadbService); statusPtr != nil {
+			checkedFlag = true
 			if statusPtr.IsNormal() {
 				// 即使是当前节点状态正常，只有集群节点数量超过半数，才认为正常
 				if statusPtr.NodeSize > len(config.GetClusterHosts())/2 {
 					unexpectFlag = false
 				}
 			}
-
-			if unexpectFlag {
-				// 如果节点状态异常，则要进行异常计数
-				if s.unexpectCount == 0 {
-					s.unexpectTime = currentTime
-				}
-
-				s.unexpectCount++
-			} else {
-				if s.unexpectCount > 0 {
-					log.Infof("Detected %s back to normal", mariadbService)
-				}
-				s.unexpectCount = 0
-			}
+		}
+		if !checkedFlag {
+			// 状态查询失败时检查容器状态，容器已退出同样视为异常
+			containerPtr := s.inspectContainer(mariadbService)
+			checkedFlag = containerPtr != nil && !containerPtr.IsRunning()
 		}
 
-		if s.unexpectCount < 3 {
+		if !s.countUnexpected(mariadbService, statusVal, currentTime, checkedFlag, unexpectFlag) {
 			break
 		}
 
-		// 持续超过3次检测异常，并且持续超过30s，这里就要考虑进行重启
-		if time.Since(s.unexpectTime) < time.Duration(config.GetTimeOut())*time.Second {
+		// Non-Primary时单纯重启往往无效，按各节点视图决定处理方式
+		if statusPtr != nil && statusPtr.Status == common.NonPrimary {
+			s.handleNonPrimary(mariadbService, statusVal)
+			statusVal.unexpectCount = 0
 			break
 		}
 
-		s.sendAlarmInfo(s.unexpectTime, mariadbService)
 		// 一旦需要对节点进行重启，这里就要主动重置异常计数值
-		s.restartMariadb(mariadbService)
-		s.unexpectCount = 0
+		s.restartGuard(statusVal.unexpectTime, mariadbService, "")
+		statusVal.unexpectCount = 0
+		statusVal.restartTime = time.Now()
 		break
 	}
+}
 
-	return
+// countUnexpected 记录检测结果，持续超过3次检测异常并且持续超过超时时间时返回true
+func (s *Base) countUnexpected(serviceName string, statusVal *guardStatus, currentTime time.Time, checkedFlag, unexpectFlag bool) bool {
+	if checkedFlag {
+		if unexpectFlag {
+			// 如果节点状态异常，则要进行异常计数
+			if statusVal.unexpectCount == 0 {
+				statusVal.unexpectTime = currentTime
+			}
+
+			statusVal.unexpectCount++
+		} else {
+			if statusVal.unexpectCount > 0 {
+				log.Infof("Detected %s back to normal", serviceName)
+			}
+			statusVal.unexpectCount = 0
+		}
+	}
+
+	if statusVal.unexpectCount < 3 {
+		return false
+	}
+
+	// 持续超过3次检测异常，并且持续超过30s，这里就要考虑进行重启
+	return time.Since(statusVal.unexpectTime) >= time.Duration(config.GetTimeOut())*time.Second
+}
+
+// containerEvent 守护对象容器退出或OOM时立即检查并重启，健康检查失败时立即进行一次检测
+func (s *Base) containerEvent(ev event.Event, _ event.Result) {
+	eventPtr, eventOK := ev.Data().(*common.ContainerEvent)
+	if !eventOK {
+		log.Warnf("containerEvent failed, illegal param")
+		return
+	}
+
+	guardPtr := config.GetGuard(eventPtr.Name)
+	if guardPtr == nil {
+		return
+	}
+
+	s.checkLock.Lock()
+	defer s.checkLock.Unlock()
+
+	switch eventPtr.Action {
+	case common.ContainerDie, common.ContainerOOM:
+		s.reactService(eventPtr)
+	case common.ContainerHealthStatus:
+		if eventPtr.Health == "unhealthy" {
+			s.checkGuard(guardPtr)
+		}
+	}
+}
+
+func (s *Base) reactService(eventPtr *common.ContainerEvent) {
+	mariadbService := eventPtr.Name
+	statusVal := s.getGuardStatus(mariadbService)
+	if s.isPaused(mariadbService) {
+		return
+	}
+
+	// 重启过程中同样会产生die事件，重启后超时时间内的事件忽略
+	if time.Since(statusVal.restartTime) < time.Duration(config.GetTimeOut())*time.Second {
+		return
+	}
+
+	// 运行时的重启策略可能已经拉起容器，这里交由定时检测继续判断
+	containerPtr := s.inspectContainer(mariadbService)
+	if containerPtr == nil || containerPtr.IsRunning() {
+		return
+	}
+
+	reason := fmt.Sprintf("container event: %s", eventPtr.Action)
+	if eventPtr.Action == common.ContainerDie {
+		reason += fmt.Sprintf(", exit code: %d", eventPtr.ExitCode)
+	}
+	log.Warnf("Detected %s %s, restart immediately", mariadbService, reason)
+
+	s.restartGuard(eventPtr.TimeStamp, mariadbService, reason)
+	statusVal.unexpectCount = 0
+	statusVal.restartTime = time.Now()
+}
+
+func (s *Base) pauseRemediation(ev event.Event, re event.Result) {
+	param, paramOK := ev.Data().(*common.RemediationParam)
+	if !paramOK {
+		log.Warnf("pauseRemediation failed, illegal param")
+		return
+	}
+
+	statusPtr := s.PauseRemediation(param)
+	if re != nil {
+		re.Set(statusPtr, nil)
+	}
+}
+
+func (s *Base) resumeRemediation(ev event.Event, re event.Result) {
+	param, paramOK := ev.Data().(*common.RemediationParam)
+	if !paramOK {
+		log.Warnf("resumeRemediation failed, illegal param")
+		return
+	}
+
+	statusPtr := s.ResumeRemediation(param)
+	if re != nil {
+		re.Set(statusPtr, nil)
+	}
+}
+
+func (s *Base) queryRemediation(_ event.Event, re event.Result) {
+	if re != nil {
+		re.Set(s.QueryRemediation(), nil)
+	}
+}
+
+// PauseRemediation 暂停自动修复，Service为空时暂停全部守护对象
+func (s *Base) PauseRemediation(param *common.RemediationParam) *common.RemediationStatus {
+	func() {
+		s.pauseLock.Lock()
+		defer s.pauseLock.Unlock()
+
+		if param.Service == "" {
+			s.pauseAll = true
+			s.pauseReason = param.Reason
+			return
+		}
+
+		s.pauseServices[param.Service] = param.Reason
+	}()
+
+	log.Infof("pause remediation, service:%s, reason:%s", param.Service, param.Reason)
+	return s.QueryRemediation()
+}
+
+// ResumeRemediation 恢复自动修复，Service为空时恢复全部守护对象
+func (s *Base) ResumeRemediation(param *common.RemediationParam) *common.RemediationStatus {
+	func() {
+		s.pauseLock.Lock()
+		defer s.pauseLock.Unlock()
+
+		if param.Service == "" {
+			s.pauseAll = false
+			s.pauseReason = ""
+			s.pauseServices = map[string]string{}
+			return
+		}
+
+		delete(s.pauseServices, param.Service)
+	}()
+
+	log.Infof("resume remediation, service:%s", param.Service)
+	return s.QueryRemediation()
+}
+
+func (s *Base) QueryRemediation() *common.RemediationStatus {
+	s.pauseLock.RLock()
+	defer s.pauseLock.RUnlock()
+
+	statusPtr := &common.RemediationStatus{
+		Paused:   s.pauseAll,
+		Reason:   s.pauseReason,
+		Services: map[string]string{},
+	}
+	for key, val := range s.pauseServices {
+		statusPtr.Services[key] = val
+	}
+
+	return statusPtr
+}
+
+func (s *Base) isPaused(serviceName string) bool {
+	s.pauseLock.RLock()
+	defer s.pauseLock.RUnlock()
+
+	if s.pauseAll {
+		return true
+	}
+
+	_, ok := s.pauseServices[serviceName]
+	return ok
 }
 
 func (s *Base) queryMariadbStatus(mariadbService string) *common.ClusterStatus {
@@ -106,25 +381,123 @@ func (s *Base) queryMariadbStatus(mariadbService string) *common.ClusterStatus {
 	return statusVal.(*common.ClusterStatus)
 }
 
-func (s *Base) restartMariadb(mariadbService string) {
-	ev := event.NewEvent(common.StopService, s.ID(), common.DockerModule, nil, mariadbService)
-	result := s.SendEvent(ev)
-	_, stopErr := result.Get()
-	if stopErr != nil {
-		log.Errorf("restartMariadb failed, error:%s", stopErr.Error())
+func (s *Base) queryReplicationStatus(mariadbService string) *common.ReplicationStatus {
+	ev := event.NewEvent(common.QueryReplication, s.ID(), common.MariadbModule, nil, mariadbService)
+	statusVal, statusErr := s.SendEvent(ev).Get()
+	if statusErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryReplicationStatus failed, error:%s", statusErr.Error())
+		}
+		return nil
+	}
+
+	statusPtr, _ := statusVal.(*common.ReplicationStatus)
+	return statusPtr
+}
+
+// restartGuard 告警并重启守护对象；数据卷已满时重启无法恢复服务，改为告警，等待人工处理
+func (s *Base) restartGuard(timeStamp time.Time, serviceName, reason string) {
+	volumePtr := s.queryDataVolume(serviceName)
+	statusVal := s.getGuardStatus(serviceName)
+	curTime := time.Now()
+	blocked, alarm := restartBlocked(volumePtr, statusVal.blockTime, curTime)
+	if !blocked {
+		s.sendAlarmInfo(timeStamp, serviceName, reason)
+		s.restartService(serviceName)
+		return
+	}
+
+	log.Warnf("Detected %s data volume %s full, usage:%.1f%%, inode usage:%.1f%%, restart blocked",
+		serviceName, volumePtr.Path, volumePtr.Usage.Usage, volumePtr.Usage.InodeUsage)
+	if !alarm {
 		return
 	}
+	statusVal.blockTime = curTime
 
-	ev = event.NewEvent(common.StartService, s.ID(), common.DockerModule, nil, mariadbService)
-	result = s.SendEvent(ev)
-	_, startErr := result.Get()
-	if startErr != nil {
-		log.Errorf("restartMariadb failed, error:%s", startErr.Error())
+	content := fmt.Sprintf("Node-%s service-%s exception was detected at %v but restart is blocked, data volume %s is full, usage %.1f%%, %d bytes available, inode usage %.1f%%",
+		config.GetLocalHost(),
+		serviceName,
+		timeStamp,
+		volumePtr.Path,
+		volumePtr.Usage.Usage,
+		volumePtr.Usage.Available,
+		volumePtr.Usage.InodeUsage,
+	)
+	if reason != "" {
+		content += ", " + reason
+	}
+	alarmInfo := &common.AlarmInfo{Title: "Restart Blocked", Content: content}
+	s.PostEvent(event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo))
+}
+
+// restartBlocked 数据卷已满时阻止重启，距上次阻止告警blockTime超过repeatAlarmInterval时需要再次告警
+func restartBlocked(volumePtr *common.DataVolumeStatus, blockTime, curTime time.Time) (blocked, alarm bool) {
+	if volumePtr == nil || !volumePtr.Full {
 		return
 	}
+
+	blocked = true
+	alarm = blockTime.IsZero() || curTime.Sub(blockTime) >= repeatAlarmInterval
+	return
+}
+
+// queryDataVolume 无法确定数据卷时返回空，不阻止重启
+func (s *Base) queryDataVolume(serviceName string) *common.DataVolumeStatus {
+	ev := event.NewEvent(common.QueryDataVolume, s.ID(), common.HostModule, nil, serviceName)
+	volumeVal, volumeErr := s.SendEvent(ev).Get()
+	if volumeErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryDataVolume failed, error:%s", volumeErr.Error())
+		}
+		return nil
+	}
+
+	volumePtr, _ := volumeVal.(*common.DataVolumeStatus)
+	return volumePtr
 }
 
-func (s *Base) sendAlarmInfo(timeStamp time.Time, mariadbService string) {
+func (s *Base) restartService(serviceName string) {
+	ev := event.NewEvent(common.RestartService, s.ID(), common.RuntimeModule, nil, serviceName)
+	result := s.SendEvent(ev)
+	_, restartErr := result.Get()
+	if restartErr != nil {
+		log.Errorf("restartService failed, error:%s", restartErr.Error())
+		return
+	}
+}
+
+func (s *Base) inspectContainer(serviceName string) *common.ContainerInfo {
+	ev := event.NewEvent(common.InspectContainer, s.ID(), common.RuntimeModule, nil, serviceName)
+	result := s.SendEvent(ev)
+	infoVal, infoErr := result.Get()
+	if infoErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("inspectContainer failed, error:%s", infoErr.Error())
+		}
+		return nil
+	}
+
+	infoPtr, _ := infoVal.(*common.ContainerInfo)
+	return infoPtr
+}
+
+// queryContainerLogs 采集服务最近的日志，失败时返回空
+func (s *Base) queryContainerLogs(serviceName string, tail int) string {
+	param := &common.ContainerLogParam{Service: serviceName, Tail: tail}
+	ev := event.NewEvent(common.QueryContainerLogs, s.ID(), common.RuntimeModule, nil, param)
+	result := s.SendEvent(ev)
+	logVal, logErr := result.Get()
+	if logErr != nil {
+		log.Errorf("queryContainerLogs failed, service:%s, error:%s", serviceName, logErr.Error())
+		return ""
+	}
+
+	logs, _ := logVal.(string)
+	return logs
+}
+
+// sendAlarmInfo 发送告警，reason为触发原因，可为空
+func (s *Base) sendAlarmInfo(timeStamp time.Time, mariadbService, reason string) {
 	alarmInfo := &common.AlarmInfo{
 		Title: "Exception Alerts",
 		Content: fmt.Sprintf("Node-%s service-%s exception was detected and a restart of the service is in progress. Exception time: %v, restart time: %v",
@@ -134,6 +507,20 @@ func (s *Base) sendAlarmInfo(timeStamp time.Time, mariadbService string) {
 			time.Now(),
 		),
 	}
+	if reason != "" {
+		alarmInfo.Content += ", " + reason
+	}
+	if containerPtr := s.inspectContainer(mariadbService); containerPtr != nil {
+		alarmInfo.Content += fmt.Sprintf(", container state: %s, exit code: %d, OOMKilled: %v, restart count: %d",
+			containerPtr.State,
+			containerPtr.ExitCode,
+			containerPtr.OOMKilled,
+			containerPtr.RestartCount,
+		)
+	}
+	if logLines := config.GetAlarmLogLines(); logLines > 0 {
+		alarmInfo.Logs = s.queryContainerLogs(mariadbService, logLines)
+	}
 
 	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
 	s.PostEvent(ev)
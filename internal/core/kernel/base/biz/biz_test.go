@@ -0,0 +1,36 @@
+package biz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestRestartBlocked(t *testing.T) {
+	curTime := time.Now()
+	full := &common.DataVolumeStatus{Path: "/data", Full: true, Usage: &common.MountUsage{Usage: 99}}
+	cases := []struct {
+		name      string
+		volume    *common.DataVolumeStatus
+		blockTime time.Time
+		blocked   bool
+		alarm     bool
+	}{
+		{name: "volume unknown"},
+		{name: "volume not full", volume: &common.DataVolumeStatus{Path: "/data", Usage: &common.MountUsage{Usage: 50}}},
+		{name: "not full after block", volume: &common.DataVolumeStatus{Path: "/data", Usage: &common.MountUsage{}}, blockTime: curTime.Add(-time.Minute)},
+		{name: "first block", volume: full, blocked: true, alarm: true},
+		{name: "block alarmed recently", volume: full, blockTime: curTime.Add(-time.Minute), blocked: true},
+		{name: "block alarm repeat", volume: full, blockTime: curTime.Add(-repeatAlarmInterval), blocked: true, alarm: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			blocked, alarm := restartBlocked(val.volume, val.blockTime, curTime)
+			if blocked != val.blocked || alarm != val.alarm {
+				t.Errorf("blocked %v, alarm %v, expect %v, %v", blocked, alarm, val.blocked, val.alarm)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package biz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// handleNonPrimary 其他节点存在Primary组件时重启本节点；不存在时由被选中的节点执行pc.bootstrap，
+// 未开启autoBootstrap时只在告警中给出建议；无法判断时不做处理，等待人工介入
+func (s *Base) handleNonPrimary(mariadbService string, statusVal *guardStatus) {
+	ev := event.NewEvent(common.QueryComponent, s.ID(), common.MariadbModule, nil, mariadbService)
+	decisionVal, decisionErr := s.SendEvent(ev).Get()
+	decisionPtr, _ := decisionVal.(*common.ComponentDecision)
+	if decisionErr != nil || decisionPtr == nil {
+		reason := "Non-Primary component"
+		if decisionErr != nil {
+			reason += fmt.Sprintf(", analyze component failed: %s", decisionErr.Reason)
+		}
+		s.restartGuard(statusVal.unexpectTime, mariadbService, reason)
+		statusVal.restartTime = time.Now()
+		return
+	}
+
+	action := ""
+	performed := false
+	switch decisionPtr.Action {
+	case common.ComponentNormal:
+		return
+	case common.ComponentRestart:
+		s.restartGuard(statusVal.unexpectTime, mariadbService, decisionPtr.Reason)
+		statusVal.restartTime = time.Now()
+		return
+	case common.ComponentBootstrap:
+		guardPtr := config.GetGuard(mariadbService)
+		switch {
+		case decisionPtr.Candidate != config.AgentAddress(config.GetLocalHost()):
+			action = fmt.Sprintf("waiting for %s to bootstrap", decisionPtr.Candidate)
+		case guardPtr != nil && guardPtr.AutoBootstrap:
+			performed = true
+			action = "pc.bootstrap performed on local node"
+			bootstrapEvent := event.NewEvent(common.BootstrapNode, s.ID(), common.MariadbModule, nil, mariadbService)
+			if _, bootstrapErr := s.SendEvent(bootstrapEvent).Get(); bootstrapErr != nil {
+				action = fmt.Sprintf("pc.bootstrap on local node failed, %s", bootstrapErr.Reason)
+			}
+		default:
+			action = fmt.Sprintf("autoBootstrap disabled, run 'magicagentctl bootstrap -service %s' on %s", mariadbService, decisionPtr.Candidate)
+		}
+	default:
+		action = "no automatic action, manual intervention required"
+	}
+	log.Warnf("Detected %s in Non-Primary component, decision:%s, %s, reason:%s", mariadbService, decisionPtr.Action, action, decisionPtr.Reason)
+
+	// 结论不变时不重复告警
+	componentKey := decisionPtr.Action + "|" + decisionPtr.Candidate + "|" + action
+	if !performed && componentKey == statusVal.componentKey && time.Since(statusVal.componentTime) < repeatAlarmInterval {
+		return
+	}
+	statusVal.componentKey = componentKey
+	statusVal.componentTime = time.Now()
+
+	alarmInfo := &common.AlarmInfo{
+		Title: "Non-Primary Component",
+		Content: fmt.Sprintf("Node-%s service-%s is in Non-Primary component since %v, decision: %s, %s. Reason: %s",
+			config.GetLocalHost(),
+			mariadbService,
+			statusVal.unexpectTime,
+			decisionPtr.Action,
+			action,
+			decisionPtr.Reason,
+		),
+	}
+	s.PostEvent(event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo))
+}
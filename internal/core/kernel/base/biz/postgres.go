@@ -0,0 +1,136 @@
+package biz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// 非活动复制槽保留的WAL超过该值时告警
+const postgresSlotRetained = 1024 * 1024 * 1024
+
+// postgresConnectionRatio 连接数超过max_connections的比例时告警
+const postgresConnectionRatio = 0.9
+
+// postgres问题，同一时间只告警优先级最高的一个
+const (
+	postgresReceiver    = "receiver"
+	postgresLag         = "lag"
+	postgresSlot        = "slot"
+	postgresConnections = "connections"
+)
+
+var postgresAlarmTitle = map[string]string{
+	postgresReceiver:    "Postgres Replication Broken",
+	postgresLag:         "Postgres Replication Lag",
+	postgresSlot:        "Postgres Replication Slot",
+	postgresConnections: "Postgres Connections",
+}
+
+// checkPostgres 无法连接或查询失败时按重启策略计数；
+// 备库WAL接收中断、复制延迟、非活动复制槽堆积WAL及连接数接近上限时只告警，不重启服务
+func (s *Base) checkPostgres(guardPtr *config.GuardItem) {
+	postgresService := guardPtr.Name
+	statusVal := s.getGuardStatus(postgresService)
+
+	// 暂停期间不进行检测，恢复后重新计数
+	if s.isPaused(postgresService) {
+		statusVal.unexpectCount = 0
+		return
+	}
+
+	currentTime := time.Now()
+	statusPtr := s.queryPostgresStatus(postgresService)
+	if statusPtr != nil {
+		s.checkPostgresIssue(guardPtr, statusVal, statusPtr)
+	}
+
+	if !s.countUnexpected(postgresService, statusVal, currentTime, true, statusPtr == nil) {
+		return
+	}
+
+	s.restartGuard(statusVal.unexpectTime, postgresService, "postgres not responding")
+	statusVal.unexpectCount = 0
+	statusVal.restartTime = time.Now()
+}
+
+// postgresIssue 按WAL接收中断、复制延迟、非活动复制槽堆积WAL、连接数接近上限的优先级返回一个问题
+func postgresIssue(guardPtr *config.GuardItem, statusPtr *common.PostgresStatus) (issue, detail string) {
+	lagItems, slotItems := []string{}, []string{}
+	for _, val := range statusPtr.Replicas {
+		if val.ReplayLag > float64(guardPtr.GetMaxReplicaLag()) {
+			lagItems = append(lagItems, fmt.Sprintf("%s(%s) %.0f seconds, %d bytes", val.ApplicationName, val.ClientAddr, val.ReplayLag, val.LagBytes))
+		}
+	}
+	for _, val := range statusPtr.Slots {
+		if !val.Active && val.RetainedBytes > postgresSlotRetained {
+			slotItems = append(slotItems, fmt.Sprintf("%s retained %d bytes", val.Name, val.RetainedBytes))
+		}
+	}
+
+	if statusPtr.IsReceiverDown() {
+		issue, detail = postgresReceiver, "wal receiver not running"
+		if statusPtr.Receiver != nil {
+			detail = fmt.Sprintf("wal receiver %s, sender %s:%d", statusPtr.Receiver.Status, statusPtr.Receiver.SenderHost, statusPtr.Receiver.SenderPort)
+		}
+	} else if len(lagItems) > 0 {
+		issue, detail = postgresLag, "replay lag "+strings.Join(lagItems, ", ")
+	} else if len(slotItems) > 0 {
+		issue, detail = postgresSlot, "inactive slot "+strings.Join(slotItems, ", ")
+	} else if statusPtr.MaxConnections > 0 && float64(statusPtr.Connections) > float64(statusPtr.MaxConnections)*postgresConnectionRatio {
+		issue = postgresConnections
+		detail = fmt.Sprintf("connections %d of max_connections %d", statusPtr.Connections, statusPtr.MaxConnections)
+	}
+
+	return
+}
+
+func (s *Base) checkPostgresIssue(guardPtr *config.GuardItem, statusVal *guardStatus, statusPtr *common.PostgresStatus) {
+	issue, detail := postgresIssue(guardPtr, statusPtr)
+
+	stateVal := &statusVal.postgres
+	alarm, recovered := stateVal.update(issue)
+	if recovered {
+		log.Infof("Detected %s back to normal", guardPtr.Name)
+		s.sendPostgresAlarm("Postgres Recovered", guardPtr.Name, statusPtr, "back to normal")
+	}
+	if alarm {
+		log.Warnf("Detected %s %s, %s", guardPtr.Name, issue, detail)
+		s.sendPostgresAlarm(postgresAlarmTitle[issue], guardPtr.Name, statusPtr, fmt.Sprintf("since %v, %s", stateVal.since, detail))
+	}
+}
+
+func (s *Base) sendPostgresAlarm(title, postgresService string, statusPtr *common.PostgresStatus, content string) {
+	alarmInfo := &common.AlarmInfo{
+		Title: title,
+		Content: fmt.Sprintf("Node-%s service-%s role %s, %s",
+			config.GetLocalHost(),
+			postgresService,
+			statusPtr.Role,
+			content,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
+
+func (s *Base) queryPostgresStatus(postgresService string) *common.PostgresStatus {
+	ev := event.NewEvent(common.QueryPostgresStatus, s.ID(), common.PostgresModule, nil, postgresService)
+	statusVal, statusErr := s.SendEvent(ev).Get()
+	if statusErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryPostgresStatus failed, error:%s", statusErr.Error())
+		}
+		return nil
+	}
+
+	statusPtr, _ := statusVal.(*common.PostgresStatus)
+	return statusPtr
+}
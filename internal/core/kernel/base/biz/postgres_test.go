@@ -0,0 +1,80 @@
+package biz
+
+import (
+	"testing"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestPostgresIssue(t *testing.T) {
+	streaming := &common.WalReceiver{Status: "streaming", SenderHost: "10.0.0.1", SenderPort: 5432}
+	cases := []struct {
+		name   string
+		guard  *config.GuardItem
+		status *common.PostgresStatus
+		issue  string
+		detail string
+	}{
+		{
+			name:   "healthy standby",
+			guard:  &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresStandby, InRecovery: true, Receiver: streaming},
+		},
+		{
+			name:   "receiver not running",
+			guard:  &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresStandby, InRecovery: true, Connections: 95, MaxConnections: 100},
+			issue:  postgresReceiver,
+			detail: "wal receiver not running",
+		},
+		{
+			name:   "receiver stopped",
+			guard:  &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresStandby, InRecovery: true, Receiver: &common.WalReceiver{Status: "stopping", SenderHost: "10.0.0.1", SenderPort: 5432}},
+			issue:  postgresReceiver,
+			detail: "wal receiver stopping, sender 10.0.0.1:5432",
+		},
+		{
+			name:  "replay lag",
+			guard: &config.GuardItem{MaxReplicaLag: 10},
+			status: &common.PostgresStatus{Role: common.PostgresPrimary, Replicas: []*common.PostgresReplica{
+				{ApplicationName: "s1", ClientAddr: "10.0.0.2", ReplayLag: 5},
+				{ApplicationName: "s2", ClientAddr: "10.0.0.3", ReplayLag: 30.4, LagBytes: 4096},
+			}},
+			issue:  postgresLag,
+			detail: "replay lag s2(10.0.0.3) 30 seconds, 4096 bytes",
+		},
+		{
+			name:  "inactive slot",
+			guard: &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresPrimary, Slots: []*common.ReplicationSlot{
+				{Name: "active", Active: true, RetainedBytes: 2 * postgresSlotRetained},
+				{Name: "stale", RetainedBytes: postgresSlotRetained + 1},
+			}},
+			issue:  postgresSlot,
+			detail: "inactive slot stale retained 1073741825 bytes",
+		},
+		{
+			name:   "connections",
+			guard:  &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresPrimary, Connections: 91, MaxConnections: 100},
+			issue:  postgresConnections,
+			detail: "connections 91 of max_connections 100",
+		},
+		{
+			name:   "connections below ratio",
+			guard:  &config.GuardItem{},
+			status: &common.PostgresStatus{Role: common.PostgresPrimary, Connections: 90, MaxConnections: 100},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			issue, detail := postgresIssue(val.guard, val.status)
+			if issue != val.issue || detail != val.detail {
+				t.Errorf("issue %q, detail %q, expect %q, %q", issue, detail, val.issue, val.detail)
+			}
+		})
+	}
+}
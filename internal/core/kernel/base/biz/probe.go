@@ -0,0 +1,117 @@
+package biz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// probeCertExpiring 证书即将过期，重启无法恢复，只告警
+const probeCertExpiring = "cert expiring"
+
+// checkProbe 任一探测失败时按重启策略计数，持续失败后重启所在容器
+func (s *Base) checkProbe(guardPtr *config.GuardItem) {
+	probeService := guardPtr.Name
+	statusVal := s.getGuardStatus(probeService)
+
+	// 暂停期间不进行检测，恢复后重新计数
+	if s.isPaused(probeService) {
+		statusVal.unexpectCount = 0
+		return
+	}
+
+	currentTime := time.Now()
+	statusPtr := s.queryProbeStatus(probeService)
+	if statusPtr != nil {
+		s.checkProbeCert(guardPtr, statusVal, statusPtr)
+	}
+
+	if !s.countUnexpected(probeService, statusVal, currentTime, true, statusPtr == nil || !statusPtr.Healthy) {
+		return
+	}
+
+	reason := "probe not available"
+	if statusPtr != nil {
+		reason = describeProbeFailure(statusPtr)
+	}
+	s.restartGuard(statusVal.unexpectTime, probeService, reason)
+	statusVal.unexpectCount = 0
+	statusVal.restartTime = time.Now()
+}
+
+func (s *Base) checkProbeCert(guardPtr *config.GuardItem, statusVal *guardStatus, statusPtr *common.ProbeStatus) {
+	items := expiringCerts(statusPtr)
+	issue := ""
+	if len(items) > 0 {
+		issue = probeCertExpiring
+	}
+
+	stateVal := &statusVal.probe
+	alarm, recovered := stateVal.update(issue)
+	if recovered {
+		log.Infof("Detected %s certificate renewed", guardPtr.Name)
+		s.sendProbeAlarm("Certificate Renewed", guardPtr.Name, "back to normal")
+	}
+	if alarm {
+		detail := strings.Join(items, ", ")
+		log.Warnf("Detected %s %s, %s", guardPtr.Name, issue, detail)
+		s.sendProbeAlarm("Certificate Expiring", guardPtr.Name, fmt.Sprintf("since %v, %s", stateVal.since, detail))
+	}
+}
+
+// expiringCerts 即将过期的证书及过期时间
+func expiringCerts(statusPtr *common.ProbeStatus) []string {
+	items := []string{}
+	for _, val := range statusPtr.Results {
+		if val.CertExpiring && val.CertExpire != nil {
+			items = append(items, fmt.Sprintf("%s expire at %s", val.Target, val.CertExpire.Format(time.RFC3339)))
+		}
+	}
+
+	return items
+}
+
+func describeProbeFailure(statusPtr *common.ProbeStatus) string {
+	items := []string{}
+	for _, val := range statusPtr.Results {
+		if !val.Healthy {
+			items = append(items, fmt.Sprintf("%s probe %s failed, %s", val.Type, val.Target, val.Reason))
+		}
+	}
+
+	return strings.Join(items, "; ")
+}
+
+func (s *Base) sendProbeAlarm(title, probeService, content string) {
+	alarmInfo := &common.AlarmInfo{
+		Title: title,
+		Content: fmt.Sprintf("Node-%s service-%s, %s",
+			config.GetLocalHost(),
+			probeService,
+			content,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
+
+func (s *Base) queryProbeStatus(probeService string) *common.ProbeStatus {
+	ev := event.NewEvent(common.QueryProbeStatus, s.ID(), common.ProbeModule, nil, probeService)
+	statusVal, statusErr := s.SendEvent(ev).Get()
+	if statusErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryProbeStatus failed, error:%s", statusErr.Error())
+		}
+		return nil
+	}
+
+	statusPtr, _ := statusVal.(*common.ProbeStatus)
+	return statusPtr
+}
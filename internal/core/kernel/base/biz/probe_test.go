@@ -0,0 +1,30 @@
+package biz
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestProbeDescribe(t *testing.T) {
+	expire := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	statusPtr := &common.ProbeStatus{Results: []*common.ProbeResult{
+		{Type: "tcp", Target: "127.0.0.1:80", Healthy: true},
+		{Type: "http", Target: "https://a/health", Reason: "unexpected status 500, expect 200"},
+		{Type: "tls", Target: "b:443", Healthy: true, CertExpiring: true, CertExpire: &expire},
+		{Type: "tls", Target: "c:443", Healthy: true, CertExpire: &expire},
+		{Type: "command", Target: "check.sh", Reason: "exit code 2"},
+	}}
+
+	failure := describeProbeFailure(statusPtr)
+	if failure != "http probe https://a/health failed, unexpected status 500, expect 200; command probe check.sh failed, exit code 2" {
+		t.Errorf("failure %q", failure)
+	}
+
+	items := expiringCerts(statusPtr)
+	if strings.Join(items, ",") != "b:443 expire at 2026-11-01T00:00:00Z" {
+		t.Errorf("expiring %q", items)
+	}
+}
@@ -0,0 +1,131 @@
+package biz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// redisMemoryRatio 已用内存超过maxmemory的比例时告警
+const redisMemoryRatio = 0.9
+
+// redis问题，同一时间只告警优先级最高的一个
+const (
+	redisLinkDown    = "link down"
+	redisPersistence = "persistence"
+	redisMemory      = "memory"
+	redisSentinel    = "sentinel"
+)
+
+var redisAlarmTitle = map[string]string{
+	redisLinkDown:    "Redis Replication Broken",
+	redisPersistence: "Redis Persistence Failed",
+	redisMemory:      "Redis Memory",
+	redisSentinel:    "Redis Sentinel",
+}
+
+// checkRedis 无法连接或INFO失败时按重启策略计数，加载数据期间视为正常；
+// 复制中断、持久化失败、内存不足及Sentinel发现主节点下线时只告警，不重启服务
+func (s *Base) checkRedis(guardPtr *config.GuardItem) {
+	redisService := guardPtr.Name
+	statusVal := s.getGuardStatus(redisService)
+
+	// 暂停期间不进行检测，恢复后重新计数
+	if s.isPaused(redisService) {
+		statusVal.unexpectCount = 0
+		return
+	}
+
+	currentTime := time.Now()
+	statusPtr := s.queryRedisStatus(redisService)
+	if statusPtr != nil && !statusPtr.Loading {
+		s.checkRedisIssue(guardPtr, statusVal, statusPtr)
+	}
+
+	// 直接通过RESP访问，容器运行但无法连接说明服务已无响应，同样计为异常
+	if !s.countUnexpected(redisService, statusVal, currentTime, true, statusPtr == nil) {
+		return
+	}
+
+	s.restartGuard(statusVal.unexpectTime, redisService, "redis not responding")
+	statusVal.unexpectCount = 0
+	statusVal.restartTime = time.Now()
+}
+
+// redisIssue Sentinel进程检查各主节点状态，其他按复制中断、持久化失败、内存不足的优先级返回一个问题
+func redisIssue(statusPtr *common.RedisStatus) (issue, detail string) {
+	if statusPtr.Role == common.RedisSentinel {
+		items := []string{}
+		for _, val := range statusPtr.SentinelMasters {
+			if val.Status != "ok" {
+				items = append(items, fmt.Sprintf("master %s(%s) %s", val.Name, val.Address, val.Status))
+			}
+		}
+		if len(items) > 0 {
+			issue, detail = redisSentinel, strings.Join(items, ", ")
+		}
+	} else if statusPtr.IsLinkDown() {
+		issue = redisLinkDown
+		detail = fmt.Sprintf("master %s:%d link %s, last IO %d seconds ago",
+			statusPtr.MasterHost, statusPtr.MasterPort, statusPtr.MasterLinkStatus, statusPtr.MasterLastIOSeconds)
+	} else if persistenceErr := statusPtr.PersistenceError(); persistenceErr != "" {
+		issue = redisPersistence
+		detail = fmt.Sprintf("%s, %d changes since last save", persistenceErr, statusPtr.RDBChangesSinceSave)
+	} else if statusPtr.MaxMemory > 0 && float64(statusPtr.UsedMemory) > float64(statusPtr.MaxMemory)*redisMemoryRatio {
+		issue = redisMemory
+		detail = fmt.Sprintf("used memory %d of maxmemory %d, fragmentation ratio %.2f",
+			statusPtr.UsedMemory, statusPtr.MaxMemory, statusPtr.FragmentationRatio)
+	}
+
+	return
+}
+
+func (s *Base) checkRedisIssue(guardPtr *config.GuardItem, statusVal *guardStatus, statusPtr *common.RedisStatus) {
+	issue, detail := redisIssue(statusPtr)
+
+	stateVal := &statusVal.redis
+	alarm, recovered := stateVal.update(issue)
+	if recovered {
+		log.Infof("Detected %s back to normal", guardPtr.Name)
+		s.sendRedisAlarm("Redis Recovered", guardPtr.Name, statusPtr, "back to normal")
+	}
+	if alarm {
+		log.Warnf("Detected %s %s, %s", guardPtr.Name, issue, detail)
+		s.sendRedisAlarm(redisAlarmTitle[issue], guardPtr.Name, statusPtr, fmt.Sprintf("since %v, %s", stateVal.since, detail))
+	}
+}
+
+func (s *Base) sendRedisAlarm(title, redisService string, statusPtr *common.RedisStatus, content string) {
+	alarmInfo := &common.AlarmInfo{
+		Title: title,
+		Content: fmt.Sprintf("Node-%s service-%s role %s, %s",
+			config.GetLocalHost(),
+			redisService,
+			statusPtr.Role,
+			content,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
+
+func (s *Base) queryRedisStatus(redisService string) *common.RedisStatus {
+	ev := event.NewEvent(common.QueryRedisStatus, s.ID(), common.RedisModule, nil, redisService)
+	statusVal, statusErr := s.SendEvent(ev).Get()
+	if statusErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryRedisStatus failed, error:%s", statusErr.Error())
+		}
+		return nil
+	}
+
+	statusPtr, _ := statusVal.(*common.RedisStatus)
+	return statusPtr
+}
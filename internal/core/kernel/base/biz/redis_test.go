@@ -0,0 +1,65 @@
+package biz
+
+import (
+	"testing"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestRedisIssue(t *testing.T) {
+	cases := []struct {
+		name   string
+		status *common.RedisStatus
+		issue  string
+		detail string
+	}{
+		{
+			name:   "healthy master",
+			status: &common.RedisStatus{Role: common.RedisMaster, RDBLastBgsaveStatus: "ok", UsedMemory: 100, MaxMemory: 1000},
+		},
+		{
+			name: "sentinel master down",
+			status: &common.RedisStatus{Role: common.RedisSentinel, SentinelMasters: []*common.SentinelMaster{
+				{Name: "mymaster", Status: "ok", Address: "10.0.0.1:6379"},
+				{Name: "cache", Status: "odown", Address: "10.0.0.2:6379"},
+			}},
+			issue:  redisSentinel,
+			detail: "master cache(10.0.0.2:6379) odown",
+		},
+		{
+			name:   "link down before persistence",
+			status: &common.RedisStatus{Role: common.RedisSlave, MasterHost: "10.0.0.1", MasterPort: 6379, MasterLinkStatus: "down", MasterLastIOSeconds: 30, RDBLastBgsaveStatus: "err"},
+			issue:  redisLinkDown,
+			detail: "master 10.0.0.1:6379 link down, last IO 30 seconds ago",
+		},
+		{
+			name:   "full sync in progress",
+			status: &common.RedisStatus{Role: common.RedisSlave, MasterLinkStatus: "down", MasterSyncInProgress: true},
+		},
+		{
+			name:   "persistence failed",
+			status: &common.RedisStatus{Role: common.RedisMaster, RDBLastBgsaveStatus: "err", RDBChangesSinceSave: 5, AOFEnabled: true, AOFLastWriteStatus: "err"},
+			issue:  redisPersistence,
+			detail: "rdb_last_bgsave_status:err, aof_last_write_status:err, 5 changes since last save",
+		},
+		{
+			name:   "memory",
+			status: &common.RedisStatus{Role: common.RedisMaster, UsedMemory: 950, MaxMemory: 1000, FragmentationRatio: 1.5},
+			issue:  redisMemory,
+			detail: "used memory 950 of maxmemory 1000, fragmentation ratio 1.50",
+		},
+		{
+			name:   "no maxmemory",
+			status: &common.RedisStatus{Role: common.RedisMaster, UsedMemory: 950},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			issue, detail := redisIssue(val.status)
+			if issue != val.issue || detail != val.detail {
+				t.Errorf("issue %q, detail %q, expect %q, %q", issue, detail, val.issue, val.detail)
+			}
+		})
+	}
+}
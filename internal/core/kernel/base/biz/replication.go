@@ -0,0 +1,107 @@
+package biz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// 复制问题
+const (
+	replicationBroken = "broken"
+	replicationLag    = "lag"
+)
+
+var replicationAlarmTitle = map[string]string{
+	replicationBroken: "Replication Broken",
+	replicationLag:    "Replication Lag",
+}
+
+// replicationIssue 复制线程未运行为复制中断，延迟超过阈值为复制延迟，detail为告警说明
+func replicationIssue(guardPtr *config.GuardItem, replicationPtr *common.ReplicationStatus) (issue, detail string) {
+	switch {
+	case replicationPtr.IsBroken():
+		issue = replicationBroken
+		detail = fmt.Sprintf("IO thread: %s, SQL thread: %s", replicationPtr.IORunning, replicationPtr.SQLRunning)
+		if replicationPtr.LastIOError != "" {
+			detail += fmt.Sprintf(", last IO error %d: %s", replicationPtr.LastIOErrno, replicationPtr.LastIOError)
+		}
+		if replicationPtr.LastSQLError != "" {
+			detail += fmt.Sprintf(", last SQL error %d: %s", replicationPtr.LastSQLErrno, replicationPtr.LastSQLError)
+		}
+	case replicationPtr.SecondsBehindMaster != nil && *replicationPtr.SecondsBehindMaster > int64(guardPtr.GetMaxReplicaLag()):
+		issue = replicationLag
+		detail = fmt.Sprintf("%d seconds behind master, threshold %d seconds", *replicationPtr.SecondsBehindMaster, guardPtr.GetMaxReplicaLag())
+	}
+
+	return
+}
+
+// checkReplication 复制中断与复制延迟分别告警，问题持续超过超时时间后告警，问题消失时发送恢复通知
+func (s *Base) checkReplication(guardPtr *config.GuardItem, statusVal *guardStatus, replicationPtr *common.ReplicationStatus) {
+	issue, detail := replicationIssue(guardPtr, replicationPtr)
+
+	stateVal := &statusVal.replication
+	alarm, recovered := stateVal.update(issue)
+	if recovered {
+		log.Infof("Detected %s replication back to normal", guardPtr.Name)
+		s.sendReplicationAlarm("Replication Recovered", guardPtr.Name, replicationPtr, "replication back to normal")
+	}
+
+	// IO线程未运行说明无法连接主库，持续超过切换超时后尝试故障切换，失败后间隔同样的时间重试
+	failoverTimeOut := time.Duration(guardPtr.GetFailoverTimeOut()) * time.Second
+	if guardPtr.AutoFailover && issue == replicationBroken && replicationPtr.IORunning != "Yes" &&
+		time.Since(stateVal.since) >= failoverTimeOut && time.Since(stateVal.failoverTime) >= failoverTimeOut {
+		stateVal.failoverTime = time.Now()
+		s.handleFailover(guardPtr.Name)
+	}
+
+	if alarm {
+		log.Warnf("Detected %s replication %s, %s", guardPtr.Name, issue, detail)
+		s.sendReplicationAlarm(replicationAlarmTitle[issue], guardPtr.Name, replicationPtr, fmt.Sprintf("since %v, %s", stateVal.since, detail))
+	}
+}
+
+// handleFailover 由各节点一致选出的从库执行切换，切换完成的告警由mariadb模块发送
+func (s *Base) handleFailover(mariadbService string) {
+	ev := event.NewEvent(common.Failover, s.ID(), common.MariadbModule, nil, mariadbService)
+	decisionVal, decisionErr := s.SendEvent(ev).Get()
+	if decisionErr != nil {
+		log.Errorf("failover %s failed, error:%s", mariadbService, decisionErr.Error())
+		return
+	}
+
+	if decisionPtr, ok := decisionVal.(*common.FailoverDecision); ok && decisionPtr != nil {
+		log.Warnf("failover %s, decision:%s, candidate:%s, reason:%s", mariadbService, decisionPtr.Action, decisionPtr.Candidate, decisionPtr.Reason)
+	}
+}
+
+func (s *Base) sendReplicationAlarm(title, mariadbService string, replicationPtr *common.ReplicationStatus, content string) {
+	position := replicationPtr.GtidIOPos
+	if position == "" {
+		position = replicationPtr.ExecutedGtidSet
+	}
+	if position == "" {
+		position = fmt.Sprintf("%s:%d", replicationPtr.MasterLogFile, replicationPtr.ExecMasterLogPos)
+	}
+
+	alarmInfo := &common.AlarmInfo{
+		Title: title,
+		Content: fmt.Sprintf("Node-%s service-%s replica of %s:%d, %s, position: %s",
+			config.GetLocalHost(),
+			mariadbService,
+			replicationPtr.MasterHost,
+			replicationPtr.MasterPort,
+			content,
+			position,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
@@ -0,0 +1,113 @@
+package biz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestReplicationIssue(t *testing.T) {
+	lagVal := func(val int64) *int64 { return &val }
+	cases := []struct {
+		name        string
+		guard       *config.GuardItem
+		replication *common.ReplicationStatus
+		issue       string
+		detail      string
+	}{
+		{
+			name:        "primary",
+			guard:       &config.GuardItem{},
+			replication: &common.ReplicationStatus{Role: common.ReplicationPrimary},
+		},
+		{
+			name:        "healthy",
+			guard:       &config.GuardItem{},
+			replication: &common.ReplicationStatus{Role: common.ReplicationReplica, IORunning: "Yes", SQLRunning: "Yes", SecondsBehindMaster: lagVal(300)},
+		},
+		{
+			name:  "io thread stopped",
+			guard: &config.GuardItem{},
+			replication: &common.ReplicationStatus{Role: common.ReplicationReplica, IORunning: "Connecting", SQLRunning: "Yes",
+				LastIOErrno: 2003, LastIOError: "can't connect"},
+			issue:  replicationBroken,
+			detail: "IO thread: Connecting, SQL thread: Yes, last IO error 2003: can't connect",
+		},
+		{
+			name:  "sql thread stopped",
+			guard: &config.GuardItem{},
+			replication: &common.ReplicationStatus{Role: common.ReplicationReplica, IORunning: "Yes", SQLRunning: "No",
+				LastSQLErrno: 1062, LastSQLError: "Duplicate entry", SecondsBehindMaster: lagVal(1000)},
+			issue:  replicationBroken,
+			detail: "IO thread: Yes, SQL thread: No, last SQL error 1062: Duplicate entry",
+		},
+		{
+			name:        "default lag threshold",
+			guard:       &config.GuardItem{},
+			replication: &common.ReplicationStatus{Role: common.ReplicationReplica, IORunning: "Yes", SQLRunning: "Yes", SecondsBehindMaster: lagVal(301)},
+			issue:       replicationLag,
+			detail:      "301 seconds behind master, threshold 300 seconds",
+		},
+		{
+			name:        "configured lag threshold",
+			guard:       &config.GuardItem{MaxReplicaLag: 10},
+			replication: &common.ReplicationStatus{Role: common.ReplicationReplica, IORunning: "Yes", SQLRunning: "Yes", SecondsBehindMaster: lagVal(11)},
+			issue:       replicationLag,
+			detail:      "11 seconds behind master, threshold 10 seconds",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			issue, detail := replicationIssue(val.guard, val.replication)
+			if issue != val.issue || detail != val.detail {
+				t.Errorf("issue %q, detail %q, expect %q, %q", issue, detail, val.issue, val.detail)
+			}
+		})
+	}
+}
+
+func TestIssueStateUpdate(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.TimeOut = 30 })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	stateVal := &issueState{}
+	if alarm, recovered := stateVal.update(replicationLag); alarm || recovered {
+		t.Errorf("new issue alarm %v, recovered %v", alarm, recovered)
+	}
+
+	stateVal.since = time.Now().Add(-time.Minute)
+	if alarm, _ := stateVal.update(replicationLag); !alarm {
+		t.Errorf("issue lasting over timeout should alarm")
+	}
+	if alarm, _ := stateVal.update(replicationLag); alarm {
+		t.Errorf("issue should not alarm again within repeat interval")
+	}
+
+	stateVal.alarmTime = time.Now().Add(-repeatAlarmInterval)
+	if alarm, _ := stateVal.update(replicationLag); !alarm {
+		t.Errorf("issue should alarm again after repeat interval")
+	}
+
+	// 问题变化时重新计时
+	if alarm, recovered := stateVal.update(replicationBroken); alarm || recovered {
+		t.Errorf("changed issue alarm %v, recovered %v", alarm, recovered)
+	}
+	if _, recovered := stateVal.update(""); !recovered {
+		t.Errorf("issue gone after alarm should recover")
+	}
+	if _, recovered := stateVal.update(""); recovered {
+		t.Errorf("recovered should be reported once")
+	}
+
+	stateVal.update(replicationLag)
+	if _, recovered := stateVal.update(""); recovered {
+		t.Errorf("issue gone before alarm should not recover")
+	}
+}
@@ -1,8 +1,17 @@
 package service
 
 import (
+	"context"
+	"net/http"
+
 	engine "github.com/muidea/magicEngine"
 
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/service"
 	"github.com/muidea/magicAgent/internal/core/kernel/base/biz"
 	"github.com/muidea/magicAgent/pkg/common"
 )
@@ -36,4 +45,81 @@ func (s *Base) BindRegistry(
 
 // RegisterRoute 注册路由
 func (s *Base) RegisterRoute() {
+	configRoute := engine.CreateRoute(common.QueryConfig, engine.GET, s.QueryConfigHandle)
+	s.routeRegistry.AddRoute(configRoute)
+
+	pauseRoute := engine.CreateRoute(common.PauseRemediation, engine.POST, s.PauseRemediationHandle)
+	s.routeRegistry.AddRoute(pauseRoute)
+
+	resumeRoute := engine.CreateRoute(common.ResumeRemediation, engine.POST, s.ResumeRemediationHandle)
+	s.routeRegistry.AddRoute(resumeRoute)
+
+	remediationRoute := engine.CreateRoute(common.QueryRemediation, engine.GET, s.QueryRemediationHandle)
+	s.routeRegistry.AddRoute(remediationRoute)
+}
+
+func (s *Base) QueryConfigHandle(_ context.Context, res http.ResponseWriter, _ *http.Request) {
+	result := &common.QueryConfigResult{}
+	result.Config = []byte(config.Dump())
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// PauseRemediationHandle 暂停自动修复，需要管理员权限
+func (s *Base) PauseRemediationHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.RemediationResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject pause remediation, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		param := &common.RemediationParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "非法参数"
+			break
+		}
+
+		result.Status = s.bizPtr.PauseRemediation(param)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// ResumeRemediationHandle 恢复自动修复，需要管理员权限
+func (s *Base) ResumeRemediationHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.RemediationResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject resume remediation, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		param := &common.RemediationParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "非法参数"
+			break
+		}
+
+		result.Status = s.bizPtr.ResumeRemediation(param)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Base) QueryRemediationHandle(_ context.Context, res http.ResponseWriter, _ *http.Request) {
+	result := &common.RemediationResult{}
+	result.Status = s.bizPtr.QueryRemediation()
+
+	fn.PackageHTTPResponse(res, result)
 }
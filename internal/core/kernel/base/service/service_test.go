@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/kernel/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestRemediationAuthority(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+
+	eventHub := event.NewHub(10)
+	defer eventHub.Terminate()
+	servicePtr := New(common.BaseModule, biz.New(eventHub, task.NewBackgroundRoutine(10)))
+
+	cases := []struct {
+		name       string
+		handle     func(context.Context, http.ResponseWriter, *http.Request)
+		token      string
+		expectCode cd.ErrorCode
+		expectStop bool
+	}{
+		{name: "pause without token", handle: servicePtr.PauseRemediationHandle, expectCode: cd.InvalidAuthority},
+		{name: "pause with wrong token", handle: servicePtr.PauseRemediationHandle, token: "other", expectCode: cd.InvalidAuthority},
+		{name: "resume without token", handle: servicePtr.ResumeRemediationHandle, expectCode: cd.InvalidAuthority},
+		{name: "pause", handle: servicePtr.PauseRemediationHandle, token: "admin-token", expectCode: cd.Succeeded, expectStop: true},
+		{name: "resume", handle: servicePtr.ResumeRemediationHandle, token: "admin-token", expectCode: cd.Succeeded},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, common.PauseRemediation, strings.NewReader(`{"service":"mariadb001","reason":"test"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if val.token != "" {
+				req.Header.Set("Authorization", "Bearer "+val.token)
+			}
+			recorder := httptest.NewRecorder()
+			val.handle(context.Background(), recorder, req)
+
+			result := &common.RemediationResult{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("illegal response %s", recorder.Body.String())
+			}
+			if result.ErrorCode != val.expectCode {
+				t.Fatalf("error code %d, expect %d", result.ErrorCode, val.expectCode)
+			}
+
+			_, paused := servicePtr.bizPtr.QueryRemediation().Services["mariadb001"]
+			if paused != val.expectStop {
+				t.Errorf("paused %v, expect %v", paused, val.expectStop)
+			}
+		})
+	}
+}
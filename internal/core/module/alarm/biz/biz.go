@@ -1,12 +1,16 @@
 package biz
 
 import (
+	"bufio"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"sync"
+	"time"
 
 	cd "github.com/muidea/magicCommon/def"
 	"github.com/muidea/magicCommon/event"
@@ -19,8 +23,14 @@ import (
 	"github.com/muidea/magicAgent/pkg/common"
 )
 
+// maxHistorySize 内存中保留的告警记录数量
+const maxHistorySize = 500
+
 type Alarm struct {
 	biz.Base
+
+	historyLock sync.RWMutex
+	historyList []*common.AlarmRecord
 }
 
 func New(
@@ -31,6 +41,7 @@ func New(
 		Base: biz.New(common.AlarmModule, eventHub, backgroundRoutine),
 	}
 
+	ptr.loadAlarmHistory()
 	ptr.SubscribeFunc(common.SendAlarm, ptr.sendAlarm)
 
 	return ptr
@@ -60,18 +71,111 @@ func (s *Alarm) SendAlarm(alarmInfo *common.AlarmInfo) (err *cd.Result) {
 	}
 
 	s.recordAlarmInfo(alarmInfo)
+	s.appendAlarmHistory(&common.AlarmRecord{
+		AlarmInfo: *alarmInfo,
+		Host:      config.GetLocalHost(),
+		TimeStamp: time.Now(),
+	})
 
 	return
 }
 
+// QueryAlarmHistory 查询告警记录，按时间倒序，count<=0时返回全部
+func (s *Alarm) QueryAlarmHistory(count int) []*common.AlarmRecord {
+	s.historyLock.RLock()
+	defer s.historyLock.RUnlock()
+
+	ret := []*common.AlarmRecord{}
+	for idx := len(s.historyList) - 1; idx >= 0; idx-- {
+		if count > 0 && len(ret) >= count {
+			break
+		}
+
+		ret = append(ret, s.historyList[idx])
+	}
+
+	return ret
+}
+
+func historyFilePath() string {
+	return path.Join(config.GetWorkPath(), "log", "alarm.json")
+}
+
+// loadAlarmHistory 启动时加载历史告警记录
+func (s *Alarm) loadAlarmHistory() {
+	fileHandle, fileErr := os.Open(historyFilePath())
+	if fileErr != nil {
+		return
+	}
+	defer fileHandle.Close()
+
+	historyList := []*common.AlarmRecord{}
+	scanner := bufio.NewScanner(fileHandle)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		recordPtr := &common.AlarmRecord{}
+		if json.Unmarshal(scanner.Bytes(), recordPtr) != nil {
+			continue
+		}
+
+		historyList = append(historyList, recordPtr)
+		if len(historyList) > maxHistorySize {
+			historyList = historyList[1:]
+		}
+	}
+
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+	s.historyList = historyList
+}
+
+func (s *Alarm) appendAlarmHistory(recordPtr *common.AlarmRecord) {
+	func() {
+		s.historyLock.Lock()
+		defer s.historyLock.Unlock()
+
+		s.historyList = append(s.historyList, recordPtr)
+		if len(s.historyList) > maxHistorySize {
+			s.historyList = s.historyList[len(s.historyList)-maxHistorySize:]
+		}
+	}()
+
+	byteVal, byteErr := json.Marshal(recordPtr)
+	if byteErr != nil {
+		log.Errorf("appendAlarmHistory failed, marshal record failed, error:%s", byteErr.Error())
+		return
+	}
+
+	logFullPath := historyFilePath()
+	logPath, _ := path.Split(logFullPath)
+	_ = os.MkdirAll(logPath, os.ModePerm)
+
+	fileHandle, fileErr := os.OpenFile(logFullPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if fileErr != nil {
+		log.Errorf("appendAlarmHistory failed, open file %s failed, error:%s", logFullPath, fileErr.Error())
+		return
+	}
+	defer fileHandle.Close()
+
+	_, writeErr := fileHandle.Write(append(byteVal, '\n'))
+	if writeErr != nil {
+		log.Errorf("appendAlarmHistory failed, write record failed, error:%s", writeErr.Error())
+	}
+}
+
 func (s *Alarm) sendEMail(alarmInfo *common.AlarmInfo, emailServer *config.ServerInfo) (err *cd.Result) {
+	content := alarmInfo.Content
+	if alarmInfo.Logs != "" {
+		content = fmt.Sprintf("%s\n\nRecent logs:\n%s", content, alarmInfo.Logs)
+	}
+
 	sendErr := net.SendMail(
 		emailServer.Account,
 		emailServer.Password,
 		emailServer.ServerUrl,
 		[]string{emailServer.Receiver},
 		alarmInfo.Title,
-		alarmInfo.Content,
+		content,
 		[]string{},
 		"text",
 	)
@@ -95,8 +199,6 @@ func (s *Alarm) sendEMail(alarmInfo *common.AlarmInfo, emailServer *config.Serve
 }
 
 serverUrl: http://10.192.20.6:50000/RESTAdapter/ALL/sendMsgByZLSPCToMSB
-user: zlmes_pro
-password: qwert123
 */
 
 type RayLinkMessage struct {
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"net/http"
+	"strconv"
 
 	cd "github.com/muidea/magicCommon/def"
 	fn "github.com/muidea/magicCommon/foundation/net"
@@ -44,6 +45,9 @@ func (s *Alarm) BindRegistry(
 func (s *Alarm) RegisterRoute() {
 	statusRoute := engine.CreateRoute(common.SendAlarm, engine.POST, s.SendAlarmHandle)
 	s.routeRegistry.AddRoute(statusRoute)
+
+	historyRoute := engine.CreateRoute(common.QueryAlarmHistory, engine.GET, s.QueryAlarmHistoryHandle)
+	s.routeRegistry.AddRoute(historyRoute)
 }
 
 func (s *Alarm) SendAlarmHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
@@ -67,3 +71,25 @@ func (s *Alarm) SendAlarmHandle(_ context.Context, res http.ResponseWriter, req
 
 	fn.PackageHTTPResponse(res, result)
 }
+
+func (s *Alarm) QueryAlarmHistoryHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryAlarmHistoryResult{}
+	for {
+		count := 0
+		countVal := req.URL.Query().Get("count")
+		if countVal != "" {
+			val, valErr := strconv.Atoi(countVal)
+			if valErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = "illegal count"
+				break
+			}
+			count = val
+		}
+
+		result.Alarms = s.bizPtr.QueryAlarmHistory(count)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
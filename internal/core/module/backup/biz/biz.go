@@ -0,0 +1,422 @@
+package biz
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/internal/cron"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// backupTimeFormat 备份文件名中的时间格式
+const backupTimeFormat = "20060102T150405"
+
+// maxErrorOutput 备份失败时保留的错误输出字节数
+const maxErrorOutput = 64 * 1024
+
+const partSuffix = ".part"
+
+// 备份文件扩展名，mariabackup输出为xbstream格式
+var backupExtMap = map[string]string{
+	config.Mariabackup: ".xb.gz",
+	config.MariadbDump: ".sql.gz",
+}
+
+type Backup struct {
+	biz.Base
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Backup {
+	ptr := &Backup{
+		Base: biz.New(common.BackupModule, eventHub, backgroundRoutine),
+	}
+
+	ptr.SubscribeFunc(common.NotifyTimer, ptr.timerCheck)
+	ptr.SubscribeJob(common.BackupJob, ptr.backupJob)
+	ptr.SubscribeJob(common.RestoreJob, ptr.restoreJob)
+
+	return ptr
+}
+
+// timerCheck 到达备份时间时创建备份任务，与其他任务一样依次执行
+func (s *Backup) timerCheck(ev event.Event, _ event.Result) {
+	backupPtr := config.GetBackup()
+	notifyPtr, notifyOK := ev.Data().(*common.TimerNotify)
+	if backupPtr == nil || !notifyOK {
+		return
+	}
+
+	schedule, scheduleErr := cron.Parse(backupPtr.Schedule)
+	if scheduleErr != nil || !schedule.Due(notifyPtr.PreTime, notifyPtr.CurTime) {
+		return
+	}
+
+	param := &common.JobParam{Type: common.BackupJob, Service: backupPtr.GetService()}
+	jobVal, jobErr := s.SendEvent(event.NewEvent(common.CreateJob, s.ID(), common.JobModule, nil, param)).Get()
+	if jobErr != nil {
+		log.Errorf("create backup job failed, service:%s, error:%s", param.Service, jobErr.Error())
+		s.sendAlarmInfo(param.Service, backupPtr.GetMethod(), jobErr.Reason)
+		return
+	}
+
+	if infoPtr, ok := jobVal.(*common.JobInfo); ok {
+		log.Infof("create scheduled backup job, id:%s, service:%s", infoPtr.ID, infoPtr.Service)
+	}
+}
+
+// backupJob 备份成功后按保留规则清理旧备份，失败时发送告警
+func (s *Backup) backupJob(jobCtx biz.JobContext) *cd.Result {
+	backupPtr := config.GetBackup()
+	serviceName := jobCtx.Param().Service
+	if serviceName == "" {
+		serviceName = backupPtr.GetService()
+	}
+
+	param := &common.BackupParam{}
+	if len(jobCtx.Param().Param) > 0 {
+		if byteErr := json.Unmarshal(jobCtx.Param().Param, param); byteErr != nil {
+			return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal job param, %s", byteErr.Error()))
+		}
+	}
+	method := param.Method
+	if method == "" {
+		method = backupPtr.GetMethod()
+	}
+	if _, ok := backupExtMap[method]; !ok {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal backup method:%s", method))
+	}
+
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+	}
+
+	recordPtr, backupErr := s.backupService(jobCtx, guardPtr, method, backupPtr.GetPath())
+	if backupErr != nil {
+		log.Errorf("backup failed, service:%s, method:%s, error:%s", serviceName, method, backupErr.Reason)
+		s.sendAlarmInfo(serviceName, method, backupErr.Reason)
+		return backupErr
+	}
+	_, _ = fmt.Fprintf(jobCtx, "backup %s, size:%d, sha256:%s\n", recordPtr.Path, recordPtr.Size, recordPtr.Checksum)
+
+	if backupPtr != nil {
+		jobCtx.SetProgress(95, "applying retention")
+		s.applyRetention(jobCtx, backupPtr, serviceName)
+	}
+
+	jobCtx.SetProgress(100, fmt.Sprintf("backup %s finished", serviceName))
+	return nil
+}
+
+// backupService galera方式下只在Synced节点上备份，备份期间开启wsrep_desync，
+// 输出在本地压缩并计算sha256，完成后才改为正式文件名
+func (s *Backup) backupService(jobCtx biz.JobContext, guardPtr *config.GuardItem, method, backupPath string) (ret *common.BackupRecord, err *cd.Result) {
+	serviceName := guardPtr.Name
+	galera := !guardPtr.IsReplication()
+	if galera {
+		jobCtx.SetProgress(5, fmt.Sprintf("checking %s", serviceName))
+		if err = s.checkSynced(serviceName); err != nil {
+			return
+		}
+	}
+
+	mkdirErr := os.MkdirAll(backupPath, os.ModePerm)
+	if mkdirErr != nil {
+		err = cd.NewError(cd.UnExpected, mkdirErr.Error())
+		return
+	}
+	removePartFiles(backupPath, serviceName)
+
+	if galera {
+		jobCtx.SetProgress(10, fmt.Sprintf("desync %s", serviceName))
+		if err = s.setDesync(serviceName, true); err != nil {
+			return
+		}
+		defer func() {
+			if desyncErr := s.setDesync(serviceName, false); desyncErr != nil {
+				_, _ = fmt.Fprintf(jobCtx, "reset wsrep_desync failed, %s\n", desyncErr.Reason)
+				s.sendAlarmInfo(serviceName, method, fmt.Sprintf("reset wsrep_desync failed, %s", desyncErr.Reason))
+			}
+		}()
+	}
+
+	startTime := time.Now()
+	name := fmt.Sprintf("%s_%s_%s%s", serviceName, method, startTime.Format(backupTimeFormat), backupExtMap[method])
+	filePath := path.Join(backupPath, name)
+	jobCtx.SetProgress(20, fmt.Sprintf("running %s", method))
+
+	fileHandle, fileErr := os.OpenFile(filePath+partSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if fileErr != nil {
+		err = cd.NewError(cd.UnExpected, fileErr.Error())
+		return
+	}
+	defer func() {
+		fileHandle.Close()
+		if err != nil {
+			_ = os.Remove(filePath + partSuffix)
+		}
+	}()
+
+	hasher := sha256.New()
+	gzipWriter := gzip.NewWriter(io.MultiWriter(fileHandle, hasher))
+	counter := &countWriter{writer: gzipWriter}
+	errBuffer := &runtime.LimitBuffer{Limit: maxErrorOutput}
+	streamParam := &runtime.StreamParam{
+		Context: jobCtx,
+		Service: serviceName,
+		Command: backupCommand(guardPtr, method),
+		Stdout:  counter,
+		Stderr:  errBuffer,
+	}
+	if guardPtr.Password != "" {
+		streamParam.Option = &runtime.ExecOption{Env: []string{"MYSQL_PWD=" + guardPtr.Password}}
+	}
+
+	_, err = s.SendEvent(event.NewEvent(common.StreamCommand, s.ID(), common.RuntimeModule, nil, streamParam)).Get()
+	if err != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s failed, %s, %s", method, err.Reason, lastLines(errBuffer.String(), 10)))
+		return
+	}
+	if counter.size == 0 {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s output is empty, %s", method, lastLines(errBuffer.String(), 10)))
+		return
+	}
+
+	jobCtx.SetProgress(90, "saving backup")
+	closeErr := gzipWriter.Close()
+	if closeErr == nil {
+		closeErr = fileHandle.Sync()
+	}
+	if closeErr == nil {
+		closeErr = os.Rename(filePath+partSuffix, filePath)
+	}
+	if closeErr != nil {
+		err = cd.NewError(cd.UnExpected, closeErr.Error())
+		return
+	}
+
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	// 与sha256sum输出格式一致，可以直接使用 sha256sum -c 校验
+	checksumErr := os.WriteFile(filePath+".sha256", []byte(fmt.Sprintf("%s  %s\n", checksum, name)), 0640)
+	if checksumErr != nil {
+		log.Warnf("write checksum file failed, file:%s, error:%s", filePath, checksumErr.Error())
+	}
+
+	fileInfo, _ := os.Stat(filePath)
+	ret = &common.BackupRecord{
+		Name:       name,
+		Service:    serviceName,
+		Method:     method,
+		Path:       filePath,
+		Checksum:   checksum,
+		CreateTime: startTime,
+	}
+	if fileInfo != nil {
+		ret.Size = fileInfo.Size()
+	}
+	return
+}
+
+// backupCommand 备份命令在容器内执行，旧版本镜像中没有mariadb-dump时使用mysqldump
+func backupCommand(guardPtr *config.GuardItem, method string) []string {
+	account := "--user=" + guardPtr.GetAccount()
+	if method == config.MariadbDump {
+		return []string{
+			"sh", "-c", `if command -v mariadb-dump >/dev/null 2>&1; then exec mariadb-dump "$@"; fi; exec mysqldump "$@"`, "sh",
+			account, "--all-databases", "--single-transaction", "--routines", "--events", "--triggers",
+		}
+	}
+
+	if guardPtr.IsReplication() {
+		return []string{"mariabackup", "--backup", "--stream=xbstream", account}
+	}
+
+	return []string{"mariabackup", "--backup", "--stream=xbstream", "--galera-info", account}
+}
+
+// checkSynced 检查galera节点是否处于Synced状态
+func (s *Backup) checkSynced(serviceName string) *cd.Result {
+	statusVal, statusErr := s.SendEvent(event.NewEvent(common.QueryStatus, s.ID(), common.MariadbModule, nil, serviceName)).Get()
+	if statusErr != nil {
+		return statusErr
+	}
+
+	statusPtr, _ := statusVal.(*common.ClusterStatus)
+	if statusPtr == nil || statusPtr.LocalState != common.Synced {
+		localState := ""
+		if statusPtr != nil {
+			localState = statusPtr.LocalState
+		}
+		return cd.NewError(cd.UnExpected, fmt.Sprintf("node not synced, localState:%s", localState))
+	}
+
+	return nil
+}
+
+func (s *Backup) setDesync(serviceName string, desync bool) (err *cd.Result) {
+	param := &common.DesyncParam{Service: serviceName, Desync: desync}
+	_, err = s.SendEvent(event.NewEvent(common.DesyncNode, s.ID(), common.MariadbModule, nil, param)).Get()
+	return
+}
+
+func (s *Backup) sendAlarmInfo(serviceName, method, reason string) {
+	alarmInfo := &common.AlarmInfo{
+		Title: "Backup Failure",
+		Content: fmt.Sprintf("Node-%s service-%s backup failed, method: %s, time: %v, reason: %s",
+			config.GetLocalHost(),
+			serviceName,
+			method,
+			time.Now(),
+			reason,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
+
+type countWriter struct {
+	writer io.Writer
+	size   int64
+}
+
+func (s *countWriter) Write(data []byte) (int, error) {
+	size, err := s.writer.Write(data)
+	s.size += int64(size)
+	return size, err
+}
+
+func lastLines(val string, count int) string {
+	lines := strings.Split(strings.TrimSpace(val), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// removePartFiles 清理中断的备份留下的临时文件
+func removePartFiles(backupPath, serviceName string) {
+	entries, _ := os.ReadDir(backupPath)
+	for _, val := range entries {
+		if strings.HasPrefix(val.Name(), serviceName+"_") && strings.HasSuffix(val.Name(), partSuffix) {
+			_ = os.Remove(path.Join(backupPath, val.Name()))
+		}
+	}
+}
+
+// parseBackupName 文件名格式为 服务名_备份方式_时间.扩展名
+func parseBackupName(name string) (ret *common.BackupRecord) {
+	for method, ext := range backupExtMap {
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		items := strings.Split(strings.TrimSuffix(name, ext), "_")
+		if len(items) < 3 || items[len(items)-2] != method {
+			return
+		}
+		createTime, timeErr := time.ParseInLocation(backupTimeFormat, items[len(items)-1], time.Local)
+		if timeErr != nil {
+			return
+		}
+
+		ret = &common.BackupRecord{
+			Name:       name,
+			Service:    strings.Join(items[:len(items)-2], "_"),
+			Method:     method,
+			CreateTime: createTime,
+		}
+		return
+	}
+
+	return
+}
+
+// ListBackup 列出备份目录中的备份，按时间倒序，serviceName为空时列出全部服务
+func (s *Backup) ListBackup(serviceName string) (ret []*common.BackupRecord, err *cd.Result) {
+	ret = []*common.BackupRecord{}
+	backupPath := config.GetBackup().GetPath()
+	entries, entryErr := os.ReadDir(backupPath)
+	if entryErr != nil {
+		if !os.IsNotExist(entryErr) {
+			err = cd.NewError(cd.UnExpected, entryErr.Error())
+		}
+		return
+	}
+
+	for _, val := range entries {
+		recordPtr := parseBackupName(val.Name())
+		if recordPtr == nil || val.IsDir() || (serviceName != "" && recordPtr.Service != serviceName) {
+			continue
+		}
+
+		recordPtr.Path = path.Join(backupPath, recordPtr.Name)
+		if fileInfo, infoErr := val.Info(); infoErr == nil {
+			recordPtr.Size = fileInfo.Size()
+		}
+		if byteVal, byteErr := os.ReadFile(recordPtr.Path + ".sha256"); byteErr == nil {
+			recordPtr.Checksum = strings.SplitN(strings.TrimSpace(string(byteVal)), " ", 2)[0]
+		}
+		ret = append(ret, recordPtr)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CreateTime.After(ret[j].CreateTime)
+	})
+	return
+}
+
+// applyRetention 超出保留数量或保留天数的备份被删除，最新的备份始终保留
+func (s *Backup) applyRetention(writer io.Writer, backupPtr *config.BackupItem, serviceName string) {
+	if backupPtr.KeepCount <= 0 && backupPtr.KeepDays <= 0 {
+		return
+	}
+
+	records, listErr := s.ListBackup(serviceName)
+	if listErr != nil {
+		log.Warnf("apply backup retention failed, error:%s", listErr.Reason)
+		return
+	}
+
+	expireTime := time.Now().AddDate(0, 0, -backupPtr.KeepDays)
+	for idx, val := range records {
+		if idx == 0 {
+			continue
+		}
+
+		overCount := backupPtr.KeepCount > 0 && idx >= backupPtr.KeepCount
+		overDays := backupPtr.KeepDays > 0 && val.CreateTime.Before(expireTime)
+		if !overCount && !overDays {
+			continue
+		}
+
+		removeErr := os.Remove(val.Path)
+		if removeErr != nil {
+			log.Warnf("remove backup failed, file:%s, error:%s", val.Path, removeErr.Error())
+			continue
+		}
+		_ = os.Remove(val.Path + ".sha256")
+		_, _ = fmt.Fprintf(writer, "removed expired backup %s\n", val.Name)
+	}
+}
@@ -0,0 +1,187 @@
+package biz
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/internal/config"
+)
+
+func TestParseBackupName(t *testing.T) {
+	createTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	cases := []struct {
+		name          string
+		file          string
+		expectService string
+		expectMethod  string
+	}{
+		{name: "mariabackup", file: "mariadb001_mariabackup_20260102T030405.xb.gz", expectService: "mariadb001", expectMethod: config.Mariabackup},
+		{name: "dump", file: "mariadb001_mariadb-dump_20260102T030405.sql.gz", expectService: "mariadb001", expectMethod: config.MariadbDump},
+		{name: "service with underscore", file: "db_node_1_mariabackup_20260102T030405.xb.gz", expectService: "db_node_1", expectMethod: config.Mariabackup},
+		{name: "method mismatch", file: "mariadb001_mariadb-dump_20260102T030405.xb.gz"},
+		{name: "illegal time", file: "mariadb001_mariabackup_20260102.xb.gz"},
+		{name: "part file", file: "mariadb001_mariabackup_20260102T030405.xb.gz.part"},
+		{name: "checksum file", file: "mariadb001_mariabackup_20260102T030405.xb.gz.sha256"},
+		{name: "too few items", file: "mariabackup_20260102T030405.xb.gz"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := parseBackupName(val.file)
+			if val.expectService == "" {
+				if ret != nil {
+					t.Errorf("record %+v, expect nil", ret)
+				}
+				return
+			}
+			if ret == nil || ret.Service != val.expectService || ret.Method != val.expectMethod || !ret.CreateTime.Equal(createTime) {
+				t.Errorf("record %+v, expect service %s, method %s", ret, val.expectService, val.expectMethod)
+			}
+		})
+	}
+}
+
+func TestBackupCommand(t *testing.T) {
+	cases := []struct {
+		name   string
+		guard  *config.GuardItem
+		method string
+		expect string
+	}{
+		{name: "galera", guard: &config.GuardItem{Name: "db"}, method: config.Mariabackup, expect: "mariabackup --backup --stream=xbstream --galera-info --user=root"},
+		{name: "replication", guard: &config.GuardItem{Name: "db", Mode: config.ReplicationMode, Account: "backup"}, method: config.Mariabackup, expect: "mariabackup --backup --stream=xbstream --user=backup"},
+		{name: "dump", guard: &config.GuardItem{Name: "db"}, method: config.MariadbDump, expect: "--user=root --all-databases --single-transaction --routines --events --triggers"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := strings.Join(backupCommand(val.guard, val.method), " ")
+			if !strings.HasSuffix(ret, val.expect) {
+				t.Errorf("command %q, expect %q", ret, val.expect)
+			}
+			if strings.Contains(ret, "password") {
+				t.Errorf("password in command %q", ret)
+			}
+		})
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	cases := []struct {
+		name   string
+		val    string
+		count  int
+		expect string
+	}{
+		{name: "fewer lines", val: "a\nb\n", count: 3, expect: "a\nb"},
+		{name: "cut", val: "a\nb\nc\nd\n", count: 2, expect: "c\nd"},
+		{name: "empty", val: "", count: 2, expect: ""},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := lastLines(val.val, val.count); ret != val.expect {
+				t.Errorf("lines %q, expect %q", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Now()
+	ages := []int{0, 1, 3, 10, 40}
+	cases := []struct {
+		name      string
+		keepCount int
+		keepDays  int
+		expect    []int
+	}{
+		{name: "no rule", expect: ages},
+		{name: "keep count", keepCount: 2, expect: []int{0, 1}},
+		{name: "keep days", keepDays: 7, expect: []int{0, 1, 3}},
+		{name: "both rules", keepCount: 4, keepDays: 20, expect: []int{0, 1, 3, 10}},
+		{name: "latest always kept", keepDays: 1, expect: []int{0}},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			backupPath := t.TempDir()
+			backupPtr := &config.BackupItem{Schedule: "@daily", Path: backupPath, KeepCount: val.keepCount, KeepDays: val.keepDays}
+			t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+			if err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.Backup = backupPtr }); err != nil {
+				t.Fatalf("load config failed, %v", err)
+			}
+			defer func() { _ = config.LoadConfig("") }()
+
+			files := map[string]int{}
+			for _, age := range ages {
+				name := "db_mariabackup_" + now.Add(-time.Duration(age)*24*time.Hour-time.Minute).Format(backupTimeFormat) + ".xb.gz"
+				files[name] = age
+				writeFile(t, path.Join(backupPath, name))
+				writeFile(t, path.Join(backupPath, name+".sha256"))
+			}
+			writeFile(t, path.Join(backupPath, "other_mariabackup_"+now.AddDate(0, 0, -90).Format(backupTimeFormat)+".xb.gz"))
+
+			output := &strings.Builder{}
+			(&Backup{}).applyRetention(output, backupPtr, "db")
+
+			records, _ := (&Backup{}).ListBackup("db")
+			kept := []int{}
+			for _, item := range records {
+				kept = append(kept, files[item.Name])
+				if _, err := os.Stat(item.Path + ".sha256"); err != nil {
+					t.Errorf("checksum of %s removed", item.Name)
+				}
+			}
+			sort.Ints(kept)
+			if !equalInts(kept, val.expect) {
+				t.Errorf("kept %v, expect %v", kept, val.expect)
+			}
+			if count := strings.Count(output.String(), "removed expired backup"); count != len(ages)-len(val.expect) {
+				t.Errorf("removed %d, output %q", count, output.String())
+			}
+			if others, _ := (&Backup{}).ListBackup("other"); len(others) != 1 {
+				t.Errorf("backup of other service removed")
+			}
+		})
+	}
+}
+
+func TestRemovePartFiles(t *testing.T) {
+	backupPath := t.TempDir()
+	for _, name := range []string{"db_mariabackup_1.xb.gz.part", "db_mariabackup_1.xb.gz", "other_mariabackup_1.xb.gz.part"} {
+		writeFile(t, path.Join(backupPath, name))
+	}
+
+	removePartFiles(backupPath, "db")
+	entries, _ := os.ReadDir(backupPath)
+	names := []string{}
+	for _, val := range entries {
+		names = append(names, val.Name())
+	}
+	if strings.Join(names, ",") != "db_mariabackup_1.xb.gz,other_mariabackup_1.xb.gz.part" {
+		t.Errorf("files %v", names)
+	}
+}
+
+func writeFile(t *testing.T, filePath string) {
+	if err := os.WriteFile(filePath, []byte("data"), 0600); err != nil {
+		t.Fatalf("write %s failed, %v", filePath, err)
+	}
+}
+
+func equalInts(left, right []int) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for idx := range left {
+		if left[idx] != right[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,367 @@
+package biz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// defaultRestoreTimeOut 恢复后节点达到Synced状态的默认等待时间，joiner需要完成IST或SST
+const defaultRestoreTimeOut = 1800 * time.Second
+
+const restoreCheckInterval = 2 * time.Second
+
+// stopTimeFormat 与mariadb-binlog --stop-datetime格式一致
+const stopTimeFormat = "2006-01-02 15:04:05"
+
+// restoreBinlogDir 需要重放的binlog在恢复期间以硬链接保存在数据目录下，重放完成后删除
+const restoreBinlogDir = "#restore-binlog"
+
+// prepareScript 解压备份到临时目录并执行prepare，参数：备份文件 临时目录
+const prepareScript = `set -e
+rm -rf "$2"
+mkdir -p "$2"
+gzip -dc "$1" | mbstream -x -C "$2"
+mariabackup --prepare --target-dir="$2"
+`
+
+// checkBinlogScript 检查备份位置之后的binlog是否在数据目录中，参数：临时目录 数据目录
+const checkBinlogScript = `set -e
+read file pos rest < "$1/xtrabackup_binlog_info"
+[ -f "$2/$file" ] || { echo "binlog $file not found in $2" >&2; exit 1; }
+echo "replay binlog from $file:$pos"
+`
+
+// replaceScript 保存需要重放的binlog后清空数据目录并copy-back，按备份中的galera信息生成grastate.dat，
+// 参数：临时目录 数据目录 binlog目录名 是否保存binlog safe_to_bootstrap
+const replaceScript = `set -e
+if [ "$4" = "1" ]; then
+  read file pos rest < "$1/xtrabackup_binlog_info"
+  base="${file%.*}"
+  start="${file##*.}"
+  rm -rf "$2/$3"
+  mkdir -p "$2/$3"
+  for val in "$2/$base".[0-9]*; do
+    if [ "${val##*.}" -ge "$start" ]; then
+      ln "$val" "$2/$3/" 2>/dev/null || cp -p "$val" "$2/$3/"
+    fi
+  done
+  echo "$file $pos" > "$2/$3/start"
+fi
+owner=$(stat -c %u:%g "$2")
+find "$2" -mindepth 1 -maxdepth 1 ! -name "$3" -exec rm -rf {} +
+mariabackup --copy-back --force-non-empty-directories --target-dir="$1" --datadir="$2"
+if [ -f "$1/xtrabackup_galera_info" ]; then
+  IFS=':, ' read uuid seqno rest < "$1/xtrabackup_galera_info"
+  printf '# GALERA saved state\nversion: 2.1\nuuid:    %s\nseqno:   %s\nsafe_to_bootstrap: %s\n' "$uuid" "$seqno" "$5" > "$2/grastate.dat"
+fi
+chown -R "$owner" "$2"
+`
+
+// replayScript 在服务容器中重放binlog到指定时间，参数：binlog目录 结束时间 账号
+const replayScript = `set -e
+cd "$1"
+read file pos < start
+files=$(ls "${file%.*}".[0-9]* | sort)
+binlog=mariadb-binlog
+command -v $binlog >/dev/null 2>&1 || binlog=mysqlbinlog
+client=mariadb
+command -v $client >/dev/null 2>&1 || client=mysql
+rm -f replay.failed
+{ $binlog --start-position="$pos" --stop-datetime="$2" $files || touch replay.failed; } | $client --user="$3"
+[ ! -f replay.failed ] || { echo "$binlog failed" >&2; exit 1; }
+cd /
+rm -rf "$1"
+`
+
+// restoreJob 恢复前校验备份，停止服务后在临时容器中prepare和替换数据目录，再以bootstrap或joiner方式启动，
+// 需要时重放binlog；恢复期间暂停自动修复，数据目录替换后失败时保持暂停，等待人工处理
+func (s *Backup) restoreJob(jobCtx biz.JobContext) (err *cd.Result) {
+	serviceName := jobCtx.Param().Service
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+	}
+
+	param := &common.RestoreParam{}
+	if byteErr := json.Unmarshal(jobCtx.Param().Param, param); byteErr != nil {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal job param, %s", byteErr.Error()))
+	}
+	switch {
+	case guardPtr.IsReplication():
+		// 主从复制方式下恢复后直接启动，Mode不生效
+		param.Mode = ""
+	case param.Mode == common.RestoreBootstrap:
+	case param.Mode == common.RestoreJoiner:
+		if param.StopTime != "" {
+			return cd.NewError(cd.IllegalParam, "stopTime only supported in bootstrap mode")
+		}
+	default:
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal restore mode:%s", param.Mode))
+	}
+	if param.StopTime != "" {
+		if _, timeErr := time.Parse(stopTimeFormat, param.StopTime); timeErr != nil {
+			return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal stopTime, expect format %s", stopTimeFormat))
+		}
+	}
+
+	jobCtx.SetProgress(5, fmt.Sprintf("verifying backup %s", param.Backup))
+	recordPtr, recordErr := s.verifyBackup(jobCtx, serviceName, param.Backup)
+	if recordErr != nil {
+		return recordErr
+	}
+
+	resume := s.PauseRemediation(serviceName, fmt.Sprintf("restore job %s", jobCtx.JobID()))
+	replaced := false
+	stagingPath := path.Join(config.GetBackup().GetPath(), "restore-"+serviceName)
+	defer func() {
+		if err == nil || !replaced {
+			_ = os.RemoveAll(stagingPath)
+			resume()
+		}
+		if err != nil && replaced {
+			_, _ = fmt.Fprintf(jobCtx, "data directory of %s replaced, remediation kept paused, prepared backup kept in %s\n", serviceName, stagingPath)
+			s.sendRestoreAlarm(serviceName, param.Backup, err.Reason)
+		}
+	}()
+
+	jobCtx.SetProgress(10, fmt.Sprintf("stopping %s", serviceName))
+	if err = s.serviceOperate(common.StopService, serviceName); err != nil {
+		return
+	}
+	defer func() {
+		// 数据目录替换前失败时使用原数据启动服务
+		if err != nil && !replaced {
+			_, _ = fmt.Fprintf(jobCtx, "%s, starting %s with original data\n", err.Reason, serviceName)
+			if startErr := s.serviceOperate(common.StartService, serviceName); startErr != nil {
+				log.Errorf("start %s failed, error:%s", serviceName, startErr.Reason)
+			}
+		}
+	}()
+
+	mountOption := &runtime.RunOption{Mounts: []string{config.GetBackup().GetPath()}}
+	jobCtx.SetProgress(20, fmt.Sprintf("preparing backup %s", recordPtr.Name))
+	err = s.runStep(jobCtx, serviceName, mountOption, prepareScript, recordPtr.Path, stagingPath)
+	if err != nil {
+		return
+	}
+
+	saveBinlog, safeToBootstrap := "0", "0"
+	if param.StopTime != "" {
+		saveBinlog = "1"
+		err = s.runStep(jobCtx, serviceName, mountOption, checkBinlogScript, stagingPath, guardPtr.GetDataDir())
+		if err != nil {
+			return
+		}
+	}
+	if param.Mode == common.RestoreBootstrap {
+		safeToBootstrap = "1"
+	}
+	if jobCtx.Err() != nil {
+		err = cd.NewError(cd.UnExpected, "job canceled")
+		return
+	}
+	jobCtx.SetProgress(50, fmt.Sprintf("replacing data directory %s", guardPtr.GetDataDir()))
+	replaced = true
+	err = s.runStep(jobCtx, serviceName, mountOption, replaceScript, stagingPath, guardPtr.GetDataDir(), restoreBinlogDir, saveBinlog, safeToBootstrap)
+	if err != nil {
+		return
+	}
+
+	jobCtx.SetProgress(70, fmt.Sprintf("starting %s", serviceName))
+	if err = s.serviceOperate(common.StartService, serviceName); err != nil {
+		return
+	}
+	timeOut := defaultRestoreTimeOut
+	if param.TimeOut > 0 {
+		timeOut = time.Duration(param.TimeOut) * time.Second
+	}
+	if err = s.waitRestored(jobCtx, serviceName, param.Mode, timeOut); err != nil {
+		return
+	}
+
+	if param.StopTime != "" {
+		jobCtx.SetProgress(85, fmt.Sprintf("replaying binlog to %s", param.StopTime))
+		streamParam := &runtime.StreamParam{
+			Context: jobCtx,
+			Service: serviceName,
+			Command: []string{"sh", "-c", replayScript, "sh", path.Join(guardPtr.GetDataDir(), restoreBinlogDir), param.StopTime, guardPtr.GetAccount()},
+			Stdout:  jobCtx,
+			Stderr:  jobCtx,
+		}
+		if guardPtr.Password != "" {
+			streamParam.Option = &runtime.ExecOption{Env: []string{"MYSQL_PWD=" + guardPtr.Password}}
+		}
+		_, err = s.SendEvent(event.NewEvent(common.StreamCommand, s.ID(), common.RuntimeModule, nil, streamParam)).Get()
+		if err != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("replay binlog failed, %s", err.Reason))
+			return
+		}
+	}
+
+	jobCtx.SetProgress(100, fmt.Sprintf("%s restored from %s", serviceName, recordPtr.Name))
+	return
+}
+
+// verifyBackup 只支持mariabackup备份，存在校验文件时校验sha256
+func (s *Backup) verifyBackup(jobCtx biz.JobContext, serviceName, name string) (ret *common.BackupRecord, err *cd.Result) {
+	records, listErr := s.ListBackup(serviceName)
+	if listErr != nil {
+		err = listErr
+		return
+	}
+	for _, val := range records {
+		if val.Name == name {
+			ret = val
+			break
+		}
+	}
+	if ret == nil {
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("backup %s not exist", name))
+		return
+	}
+	if ret.Method != config.Mariabackup {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("backup %s is not a %s backup", name, config.Mariabackup))
+		return
+	}
+	if ret.Checksum == "" {
+		_, _ = fmt.Fprintf(jobCtx, "checksum of %s not found, skip verify\n", name)
+		return
+	}
+
+	fileHandle, fileErr := os.Open(ret.Path)
+	if fileErr != nil {
+		err = cd.NewError(cd.UnExpected, fileErr.Error())
+		return
+	}
+	defer fileHandle.Close()
+
+	hasher := sha256.New()
+	if _, copyErr := io.Copy(hasher, &contextReader{ctx: jobCtx, reader: fileHandle}); copyErr != nil {
+		err = cd.NewError(cd.UnExpected, copyErr.Error())
+		return
+	}
+	if checksum := hex.EncodeToString(hasher.Sum(nil)); checksum != ret.Checksum {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("backup %s checksum mismatch, expect %s, actual %s", name, ret.Checksum, checksum))
+	}
+	return
+}
+
+// runStep 在临时容器中执行脚本，输出写入任务输出
+func (s *Backup) runStep(jobCtx biz.JobContext, serviceName string, option *runtime.RunOption, script string, args ...string) *cd.Result {
+	runParam := &runtime.RunParam{
+		Context: jobCtx,
+		Service: serviceName,
+		Command: append([]string{"sh", "-c", script, "sh"}, args...),
+		Option:  option,
+		Stdout:  jobCtx,
+		Stderr:  jobCtx,
+	}
+
+	_, err := s.SendEvent(event.NewEvent(common.RunCommand, s.ID(), common.RuntimeModule, nil, runParam)).Get()
+	return err
+}
+
+func (s *Backup) serviceOperate(eventID, serviceName string) *cd.Result {
+	_, err := s.SendEvent(event.NewEvent(eventID, s.ID(), common.RuntimeModule, nil, serviceName)).Get()
+	return err
+}
+
+// waitRestored 等待节点达到Synced状态，bootstrap方式下节点为Non-Primary时设置pc.bootstrap，
+// mode为空表示主从复制方式，服务可访问即可
+func (s *Backup) waitRestored(jobCtx biz.JobContext, serviceName, mode string, timeOut time.Duration) *cd.Result {
+	deadline := time.Now().Add(timeOut)
+	pending := ""
+	for {
+		select {
+		case <-jobCtx.Done():
+			return cd.NewError(cd.UnExpected, "job canceled")
+		case <-time.After(restoreCheckInterval):
+		}
+
+		containerVal, containerErr := s.SendEvent(event.NewEvent(common.InspectContainer, s.ID(), common.RuntimeModule, nil, serviceName)).Get()
+		if containerPtr, ok := containerVal.(*common.ContainerInfo); containerErr == nil && ok && containerPtr.State == common.ContainerExited {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("%s exited with code %d", serviceName, containerPtr.ExitCode))
+		}
+
+		// 主从复制方式下服务可访问即恢复完成
+		if mode == "" {
+			_, replicationErr := s.SendEvent(event.NewEvent(common.QueryReplication, s.ID(), common.MariadbModule, nil, serviceName)).Get()
+			if replicationErr == nil {
+				return nil
+			}
+			if time.Now().After(deadline) {
+				return cd.NewError(cd.UnExpected, fmt.Sprintf("%s not available after %v", serviceName, timeOut))
+			}
+			continue
+		}
+
+		statusVal, statusErr := s.SendEvent(event.NewEvent(common.QueryStatus, s.ID(), common.MariadbModule, nil, serviceName)).Get()
+		statusPtr, _ := statusVal.(*common.ClusterStatus)
+		switch {
+		case statusErr != nil || statusPtr == nil:
+			pending = "waiting for mariadb"
+		case mode == common.RestoreBootstrap && statusPtr.Status == common.NonPrimary:
+			pending = "bootstrapping primary component"
+			_, bootstrapErr := s.SendEvent(event.NewEvent(common.BootstrapNode, s.ID(), common.MariadbModule, nil, serviceName)).Get()
+			if bootstrapErr != nil {
+				pending = fmt.Sprintf("bootstrap failed, %s", bootstrapErr.Reason)
+			}
+		case statusPtr.LocalState != common.Synced:
+			pending = fmt.Sprintf("localState %s", statusPtr.LocalState)
+		case statusPtr.Status != common.Primary:
+			pending = fmt.Sprintf("cluster status %s", statusPtr.Status)
+		default:
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("%s not synced after %v, %s", serviceName, timeOut, pending))
+		}
+	}
+}
+
+func (s *Backup) sendRestoreAlarm(serviceName, backupName, reason string) {
+	alarmInfo := &common.AlarmInfo{
+		Title: "Restore Failure",
+		Content: fmt.Sprintf("Node-%s service-%s restore from %s failed, time: %v, reason: %s",
+			config.GetLocalHost(),
+			serviceName,
+			backupName,
+			time.Now(),
+			reason,
+		),
+	}
+
+	ev := event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo)
+	s.PostEvent(ev)
+}
+
+// contextReader 读取大文件时响应任务取消
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (s *contextReader) Read(data []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return s.reader.Read(data)
+}
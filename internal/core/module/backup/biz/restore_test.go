@@ -0,0 +1,247 @@
+package biz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	goruntime "runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// fakeJobContext 记录任务输出和进度
+type fakeJobContext struct {
+	context.Context
+	param *common.JobParam
+
+	lock   sync.Mutex
+	output strings.Builder
+}
+
+func (s *fakeJobContext) Write(data []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.output.Write(data)
+}
+
+func (s *fakeJobContext) JobID() string {
+	return "job001"
+}
+
+func (s *fakeJobContext) Param() *common.JobParam {
+	return s.param
+}
+
+func (s *fakeJobContext) SetProgress(int, string) {
+}
+
+// loadRestoreConfig galera守护对象db和主从复制守护对象rep，备份目录为backupPath
+func loadRestoreConfig(t *testing.T, backupPath string) {
+	err := config.LoadConfig("", func(cfg *config.CfgItem) {
+		cfg.Guards = []*config.GuardItem{
+			{Name: "db", Type: config.MariadbGuard, Password: "secret"},
+			{Name: "rep", Type: config.MariadbGuard, Password: "secret", Mode: config.ReplicationMode},
+			{Name: "cache", Type: config.RedisGuard},
+		}
+		cfg.Backup = &config.BackupItem{Schedule: "@daily", Path: backupPath}
+	})
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	t.Cleanup(func() { _ = config.LoadConfig("") })
+}
+
+func TestRestoreParam(t *testing.T) {
+	loadRestoreConfig(t, t.TempDir())
+	cases := []struct {
+		name    string
+		service string
+		param   string
+		expect  string
+	}{
+		{name: "not mariadb", service: "cache", param: `{}`, expect: "illegal mariadb guard, service:cache"},
+		{name: "not exist", service: "other", param: `{}`, expect: "illegal mariadb guard, service:other"},
+		{name: "illegal param", service: "db", param: `[]`, expect: "illegal job param"},
+		{name: "illegal mode", service: "db", param: `{"backup":"a","mode":"other"}`, expect: "illegal restore mode:other"},
+		{name: "joiner with stop time", service: "db", param: `{"backup":"a","mode":"joiner","stopTime":"2026-01-02 03:04:05"}`, expect: "stopTime only supported in bootstrap mode"},
+		{name: "illegal stop time", service: "db", param: `{"backup":"a","mode":"bootstrap","stopTime":"2026-01-02T03:04:05Z"}`, expect: "illegal stopTime"},
+		{name: "replication ignore mode", service: "rep", param: `{"backup":"a","mode":"other"}`, expect: "backup a not exist"},
+		{name: "backup not exist", service: "db", param: `{"backup":"a","mode":"joiner"}`, expect: "backup a not exist"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			jobCtx := &fakeJobContext{Context: context.Background(), param: &common.JobParam{Type: common.RestoreJob, Service: val.service, Param: json.RawMessage(val.param)}}
+			err := (&Backup{}).restoreJob(jobCtx)
+			if err == nil || !strings.HasPrefix(err.Reason, val.expect) {
+				t.Errorf("error %v, expect %q", err, val.expect)
+			}
+		})
+	}
+}
+
+func TestVerifyBackup(t *testing.T) {
+	backupPath := t.TempDir()
+	loadRestoreConfig(t, backupPath)
+
+	content := []byte("backup data")
+	checksum := sha256.Sum256(content)
+	files := map[string]string{
+		"db_mariabackup_20260102T030405.xb.gz":        "",
+		"db_mariabackup_20260102T030405.xb.gz.sha256": hex.EncodeToString(checksum[:]) + "  db_mariabackup_20260102T030405.xb.gz\n",
+		"db_mariabackup_20260103T030405.xb.gz":        "",
+		"db_mariabackup_20260103T030405.xb.gz.sha256": strings.Repeat("0", 64) + "  db_mariabackup_20260103T030405.xb.gz\n",
+		"db_mariabackup_20260104T030405.xb.gz":        "",
+		"db_mariadb-dump_20260102T030405.sql.gz":      "",
+	}
+	for name, val := range files {
+		data := []byte(val)
+		if val == "" {
+			data = content
+		}
+		if err := os.WriteFile(path.Join(backupPath, name), data, 0600); err != nil {
+			t.Fatalf("write backup failed, %v", err)
+		}
+	}
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cases := []struct {
+		name         string
+		ctx          context.Context
+		backup       string
+		expectCode   cd.ErrorCode
+		expectReason string
+		expectOutput string
+	}{
+		{name: "verified", backup: "db_mariabackup_20260102T030405.xb.gz"},
+		{name: "checksum mismatch", backup: "db_mariabackup_20260103T030405.xb.gz", expectCode: cd.UnExpected, expectReason: "checksum mismatch"},
+		{name: "no checksum", backup: "db_mariabackup_20260104T030405.xb.gz", expectOutput: "skip verify"},
+		{name: "dump backup", backup: "db_mariadb-dump_20260102T030405.sql.gz", expectCode: cd.IllegalParam, expectReason: "is not a mariabackup backup"},
+		{name: "not exist", backup: "db_mariabackup_20260105T030405.xb.gz", expectCode: cd.NoExist, expectReason: "not exist"},
+		{name: "canceled", ctx: canceledCtx, backup: "db_mariabackup_20260102T030405.xb.gz", expectCode: cd.UnExpected, expectReason: "context canceled"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ctx := val.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			jobCtx := &fakeJobContext{Context: ctx}
+			recordPtr, err := (&Backup{}).verifyBackup(jobCtx, "db", val.backup)
+			if val.expectCode == cd.Succeeded {
+				if err != nil || recordPtr == nil || recordPtr.Name != val.backup {
+					t.Fatalf("record %+v, error %v", recordPtr, err)
+				}
+			} else if err == nil || err.ErrorCode != val.expectCode || !strings.Contains(err.Reason, val.expectReason) {
+				t.Fatalf("error %v, expect %q", err, val.expectReason)
+			}
+			if !strings.Contains(jobCtx.output.String(), val.expectOutput) {
+				t.Errorf("output %q, expect %q", jobCtx.output.String(), val.expectOutput)
+			}
+		})
+	}
+}
+
+func TestCheckBinlogScript(t *testing.T) {
+	stagingPath := t.TempDir()
+	dataDir := t.TempDir()
+	if err := os.WriteFile(path.Join(stagingPath, "xtrabackup_binlog_info"), []byte("mysql-bin.000002\t385\t0-1-10\n"), 0600); err != nil {
+		t.Fatalf("write binlog info failed, %v", err)
+	}
+
+	output, err := exec.Command("sh", "-c", checkBinlogScript, "sh", stagingPath, dataDir).CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "binlog mysql-bin.000002 not found") {
+		t.Errorf("output %q, error %v, expect binlog not found", output, err)
+	}
+
+	writeFile(t, path.Join(dataDir, "mysql-bin.000002"))
+	output, err = exec.Command("sh", "-c", checkBinlogScript, "sh", stagingPath, dataDir).CombinedOutput()
+	if err != nil || string(output) != "replay binlog from mysql-bin.000002:385\n" {
+		t.Errorf("output %q, error %v", output, err)
+	}
+}
+
+func TestReplaceScript(t *testing.T) {
+	if goruntime.GOOS != "linux" {
+		t.Skip("replace script runs in linux containers")
+	}
+
+	// mariabackup --copy-back 以复制临时目录中的文件代替
+	binPath := t.TempDir()
+	fakeBackup := "#!/bin/sh\nfor val in \"$@\"; do case $val in --target-dir=*) src=${val#*=};; --datadir=*) dst=${val#*=};; esac; done\ncp -R \"$src\"/. \"$dst\"/\n"
+	if err := os.WriteFile(path.Join(binPath, "mariabackup"), []byte(fakeBackup), 0700); err != nil {
+		t.Fatalf("write fake mariabackup failed, %v", err)
+	}
+
+	cases := []struct {
+		name            string
+		saveBinlog      string
+		safeToBootstrap string
+		expectFiles     string
+	}{
+		{name: "bootstrap with binlog", saveBinlog: "1", safeToBootstrap: "1", expectFiles: "#restore-binlog,grastate.dat,ibdata1,xtrabackup_binlog_info,xtrabackup_galera_info"},
+		{name: "joiner", saveBinlog: "0", safeToBootstrap: "0", expectFiles: "grastate.dat,ibdata1,xtrabackup_binlog_info,xtrabackup_galera_info"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			stagingPath := t.TempDir()
+			dataDir := t.TempDir()
+			writeFile(t, path.Join(stagingPath, "ibdata1"))
+			_ = os.WriteFile(path.Join(stagingPath, "xtrabackup_binlog_info"), []byte("mysql-bin.000002\t385\n"), 0600)
+			_ = os.WriteFile(path.Join(stagingPath, "xtrabackup_galera_info"), []byte("6c2d1c8e-0000-11ee-8000-000000000001:42\n"), 0600)
+			for _, name := range []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003", "mysql-bin.index", "old.frm"} {
+				writeFile(t, path.Join(dataDir, name))
+			}
+
+			cmdPtr := exec.Command("sh", "-c", replaceScript, "sh", stagingPath, dataDir, restoreBinlogDir, val.saveBinlog, val.safeToBootstrap)
+			cmdPtr.Env = append(os.Environ(), "PATH="+binPath+":"+os.Getenv("PATH"))
+			if output, err := cmdPtr.CombinedOutput(); err != nil {
+				t.Fatalf("replace failed, %v, %s", err, output)
+			}
+
+			if names := dirNames(t, dataDir); names != val.expectFiles {
+				t.Errorf("data files %s, expect %s", names, val.expectFiles)
+			}
+			grastate, _ := os.ReadFile(path.Join(dataDir, "grastate.dat"))
+			if !strings.Contains(string(grastate), "uuid:    6c2d1c8e-0000-11ee-8000-000000000001\nseqno:   42\nsafe_to_bootstrap: "+val.safeToBootstrap) {
+				t.Errorf("grastate %q", grastate)
+			}
+			if val.saveBinlog == "1" {
+				binlogPath := path.Join(dataDir, restoreBinlogDir)
+				if names := dirNames(t, binlogPath); names != "mysql-bin.000002,mysql-bin.000003,start" {
+					t.Errorf("saved binlog %s", names)
+				}
+				start, _ := os.ReadFile(path.Join(binlogPath, "start"))
+				if string(start) != "mysql-bin.000002 385\n" {
+					t.Errorf("start %q", start)
+				}
+			}
+		})
+	}
+}
+
+func dirNames(t *testing.T, dirPath string) string {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		t.Fatalf("read %s failed, %v", dirPath, err)
+	}
+
+	names := []string{}
+	for _, val := range entries {
+		names = append(names, val.Name())
+	}
+	return strings.Join(names, ",")
+}
@@ -1,4 +1,4 @@
-package docker
+package backup
 
 import (
 	engine "github.com/muidea/magicEngine"
@@ -7,8 +7,8 @@ import (
 	"github.com/muidea/magicCommon/module"
 	"github.com/muidea/magicCommon/task"
 
-	"github.com/muidea/magicAgent/internal/core/module/docker/biz"
-	"github.com/muidea/magicAgent/internal/core/module/docker/service"
+	"github.com/muidea/magicAgent/internal/core/module/backup/biz"
+	"github.com/muidea/magicAgent/internal/core/module/backup/service"
 	"github.com/muidea/magicAgent/pkg/common"
 )
 
@@ -16,27 +16,26 @@ func init() {
 	module.Register(New())
 }
 
-type Docker struct {
+type Backup struct {
 	routeRegistry engine.Router
 
-	service *service.Docker
-	biz     *biz.Docker
+	service *service.Backup
+	biz     *biz.Backup
 }
 
-func New() *Docker {
-	return &Docker{}
+func New() *Backup {
+	return &Backup{}
 }
 
-func (s *Docker) ID() string {
-	return common.DockerModule
+func (s *Backup) ID() string {
+	return common.BackupModule
 }
 
-func (s *Docker) BindRegistry(routeRegistry engine.Router) {
-
+func (s *Backup) BindRegistry(routeRegistry engine.Router) {
 	s.routeRegistry = routeRegistry
 }
 
-func (s *Docker) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+func (s *Backup) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
 	s.biz = biz.New(eventHub, backgroundRoutine)
 
 	s.service = service.New(endpointName, s.biz)
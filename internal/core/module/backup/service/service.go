@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicAgent/internal/core/module/backup/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Backup BaseService
+type Backup struct {
+	routeRegistry engine.Router
+
+	bizPtr *biz.Backup
+
+	endpointName string
+}
+
+// New create base
+func New(endpointName string, bizPtr *biz.Backup) *Backup {
+	ptr := &Backup{
+		endpointName: endpointName,
+		bizPtr:       bizPtr,
+	}
+
+	return ptr
+}
+
+func (s *Backup) BindRegistry(
+	routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+
+	s.routeRegistry.SetApiVersion(common.ApiVersion)
+}
+
+// RegisterRoute 注册路由
+func (s *Backup) RegisterRoute() {
+	listRoute := engine.CreateRoute(common.ListBackup, engine.GET, s.ListBackupHandle)
+	s.routeRegistry.AddRoute(listRoute)
+}
+
+// ListBackupHandle 备份目录中的备份，按时间倒序，service为空时列出全部服务，count<=0时返回全部
+func (s *Backup) ListBackupHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListBackupResult{}
+	for {
+		count := 0
+		countVal := req.URL.Query().Get("count")
+		if countVal != "" {
+			val, valErr := strconv.Atoi(countVal)
+			if valErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = "illegal count"
+				break
+			}
+			count = val
+		}
+
+		backups, listErr := s.bizPtr.ListBackup(req.URL.Query().Get("service"))
+		if listErr != nil {
+			result.Result = *listErr
+			break
+		}
+
+		if count > 0 && len(backups) > count {
+			backups = backups[:count]
+		}
+		result.Backups = backups
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
@@ -0,0 +1,354 @@
+package biz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// hostSampleInterval 主机资源的检查间隔
+const hostSampleInterval = 30 * time.Second
+
+// repeatAlarmInterval 问题持续存在时重复告警的间隔
+const repeatAlarmInterval = 10 * time.Minute
+
+// hostWarning 超过阈值的项，key用于识别同一问题
+type hostWarning struct {
+	key     string
+	content string
+}
+
+type Host struct {
+	biz.Base
+
+	// procPath 读取meminfo、loadavg的目录，测试时替换
+	procPath   string
+	sampleTime time.Time
+	// alarmed 已告警的问题及告警时间
+	alarmed map[string]time.Time
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Host {
+	ptr := &Host{
+		Base:     biz.New(common.HostModule, eventHub, backgroundRoutine),
+		procPath: "/proc",
+		alarmed:  map[string]time.Time{},
+	}
+
+	ptr.SubscribeFunc(common.NotifyTimer, ptr.timerCheck)
+	ptr.SubscribeFunc(common.QueryDataVolume, ptr.queryDataVolume)
+
+	return ptr
+}
+
+func (s *Host) timerCheck(ev event.Event, _ event.Result) {
+	notifyPtr, notifyOK := ev.Data().(*common.TimerNotify)
+	if !notifyOK || notifyPtr.CurTime.Sub(s.sampleTime) < hostSampleInterval {
+		return
+	}
+	s.sampleTime = notifyPtr.CurTime
+
+	_, warnings := s.sampleHost()
+	s.checkHost(warnings)
+}
+
+func (s *Host) queryDataVolume(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryDataVolume failed, illegal param")
+		return
+	}
+
+	volumePtr, volumeErr := s.QueryDataVolume(serviceVal)
+	if re != nil {
+		re.Set(volumePtr, volumeErr)
+	}
+}
+
+// QueryHostStatus 读取配置的挂载点、/proc/meminfo和/proc/loadavg，并给出超过阈值的项
+func (s *Host) QueryHostStatus() *common.HostStatus {
+	statusPtr, _ := s.sampleHost()
+	return statusPtr
+}
+
+func (s *Host) sampleHost() (*common.HostStatus, []*hostWarning) {
+	hostPtr := config.GetHost()
+	statusPtr := &common.HostStatus{SampleTime: time.Now(), Mounts: []*common.MountUsage{}, CPUCount: runtime.NumCPU()}
+	warnings := []*hostWarning{}
+	addWarning := func(key, format string, args ...interface{}) {
+		warnings = append(warnings, &hostWarning{key: key, content: fmt.Sprintf(format, args...)})
+	}
+	for _, val := range hostPtr.GetMounts() {
+		usagePtr := statMount(val.Path)
+		statusPtr.Mounts = append(statusPtr.Mounts, usagePtr)
+		switch {
+		case usagePtr.Unavailable != "":
+			addWarning("mount "+val.Path, "mount %s unavailable, %s", val.Path, usagePtr.Unavailable)
+		case usagePtr.Usage >= float64(val.GetDiskUsage()):
+			addWarning("disk "+val.Path, "mount %s disk usage %.1f%% over %d%%, %d bytes available",
+				val.Path, usagePtr.Usage, val.GetDiskUsage(), usagePtr.Available)
+		}
+		if usagePtr.InodeUsage >= float64(val.GetInodeUsage()) {
+			addWarning("inode "+val.Path, "mount %s inode usage %.1f%% over %d%%, %d of %d used",
+				val.Path, usagePtr.InodeUsage, val.GetInodeUsage(), usagePtr.InodesUsed, usagePtr.Inodes)
+		}
+	}
+
+	if memoryErr := s.readMemory(statusPtr); memoryErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("read memory failed, error:%s", memoryErr.Error())
+		}
+	} else {
+		if statusPtr.MemoryUsage >= float64(hostPtr.GetMemoryUsage()) {
+			addWarning("memory", "memory usage %.1f%% over %d%%, %d bytes available",
+				statusPtr.MemoryUsage, hostPtr.GetMemoryUsage(), statusPtr.MemoryAvailable)
+		}
+		if statusPtr.SwapTotal > 0 && statusPtr.SwapUsage >= float64(hostPtr.GetSwapUsage()) {
+			addWarning("swap", "swap usage %.1f%% over %d%%", statusPtr.SwapUsage, hostPtr.GetSwapUsage())
+		}
+	}
+
+	if loadErr := s.readLoad(statusPtr); loadErr != nil {
+		if config.EnableTrace() {
+			log.Errorf("read load average failed, error:%s", loadErr.Error())
+		}
+	} else if statusPtr.Load1 >= hostPtr.GetLoadRatio()*float64(statusPtr.CPUCount) {
+		addWarning("load", "load average %.2f %.2f %.2f over %.1f per cpu, %d cpus",
+			statusPtr.Load1, statusPtr.Load5, statusPtr.Load15, hostPtr.GetLoadRatio(), statusPtr.CPUCount)
+	}
+
+	for _, val := range warnings {
+		statusPtr.Warnings = append(statusPtr.Warnings, val.content)
+	}
+	return statusPtr, warnings
+}
+
+// checkHost 每个问题只告警一次，持续存在时每repeatAlarmInterval重复告警，问题消失时发送恢复通知
+func (s *Host) checkHost(warnings []*hostWarning) {
+	current := map[string]bool{}
+	items := []string{}
+	for _, val := range warnings {
+		current[val.key] = true
+		alarmTime, ok := s.alarmed[val.key]
+		if ok && time.Since(alarmTime) < repeatAlarmInterval {
+			continue
+		}
+		s.alarmed[val.key] = time.Now()
+		items = append(items, val.content)
+	}
+
+	recovered := []string{}
+	for key := range s.alarmed {
+		if !current[key] {
+			recovered = append(recovered, key)
+			delete(s.alarmed, key)
+		}
+	}
+
+	if len(items) > 0 {
+		log.Warnf("Detected host resource warnings:\n%s", strings.Join(items, "\n"))
+		s.sendHostAlarm("Host Resource", strings.Join(items, "\n"))
+	}
+	if len(recovered) > 0 {
+		log.Infof("Detected host resource back to normal, %s", strings.Join(recovered, ", "))
+		s.sendHostAlarm("Host Resource Recovered", strings.Join(recovered, ", ")+" back to normal")
+	}
+}
+
+func (s *Host) sendHostAlarm(title, content string) {
+	alarmInfo := &common.AlarmInfo{
+		Title:   title,
+		Content: fmt.Sprintf("Node-%s:\n%s", config.GetLocalHost(), content),
+	}
+
+	s.PostEvent(event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo))
+}
+
+// QueryDataVolume 数据卷路径优先使用dataVolume配置，否则取容器中挂载数据目录的主机路径
+func (s *Host) QueryDataVolume(serviceName string) (ret *common.DataVolumeStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal guard, service:%s", serviceName))
+		return
+	}
+
+	volumePath := guardPtr.DataVolume
+	if volumePath == "" {
+		volumePath, err = s.mountSource(serviceName, guardPtr.GetDataDir())
+		if err != nil {
+			return
+		}
+	}
+
+	usagePtr := statMount(volumePath)
+	if usagePtr.Unavailable != "" {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("stat data volume %s failed, %s", volumePath, usagePtr.Unavailable))
+		return
+	}
+
+	ret = &common.DataVolumeStatus{
+		Service: serviceName,
+		Path:    volumePath,
+		Usage:   usagePtr,
+		Full:    isVolumeFull(usagePtr, config.GetHost().GetFullUsage()),
+	}
+	return
+}
+
+// isVolumeFull 没有可用空间，或空间、inode使用率达到fullUsage时视为已满
+func isVolumeFull(usagePtr *common.MountUsage, fullUsage int) bool {
+	return usagePtr.Available == 0 || usagePtr.Usage >= float64(fullUsage) || usagePtr.InodeUsage >= float64(fullUsage)
+}
+
+// mountSource 查找挂载了数据目录的最长挂载点，返回其主机路径
+func (s *Host) mountSource(serviceName, dataDir string) (ret string, err *cd.Result) {
+	if dataDir == "" {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("no data directory, service:%s", serviceName))
+		return
+	}
+
+	containerVal, containerErr := s.SendEvent(event.NewEvent(common.InspectContainer, s.ID(), common.RuntimeModule, nil, serviceName)).Get()
+	if containerErr != nil {
+		err = containerErr
+		return
+	}
+
+	containerPtr, _ := containerVal.(*common.ContainerInfo)
+	dataDir = path.Clean(dataDir)
+	destination := ""
+	if containerPtr != nil {
+		for _, val := range containerPtr.Mounts {
+			target := path.Clean(val.Destination)
+			if val.Source == "" || len(target) <= len(destination) {
+				continue
+			}
+			if dataDir == target || strings.HasPrefix(dataDir, strings.TrimSuffix(target, "/")+"/") {
+				ret, destination = val.Source, target
+			}
+		}
+	}
+	if ret == "" {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("data directory %s not mounted, service:%s", dataDir, serviceName))
+	}
+	return
+}
+
+// statMount 与df一致，使用率为已用空间占已用与普通用户可用空间之和的比例
+func statMount(mountPath string) *common.MountUsage {
+	usagePtr := &common.MountUsage{Path: mountPath}
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(mountPath, &stat); err != nil {
+		usagePtr.Unavailable = err.Error()
+		return usagePtr
+	}
+
+	blockSize := uint64(stat.Bsize)
+	usagePtr.Total = stat.Blocks * blockSize
+	usagePtr.Used = (stat.Blocks - stat.Bfree) * blockSize
+	usagePtr.Available = stat.Bavail * blockSize
+	if usagePtr.Used+usagePtr.Available > 0 {
+		usagePtr.Usage = float64(usagePtr.Used) * 100 / float64(usagePtr.Used+usagePtr.Available)
+	}
+
+	// 部分文件系统不限制inode数量，此时总数为0
+	usagePtr.Inodes = stat.Files
+	if stat.Files > 0 {
+		usagePtr.InodesUsed = stat.Files - stat.Ffree
+		usagePtr.InodeUsage = float64(usagePtr.InodesUsed) * 100 / float64(stat.Files)
+	}
+	return usagePtr
+}
+
+// readMemory 读取meminfo
+func (s *Host) readMemory(statusPtr *common.HostStatus) error {
+	filePtr, fileErr := os.Open(path.Join(s.procPath, "meminfo"))
+	if fileErr != nil {
+		return fileErr
+	}
+	defer filePtr.Close()
+
+	return parseMemory(filePtr, statusPtr)
+}
+
+// parseMemory 解析/proc/meminfo格式的内容，单位为kB
+func parseMemory(reader io.Reader, statusPtr *common.HostStatus) error {
+	fields := map[string]uint64{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		items := strings.Fields(scanner.Text())
+		if len(items) < 2 {
+			continue
+		}
+		val, valErr := strconv.ParseUint(items[1], 10, 64)
+		if valErr == nil {
+			fields[strings.TrimSuffix(items[0], ":")] = val * 1024
+		}
+	}
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+	if fields["MemTotal"] == 0 {
+		return fmt.Errorf("illegal /proc/meminfo, MemTotal not found")
+	}
+
+	statusPtr.MemoryTotal = fields["MemTotal"]
+	statusPtr.MemoryAvailable = fields["MemAvailable"]
+	// 3.14之前的内核没有MemAvailable
+	if _, ok := fields["MemAvailable"]; !ok {
+		statusPtr.MemoryAvailable = fields["MemFree"] + fields["Buffers"] + fields["Cached"]
+	}
+	statusPtr.MemoryUsage = float64(statusPtr.MemoryTotal-statusPtr.MemoryAvailable) * 100 / float64(statusPtr.MemoryTotal)
+	statusPtr.SwapTotal = fields["SwapTotal"]
+	statusPtr.SwapFree = fields["SwapFree"]
+	if statusPtr.SwapTotal > 0 {
+		statusPtr.SwapUsage = float64(statusPtr.SwapTotal-statusPtr.SwapFree) * 100 / float64(statusPtr.SwapTotal)
+	}
+	return nil
+}
+
+// readLoad 读取loadavg
+func (s *Host) readLoad(statusPtr *common.HostStatus) error {
+	content, contentErr := os.ReadFile(path.Join(s.procPath, "loadavg"))
+	if contentErr != nil {
+		return contentErr
+	}
+
+	return parseLoad(content, statusPtr)
+}
+
+// parseLoad 解析/proc/loadavg的前三项
+func parseLoad(content []byte, statusPtr *common.HostStatus) error {
+	items := strings.Fields(string(content))
+	if len(items) < 3 {
+		return fmt.Errorf("illegal /proc/loadavg:%q", content)
+	}
+
+	loads := []*float64{&statusPtr.Load1, &statusPtr.Load5, &statusPtr.Load15}
+	for idx, val := range loads {
+		loadVal, loadErr := strconv.ParseFloat(items[idx], 64)
+		if loadErr != nil {
+			return loadErr
+		}
+		*val = loadVal
+	}
+	return nil
+}
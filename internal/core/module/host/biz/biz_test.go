@@ -0,0 +1,148 @@
+package biz
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const meminfo = `MemTotal:        8000000 kB
+MemFree:          500000 kB
+MemAvailable:    2000000 kB
+Buffers:          100000 kB
+Cached:          1400000 kB
+SwapTotal:       1000000 kB
+SwapFree:         250000 kB
+HugePages_Total:       0
+`
+
+func TestParseMemory(t *testing.T) {
+	cases := []struct {
+		name      string
+		content   string
+		expect    *common.HostStatus
+		expectErr bool
+	}{
+		{
+			name:    "available",
+			content: meminfo,
+			expect:  &common.HostStatus{MemoryTotal: 8000000 * 1024, MemoryAvailable: 2000000 * 1024, MemoryUsage: 75, SwapTotal: 1000000 * 1024, SwapFree: 250000 * 1024, SwapUsage: 75},
+		},
+		{
+			name:    "old kernel without available",
+			content: "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 50 kB\nCached: 50 kB\n",
+			expect:  &common.HostStatus{MemoryTotal: 1000 * 1024, MemoryAvailable: 200 * 1024, MemoryUsage: 80},
+		},
+		{
+			name:    "no swap",
+			content: "MemTotal: 1000 kB\nMemAvailable: 1000 kB\nSwapTotal: 0 kB\nSwapFree: 0 kB\n",
+			expect:  &common.HostStatus{MemoryTotal: 1000 * 1024, MemoryAvailable: 1000 * 1024},
+		},
+		{name: "no total", content: "MemFree: 100 kB\nillegal\nMemTotal: x kB\n", expectErr: true},
+		{name: "empty", expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			statusPtr := &common.HostStatus{}
+			err := parseMemory(strings.NewReader(val.content), statusPtr)
+			if (err != nil) != val.expectErr {
+				t.Fatalf("err %v, expect error %v", err, val.expectErr)
+			}
+			if !val.expectErr && !reflect.DeepEqual(statusPtr, val.expect) {
+				t.Errorf("status %+v, expect %+v", statusPtr, val.expect)
+			}
+		})
+	}
+}
+
+func TestParseLoad(t *testing.T) {
+	cases := []struct {
+		name      string
+		content   string
+		expect    []float64
+		expectErr bool
+	}{
+		{name: "loadavg", content: "0.52 1.25 2.00 3/512 12345\n", expect: []float64{0.52, 1.25, 2}},
+		{name: "too short", content: "0.52 1.25\n", expectErr: true},
+		{name: "illegal value", content: "0.52 x 2.00 3/512 12345\n", expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			statusPtr := &common.HostStatus{}
+			err := parseLoad([]byte(val.content), statusPtr)
+			if (err != nil) != val.expectErr {
+				t.Fatalf("err %v, expect error %v", err, val.expectErr)
+			}
+			if ret := []float64{statusPtr.Load1, statusPtr.Load5, statusPtr.Load15}; !val.expectErr && !reflect.DeepEqual(ret, val.expect) {
+				t.Errorf("load %v, expect %v", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestIsVolumeFull(t *testing.T) {
+	cases := []struct {
+		name   string
+		usage  *common.MountUsage
+		expect bool
+	}{
+		{name: "normal", usage: &common.MountUsage{Available: 1024, Usage: 80, InodeUsage: 10}},
+		{name: "no space available", usage: &common.MountUsage{Available: 0, Usage: 90}, expect: true},
+		{name: "usage reached", usage: &common.MountUsage{Available: 1024, Usage: 98}, expect: true},
+		{name: "inode reached", usage: &common.MountUsage{Available: 1024, Usage: 10, InodeUsage: 99.5}, expect: true},
+		{name: "below full usage", usage: &common.MountUsage{Available: 1024, Usage: 97.9, InodeUsage: 97.9}},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := isVolumeFull(val.usage, 98); ret != val.expect {
+				t.Errorf("full %v, expect %v", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestSampleHost(t *testing.T) {
+	procPath := t.TempDir()
+	if err := os.WriteFile(path.Join(procPath, "meminfo"), []byte(meminfo), 0600); err != nil {
+		t.Fatalf("write meminfo failed, %v", err)
+	}
+	if err := os.WriteFile(path.Join(procPath, "loadavg"), []byte("1000.00 10.00 1.00 3/512 12345\n"), 0600); err != nil {
+		t.Fatalf("write loadavg failed, %v", err)
+	}
+
+	mountPath := path.Join(procPath, "none")
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) {
+		cfg.Host = &config.HostItem{Mounts: []*config.MountItem{{Path: mountPath}}, MemoryUsage: 70, SwapUsage: 80, LoadRatio: 1}
+	})
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	statusPtr, warnings := (&Host{procPath: procPath}).sampleHost()
+	if statusPtr.MemoryUsage != 75 || statusPtr.SwapUsage != 75 || statusPtr.Load1 != 1000 {
+		t.Errorf("status %+v", statusPtr)
+	}
+	keys := []string{}
+	for _, val := range warnings {
+		keys = append(keys, val.key)
+	}
+	if expect := []string{"mount " + mountPath, "memory", "load"}; !reflect.DeepEqual(keys, expect) {
+		t.Errorf("warnings %v, expect %v", keys, expect)
+	}
+
+	// proc不可读时跳过内存和负载检查
+	statusPtr, warnings = (&Host{procPath: path.Join(procPath, "none")}).sampleHost()
+	if statusPtr.MemoryTotal != 0 || len(warnings) != 1 {
+		t.Errorf("status %+v, warnings %d", statusPtr, len(warnings))
+	}
+}
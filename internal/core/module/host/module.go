@@ -0,0 +1,44 @@
+package host
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/host/biz"
+	"github.com/muidea/magicAgent/internal/core/module/host/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Host struct {
+	routeRegistry engine.Router
+
+	service *service.Host
+	biz     *biz.Host
+}
+
+func New() *Host {
+	return &Host{}
+}
+
+func (s *Host) ID() string {
+	return common.HostModule
+}
+
+func (s *Host) BindRegistry(routeRegistry engine.Router) {
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Host) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicAgent/internal/core/module/host/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Host BaseService
+type Host struct {
+	routeRegistry engine.Router
+
+	bizPtr *biz.Host
+
+	endpointName string
+}
+
+// New create base
+func New(endpointName string, bizPtr *biz.Host) *Host {
+	ptr := &Host{
+		endpointName: endpointName,
+		bizPtr:       bizPtr,
+	}
+
+	return ptr
+}
+
+func (s *Host) BindRegistry(
+	routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+
+	s.routeRegistry.SetApiVersion(common.ApiVersion)
+}
+
+// RegisterRoute 注册路由
+func (s *Host) RegisterRoute() {
+	statusRoute := engine.CreateRoute(common.QueryHostStatus, engine.GET, s.QueryStatusHandle)
+	s.routeRegistry.AddRoute(statusRoute)
+
+	volumeRoute := engine.CreateRoute(common.QueryDataVolume, engine.GET, s.QueryDataVolumeHandle)
+	s.routeRegistry.AddRoute(volumeRoute)
+}
+
+func (s *Host) QueryStatusHandle(_ context.Context, res http.ResponseWriter, _ *http.Request) {
+	result := &common.QueryHostStatusResult{}
+	result.Status = s.bizPtr.QueryHostStatus()
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Host) QueryDataVolumeHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryDataVolumeResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		volumePtr, volumeErr := s.bizPtr.QueryDataVolume(serviceName)
+		if volumeErr != nil {
+			result.Result = *volumeErr
+			break
+		}
+
+		result.Volume = volumePtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
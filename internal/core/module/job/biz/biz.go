@@ -0,0 +1,451 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// maxJobSize 保留的任务记录数量
+const maxJobSize = 200
+
+// maxJobOutput 单个任务保留的最大输出字节数
+const maxJobOutput = 1024 * 1024
+
+// maxPendingJobs 等待执行的任务上限
+const maxPendingJobs = 50
+
+type Job struct {
+	biz.Base
+
+	// 任务依次执行，不占用公共的后台任务队列
+	jobRoutine task.BackgroundRoutine
+
+	jobLock sync.RWMutex
+	jobList []*jobItem
+	jobMap  map[string]*jobItem
+	jobFile string
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Job {
+	return newJob(eventHub, backgroundRoutine, jobFilePath())
+}
+
+// newJob 使用指定的任务记录文件创建
+func newJob(eventHub event.Hub, backgroundRoutine task.BackgroundRoutine, jobFile string) *Job {
+	ptr := &Job{
+		Base:       biz.New(common.JobModule, eventHub, backgroundRoutine),
+		jobRoutine: task.NewBackgroundRoutine(maxPendingJobs + 1),
+		jobMap:     map[string]*jobItem{},
+		jobFile:    jobFile,
+	}
+
+	ptr.loadJobHistory()
+	ptr.SubscribeFunc(common.CreateJob, ptr.createJob)
+
+	return ptr
+}
+
+// jobItem 任务运行状态，info与output由Job.jobLock保护
+type jobItem struct {
+	context.Context
+	cancel context.CancelFunc
+
+	owner     *Job
+	info      *common.JobInfo
+	output    strings.Builder
+	truncated bool
+}
+
+func (s *jobItem) JobID() string {
+	return s.info.ID
+}
+
+func (s *jobItem) Param() *common.JobParam {
+	return &common.JobParam{Type: s.info.Type, Service: s.info.Service, Param: s.info.Param}
+}
+
+func (s *jobItem) SetProgress(progress int, message string) {
+	if progress < 0 {
+		progress = 0
+	}
+	if progress > 100 {
+		progress = 100
+	}
+
+	s.owner.jobLock.Lock()
+	defer s.owner.jobLock.Unlock()
+	s.info.Progress = progress
+	s.info.Message = message
+}
+
+// Write 追加任务输出，超出上限的内容丢弃
+func (s *jobItem) Write(data []byte) (int, error) {
+	s.owner.jobLock.Lock()
+	defer s.owner.jobLock.Unlock()
+
+	remain := maxJobOutput - s.output.Len()
+	if remain < len(data) {
+		if remain > 0 {
+			s.output.Write(data[:remain])
+		}
+		s.truncated = true
+		return len(data), nil
+	}
+
+	return s.output.Write(data)
+}
+
+// snapshot 复制任务信息，调用方需持有jobLock
+func (s *jobItem) snapshot(withOutput bool) *common.JobInfo {
+	infoVal := *s.info
+	infoVal.Output = ""
+	if withOutput {
+		infoVal.Output = s.output.String()
+		if s.truncated {
+			infoVal.Output += "\n...(output truncated)\n"
+		}
+	}
+
+	return &infoVal
+}
+
+func (s *Job) createJob(ev event.Event, re event.Result) {
+	param, paramOK := ev.Data().(*common.JobParam)
+	if !paramOK {
+		log.Warnf("createJob failed, illegal param")
+		return
+	}
+
+	infoPtr, err := s.CreateJob(param)
+	if re != nil {
+		re.Set(infoPtr, err)
+	}
+}
+
+func newJobID() string {
+	randVal := make([]byte, 4)
+	_, _ = rand.Read(randVal)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(randVal)
+}
+
+// CreateJob 创建任务，任务按创建顺序依次执行
+func (s *Job) CreateJob(param *common.JobParam) (ret *common.JobInfo, err *cd.Result) {
+	if param.Type == "" {
+		err = cd.NewError(cd.IllegalParam, "illegal job type")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	itemPtr := &jobItem{
+		Context: ctx,
+		cancel:  cancel,
+		owner:   s,
+		info: &common.JobInfo{
+			ID:         newJobID(),
+			Type:       param.Type,
+			Service:    param.Service,
+			Param:      param.Param,
+			Status:     common.JobPending,
+			CreateTime: time.Now(),
+		},
+	}
+
+	func() {
+		s.jobLock.Lock()
+		defer s.jobLock.Unlock()
+
+		pendingCount := 0
+		for _, val := range s.jobList {
+			if val.info.Status == common.JobPending {
+				pendingCount++
+			}
+		}
+		if pendingCount >= maxPendingJobs {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("too many pending jobs, limit:%d", maxPendingJobs))
+			return
+		}
+
+		s.jobList = append(s.jobList, itemPtr)
+		s.jobMap[itemPtr.info.ID] = itemPtr
+		s.trimJobs()
+		ret = itemPtr.snapshot(false)
+	}()
+	if err != nil {
+		cancel()
+		return
+	}
+
+	log.Infof("create job, id:%s, type:%s, service:%s", ret.ID, ret.Type, ret.Service)
+	s.appendJobHistory(ret)
+	// 每个任务对应一次调度，调度时执行最早创建的等待任务，保证按创建顺序执行
+	s.jobRoutine.AsyncTask(&jobTask{funcPtr: s.runPendingJob})
+	return
+}
+
+type jobTask struct {
+	funcPtr func()
+}
+
+func (s *jobTask) Run() {
+	s.funcPtr()
+}
+
+// runPendingJob 执行最早创建的等待任务，通过事件交给订阅该任务类型的模块执行
+func (s *Job) runPendingJob() {
+	var itemPtr *jobItem
+	func() {
+		s.jobLock.Lock()
+		defer s.jobLock.Unlock()
+
+		for _, val := range s.jobList {
+			if val.info.Status == common.JobPending {
+				itemPtr = val
+				break
+			}
+		}
+		if itemPtr == nil {
+			return
+		}
+
+		startTime := time.Now()
+		itemPtr.info.Status = common.JobRunning
+		itemPtr.info.StartTime = &startTime
+	}()
+	if itemPtr == nil {
+		return
+	}
+
+	ev := event.NewEvent(common.RunJob+itemPtr.info.Type, s.ID(), s.RootDestination(), nil, itemPtr)
+	result := s.CallEvent(ev)
+	_, runErr := result.Get()
+	if runErr != nil && runErr.ErrorCode == cd.Warned {
+		// 没有模块订阅该任务类型
+		runErr = cd.NewError(cd.IllegalParam, fmt.Sprintf("unsupported job type:%s", itemPtr.info.Type))
+	}
+
+	var infoPtr *common.JobInfo
+	func() {
+		s.jobLock.Lock()
+		defer s.jobLock.Unlock()
+
+		finishTime := time.Now()
+		itemPtr.info.FinishTime = &finishTime
+		switch {
+		case itemPtr.Err() != nil:
+			itemPtr.info.Status = common.JobCanceled
+		case runErr != nil:
+			itemPtr.info.Status = common.JobFailed
+			itemPtr.info.Error = runErr
+		default:
+			itemPtr.info.Status = common.JobSucceeded
+			itemPtr.info.Progress = 100
+		}
+		infoPtr = itemPtr.snapshot(true)
+	}()
+	itemPtr.cancel()
+
+	s.finishJob(infoPtr)
+}
+
+// finishJob 记录任务结果并广播任务结束事件
+func (s *Job) finishJob(infoPtr *common.JobInfo) {
+	if infoPtr.Error != nil {
+		log.Warnf("job finished, id:%s, type:%s, status:%s, error:%s", infoPtr.ID, infoPtr.Type, infoPtr.Status, infoPtr.Error.Error())
+	} else {
+		log.Infof("job finished, id:%s, type:%s, status:%s", infoPtr.ID, infoPtr.Type, infoPtr.Status)
+	}
+
+	s.appendJobHistory(infoPtr)
+	s.BroadCast(common.NotifyJob, nil, infoPtr)
+}
+
+// CancelJob 取消任务，等待中的任务直接结束，执行中的任务由处理方响应取消后结束
+func (s *Job) CancelJob(id string) (ret *common.JobInfo, err *cd.Result) {
+	var finishPtr *common.JobInfo
+	func() {
+		s.jobLock.Lock()
+		defer s.jobLock.Unlock()
+
+		itemPtr, itemOK := s.jobMap[id]
+		if !itemOK {
+			err = cd.NewWarn(cd.NoExist, fmt.Sprintf("job %s not exist", id))
+			return
+		}
+		if itemPtr.info.IsFinished() {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("job %s already %s", id, itemPtr.info.Status))
+			return
+		}
+
+		itemPtr.cancel()
+		if itemPtr.info.Status == common.JobPending {
+			finishTime := time.Now()
+			itemPtr.info.Status = common.JobCanceled
+			itemPtr.info.FinishTime = &finishTime
+			finishPtr = itemPtr.snapshot(true)
+		}
+		ret = itemPtr.snapshot(false)
+	}()
+
+	if finishPtr != nil {
+		s.finishJob(finishPtr)
+	}
+	return
+}
+
+// QueryJob 查询任务，包含任务输出
+func (s *Job) QueryJob(id string) (ret *common.JobInfo, err *cd.Result) {
+	s.jobLock.RLock()
+	defer s.jobLock.RUnlock()
+
+	itemPtr, itemOK := s.jobMap[id]
+	if !itemOK {
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("job %s not exist", id))
+		return
+	}
+
+	ret = itemPtr.snapshot(true)
+	return
+}
+
+// ListJob 查询任务列表，按创建时间倒序，不包含任务输出，count<=0时返回全部
+func (s *Job) ListJob(count int) []*common.JobInfo {
+	s.jobLock.RLock()
+	defer s.jobLock.RUnlock()
+
+	ret := []*common.JobInfo{}
+	for idx := len(s.jobList) - 1; idx >= 0; idx-- {
+		if count > 0 && len(ret) >= count {
+			break
+		}
+
+		ret = append(ret, s.jobList[idx].snapshot(false))
+	}
+
+	return ret
+}
+
+// trimJobs 只保留最近的已结束任务，调用方需持有jobLock
+func (s *Job) trimJobs() {
+	for len(s.jobList) > maxJobSize {
+		idx := 0
+		for idx < len(s.jobList) && !s.jobList[idx].info.IsFinished() {
+			idx++
+		}
+		if idx >= len(s.jobList) {
+			return
+		}
+
+		delete(s.jobMap, s.jobList[idx].info.ID)
+		s.jobList = append(s.jobList[:idx], s.jobList[idx+1:]...)
+	}
+}
+
+func jobFilePath() string {
+	return path.Join(config.GetWorkPath(), "log", "job.json")
+}
+
+// loadJobHistory 启动时加载任务记录，同一任务以最后一条记录为准
+// 上次退出时未结束的任务标记为失败，加载后重写记录文件
+func (s *Job) loadJobHistory() {
+	fileHandle, fileErr := os.Open(s.jobFile)
+	if fileErr != nil {
+		return
+	}
+
+	infoMap := map[string]*common.JobInfo{}
+	scanner := bufio.NewScanner(fileHandle)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*maxJobOutput)
+	for scanner.Scan() {
+		infoPtr := &common.JobInfo{}
+		if json.Unmarshal(scanner.Bytes(), infoPtr) != nil || infoPtr.ID == "" {
+			continue
+		}
+
+		infoMap[infoPtr.ID] = infoPtr
+	}
+	fileHandle.Close()
+
+	infoList := []*common.JobInfo{}
+	for _, val := range infoMap {
+		if !val.IsFinished() {
+			finishTime := time.Now()
+			val.Status = common.JobFailed
+			val.Error = cd.NewError(cd.UnExpected, "interrupted by agent restart")
+			val.FinishTime = &finishTime
+		}
+		infoList = append(infoList, val)
+	}
+	sort.Slice(infoList, func(i, j int) bool {
+		return infoList[i].CreateTime.Before(infoList[j].CreateTime)
+	})
+	if len(infoList) > maxJobSize {
+		infoList = infoList[len(infoList)-maxJobSize:]
+	}
+
+	content := []byte{}
+	s.jobLock.Lock()
+	defer s.jobLock.Unlock()
+	for _, val := range infoList {
+		itemPtr := &jobItem{Context: context.Background(), cancel: func() {}, owner: s, info: val}
+		itemPtr.output.WriteString(val.Output)
+		val.Output = ""
+		s.jobList = append(s.jobList, itemPtr)
+		s.jobMap[val.ID] = itemPtr
+
+		byteVal, byteErr := json.Marshal(itemPtr.snapshot(true))
+		if byteErr == nil {
+			content = append(append(content, byteVal...), '\n')
+		}
+	}
+
+	writeErr := os.WriteFile(s.jobFile, content, 0660)
+	if writeErr != nil {
+		log.Errorf("loadJobHistory failed, rewrite job file failed, error:%s", writeErr.Error())
+	}
+}
+
+func (s *Job) appendJobHistory(infoPtr *common.JobInfo) {
+	byteVal, byteErr := json.Marshal(infoPtr)
+	if byteErr != nil {
+		log.Errorf("appendJobHistory failed, marshal job failed, error:%s", byteErr.Error())
+		return
+	}
+
+	logFullPath := s.jobFile
+	logPath, _ := path.Split(logFullPath)
+	_ = os.MkdirAll(logPath, os.ModePerm)
+
+	fileHandle, fileErr := os.OpenFile(logFullPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if fileErr != nil {
+		log.Errorf("appendJobHistory failed, open file %s failed, error:%s", logFullPath, fileErr.Error())
+		return
+	}
+	defer fileHandle.Close()
+
+	_, writeErr := fileHandle.Write(append(byteVal, '\n'))
+	if writeErr != nil {
+		log.Errorf("appendJobHistory failed, write job failed, error:%s", writeErr.Error())
+	}
+}
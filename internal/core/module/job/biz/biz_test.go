@@ -0,0 +1,193 @@
+package biz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func newTestJob(jobFile string) (*Job, *biz.Base) {
+	// 广播过事件的事件中心Terminate时可能阻塞，测试中不终止
+	eventHub := event.NewHub(10)
+
+	handler := biz.New("/module/test", eventHub, task.NewBackgroundRoutine(10))
+	return newJob(eventHub, task.NewBackgroundRoutine(10), jobFile), &handler
+}
+
+// waitJob 等待任务结束并返回任务信息
+func waitJob(t *testing.T, jobPtr *Job, id string) *common.JobInfo {
+	for idx := 0; idx < 200; idx++ {
+		infoPtr, infoErr := jobPtr.QueryJob(id)
+		if infoErr != nil {
+			t.Fatalf("query job failed, %v", infoErr)
+		}
+		if infoPtr.IsFinished() {
+			return infoPtr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s not finished", id)
+	return nil
+}
+
+func TestRunJob(t *testing.T) {
+	jobPtr, handler := newTestJob(path.Join(t.TempDir(), "job.json"))
+	handler.SubscribeJob("echo", func(jobCtx biz.JobContext) *cd.Result {
+		_, _ = fmt.Fprintf(jobCtx, "run %s\n", jobCtx.Param().Service)
+		jobCtx.SetProgress(150, "done")
+		return nil
+	})
+	handler.SubscribeJob("fail", func(jobCtx biz.JobContext) *cd.Result {
+		return cd.NewError(cd.UnExpected, "broken")
+	})
+
+	cases := []struct {
+		name          string
+		jobType       string
+		expectStatus  string
+		expectOutput  string
+		expectError   string
+		expectProcess int
+	}{
+		{name: "succeeded", jobType: "echo", expectStatus: common.JobSucceeded, expectOutput: "run db\n", expectProcess: 100},
+		{name: "failed", jobType: "fail", expectStatus: common.JobFailed, expectError: "broken"},
+		{name: "unsupported", jobType: "unknown", expectStatus: common.JobFailed, expectError: "unsupported job type:unknown"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			infoPtr, infoErr := jobPtr.CreateJob(&common.JobParam{Type: val.jobType, Service: "db"})
+			if infoErr != nil {
+				t.Fatalf("create job failed, %v", infoErr)
+			}
+
+			infoPtr = waitJob(t, jobPtr, infoPtr.ID)
+			if infoPtr.Status != val.expectStatus || infoPtr.Output != val.expectOutput || infoPtr.Progress != val.expectProcess {
+				t.Errorf("job %+v, expect status %s, output %q", infoPtr, val.expectStatus, val.expectOutput)
+			}
+			if val.expectError != "" && (infoPtr.Error == nil || infoPtr.Error.Reason != val.expectError) {
+				t.Errorf("error %v, expect %q", infoPtr.Error, val.expectError)
+			}
+			if infoPtr.StartTime == nil || infoPtr.FinishTime == nil {
+				t.Errorf("illegal start or finish time, %+v", infoPtr)
+			}
+		})
+	}
+
+	if _, err := jobPtr.CreateJob(&common.JobParam{}); err == nil || err.ErrorCode != cd.IllegalParam {
+		t.Errorf("error %v, expect illegal param", err)
+	}
+	if jobs := jobPtr.ListJob(2); len(jobs) != 2 || jobs[0].Type != "unknown" || jobs[1].Type != "fail" {
+		t.Errorf("unexpected job list %+v", jobs)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	jobPtr, handler := newTestJob(path.Join(t.TempDir(), "job.json"))
+	started := make(chan struct{})
+	handler.SubscribeJob("block", func(jobCtx biz.JobContext) *cd.Result {
+		close(started)
+		<-jobCtx.Done()
+		return cd.NewError(cd.UnExpected, "canceled")
+	})
+
+	runningPtr, _ := jobPtr.CreateJob(&common.JobParam{Type: "block"})
+	pendingPtr, _ := jobPtr.CreateJob(&common.JobParam{Type: "block"})
+	<-started
+
+	cases := []struct {
+		name         string
+		id           string
+		expectStatus string
+		expectCode   cd.ErrorCode
+	}{
+		{name: "pending", id: pendingPtr.ID, expectStatus: common.JobCanceled},
+		{name: "running", id: runningPtr.ID, expectStatus: common.JobRunning},
+		{name: "finished", id: pendingPtr.ID, expectCode: cd.IllegalParam},
+		{name: "not exist", id: "j1", expectCode: cd.NoExist},
+	}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			infoPtr, infoErr := jobPtr.CancelJob(val.id)
+			if val.expectCode != cd.Succeeded {
+				if infoErr == nil || infoErr.ErrorCode != val.expectCode {
+					t.Errorf("error %v, expect code %d", infoErr, val.expectCode)
+				}
+				return
+			}
+			if infoErr != nil || infoPtr.Status != val.expectStatus {
+				t.Errorf("job %+v, error %v, expect status %s", infoPtr, infoErr, val.expectStatus)
+			}
+		})
+	}
+
+	if infoPtr := waitJob(t, jobPtr, runningPtr.ID); infoPtr.Status != common.JobCanceled {
+		t.Errorf("running job status %s, expect canceled", infoPtr.Status)
+	}
+}
+
+func TestJobOutputLimit(t *testing.T) {
+	jobPtr, _ := newTestJob(path.Join(t.TempDir(), "job.json"))
+	itemPtr := &jobItem{owner: jobPtr, info: &common.JobInfo{ID: "j1"}}
+
+	block := strings.Repeat("a", maxJobOutput-2)
+	for _, val := range []string{block, "bbbb", "cc"} {
+		if size, err := itemPtr.Write([]byte(val)); err != nil || size != len(val) {
+			t.Fatalf("write size %d, error %v", size, err)
+		}
+	}
+
+	output := itemPtr.snapshot(true).Output
+	if output != block+"bb\n...(output truncated)\n" {
+		t.Errorf("output tail %q", output[len(output)-32:])
+	}
+	if itemPtr.snapshot(false).Output != "" {
+		t.Errorf("snapshot without output contains output")
+	}
+}
+
+func TestLoadJobHistory(t *testing.T) {
+	jobFile := path.Join(t.TempDir(), "job.json")
+	baseTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	lines := []string{"illegal"}
+	for _, val := range []*common.JobInfo{
+		{ID: "j2", Type: "restart", Status: common.JobRunning, CreateTime: baseTime.Add(time.Minute)},
+		{ID: "j1", Type: "restart", Status: common.JobPending, CreateTime: baseTime},
+		{ID: "j1", Type: "restart", Status: common.JobSucceeded, Output: "ok\n", CreateTime: baseTime},
+	} {
+		byteVal, _ := json.Marshal(val)
+		lines = append(lines, string(byteVal))
+	}
+	if err := os.WriteFile(jobFile, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("write job file failed, %v", err)
+	}
+
+	jobPtr, _ := newTestJob(jobFile)
+	jobs := jobPtr.ListJob(0)
+	if len(jobs) != 2 || jobs[0].ID != "j2" || jobs[1].ID != "j1" {
+		t.Fatalf("unexpected job list %+v", jobs)
+	}
+	if jobs[0].Status != common.JobFailed || jobs[0].Error == nil || jobs[0].FinishTime == nil {
+		t.Errorf("interrupted job %+v, expect failed", jobs[0])
+	}
+	if infoPtr, _ := jobPtr.QueryJob("j1"); infoPtr.Status != common.JobSucceeded || infoPtr.Output != "ok\n" {
+		t.Errorf("job %+v, expect succeeded with output", infoPtr)
+	}
+
+	content, _ := os.ReadFile(jobFile)
+	if count := strings.Count(string(content), "\n"); count != 2 {
+		t.Errorf("rewrite %d records, expect 2", count)
+	}
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/job/biz"
+	"github.com/muidea/magicAgent/internal/core/module/job/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Job struct {
+	routeRegistry engine.Router
+
+	service *service.Job
+	biz     *biz.Job
+}
+
+func New() *Job {
+	return &Job{}
+}
+
+func (s *Job) ID() string {
+	return common.JobModule
+}
+
+func (s *Job) BindRegistry(routeRegistry engine.Router) {
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Job) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicAgent/internal/core/base/service"
+	"github.com/muidea/magicAgent/internal/core/module/job/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Job JobService
+type Job struct {
+	routeRegistry engine.Router
+
+	bizPtr *biz.Job
+
+	endpointName string
+}
+
+// New create job
+func New(endpointName string, bizPtr *biz.Job) *Job {
+	ptr := &Job{
+		endpointName: endpointName,
+		bizPtr:       bizPtr,
+	}
+
+	return ptr
+}
+
+func (s *Job) BindRegistry(
+	routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+
+	s.routeRegistry.SetApiVersion(common.ApiVersion)
+}
+
+// RegisterRoute 注册路由
+func (s *Job) RegisterRoute() {
+	createRoute := engine.CreateRoute(common.CreateJob, engine.POST, s.CreateJobHandle)
+	s.routeRegistry.AddRoute(createRoute)
+
+	listRoute := engine.CreateRoute(common.ListJob, engine.GET, s.ListJobHandle)
+	s.routeRegistry.AddRoute(listRoute)
+
+	queryRoute := engine.CreateRoute(common.QueryJob, engine.GET, s.QueryJobHandle)
+	s.routeRegistry.AddRoute(queryRoute)
+
+	cancelRoute := engine.CreateRoute(common.CancelJob, engine.POST, s.CancelJobHandle)
+	s.routeRegistry.AddRoute(cancelRoute)
+}
+
+// CreateJobHandle 创建任务，需要管理员权限
+func (s *Job) CreateJobHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.JobResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject create job, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		param := &common.JobParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Type == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "非法参数"
+			break
+		}
+
+		jobPtr, jobErr := s.bizPtr.CreateJob(param)
+		if jobErr != nil {
+			result.Result = *jobErr
+			break
+		}
+
+		result.Job = jobPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Job) ListJobHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListJobResult{}
+	for {
+		count := 0
+		countVal := req.URL.Query().Get("count")
+		if countVal != "" {
+			val, valErr := strconv.Atoi(countVal)
+			if valErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = "illegal count"
+				break
+			}
+			count = val
+		}
+
+		result.Jobs = s.bizPtr.ListJob(count)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Job) QueryJobHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.JobResult{}
+	for {
+		_, jobID := fn.SplitRESTPath(req.URL.Path)
+		jobPtr, jobErr := s.bizPtr.QueryJob(jobID)
+		if jobErr != nil {
+			result.Result = *jobErr
+			break
+		}
+
+		result.Job = jobPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// CancelJobHandle 取消任务，需要管理员权限
+func (s *Job) CancelJobHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.JobResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject cancel job, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		// 路径格式为 .../job/{id}/cancel
+		jobID := path.Base(path.Dir(req.URL.Path))
+		jobPtr, jobErr := s.bizPtr.CancelJob(jobID)
+		if jobErr != nil {
+			result.Result = *jobErr
+			break
+		}
+
+		result.Job = jobPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
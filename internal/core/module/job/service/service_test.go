@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/module/job/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestJobAuthority(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+
+	eventHub := event.NewHub(10)
+	servicePtr := New(common.JobModule, biz.New(eventHub, task.NewBackgroundRoutine(10)))
+
+	cases := []struct {
+		name       string
+		handle     func(context.Context, http.ResponseWriter, *http.Request)
+		path       string
+		body       string
+		token      string
+		expectCode cd.ErrorCode
+	}{
+		{name: "create without token", handle: servicePtr.CreateJobHandle, path: "/job", body: `{"type":"restart","service":"db"}`, expectCode: cd.InvalidAuthority},
+		{name: "create with wrong token", handle: servicePtr.CreateJobHandle, path: "/job", body: `{"type":"restart","service":"db"}`, token: "other", expectCode: cd.InvalidAuthority},
+		{name: "cancel without token", handle: servicePtr.CancelJobHandle, path: "/job/j1/cancel", expectCode: cd.InvalidAuthority},
+		{name: "create illegal param", handle: servicePtr.CreateJobHandle, path: "/job", body: `{"service":"db"}`, token: "admin-token", expectCode: cd.IllegalParam},
+		{name: "cancel not exist", handle: servicePtr.CancelJobHandle, path: "/job/j1/cancel", token: "admin-token", expectCode: cd.NoExist},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, val.path, strings.NewReader(val.body))
+			req.Header.Set("Content-Type", "application/json")
+			if val.token != "" {
+				req.Header.Set("Authorization", "Bearer "+val.token)
+			}
+			recorder := httptest.NewRecorder()
+			val.handle(context.Background(), recorder, req)
+
+			result := &common.JobResult{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("illegal response %s", recorder.Body.String())
+			}
+			if result.ErrorCode != val.expectCode {
+				t.Errorf("error code %d, expect %d, reason %s", result.ErrorCode, val.expectCode, result.Reason)
+			}
+			if result.Job != nil {
+				t.Errorf("unexpected job %+v", result.Job)
+			}
+		})
+	}
+
+	if jobs := servicePtr.bizPtr.ListJob(0); len(jobs) != 0 {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+}
@@ -3,8 +3,11 @@ package biz
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	cd "github.com/muidea/magicCommon/def"
 	"github.com/muidea/magicCommon/event"
@@ -18,6 +21,14 @@ import (
 
 type Mariadb struct {
 	biz.Base
+
+	// failoverLock 同一时间只执行一次故障切换
+	failoverLock sync.Mutex
+
+	// sampleTime 最近一次慢查询采样时间，processAlarmed 为各服务已告警的查询和事务，由processLock保护
+	processLock    sync.Mutex
+	sampleTime     time.Time
+	processAlarmed map[string]map[string]bool
 }
 
 func New(
@@ -25,10 +36,17 @@ func New(
 	backgroundRoutine task.BackgroundRoutine,
 ) *Mariadb {
 	ptr := &Mariadb{
-		Base: biz.New(common.MariadbModule, eventHub, backgroundRoutine),
+		Base:           biz.New(common.MariadbModule, eventHub, backgroundRoutine),
+		processAlarmed: map[string]map[string]bool{},
 	}
 
 	ptr.SubscribeFunc(common.QueryStatus, ptr.queryStatus)
+	ptr.SubscribeFunc(common.DesyncNode, ptr.desyncNode)
+	ptr.SubscribeFunc(common.BootstrapNode, ptr.bootstrapNode)
+	ptr.SubscribeFunc(common.QueryComponent, ptr.queryComponent)
+	ptr.SubscribeFunc(common.QueryReplication, ptr.queryReplication)
+	ptr.SubscribeFunc(common.Failover, ptr.failover)
+	ptr.SubscribeFunc(common.NotifyTimer, ptr.timerCheck)
 
 	return ptr
 }
@@ -45,17 +63,67 @@ func (s *Mariadb) queryStatus(ev event.Event, re event.Result) {
 	}
 }
 
+func (s *Mariadb) desyncNode(ev event.Event, re event.Result) {
+	param, paramOK := ev.Data().(*common.DesyncParam)
+	if !paramOK {
+		log.Warnf("desyncNode failed, illegal param")
+		return
+	}
+
+	err := s.SetDesync(param.Service, param.Desync)
+	if re != nil {
+		re.Set(nil, err)
+	}
+}
+
+func (s *Mariadb) bootstrapNode(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("bootstrapNode failed, illegal param")
+		return
+	}
+
+	err := s.Bootstrap(serviceVal)
+	if re != nil {
+		re.Set(nil, err)
+	}
+}
+
 const wsrepIncomingAddresses = "wsrep_incoming_addresses"
 const wsrepClusterSize = "wsrep_cluster_size"
 const wsrepClusterStatus = "wsrep_cluster_status"
+const wsrepLocalStateComment = "wsrep_local_state_comment"
+const wsrepClusterStateUUID = "wsrep_cluster_state_uuid"
+const wsrepLastCommitted = "wsrep_last_committed"
 
-func (s *Mariadb) QueryMariadbClusterStatus(serviceName string) (ret *common.ClusterStatus, err *cd.Result) {
+// shellQuote 使用单引号包裹参数，避免账号信息被shell解析
+func shellQuote(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", "'\\''") + "'"
+}
+
+// mysqlParam 构造执行mysql命令的参数，密码通过exec环境变量MYSQL_PWD传递，不出现在命令行中
+func mysqlParam(serviceName string, guardPtr *config.GuardItem, sql string) *common.ServiceParam {
 	param := &common.ServiceParam{
 		Service:  serviceName,
-		CmdParam: "mysql -uroot -prootkit -e\"show status like '%wsrep%';\"",
+		CmdParam: fmt.Sprintf("mysql -u%s -e%s", shellQuote(guardPtr.GetAccount()), shellQuote(sql)),
+	}
+	if guardPtr.Password != "" {
+		param.Env = []string{"MYSQL_PWD=" + guardPtr.Password}
+	}
+
+	return param
+}
+
+func (s *Mariadb) QueryMariadbClusterStatus(serviceName string) (ret *common.ClusterStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
 	}
 
-	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.DockerModule, nil, param)
+	param := mysqlParam(serviceName, guardPtr, "show status like '%wsrep%';")
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
 	result := s.SendEvent(execEvent)
 	execVal, execErr := result.Get()
 	if execErr != nil {
@@ -81,6 +149,12 @@ func (s *Mariadb) QueryMariadbClusterStatus(serviceName string) (ret *common.Clu
 			statusPtr.NodeSize, _ = strconv.Atoi(items[1])
 		case wsrepClusterStatus:
 			statusPtr.Status = items[1]
+		case wsrepLocalStateComment:
+			statusPtr.LocalState = items[1]
+		case wsrepClusterStateUUID:
+			statusPtr.StateUUID = items[1]
+		case wsrepLastCommitted:
+			statusPtr.LastCommitted, _ = strconv.ParseInt(items[1], 10, 64)
 		default:
 		}
 	}
@@ -88,3 +162,43 @@ func (s *Mariadb) QueryMariadbClusterStatus(serviceName string) (ret *common.Clu
 	ret = statusPtr
 	return
 }
+
+// SetDesync 设置节点的wsrep_desync，开启后节点不参与流控，备份等耗时操作不会拖慢集群写入
+func (s *Mariadb) SetDesync(serviceName string, desync bool) (err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
+	}
+
+	value := "OFF"
+	if desync {
+		value = "ON"
+	}
+	param := mysqlParam(serviceName, guardPtr, fmt.Sprintf("SET GLOBAL wsrep_desync=%s;", value))
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+	_, err = s.SendEvent(execEvent).Get()
+	if err != nil {
+		log.Errorf("set wsrep_desync=%s failed, service:%s, error:%s", value, serviceName, err.Error())
+	}
+	return
+}
+
+// Bootstrap 设置pc.bootstrap，使当前Non-Primary节点成为新的Primary组件，其他节点需重新加入
+func (s *Mariadb) Bootstrap(serviceName string) (err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
+	}
+
+	param := mysqlParam(serviceName, guardPtr, "SET GLOBAL wsrep_provider_options='pc.bootstrap=YES';")
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+	_, err = s.SendEvent(execEvent).Get()
+	if err != nil {
+		log.Errorf("set pc.bootstrap failed, service:%s, error:%s", serviceName, err.Error())
+	}
+	return
+}
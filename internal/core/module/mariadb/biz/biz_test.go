@@ -0,0 +1,50 @@
+package biz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muidea/magicAgent/internal/config"
+)
+
+func TestMysqlParam(t *testing.T) {
+	cases := []struct {
+		name      string
+		guard     *config.GuardItem
+		sql       string
+		expectCmd string
+		expectEnv []string
+	}{
+		{
+			name:      "default account",
+			guard:     &config.GuardItem{Name: "mariadb001", Type: config.MariadbGuard},
+			sql:       "select 1;",
+			expectCmd: "mysql -u'root' -e'select 1;'",
+		},
+		{
+			name:      "password in env",
+			guard:     &config.GuardItem{Name: "mariadb001", Type: config.MariadbGuard, Account: "admin", Password: "p'ss word"},
+			sql:       "show status like '%wsrep%';",
+			expectCmd: `mysql -u'admin' -e'show status like '\''%wsrep%'\'';'`,
+			expectEnv: []string{"MYSQL_PWD=p'ss word"},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			param := mysqlParam(val.guard.Name, val.guard, val.sql)
+			if param.Service != val.guard.Name {
+				t.Errorf("service %q, expect %q", param.Service, val.guard.Name)
+			}
+			if param.CmdParam != val.expectCmd {
+				t.Errorf("command %q, expect %q", param.CmdParam, val.expectCmd)
+			}
+			if val.guard.Password != "" && strings.Contains(param.CmdParam, val.guard.Password) {
+				t.Errorf("password leaked into command %q", param.CmdParam)
+			}
+			if strings.Join(param.Env, "\n") != strings.Join(val.expectEnv, "\n") {
+				t.Errorf("env %v, expect %v", param.Env, val.expectEnv)
+			}
+		})
+	}
+}
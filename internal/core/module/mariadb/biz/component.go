@@ -0,0 +1,204 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/client"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// peerQueryTimeOut 查询其他节点视图的超时时间
+const peerQueryTimeOut = 5 * time.Second
+
+func (s *Mariadb) queryComponent(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryComponent failed, illegal param")
+		return
+	}
+
+	decisionPtr, decisionErr := s.AnalyzeComponent(serviceVal)
+	if re != nil {
+		re.Set(decisionPtr, decisionErr)
+	}
+}
+
+// clusterAddresses 集群全部节点的agent地址，本节点在前
+func clusterAddresses() []string {
+	addresses := []string{}
+	existed := map[string]bool{}
+	for _, val := range append([]string{config.GetLocalHost()}, config.GetClusterHosts()...) {
+		if val == "" {
+			continue
+		}
+
+		address := config.AgentAddress(val)
+		if !existed[address] {
+			existed[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+// collectViews 并发查询各节点视图，本节点直接查询
+func (s *Mariadb) collectViews(serviceName string) []*common.NodeView {
+	addresses := clusterAddresses()
+	views := make([]*common.NodeView, len(addresses))
+	wg := sync.WaitGroup{}
+	for idx, address := range addresses {
+		viewPtr := &common.NodeView{Host: address, Local: idx == 0}
+		views[idx] = viewPtr
+		if viewPtr.Local {
+			statusPtr, statusErr := s.QueryMariadbClusterStatus(serviceName)
+			viewPtr.Status = statusPtr
+			if statusErr != nil {
+				viewPtr.Error = statusErr.Reason
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), peerQueryTimeOut)
+			defer cancel()
+
+			statusPtr, statusErr := client.NewClient(viewPtr.Host).QueryStatus(ctx, serviceName)
+			viewPtr.Status = statusPtr
+			if statusErr != nil {
+				viewPtr.Error = statusErr.Reason
+			}
+		}()
+	}
+	wg.Wait()
+
+	return views
+}
+
+// AnalyzeComponent 查询各节点视图并判断Non-Primary组件的处理方式
+func (s *Mariadb) AnalyzeComponent(serviceName string) (ret *common.ComponentDecision, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
+	}
+
+	ret = decideComponent(s.collectViews(serviceName))
+	return
+}
+
+// decideComponent 存在Primary组件时其他节点重启后重新加入；可达节点未过半时可能存在不可见的Primary组件，不做处理；
+// 否则优先选择节点数过半的组件，没有时选择seqno最大的节点执行pc.bootstrap，seqno相同时按地址排序，各节点结论一致，
+// views中第一个为本节点
+func decideComponent(views []*common.NodeView) (ret *common.ComponentDecision) {
+	ret = &common.ComponentDecision{Views: views}
+	reachable := []*common.NodeView{}
+	for _, val := range views {
+		if val.Status == nil {
+			continue
+		}
+
+		reachable = append(reachable, val)
+		if val.Status.Status == common.Primary {
+			if views[0].Status != nil && views[0].Status.Status == common.Primary {
+				ret.Action = common.ComponentNormal
+				ret.Reason = "local node is in Primary component"
+				return
+			}
+
+			ret.Action = common.ComponentRestart
+			ret.Reason = fmt.Sprintf("%s is in Primary component with %d node(s), local node should rejoin after restart", val.Host, val.Status.NodeSize)
+			ret.Reason += ", views: " + describeViews(views)
+			return
+		}
+	}
+
+	total := len(views)
+	if len(reachable)*2 <= total {
+		ret.Action = common.ComponentWait
+		ret.Reason = fmt.Sprintf("only %d of %d nodes reachable, a Primary component may exist on unreachable nodes", len(reachable), total)
+		ret.Reason += ", views: " + describeViews(views)
+		return
+	}
+
+	stateUUID := ""
+	for _, val := range reachable {
+		if val.Status.StateUUID == "" {
+			continue
+		}
+		if stateUUID != "" && val.Status.StateUUID != stateUUID {
+			ret.Action = common.ComponentWait
+			ret.Reason = "nodes have different cluster state UUID, histories diverged"
+			ret.Reason += ", views: " + describeViews(views)
+			return
+		}
+		stateUUID = val.Status.StateUUID
+	}
+
+	candidates, basis := reachable, "no majority component"
+	if members := majorityComponent(reachable, total); len(members) > 0 {
+		candidates = members
+		basis = fmt.Sprintf("component with %d of %d nodes holds the majority", len(members), total)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Status.LastCommitted != candidates[j].Status.LastCommitted {
+			return candidates[i].Status.LastCommitted > candidates[j].Status.LastCommitted
+		}
+
+		return candidates[i].Host < candidates[j].Host
+	})
+
+	ret.Action = common.ComponentBootstrap
+	ret.Candidate = candidates[0].Host
+	ret.Reason = fmt.Sprintf("no Primary component among %d reachable of %d nodes, %s, %s has the highest seqno %d",
+		len(reachable), total, basis, ret.Candidate, candidates[0].Status.LastCommitted)
+	ret.Reason += ", views: " + describeViews(views)
+	return
+}
+
+// majorityComponent 按组件成员分组，返回节点数过半的组件中可达的节点
+func majorityComponent(reachable []*common.NodeView, total int) []*common.NodeView {
+	components := map[string][]*common.NodeView{}
+	for _, val := range reachable {
+		nodes := append([]string{}, val.Status.Nodes...)
+		sort.Strings(nodes)
+		key := strings.Join(nodes, ",")
+		components[key] = append(components[key], val)
+	}
+
+	for _, members := range components {
+		if members[0].Status.NodeSize*2 > total {
+			return members
+		}
+	}
+
+	return nil
+}
+
+func describeViews(views []*common.NodeView) string {
+	items := []string{}
+	for _, val := range views {
+		if val.Status == nil {
+			items = append(items, fmt.Sprintf("%s(unreachable: %s)", val.Host, val.Error))
+			continue
+		}
+
+		items = append(items, fmt.Sprintf("%s(%s/%s, size %d, seqno %d)",
+			val.Host, val.Status.Status, val.Status.LocalState, val.Status.NodeSize, val.Status.LastCommitted))
+	}
+
+	return strings.Join(items, "; ")
+}
@@ -0,0 +1,195 @@
+package biz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func nodeView(host, status string, nodes []string, uuid string, seqno int64) *common.NodeView {
+	return &common.NodeView{
+		Host: host,
+		Status: &common.ClusterStatus{
+			Nodes:         nodes,
+			NodeSize:      len(nodes),
+			Status:        status,
+			StateUUID:     uuid,
+			LastCommitted: seqno,
+		},
+	}
+}
+
+func unreachableView(host string) *common.NodeView {
+	return &common.NodeView{Host: host, Error: "connection refused"}
+}
+
+func TestDecideComponent(t *testing.T) {
+	all := []string{"a", "b", "c"}
+	cases := []struct {
+		name      string
+		views     []*common.NodeView
+		action    string
+		candidate string
+		reason    string
+	}{
+		{
+			name: "local primary",
+			views: []*common.NodeView{
+				nodeView("a", common.Primary, all, "u1", 10),
+				nodeView("b", common.Primary, all, "u1", 10),
+				nodeView("c", common.Primary, all, "u1", 10),
+			},
+			action: common.ComponentNormal,
+		},
+		{
+			name: "other node primary",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 8),
+				nodeView("b", common.Primary, []string{"b", "c"}, "u1", 10),
+				nodeView("c", common.Primary, []string{"b", "c"}, "u1", 10),
+			},
+			action: common.ComponentRestart,
+			reason: "b is in Primary component with 2 node(s)",
+		},
+		{
+			name: "minority reachable",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 10),
+				unreachableView("b"),
+				unreachableView("c"),
+			},
+			action: common.ComponentWait,
+			reason: "only 1 of 3 nodes reachable",
+		},
+		{
+			name: "half reachable",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 10),
+				nodeView("b", common.NonPrimary, []string{"b"}, "u1", 10),
+				unreachableView("c"),
+				unreachableView("d"),
+			},
+			action: common.ComponentWait,
+			reason: "only 2 of 4 nodes reachable",
+		},
+		{
+			name: "state uuid diverged",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 10),
+				nodeView("b", common.NonPrimary, []string{"b"}, "u2", 12),
+				nodeView("c", common.NonPrimary, []string{"c"}, "", 0),
+			},
+			action: common.ComponentWait,
+			reason: "different cluster state UUID",
+		},
+		{
+			name: "majority component",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 20),
+				nodeView("b", common.NonPrimary, []string{"c", "b"}, "u1", 10),
+				nodeView("c", common.NonPrimary, []string{"b", "c"}, "u1", 11),
+			},
+			action:    common.ComponentBootstrap,
+			candidate: "c",
+			reason:    "component with 2 of 3 nodes holds the majority",
+		},
+		{
+			name: "highest seqno",
+			views: []*common.NodeView{
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 10),
+				nodeView("b", common.NonPrimary, []string{"b"}, "u1", 12),
+				unreachableView("c"),
+			},
+			action:    common.ComponentBootstrap,
+			candidate: "b",
+			reason:    "no majority component, b has the highest seqno 12",
+		},
+		{
+			name: "same seqno ordered by host",
+			views: []*common.NodeView{
+				nodeView("c", common.NonPrimary, []string{"c"}, "u1", 12),
+				nodeView("b", common.NonPrimary, []string{"b"}, "u1", 12),
+				nodeView("a", common.NonPrimary, []string{"a"}, "u1", 9),
+			},
+			action:    common.ComponentBootstrap,
+			candidate: "b",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := decideComponent(val.views)
+			if ret.Action != val.action {
+				t.Errorf("action %s, expect %s, reason:%s", ret.Action, val.action, ret.Reason)
+			}
+			if ret.Candidate != val.candidate {
+				t.Errorf("candidate %q, expect %q", ret.Candidate, val.candidate)
+			}
+			if !strings.Contains(ret.Reason, val.reason) {
+				t.Errorf("reason %q, expect contains %q", ret.Reason, val.reason)
+			}
+			if len(ret.Views) != len(val.views) {
+				t.Errorf("views %d, expect %d", len(ret.Views), len(val.views))
+			}
+		})
+	}
+}
+
+func TestMajorityComponent(t *testing.T) {
+	views := []*common.NodeView{
+		nodeView("a", common.NonPrimary, []string{"a"}, "u1", 1),
+		nodeView("b", common.NonPrimary, []string{"b", "c"}, "u1", 1),
+		nodeView("c", common.NonPrimary, []string{"c", "b"}, "u1", 1),
+	}
+	cases := []struct {
+		name   string
+		total  int
+		expect []string
+	}{
+		{name: "two of three", total: 3, expect: []string{"b", "c"}},
+		{name: "two of four", total: 4},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			hosts := []string{}
+			for _, member := range majorityComponent(views, val.total) {
+				hosts = append(hosts, member.Host)
+			}
+			if strings.Join(hosts, ",") != strings.Join(val.expect, ",") {
+				t.Errorf("members %v, expect %v", hosts, val.expect)
+			}
+		})
+	}
+}
+
+func TestDescribeViews(t *testing.T) {
+	views := []*common.NodeView{
+		{Host: "a", Status: &common.ClusterStatus{NodeSize: 1, Status: common.NonPrimary, LocalState: "Initialized", LastCommitted: 7}},
+		unreachableView("b"),
+	}
+	expect := "a(Non-Primary/Initialized, size 1, seqno 7); b(unreachable: connection refused)"
+	if ret := describeViews(views); ret != expect {
+		t.Errorf("describe %q, expect %q", ret, expect)
+	}
+}
+
+func TestClusterAddresses(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) {
+		cfg.ListenPort = "8080"
+		cfg.LocalHost = "10.0.0.1"
+		cfg.ClusterHosts = []string{"10.0.0.2", "10.0.0.1:8080", "10.0.0.3:9000", "10.0.0.2"}
+	})
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	expect := "10.0.0.1:8080,10.0.0.2:8080,10.0.0.3:9000"
+	if ret := strings.Join(clusterAddresses(), ","); ret != expect {
+		t.Errorf("addresses %s, expect %s", ret, expect)
+	}
+}
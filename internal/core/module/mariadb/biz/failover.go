@@ -0,0 +1,510 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/client"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// relayApplyTimeOut 提升前等待relay log执行完成的超时时间
+const relayApplyTimeOut = 60 * time.Second
+
+func (s *Mariadb) failover(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("failover failed, illegal param")
+		return
+	}
+
+	decisionPtr, decisionErr := s.Failover(serviceVal)
+	if re != nil {
+		re.Set(decisionPtr, decisionErr)
+	}
+}
+
+// localDBHost 本节点的数据库地址，localHost中包含端口时去掉端口
+func localDBHost() string {
+	host, _, hostErr := net.SplitHostPort(config.GetLocalHost())
+	if hostErr != nil {
+		return config.GetLocalHost()
+	}
+
+	return host
+}
+
+// collectReplicaViews 并发查询各节点复制状态，本节点直接查询
+func (s *Mariadb) collectReplicaViews(serviceName string) []*common.ReplicaView {
+	addresses := clusterAddresses()
+	views := make([]*common.ReplicaView, len(addresses))
+	wg := sync.WaitGroup{}
+	for idx, address := range addresses {
+		viewPtr := &common.ReplicaView{Host: address, Local: idx == 0}
+		views[idx] = viewPtr
+		if viewPtr.Local {
+			statusPtr, statusErr := s.QueryReplicationStatus(serviceName)
+			viewPtr.Status = statusPtr
+			if statusErr != nil {
+				viewPtr.Error = statusErr.Reason
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), peerQueryTimeOut)
+			defer cancel()
+
+			statusPtr, statusErr := client.NewClient(viewPtr.Host).QueryReplication(ctx, serviceName)
+			viewPtr.Status = statusPtr
+			if statusErr != nil {
+				viewPtr.Error = statusErr.Reason
+			}
+		}()
+	}
+	wg.Wait()
+
+	return views
+}
+
+// AnalyzeFailover 查询各节点复制视图并判断是否切换
+func (s *Mariadb) AnalyzeFailover(serviceName string) (ret *common.FailoverDecision, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard || !guardPtr.IsReplication() {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal replication guard, service:%s", serviceName))
+		return
+	}
+
+	ret = decideFailover(s.collectReplicaViews(serviceName))
+	return
+}
+
+// decideFailover 存在可用的主库或从库IO线程仍在运行时不切换；可达节点未过半时不切换；
+// 否则选择已接收GTID最多的从库，位置相同时按地址排序，各节点结论一致，views中第一个为本节点
+func decideFailover(views []*common.ReplicaView) (ret *common.FailoverDecision) {
+	ret = &common.FailoverDecision{Views: views}
+	replicas := []*common.ReplicaView{}
+	reachable := 0
+	for _, val := range views {
+		if val.Status == nil {
+			continue
+		}
+
+		reachable++
+		switch {
+		case val.Status.Role == common.ReplicationPrimary:
+			ret.Action = common.FailoverNone
+			ret.Reason = fmt.Sprintf("%s is primary and available", val.Host)
+			return
+		case val.Status.IORunning == "Yes":
+			ret.Action = common.FailoverNone
+			ret.Reason = fmt.Sprintf("%s still receives binlog from %s:%d", val.Host, val.Status.MasterHost, val.Status.MasterPort)
+			return
+		}
+		replicas = append(replicas, val)
+	}
+
+	total := len(views)
+	if reachable*2 <= total || len(replicas) == 0 {
+		ret.Action = common.FailoverWait
+		ret.Reason = fmt.Sprintf("only %d of %d nodes reachable, the primary may be available on the other side", reachable, total)
+		ret.Reason += ", views: " + describeReplicaViews(views)
+		return
+	}
+
+	oldPrimary := net.JoinHostPort(replicas[0].Status.MasterHost, strconv.Itoa(replicas[0].Status.MasterPort))
+	for _, val := range replicas {
+		address := net.JoinHostPort(val.Status.MasterHost, strconv.Itoa(val.Status.MasterPort))
+		if address != oldPrimary {
+			ret.Action = common.FailoverWait
+			ret.Reason = fmt.Sprintf("replicas have different primary, %s and %s", oldPrimary, address)
+			ret.Reason += ", views: " + describeReplicaViews(views)
+			return
+		}
+	}
+	ret.OldPrimary = oldPrimary
+
+	sort.SliceStable(replicas, func(i, j int) bool {
+		iSum, jSum := sumPositions(replicaPositions(replicas[i].Status)), sumPositions(replicaPositions(replicas[j].Status))
+		if iSum != jSum {
+			return iSum > jSum
+		}
+
+		return replicas[i].Host < replicas[j].Host
+	})
+	candidatePositions := replicaPositions(replicas[0].Status)
+	for _, val := range replicas[1:] {
+		if !containPositions(candidatePositions, replicaPositions(val.Status)) {
+			ret.Action = common.FailoverWait
+			ret.Reason = fmt.Sprintf("replica positions diverged between %s and %s", replicas[0].Host, val.Host)
+			ret.Reason += ", views: " + describeReplicaViews(views)
+			return
+		}
+	}
+
+	ret.Action = common.FailoverRemote
+	ret.Candidate = replicas[0].Host
+	if replicas[0].Local {
+		ret.Action = common.FailoverPromote
+	}
+	ret.Reason = fmt.Sprintf("primary %s unavailable for %d reachable of %d nodes, %s is the most up-to-date replica",
+		oldPrimary, reachable, total, ret.Candidate)
+	ret.Reason += ", views: " + describeReplicaViews(views)
+	return
+}
+
+// Failover 本节点被选中时经过半数节点确认后，等待relay log执行完成，隔离原主库，提升本节点并让其他从库切换到本节点
+// 未被选中时只返回结论，Action为FailoverPromoted表示已完成提升
+func (s *Mariadb) Failover(serviceName string) (ret *common.FailoverDecision, err *cd.Result) {
+	s.failoverLock.Lock()
+	defer s.failoverLock.Unlock()
+
+	ret, err = s.AnalyzeFailover(serviceName)
+	if err != nil || ret.Action != common.FailoverPromote {
+		return
+	}
+
+	guardPtr := config.GetGuard(serviceName)
+	ret.Action = common.FailoverWait
+	agreed := s.collectVotes(serviceName, ret)
+	if agreed*2 <= len(ret.Views) {
+		ret.Reason = fmt.Sprintf("only %d of %d nodes agree on %s, ", agreed, len(ret.Views), ret.Candidate) + ret.Reason
+		return
+	}
+
+	if applyErr := s.waitRelayApplied(serviceName); applyErr != nil {
+		ret.Reason = fmt.Sprintf("relay log not applied, %s, ", applyErr.Reason) + ret.Reason
+		return
+	}
+
+	if fenceErr := s.fencePrimary(guardPtr, ret); fenceErr != nil {
+		ret.Reason = fmt.Sprintf("fence old primary failed, %s, ", fenceErr.Reason) + ret.Reason
+		return
+	}
+
+	err = s.executeSQL(guardPtr, "STOP REPLICA; RESET REPLICA ALL; SET GLOBAL read_only=OFF;")
+	if err != nil {
+		log.Errorf("promote replica failed, service:%s, error:%s", serviceName, err.Error())
+		return
+	}
+	ret.Action = common.FailoverPromoted
+	log.Warnf("promoted %s to primary, old primary:%s", serviceName, ret.OldPrimary)
+
+	// 其他从库连接本节点数据库的端口，与原主库的端口可能不同
+	_, localPort, _ := net.SplitHostPort(guardPtr.GetAddress())
+	port, _ := strconv.Atoi(localPort)
+	repointed := []string{}
+	for _, val := range ret.Views {
+		if val.Local || val.Status == nil || val.Status.Role != common.ReplicationReplica {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), peerQueryTimeOut)
+		param := &common.RepointParam{Service: serviceName, Host: localDBHost(), Port: port}
+		repointErr := client.NewClient(val.Host, client.WithToken(config.GetAdminToken())).RepointReplica(ctx, param)
+		cancel()
+		if repointErr != nil {
+			repointed = append(repointed, fmt.Sprintf("%s(failed: %s)", val.Host, repointErr.Reason))
+			continue
+		}
+		repointed = append(repointed, val.Host)
+	}
+	ret.Reason = fmt.Sprintf("promoted local node, repointed replicas: %s. %s", strings.Join(repointed, ", "), ret.Reason)
+
+	alarmInfo := &common.AlarmInfo{
+		Title: "Replication Failover",
+		Content: fmt.Sprintf("Node-%s service-%s promoted to primary, old primary %s. Reason: %s",
+			config.GetLocalHost(),
+			serviceName,
+			ret.OldPrimary,
+			ret.Reason,
+		),
+	}
+	s.PostEvent(event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo))
+	return
+}
+
+// collectVotes 请求其他节点确认候选节点，返回同意的节点数，包括本节点
+func (s *Mariadb) collectVotes(serviceName string, decisionPtr *common.FailoverDecision) int {
+	agreed := 1
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, val := range decisionPtr.Views {
+		if val.Local || val.Status == nil {
+			continue
+		}
+
+		host := val.Host
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// 对方需要重新查询所有节点，超时时间按两轮查询计算
+			ctx, cancel := context.WithTimeout(context.Background(), 2*peerQueryTimeOut)
+			defer cancel()
+
+			param := &common.FailoverParam{Service: serviceName, Candidate: decisionPtr.Candidate}
+			voteErr := client.NewClient(host, client.WithToken(config.GetAdminToken())).VoteFailover(ctx, param)
+			if voteErr != nil {
+				log.Warnf("%s disagree failover to %s, %s", host, decisionPtr.Candidate, voteErr.Reason)
+				return
+			}
+
+			lock.Lock()
+			agreed++
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return agreed
+}
+
+// VoteFailover 本节点的结论与候选节点一致时同意
+func (s *Mariadb) VoteFailover(param *common.FailoverParam) (err *cd.Result) {
+	decisionPtr, decisionErr := s.AnalyzeFailover(param.Service)
+	if decisionErr != nil {
+		err = decisionErr
+		return
+	}
+
+	if (decisionPtr.Action != common.FailoverRemote && decisionPtr.Action != common.FailoverPromote) ||
+		decisionPtr.Candidate != param.Candidate {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("decision %s, candidate %s, reason: %s", decisionPtr.Action, decisionPtr.Candidate, decisionPtr.Reason))
+	}
+	return
+}
+
+// waitRelayApplied 等待已接收的relay log执行完成
+func (s *Mariadb) waitRelayApplied(serviceName string) (err *cd.Result) {
+	deadline := time.Now().Add(relayApplyTimeOut)
+	for {
+		statusPtr, statusErr := s.QueryReplicationStatus(serviceName)
+		if statusErr != nil {
+			err = statusErr
+			return
+		}
+		if statusPtr.Role != common.ReplicationReplica || statusPtr.IsApplied() {
+			return
+		}
+		if time.Now().After(deadline) {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("timeout after %v, read %s:%d, executed %s:%d", relayApplyTimeOut,
+				statusPtr.MasterLogFile, statusPtr.ReadMasterLogPos, statusPtr.RelayMasterLogFile, statusPtr.ExecMasterLogPos))
+			return
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// fencePrimary 原主库已确认停止或fencingHook执行成功后才能提升，agent不可达(如网络分区)时原主库可能仍在接受写入，
+// 只能由fencingHook隔离；原主库agent可达时先暂停自动修复并停止原主库服务
+func (s *Mariadb) fencePrimary(guardPtr *config.GuardItem, decisionPtr *common.FailoverDecision) (err *cd.Result) {
+	oldHost, oldPort, _ := net.SplitHostPort(decisionPtr.OldPrimary)
+	stopErr := cd.NewError(cd.UnExpected, fmt.Sprintf("old primary %s not in cluster hosts", decisionPtr.OldPrimary))
+	for _, val := range decisionPtr.Views {
+		host, _, _ := net.SplitHostPort(val.Host)
+		if host != oldHost {
+			continue
+		}
+
+		stopErr = stopOldPrimary(guardPtr, val.Host)
+		if stopErr != nil {
+			log.Warnf("stop old primary %s failed, %s", val.Host, stopErr.Reason)
+		}
+		break
+	}
+
+	hookErr := cd.NewError(cd.UnExpected, "fencingHook not configured")
+	if guardPtr.FencingHook != "" {
+		option := &runtime.ExecOption{
+			Env: []string{
+				"SERVICE=" + guardPtr.Name,
+				"OLD_PRIMARY_HOST=" + oldHost,
+				"OLD_PRIMARY_PORT=" + oldPort,
+				"NEW_PRIMARY_HOST=" + localDBHost(),
+			},
+		}
+		resultPtr, resultErr := s.ExecuteContext(context.Background(), option, "sh", "-c", guardPtr.FencingHook)
+		hookErr = resultErr
+		if resultErr != nil && resultPtr != nil && resultPtr.Stderr != "" {
+			hookErr = cd.NewError(cd.UnExpected, fmt.Sprintf("%s, %s", resultErr.Reason, strings.TrimSpace(resultPtr.Stderr)))
+		}
+		if hookErr != nil {
+			log.Warnf("fencing hook for old primary %s failed, %s", decisionPtr.OldPrimary, hookErr.Reason)
+		}
+	}
+
+	err = checkFenced(stopErr, hookErr)
+	return
+}
+
+// stopOldPrimary 暂停原主库的自动修复，避免停止后被重新启动，再停止原主库服务，
+// 返回空表示已确认原主库服务停止
+func stopOldPrimary(guardPtr *config.GuardItem, host string) (err *cd.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), peerQueryTimeOut)
+	defer cancel()
+
+	clientPtr := client.NewClient(host, client.WithToken(config.GetAdminToken()))
+	pauseParam := &common.RemediationParam{Service: guardPtr.Name, Reason: "fenced by failover to " + config.GetLocalHost()}
+	if _, err = clientPtr.PauseRemediation(ctx, pauseParam); err != nil {
+		return
+	}
+
+	_, err = clientPtr.StopService(ctx, guardPtr.Name)
+	return
+}
+
+// checkFenced 原主库已停止或fencingHook执行成功时才算隔离，否则不能提升本节点
+func checkFenced(stopErr, hookErr *cd.Result) *cd.Result {
+	if stopErr == nil || hookErr == nil {
+		return nil
+	}
+
+	return cd.NewError(cd.UnExpected, fmt.Sprintf("old primary not fenced, stop: %s, fencingHook: %s", stopErr.Reason, hookErr.Reason))
+}
+
+// RepointReplica 本节点切换到新主库，MariaDB使用gtid_slave_pos，MySQL使用自动定位
+func (s *Mariadb) RepointReplica(param *common.RepointParam) (err *cd.Result) {
+	guardPtr := config.GetGuard(param.Service)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard || !guardPtr.IsReplication() {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal replication guard, service:%s", param.Service))
+		return
+	}
+	if param.Host == "" || param.Port <= 0 {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal primary address, %s:%d", param.Host, param.Port))
+		return
+	}
+
+	statusPtr, statusErr := s.QueryReplicationStatus(param.Service)
+	if statusErr != nil {
+		err = statusErr
+		return
+	}
+	if statusPtr.Role != common.ReplicationReplica {
+		err = cd.NewError(cd.UnExpected, "local node is not a replica")
+		return
+	}
+
+	host := strings.ReplaceAll(param.Host, "'", "''")
+	sql := fmt.Sprintf("STOP SLAVE; CHANGE MASTER TO MASTER_HOST='%s', MASTER_PORT=%d, MASTER_USE_GTID=slave_pos; START SLAVE;", host, param.Port)
+	if statusPtr.Flavor == common.MysqlFlavor {
+		sql = fmt.Sprintf("STOP REPLICA; CHANGE REPLICATION SOURCE TO SOURCE_HOST='%s', SOURCE_PORT=%d, SOURCE_AUTO_POSITION=1; START REPLICA;", host, param.Port)
+	}
+
+	err = s.executeSQL(guardPtr, sql)
+	if err != nil {
+		log.Errorf("repoint replica failed, service:%s, primary:%s:%d, error:%s", param.Service, param.Host, param.Port, err.Error())
+		return
+	}
+
+	log.Warnf("repointed %s to new primary %s:%d", param.Service, param.Host, param.Port)
+	return
+}
+
+func (s *Mariadb) executeSQL(guardPtr *config.GuardItem, sql string) (err *cd.Result) {
+	param := mysqlParam(guardPtr.Name, guardPtr, sql)
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+	_, err = s.SendEvent(execEvent).Get()
+	return
+}
+
+// replicaPositions 已接收的复制位置，MariaDB为domain到seq_no，MySQL为server_uuid到最大事务号，未开启GTID时使用binlog位置
+func replicaPositions(statusPtr *common.ReplicationStatus) map[string]int64 {
+	positions := map[string]int64{}
+	updatePosition := func(key string, val int64) {
+		if val > positions[key] {
+			positions[key] = val
+		}
+	}
+
+	// MariaDB: 0-1-100,1-2-50
+	for _, item := range strings.Split(statusPtr.GtidIOPos, ",") {
+		items := strings.Split(strings.TrimSpace(item), "-")
+		if len(items) != 3 {
+			continue
+		}
+		if seqNo, seqErr := strconv.ParseInt(items[2], 10, 64); seqErr == nil {
+			updatePosition(items[0], seqNo)
+		}
+	}
+
+	// MySQL: uuid:1-100:105,uuid2:1-3
+	for _, gtidSet := range []string{statusPtr.RetrievedGtidSet, statusPtr.ExecutedGtidSet} {
+		for _, item := range strings.Split(gtidSet, ",") {
+			items := strings.Split(strings.TrimSpace(item), ":")
+			for _, interval := range items[1:] {
+				_, upper, _ := strings.Cut(interval, "-")
+				if upper == "" {
+					upper = interval
+				}
+				if seqNo, seqErr := strconv.ParseInt(upper, 10, 64); seqErr == nil {
+					updatePosition(items[0], seqNo)
+				}
+			}
+		}
+	}
+
+	if len(positions) == 0 && statusPtr.MasterLogFile != "" {
+		fileIndex := int64(0)
+		if idx := strings.LastIndex(statusPtr.MasterLogFile, "."); idx >= 0 {
+			fileIndex, _ = strconv.ParseInt(statusPtr.MasterLogFile[idx+1:], 10, 64)
+		}
+		positions["binlog"] = fileIndex<<40 | statusPtr.ReadMasterLogPos
+	}
+
+	return positions
+}
+
+func sumPositions(positions map[string]int64) (ret int64) {
+	for _, val := range positions {
+		ret += val
+	}
+	return
+}
+
+// containPositions 各复制源的位置都不小于对方
+func containPositions(positions, other map[string]int64) bool {
+	for key, val := range other {
+		if positions[key] < val {
+			return false
+		}
+	}
+
+	return true
+}
+
+func describeReplicaViews(views []*common.ReplicaView) string {
+	items := []string{}
+	for _, val := range views {
+		if val.Status == nil {
+			items = append(items, fmt.Sprintf("%s(unreachable: %s)", val.Host, val.Error))
+			continue
+		}
+		if val.Status.Role == common.ReplicationPrimary {
+			items = append(items, fmt.Sprintf("%s(primary)", val.Host))
+			continue
+		}
+
+		items = append(items, fmt.Sprintf("%s(replica of %s:%d, IO %s, position %v)",
+			val.Host, val.Status.MasterHost, val.Status.MasterPort, val.Status.IORunning, replicaPositions(val.Status)))
+	}
+
+	return strings.Join(items, "; ")
+}
@@ -0,0 +1,289 @@
+package biz
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func replicaView(host string, local bool, io string, master string, port int, gtid string) *common.ReplicaView {
+	return &common.ReplicaView{
+		Host:  host,
+		Local: local,
+		Status: &common.ReplicationStatus{
+			Role:       common.ReplicationReplica,
+			Flavor:     common.MariadbFlavor,
+			MasterHost: master,
+			MasterPort: port,
+			IORunning:  io,
+			SQLRunning: "Yes",
+			GtidIOPos:  gtid,
+		},
+	}
+}
+
+func TestDecideFailover(t *testing.T) {
+	cases := []struct {
+		name       string
+		views      []*common.ReplicaView
+		action     string
+		candidate  string
+		oldPrimary string
+		reason     string
+	}{
+		{
+			name: "primary available",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				{Host: "a:8080", Status: &common.ReplicationStatus{Role: common.ReplicationPrimary}},
+			},
+			action: common.FailoverNone,
+			reason: "a:8080 is primary and available",
+		},
+		{
+			name: "replica still receives binlog",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				replicaView("c:8080", false, "Yes", "10.0.0.1", 3306, "0-1-100"),
+			},
+			action: common.FailoverNone,
+			reason: "c:8080 still receives binlog from 10.0.0.1:3306",
+		},
+		{
+			name: "minority reachable",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action: common.FailoverWait,
+			reason: "only 1 of 2 nodes reachable",
+		},
+		{
+			name: "different primary",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				replicaView("c:8080", false, "Connecting", "10.0.0.9", 3306, "0-1-100"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action: common.FailoverWait,
+			reason: "replicas have different primary",
+		},
+		{
+			name: "positions diverged",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100,1-2-10"),
+				replicaView("c:8080", false, "Connecting", "10.0.0.1", 3306, "0-1-90,1-2-30"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action:     common.FailoverWait,
+			oldPrimary: "10.0.0.1:3306",
+			reason:     "replica positions diverged",
+		},
+		{
+			name: "promote local",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3307, "0-1-100"),
+				replicaView("c:8080", false, "Connecting", "10.0.0.1", 3307, "0-1-90"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action:     common.FailoverPromote,
+			candidate:  "b:8080",
+			oldPrimary: "10.0.0.1:3307",
+		},
+		{
+			name: "remote candidate",
+			views: []*common.ReplicaView{
+				replicaView("b:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-90"),
+				replicaView("c:8080", false, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action:     common.FailoverRemote,
+			candidate:  "c:8080",
+			oldPrimary: "10.0.0.1:3306",
+		},
+		{
+			name: "same position ordered by host",
+			views: []*common.ReplicaView{
+				replicaView("d:8080", true, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				replicaView("c:8080", false, "Connecting", "10.0.0.1", 3306, "0-1-100"),
+				{Host: "a:8080", Error: "connection refused"},
+			},
+			action:     common.FailoverRemote,
+			candidate:  "c:8080",
+			oldPrimary: "10.0.0.1:3306",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := decideFailover(val.views)
+			if ret.Action != val.action {
+				t.Errorf("action %s, expect %s, reason:%s", ret.Action, val.action, ret.Reason)
+			}
+			if ret.Candidate != val.candidate || ret.OldPrimary != val.oldPrimary {
+				t.Errorf("candidate %q, old primary %q, expect %q, %q", ret.Candidate, ret.OldPrimary, val.candidate, val.oldPrimary)
+			}
+			if !strings.Contains(ret.Reason, val.reason) {
+				t.Errorf("reason %q, expect contains %q", ret.Reason, val.reason)
+			}
+		})
+	}
+}
+
+func TestReplicaPositions(t *testing.T) {
+	cases := []struct {
+		name   string
+		status *common.ReplicationStatus
+		expect map[string]int64
+	}{
+		{name: "mariadb gtid", status: &common.ReplicationStatus{GtidIOPos: "0-1-100, 1-2-50,0-3-90"}, expect: map[string]int64{"0": 100, "1": 50}},
+		{name: "mysql gtid", status: &common.ReplicationStatus{RetrievedGtidSet: "u1:1-100:105,u2:1-3", ExecutedGtidSet: "u1:1-90"}, expect: map[string]int64{"u1": 105, "u2": 3}},
+		{name: "binlog position", status: &common.ReplicationStatus{MasterLogFile: "mysql-bin.000002", ReadMasterLogPos: 300}, expect: map[string]int64{"binlog": 2<<40 | 300}},
+		{name: "empty", status: &common.ReplicationStatus{}, expect: map[string]int64{}},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := replicaPositions(val.status)
+			if len(ret) != len(val.expect) {
+				t.Errorf("positions %v, expect %v", ret, val.expect)
+			}
+			for key, pos := range val.expect {
+				if ret[key] != pos {
+					t.Errorf("position %s %d, expect %d", key, ret[key], pos)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckFenced(t *testing.T) {
+	stopErr := cd.NewError(cd.UnExpected, "stop failed")
+	hookErr := cd.NewError(cd.UnExpected, "fencingHook not configured")
+	cases := []struct {
+		name      string
+		stopErr   *cd.Result
+		hookErr   *cd.Result
+		expectErr bool
+	}{
+		{name: "stopped", hookErr: hookErr},
+		{name: "stopped and hook succeeded"},
+		{name: "not stopped, hook succeeded", stopErr: stopErr},
+		{name: "not stopped, no hook", stopErr: stopErr, hookErr: hookErr, expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			err := checkFenced(val.stopErr, val.hookErr)
+			if (err != nil) != val.expectErr {
+				t.Errorf("err %v, expect error %v", err, val.expectErr)
+			}
+		})
+	}
+}
+
+// fakePrimaryAgent 模拟原主库agent的自动修复和停止服务接口，stopFailed为true时停止服务失败
+type fakePrimaryAgent struct {
+	lock       sync.Mutex
+	stopFailed bool
+	paused     bool
+	stopped    bool
+}
+
+func (s *fakePrimaryAgent) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result interface{}
+	switch {
+	case strings.HasSuffix(req.URL.Path, common.PauseRemediation):
+		s.paused = true
+		result = &common.RemediationResult{Status: &common.RemediationStatus{Paused: true}}
+	case strings.HasSuffix(req.URL.Path, common.StopService):
+		stopResult := &common.StopServiceResult{}
+		if s.stopFailed {
+			stopResult.Result = *cd.NewError(cd.UnExpected, "stop container failed")
+		} else {
+			s.stopped = true
+		}
+		result = stopResult
+	default:
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	byteVal, _ := json.Marshal(result)
+	_, _ = res.Write(byteVal)
+}
+
+func TestFencePrimary(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	cases := []struct {
+		name        string
+		stopFailed  bool
+		unreachable bool
+		notCluster  bool
+		hook        string
+		expectErr   string
+		expectStop  bool
+	}{
+		{name: "stopped", expectStop: true},
+		{name: "stopped hook failed", hook: "exit 1", expectStop: true},
+		{name: "stop failed", stopFailed: true, expectErr: "old primary not fenced"},
+		{name: "stop failed fenced by hook", stopFailed: true, hook: "test \"$OLD_PRIMARY_PORT\" = 3306"},
+		{name: "stop failed hook failed", stopFailed: true, hook: "echo denied >&2; exit 1", expectErr: "denied"},
+		{name: "unreachable", unreachable: true, expectErr: "fencingHook not configured"},
+		{name: "unreachable fenced by hook", unreachable: true, hook: "exit 0"},
+		{name: "unreachable hook failed", unreachable: true, hook: "exit 1", expectErr: "exit status 1"},
+		{name: "not in cluster hosts", notCluster: true, expectErr: "not in cluster hosts"},
+		{name: "not in cluster hosts fenced by hook", notCluster: true, hook: "exit 0"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			agent := &fakePrimaryAgent{stopFailed: val.stopFailed}
+			server := httptest.NewServer(agent)
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "http://")
+			if val.unreachable {
+				server.Close()
+			}
+
+			guardPtr := &config.GuardItem{Name: "mariadb001", Type: config.MariadbGuard, Mode: config.ReplicationMode, FencingHook: val.hook}
+			decisionPtr := &common.FailoverDecision{
+				OldPrimary: "127.0.0.1:3306",
+				Views:      []*common.ReplicaView{{Host: "10.0.0.2:8080", Local: true}, {Host: host}},
+			}
+			if val.notCluster {
+				decisionPtr.OldPrimary = "10.0.0.9:3306"
+			}
+			fenceErr := (&Mariadb{}).fencePrimary(guardPtr, decisionPtr)
+			if val.expectErr == "" && fenceErr != nil {
+				t.Errorf("fence failed, %s", fenceErr.Reason)
+			}
+			if val.expectErr != "" && (fenceErr == nil || !strings.Contains(fenceErr.Reason, val.expectErr)) {
+				t.Errorf("fence err %v, expect %s", fenceErr, val.expectErr)
+			}
+
+			agent.lock.Lock()
+			defer agent.lock.Unlock()
+			if agent.stopped != val.expectStop || agent.paused != !(val.unreachable || val.notCluster) {
+				t.Errorf("paused %v, stopped %v, expect stopped %v", agent.paused, agent.stopped, val.expectStop)
+			}
+		})
+	}
+}
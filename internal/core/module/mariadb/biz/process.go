@@ -0,0 +1,293 @@
+package biz
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// processSampleInterval 慢查询和长事务的采样间隔
+const processSampleInterval = 30 * time.Second
+
+// maxDigestText 告警中查询语句的最大长度
+const maxDigestText = 256
+
+// processSQL 排除空闲连接、复制线程和本次查询
+const processSQL = "SELECT ID, USER, HOST, IFNULL(DB, ''), COMMAND, TIME, IFNULL(STATE, ''), INFO FROM information_schema.PROCESSLIST " +
+	"WHERE INFO IS NOT NULL AND COMMAND NOT IN ('Sleep', 'Daemon', 'Binlog Dump', 'Binlog Dump GTID') " +
+	"AND USER <> 'system user' AND ID <> CONNECTION_ID() AND TIME >= %d ORDER BY TIME DESC"
+
+const transactionSQL = "SELECT t.trx_id, t.trx_mysql_thread_id, t.trx_started, TIMESTAMPDIFF(SECOND, t.trx_started, NOW()), t.trx_state, " +
+	"t.trx_rows_locked, t.trx_rows_modified, IFNULL(p.USER, ''), IFNULL(p.HOST, ''), IFNULL(p.DB, ''), IFNULL(t.trx_query, '') " +
+	"FROM information_schema.INNODB_TRX t LEFT JOIN information_schema.PROCESSLIST p ON p.ID = t.trx_mysql_thread_id " +
+	"WHERE t.trx_started <= NOW() - INTERVAL %d SECOND ORDER BY t.trx_started"
+
+var (
+	digestStringReg  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	digestNumberReg  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	digestListReg    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	digestCommentReg = regexp.MustCompile(`/\*.*?\*/`)
+	digestSpaceReg   = regexp.MustCompile(`\s+`)
+)
+
+func (s *Mariadb) timerCheck(ev event.Event, _ event.Result) {
+	notifyPtr, notifyOK := ev.Data().(*common.TimerNotify)
+	if !notifyOK || !s.sampleDue(notifyPtr.CurTime) {
+		return
+	}
+
+	for _, guardPtr := range config.GetGuards() {
+		if guardPtr.Type != config.MariadbGuard {
+			continue
+		}
+
+		reportPtr, reportErr := s.SampleProcess(guardPtr.Name)
+		if reportErr != nil {
+			if config.EnableTrace() {
+				log.Errorf("sample process failed, service:%s, error:%s", guardPtr.Name, reportErr.Error())
+			}
+			continue
+		}
+		s.checkProcess(guardPtr, reportPtr)
+	}
+}
+
+// sampleDue 距离上次采样超过采样间隔时记录本次采样时间
+func (s *Mariadb) sampleDue(curTime time.Time) bool {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+
+	if curTime.Sub(s.sampleTime) < processSampleInterval {
+		return false
+	}
+
+	s.sampleTime = curTime
+	return true
+}
+
+// SampleProcess 查询执行时间超过slowQueryTime的查询和持续时间超过longTrxTime的事务
+func (s *Mariadb) SampleProcess(serviceName string) (ret *common.ProcessReport, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
+	}
+
+	ret = &common.ProcessReport{SampleTime: time.Now(), SlowQueries: []*common.ProcessInfo{}, LongTransactions: []*common.TransactionInfo{}}
+	rows, rowsErr := s.queryRows(guardPtr, fmt.Sprintf(processSQL, guardPtr.GetSlowQueryTime()))
+	if rowsErr != nil {
+		err = rowsErr
+		return
+	}
+	for _, row := range rows {
+		if len(row) < 8 {
+			continue
+		}
+
+		processPtr := &common.ProcessInfo{User: row[1], Host: row[2], DB: row[3], Command: row[4], State: row[6], Query: row[7]}
+		processPtr.ID, _ = strconv.ParseInt(row[0], 10, 64)
+		processPtr.Time, _ = strconv.ParseInt(row[5], 10, 64)
+		processPtr.DigestText, processPtr.Digest = queryDigest(processPtr.Query)
+		ret.SlowQueries = append(ret.SlowQueries, processPtr)
+	}
+
+	rows, rowsErr = s.queryRows(guardPtr, fmt.Sprintf(transactionSQL, guardPtr.GetLongTrxTime()))
+	if rowsErr != nil {
+		err = rowsErr
+		return
+	}
+	for _, row := range rows {
+		if len(row) < 11 {
+			continue
+		}
+
+		trxPtr := &common.TransactionInfo{ID: row[0], Started: row[2], State: row[4], User: row[7], Host: row[8], DB: row[9], Query: row[10]}
+		trxPtr.ThreadID, _ = strconv.ParseInt(row[1], 10, 64)
+		trxPtr.Time, _ = strconv.ParseInt(row[3], 10, 64)
+		trxPtr.RowsLocked, _ = strconv.ParseInt(row[5], 10, 64)
+		trxPtr.RowsModified, _ = strconv.ParseInt(row[6], 10, 64)
+		if trxPtr.Query != "" {
+			trxPtr.DigestText, trxPtr.Digest = queryDigest(trxPtr.Query)
+		}
+		ret.LongTransactions = append(ret.LongTransactions, trxPtr)
+	}
+
+	return
+}
+
+// checkProcess 每个查询和事务只告警一次
+func (s *Mariadb) checkProcess(guardPtr *config.GuardItem, reportPtr *common.ProcessReport) {
+	items := s.updateProcessAlarmed(guardPtr.Name, reportPtr)
+	if len(items) == 0 {
+		return
+	}
+
+	log.Warnf("Detected %s slow queries or long transactions:\n%s", guardPtr.Name, strings.Join(items, "\n"))
+	alarmInfo := &common.AlarmInfo{
+		Title: "Slow Query",
+		Content: fmt.Sprintf("Node-%s service-%s, slow query threshold %ds, long transaction threshold %ds:\n%s",
+			config.GetLocalHost(),
+			guardPtr.Name,
+			guardPtr.GetSlowQueryTime(),
+			guardPtr.GetLongTrxTime(),
+			strings.Join(items, "\n"),
+		),
+	}
+	s.PostEvent(event.NewEvent(common.SendAlarm, s.ID(), common.AlarmModule, nil, alarmInfo))
+}
+
+// updateProcessAlarmed 记录本次采样中的查询和事务，返回未告警过的条目，本次采样中已结束的不再记录
+func (s *Mariadb) updateProcessAlarmed(serviceName string, reportPtr *common.ProcessReport) []string {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+
+	alarmed := s.processAlarmed[serviceName]
+	current := map[string]bool{}
+	items := []string{}
+	for _, val := range reportPtr.SlowQueries {
+		key := fmt.Sprintf("query-%d-%s", val.ID, val.Digest)
+		current[key] = true
+		if alarmed[key] {
+			continue
+		}
+
+		items = append(items, fmt.Sprintf("slow query thread %d (%s@%s, db %s) running %ds, digest %s: %s",
+			val.ID, val.User, val.Host, val.DB, val.Time, val.Digest, val.DigestText))
+	}
+	for _, val := range reportPtr.LongTransactions {
+		key := "trx-" + val.ID
+		current[key] = true
+		if alarmed[key] {
+			continue
+		}
+
+		query := "idle in transaction"
+		if val.Digest != "" {
+			query = fmt.Sprintf("digest %s: %s", val.Digest, val.DigestText)
+		}
+		items = append(items, fmt.Sprintf("long transaction %s thread %d (%s@%s, db %s) open %ds, %s, %d rows locked, %d rows modified, %s",
+			val.ID, val.ThreadID, val.User, val.Host, val.DB, val.Time, val.State, val.RowsLocked, val.RowsModified, query))
+	}
+	s.processAlarmed[serviceName] = current
+
+	return items
+}
+
+// KillProcess 结束指定线程或线程当前的查询，需要守护对象开启allowKill，操作记录到审计日志
+func (s *Mariadb) KillProcess(param *common.KillProcessParam, remoteAddr string) (err *cd.Result) {
+	guardPtr := config.GetGuard(param.Service)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", param.Service))
+		return
+	}
+	if !guardPtr.AllowKill {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("kill not allowed, service:%s", param.Service))
+		return
+	}
+	if param.ThreadID <= 0 {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal thread id:%d", param.ThreadID))
+		return
+	}
+
+	sql := fmt.Sprintf("KILL %d", param.ThreadID)
+	if param.Query {
+		sql = fmt.Sprintf("KILL QUERY %d", param.ThreadID)
+	}
+
+	recordPtr := &common.AuditRecord{
+		Service:    param.Service,
+		Command:    strings.Fields(sql),
+		RemoteAddr: remoteAddr,
+		StartTime:  time.Now(),
+		Reason:     param.Reason,
+	}
+	err = s.executeSQL(guardPtr, sql)
+	finishTime := time.Now()
+	recordPtr.FinishTime = &finishTime
+	if err != nil {
+		recordPtr.ExitCode = 1
+		recordPtr.Error = err
+		log.Errorf("kill process failed, service:%s, thread:%d, remote:%s, error:%s", param.Service, param.ThreadID, remoteAddr, err.Error())
+	} else {
+		log.Warnf("kill process, service:%s, thread:%d, query:%v, remote:%s, reason:%s", param.Service, param.ThreadID, param.Query, remoteAddr, param.Reason)
+	}
+
+	s.PostEvent(event.NewEvent(common.AppendAudit, s.ID(), common.RuntimeModule, nil, recordPtr))
+	return
+}
+
+// queryRows 以批处理格式执行查询，返回去掉表头后的各行
+func (s *Mariadb) queryRows(guardPtr *config.GuardItem, sql string) (ret [][]string, err *cd.Result) {
+	param := mysqlParam(guardPtr.Name, guardPtr, sql)
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+	execVal, execErr := s.SendEvent(execEvent).Get()
+	if execErr != nil {
+		err = execErr
+		return
+	}
+
+	byteVal, _ := execVal.([]byte)
+	ret = parseBatchRows(byteVal)
+	return
+}
+
+// parseBatchRows 解析mysql批处理格式的输出，字段以tab分隔，第一行为表头
+func parseBatchRows(content []byte) [][]string {
+	rows := [][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		items := strings.Split(scanner.Text(), "\t")
+		for idx, val := range items {
+			items[idx] = unescapeBatch(val)
+		}
+		rows = append(rows, items)
+	}
+
+	return rows
+}
+
+// unescapeBatch 批处理格式中换行、tab、反斜杠被转义
+func unescapeBatch(val string) string {
+	if !strings.Contains(val, "\\") {
+		return val
+	}
+
+	return strings.NewReplacer("\\n", "\n", "\\t", "\t", "\\0", "", "\\\\", "\\").Replace(val)
+}
+
+// queryDigest 去掉注释和常量并合并空白，IN列表合并为一项，相同结构的查询摘要一致
+func queryDigest(query string) (text, digest string) {
+	text = digestCommentReg.ReplaceAllString(query, " ")
+	text = digestStringReg.ReplaceAllString(text, "?")
+	text = digestNumberReg.ReplaceAllString(text, "?")
+	text = digestListReg.ReplaceAllString(text, "(...)")
+	text = strings.TrimSpace(digestSpaceReg.ReplaceAllString(text, " "))
+
+	sum := sha256.Sum256([]byte(strings.ToLower(text)))
+	digest = hex.EncodeToString(sum[:8])
+	if runes := []rune(text); len(runes) > maxDigestText {
+		text = string(runes[:maxDigestText]) + "..."
+	}
+	return
+}
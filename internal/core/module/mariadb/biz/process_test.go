@@ -0,0 +1,137 @@
+package biz
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestQueryDigest(t *testing.T) {
+	cases := []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{name: "constants", query: "SELECT * FROM t WHERE id = 10 AND name = 'a''b' AND v = \"x\"", expect: "SELECT * FROM t WHERE id = ? AND name = ? AND v = ?"},
+		{name: "in list", query: "select a from t where id in (1, 2,3)", expect: "select a from t where id in (...)"},
+		{name: "comment and space", query: "/* app */ SELECT\n\t1.5  FROM   dual", expect: "SELECT ? FROM dual"},
+		{name: "identifier digits kept", query: "SELECT c1 FROM t2", expect: "SELECT c1 FROM t2"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			text, digest := queryDigest(val.query)
+			if text != val.expect {
+				t.Errorf("text %q, expect %q", text, val.expect)
+			}
+			if len(digest) != 16 {
+				t.Errorf("digest %q, expect 16 hex chars", digest)
+			}
+		})
+	}
+
+	_, digest1 := queryDigest("SELECT * FROM t WHERE id IN (1,2)")
+	_, digest2 := queryDigest("select *  from t where id in (3)")
+	if digest1 != digest2 {
+		t.Errorf("digest %s and %s, expect same digest for same structure", digest1, digest2)
+	}
+
+	text, _ := queryDigest("SELECT '" + strings.Repeat("x", 10) + "', " + strings.Repeat("a,", 200) + "b")
+	if !strings.HasSuffix(text, "...") || len([]rune(text)) != maxDigestText+3 {
+		t.Errorf("long text %d runes, expect truncated to %d", len([]rune(text)), maxDigestText)
+	}
+}
+
+func TestParseBatchRows(t *testing.T) {
+	content := "ID\tUSER\tINFO\n" +
+		"12\troot\tselect 1\n" +
+		"13\tapp\tselect\\n'a\\tb'\\\\\n"
+	rows := parseBatchRows([]byte(content))
+	expect := [][]string{
+		{"12", "root", "select 1"},
+		{"13", "app", "select\n'a\tb'\\"},
+	}
+	if fmt.Sprintf("%q", rows) != fmt.Sprintf("%q", expect) {
+		t.Errorf("rows %q, expect %q", rows, expect)
+	}
+
+	if rows := parseBatchRows([]byte("ID\tUSER\n")); len(rows) != 0 {
+		t.Errorf("rows %q, expect empty", rows)
+	}
+}
+
+func TestUpdateProcessAlarmed(t *testing.T) {
+	mariadbPtr := &Mariadb{processAlarmed: map[string]map[string]bool{}}
+	slowQuery := &common.ProcessInfo{ID: 12, User: "app", Host: "10.0.0.5", DB: "shop", Time: 15, Digest: "d1", DigestText: "SELECT ?"}
+	longTrx := &common.TransactionInfo{ID: "t1", ThreadID: 13, Time: 120, State: "RUNNING", RowsLocked: 2}
+
+	items := mariadbPtr.updateProcessAlarmed("mariadb001", &common.ProcessReport{
+		SlowQueries:      []*common.ProcessInfo{slowQuery},
+		LongTransactions: []*common.TransactionInfo{longTrx},
+	})
+	if len(items) != 2 ||
+		items[0] != "slow query thread 12 (app@10.0.0.5, db shop) running 15s, digest d1: SELECT ?" ||
+		!strings.Contains(items[1], "long transaction t1 thread 13") || !strings.HasSuffix(items[1], "idle in transaction") {
+		t.Errorf("items %q", items)
+	}
+
+	// 已告警的不再告警，已结束的不再记录
+	items = mariadbPtr.updateProcessAlarmed("mariadb001", &common.ProcessReport{SlowQueries: []*common.ProcessInfo{slowQuery}})
+	if len(items) != 0 {
+		t.Errorf("items %q, expect alarmed only once", items)
+	}
+	items = mariadbPtr.updateProcessAlarmed("mariadb001", &common.ProcessReport{LongTransactions: []*common.TransactionInfo{longTrx}})
+	if len(items) != 1 {
+		t.Errorf("items %q, expect finished transaction alarmed again", items)
+	}
+}
+
+func TestSampleDue(t *testing.T) {
+	mariadbPtr := &Mariadb{processAlarmed: map[string]map[string]bool{}}
+	now := time.Now()
+	if !mariadbPtr.sampleDue(now) {
+		t.Errorf("first sample should be due")
+	}
+	if mariadbPtr.sampleDue(now.Add(processSampleInterval - time.Second)) {
+		t.Errorf("sample within interval should not be due")
+	}
+	if !mariadbPtr.sampleDue(now.Add(processSampleInterval)) {
+		t.Errorf("sample after interval should be due")
+	}
+}
+
+// TestProcessConcurrent 定时采样与告警记录并发执行，需要在go test -race下通过
+func TestProcessConcurrent(t *testing.T) {
+	mariadbPtr := &Mariadb{processAlarmed: map[string]map[string]bool{}}
+	begin := time.Now()
+	due := 0
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for idx := 0; idx < 20; idx++ {
+		idx := idx
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if mariadbPtr.sampleDue(begin) {
+				lock.Lock()
+				due++
+				lock.Unlock()
+			}
+			reportPtr := &common.ProcessReport{SlowQueries: []*common.ProcessInfo{{ID: int64(idx % 3), Digest: "d"}}}
+			mariadbPtr.updateProcessAlarmed(fmt.Sprintf("mariadb%d", idx%2), reportPtr)
+		}()
+	}
+	wg.Wait()
+
+	if due != 1 {
+		t.Errorf("due %d times, expect once", due)
+	}
+	if len(mariadbPtr.processAlarmed) != 2 {
+		t.Errorf("alarmed services %d, expect 2", len(mariadbPtr.processAlarmed))
+	}
+}
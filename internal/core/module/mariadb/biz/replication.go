@@ -0,0 +1,137 @@
+package biz
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func (s *Mariadb) queryReplication(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryReplication failed, illegal param")
+		return
+	}
+
+	statusPtr, statusErr := s.QueryReplicationStatus(serviceVal)
+	if re != nil {
+		re.Set(statusPtr, statusErr)
+	}
+}
+
+// QueryReplicationStatus 查询主从复制状态，旧版本不支持SHOW REPLICA STATUS时使用SHOW SLAVE STATUS
+func (s *Mariadb) QueryReplicationStatus(serviceName string) (ret *common.ReplicationStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal mariadb guard, service:%s", serviceName))
+		return
+	}
+
+	var execVal interface{}
+	for _, sql := range []string{"SHOW REPLICA STATUS\\G", "SHOW SLAVE STATUS\\G"} {
+		param := mysqlParam(serviceName, guardPtr, sql)
+
+		execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+		execVal, err = s.SendEvent(execEvent).Get()
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if config.EnableTrace() {
+			log.Errorf("queryReplicationStatus failed, error:%s", err.Error())
+		}
+		return
+	}
+
+	byteVal, _ := execVal.([]byte)
+	ret = parseReplicationStatus(byteVal)
+	return
+}
+
+// isFieldName 字段名只包含字母和下划线，GTID集合换行后的续行不满足
+func isFieldName(val string) bool {
+	if val == "" {
+		return false
+	}
+	for _, ch := range val {
+		if ch != '_' && (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseReplicationStatus 解析\G格式的输出，多源复制时只取第一个复制源，没有输出时为主库
+func parseReplicationStatus(content []byte) *common.ReplicationStatus {
+	fields := map[string]string{}
+	rowCount := 0
+	lastKey := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "***") {
+			rowCount++
+			if rowCount > 1 {
+				break
+			}
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, ":")
+		if !ok || !isFieldName(key) {
+			if lastKey != "" {
+				fields[lastKey] += line
+			}
+			continue
+		}
+
+		// MySQL 8.0.22之后的字段名使用Replica、Source
+		key = strings.ReplaceAll(strings.ReplaceAll(key, "Replica_", "Slave_"), "Source", "Master")
+		fields[key] = strings.TrimSpace(val)
+		lastKey = key
+	}
+
+	statusPtr := &common.ReplicationStatus{Role: common.ReplicationPrimary}
+	if len(fields) == 0 {
+		return statusPtr
+	}
+
+	statusPtr.Role = common.ReplicationReplica
+	statusPtr.Flavor = common.MysqlFlavor
+	if _, ok := fields["Using_Gtid"]; ok {
+		statusPtr.Flavor = common.MariadbFlavor
+	}
+	statusPtr.MasterHost = fields["Master_Host"]
+	statusPtr.MasterPort, _ = strconv.Atoi(fields["Master_Port"])
+	statusPtr.IORunning = fields["Slave_IO_Running"]
+	statusPtr.SQLRunning = fields["Slave_SQL_Running"]
+	statusPtr.IOState = fields["Slave_IO_State"]
+	if secondsVal, secondsErr := strconv.ParseInt(fields["Seconds_Behind_Master"], 10, 64); secondsErr == nil {
+		statusPtr.SecondsBehindMaster = &secondsVal
+	}
+	statusPtr.MasterLogFile = fields["Master_Log_File"]
+	statusPtr.ReadMasterLogPos, _ = strconv.ParseInt(fields["Read_Master_Log_Pos"], 10, 64)
+	statusPtr.ExecMasterLogPos, _ = strconv.ParseInt(fields["Exec_Master_Log_Pos"], 10, 64)
+	statusPtr.RelayMasterLogFile = fields["Relay_Master_Log_File"]
+	statusPtr.UsingGtid = fields["Using_Gtid"]
+	statusPtr.GtidIOPos = fields["Gtid_IO_Pos"]
+	statusPtr.RetrievedGtidSet = fields["Retrieved_Gtid_Set"]
+	statusPtr.ExecutedGtidSet = fields["Executed_Gtid_Set"]
+	statusPtr.LastIOErrno, _ = strconv.Atoi(fields["Last_IO_Errno"])
+	statusPtr.LastIOError = fields["Last_IO_Error"]
+	statusPtr.LastSQLErrno, _ = strconv.Atoi(fields["Last_SQL_Errno"])
+	statusPtr.LastSQLError = fields["Last_SQL_Error"]
+	return statusPtr
+}
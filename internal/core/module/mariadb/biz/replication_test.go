@@ -0,0 +1,132 @@
+package biz
+
+import (
+	"testing"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const mariadbReplicaStatus = `*************************** 1. row ***************************
+                Slave_IO_State: Waiting for master to send event
+                   Master_Host: 10.0.0.1
+                   Master_Port: 3306
+               Master_Log_File: mysql-bin.000012
+           Read_Master_Log_Pos: 4567
+         Relay_Master_Log_File: mysql-bin.000012
+              Slave_IO_Running: Yes
+             Slave_SQL_Running: No
+                 Last_IO_Errno: 0
+                 Last_IO_Error:
+                Last_SQL_Errno: 1062
+                Last_SQL_Error: Duplicate entry '1' for key 'PRIMARY'
+           Exec_Master_Log_Pos: 4000
+         Seconds_Behind_Master: NULL
+                    Using_Gtid: Slave_Pos
+                   Gtid_IO_Pos: 0-1-100
+`
+
+const mysqlReplicaStatus = `*************************** 1. row ***************************
+             Replica_IO_State: Waiting for source to send event
+                  Source_Host: db-primary
+                  Source_Port: 3307
+              Source_Log_File: binlog.000003
+          Read_Source_Log_Pos: 900
+        Relay_Source_Log_File: binlog.000003
+           Replica_IO_Running: Yes
+          Replica_SQL_Running: Yes
+          Exec_Source_Log_Pos: 900
+        Seconds_Behind_Source: 12
+           Retrieved_Gtid_Set: 3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,
+4a22fb58-71ca-11e1-9e33-c80aa9429562:1-3
+            Executed_Gtid_Set: 3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5
+*************************** 2. row ***************************
+                  Source_Host: db-other
+`
+
+func TestParseReplicationStatus(t *testing.T) {
+	lagVal := int64(12)
+	cases := []struct {
+		name    string
+		content string
+		expect  common.ReplicationStatus
+	}{
+		{
+			name:    "primary",
+			content: "",
+			expect:  common.ReplicationStatus{Role: common.ReplicationPrimary},
+		},
+		{
+			name:    "mariadb replica",
+			content: mariadbReplicaStatus,
+			expect: common.ReplicationStatus{
+				Role:               common.ReplicationReplica,
+				Flavor:             common.MariadbFlavor,
+				MasterHost:         "10.0.0.1",
+				MasterPort:         3306,
+				IORunning:          "Yes",
+				SQLRunning:         "No",
+				IOState:            "Waiting for master to send event",
+				MasterLogFile:      "mysql-bin.000012",
+				ReadMasterLogPos:   4567,
+				RelayMasterLogFile: "mysql-bin.000012",
+				ExecMasterLogPos:   4000,
+				UsingGtid:          "Slave_Pos",
+				GtidIOPos:          "0-1-100",
+				LastSQLErrno:       1062,
+				LastSQLError:       "Duplicate entry '1' for key 'PRIMARY'",
+			},
+		},
+		{
+			name:    "mysql replica renamed fields",
+			content: mysqlReplicaStatus,
+			expect: common.ReplicationStatus{
+				Role:                common.ReplicationReplica,
+				Flavor:              common.MysqlFlavor,
+				MasterHost:          "db-primary",
+				MasterPort:          3307,
+				IORunning:           "Yes",
+				SQLRunning:          "Yes",
+				IOState:             "Waiting for source to send event",
+				SecondsBehindMaster: &lagVal,
+				MasterLogFile:       "binlog.000003",
+				ReadMasterLogPos:    900,
+				RelayMasterLogFile:  "binlog.000003",
+				ExecMasterLogPos:    900,
+				RetrievedGtidSet:    "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4a22fb58-71ca-11e1-9e33-c80aa9429562:1-3",
+				ExecutedGtidSet:     "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5",
+			},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := parseReplicationStatus([]byte(val.content))
+			lag, expectLag := ret.SecondsBehindMaster, val.expect.SecondsBehindMaster
+			if (lag == nil) != (expectLag == nil) || (lag != nil && *lag != *expectLag) {
+				t.Errorf("seconds behind master %v, expect %v", lag, expectLag)
+			}
+			ret.SecondsBehindMaster, val.expect.SecondsBehindMaster = nil, nil
+			if *ret != val.expect {
+				t.Errorf("status %+v, expect %+v", *ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestIsFieldName(t *testing.T) {
+	cases := []struct {
+		val    string
+		expect bool
+	}{
+		{val: "Master_Host", expect: true},
+		{val: "", expect: false},
+		{val: "4a22fb58-71ca-11e1-9e33-c80aa9429562", expect: false},
+		{val: "Gtid IO", expect: false},
+	}
+
+	for _, val := range cases {
+		if ret := isFieldName(val.val); ret != val.expect {
+			t.Errorf("isFieldName(%q) %v, expect %v", val.val, ret, val.expect)
+		}
+	}
+}
@@ -7,8 +7,10 @@ import (
 	cd "github.com/muidea/magicCommon/def"
 	fn "github.com/muidea/magicCommon/foundation/net"
 
+	"github.com/muidea/magicCommon/foundation/log"
 	engine "github.com/muidea/magicEngine"
 
+	"github.com/muidea/magicAgent/internal/core/base/service"
 	"github.com/muidea/magicAgent/internal/core/module/mariadb/biz"
 	"github.com/muidea/magicAgent/pkg/common"
 )
@@ -44,6 +46,30 @@ func (s *Mariadb) BindRegistry(
 func (s *Mariadb) RegisterRoute() {
 	statusRoute := engine.CreateRoute(common.QueryStatus, engine.GET, s.QueryStatusHandle)
 	s.routeRegistry.AddRoute(statusRoute)
+
+	componentRoute := engine.CreateRoute(common.QueryComponent, engine.GET, s.QueryComponentHandle)
+	s.routeRegistry.AddRoute(componentRoute)
+
+	bootstrapRoute := engine.CreateRoute(common.BootstrapNode, engine.POST, s.BootstrapHandle)
+	s.routeRegistry.AddRoute(bootstrapRoute)
+
+	replicationRoute := engine.CreateRoute(common.QueryReplication, engine.GET, s.QueryReplicationHandle)
+	s.routeRegistry.AddRoute(replicationRoute)
+
+	failoverRoute := engine.CreateRoute(common.Failover, engine.POST, s.FailoverHandle)
+	s.routeRegistry.AddRoute(failoverRoute)
+
+	voteRoute := engine.CreateRoute(common.VoteFailover, engine.POST, s.VoteFailoverHandle)
+	s.routeRegistry.AddRoute(voteRoute)
+
+	repointRoute := engine.CreateRoute(common.RepointReplica, engine.POST, s.RepointReplicaHandle)
+	s.routeRegistry.AddRoute(repointRoute)
+
+	processRoute := engine.CreateRoute(common.QueryProcess, engine.GET, s.QueryProcessHandle)
+	s.routeRegistry.AddRoute(processRoute)
+
+	killRoute := engine.CreateRoute(common.KillProcess, engine.POST, s.KillProcessHandle)
+	s.routeRegistry.AddRoute(killRoute)
 }
 
 func (s *Mariadb) QueryStatusHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
@@ -68,3 +94,230 @@ func (s *Mariadb) QueryStatusHandle(_ context.Context, res http.ResponseWriter,
 
 	fn.PackageHTTPResponse(res, result)
 }
+
+// QueryComponentHandle 汇总各节点视图，返回Non-Primary组件的处理建议
+func (s *Mariadb) QueryComponentHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryComponentResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		decisionPtr, decisionErr := s.bizPtr.AnalyzeComponent(serviceName)
+		if decisionErr != nil {
+			result.Result = *decisionErr
+			break
+		}
+
+		result.Decision = decisionPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// BootstrapHandle 在本节点执行pc.bootstrap，需要管理员权限
+func (s *Mariadb) BootstrapHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.BootstrapResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject bootstrap, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			*result = common.BootstrapResult(*authErr)
+			break
+		}
+
+		param := &common.BootstrapParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Service == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		bootstrapErr := s.bizPtr.Bootstrap(param.Service)
+		if bootstrapErr != nil {
+			*result = common.BootstrapResult(*bootstrapErr)
+			break
+		}
+
+		log.Warnf("bootstrap %s, remote:%s", param.Service, req.RemoteAddr)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Mariadb) QueryReplicationHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryReplicationResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		statusPtr, statusErr := s.bizPtr.QueryReplicationStatus(serviceName)
+		if statusErr != nil {
+			result.Result = *statusErr
+			break
+		}
+
+		result.Status = statusPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// FailoverHandle 主库不可用时执行故障切换，本节点未被选中时只返回结论，需要管理员权限
+func (s *Mariadb) FailoverHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.FailoverResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject failover, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		param := &common.FailoverParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Service == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		log.Warnf("failover %s, remote:%s", param.Service, req.RemoteAddr)
+		decisionPtr, decisionErr := s.bizPtr.Failover(param.Service)
+		if decisionErr != nil {
+			result.Result = *decisionErr
+			break
+		}
+
+		result.Decision = decisionPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// VoteFailoverHandle 确认候选节点，本节点结论不一致时返回错误，需要管理员权限
+func (s *Mariadb) VoteFailoverHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.VoteFailoverResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject vote failover, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			*result = common.VoteFailoverResult(*authErr)
+			break
+		}
+
+		param := &common.FailoverParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Service == "" || param.Candidate == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal failover param"
+			break
+		}
+
+		voteErr := s.bizPtr.VoteFailover(param)
+		if voteErr != nil {
+			*result = common.VoteFailoverResult(*voteErr)
+			break
+		}
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// RepointReplicaHandle 本节点切换到新主库，需要管理员权限
+func (s *Mariadb) RepointReplicaHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.RepointResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject repoint replica, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			*result = common.RepointResult(*authErr)
+			break
+		}
+
+		param := &common.RepointParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Service == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		repointErr := s.bizPtr.RepointReplica(param)
+		if repointErr != nil {
+			*result = common.RepointResult(*repointErr)
+			break
+		}
+
+		log.Warnf("repoint %s to %s:%d, remote:%s", param.Service, param.Host, param.Port, req.RemoteAddr)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Mariadb) QueryProcessHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryProcessResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		reportPtr, reportErr := s.bizPtr.SampleProcess(serviceName)
+		if reportErr != nil {
+			result.Result = *reportErr
+			break
+		}
+
+		result.Report = reportPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// KillProcessHandle 结束指定线程，需要管理员权限且守护对象开启allowKill
+func (s *Mariadb) KillProcessHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.KillProcessResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject kill process, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			*result = common.KillProcessResult(*authErr)
+			break
+		}
+
+		param := &common.KillProcessParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil || param.Service == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		killErr := s.bizPtr.KillProcess(param, req.RemoteAddr)
+		if killErr != nil {
+			*result = common.KillProcessResult(*killErr)
+			break
+		}
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
@@ -0,0 +1,158 @@
+package biz
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// postgresTimeOut 连接postgres及单个查询的超时时间
+const postgresTimeOut = 5 * time.Second
+
+// 状态查询语句，要求PostgreSQL 11及以上版本
+const (
+	queryOverview = "SELECT pg_is_in_recovery(), " +
+		"(SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend'), " +
+		"current_setting('max_connections')"
+	queryReplicas = "SELECT application_name, coalesce(host(client_addr), ''), state, sync_state, " +
+		"coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0)::bigint, " +
+		"coalesce(extract(epoch FROM replay_lag), 0) FROM pg_stat_replication"
+	querySlots = "SELECT slot_name, slot_type, active, " +
+		"coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), restart_lsn), 0)::bigint FROM pg_replication_slots"
+	queryReceiver = "SELECT status, coalesce(sender_host, ''), coalesce(sender_port, 0) FROM pg_stat_wal_receiver"
+)
+
+type Postgres struct {
+	biz.Base
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Postgres {
+	ptr := &Postgres{
+		Base: biz.New(common.PostgresModule, eventHub, backgroundRoutine),
+	}
+
+	ptr.SubscribeFunc(common.QueryPostgresStatus, ptr.queryStatus)
+
+	return ptr
+}
+
+func (s *Postgres) queryStatus(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryStatus failed, illegal param")
+		return
+	}
+
+	statusPtr, statusErr := s.QueryPostgresStatus(serviceVal)
+	if re != nil {
+		re.Set(statusPtr, statusErr)
+	}
+}
+
+// QueryPostgresStatus 通过wire协议查询，主库查询pg_stat_replication和复制槽，备库查询pg_stat_wal_receiver
+func (s *Postgres) QueryPostgresStatus(serviceName string) (ret *common.PostgresStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.PostgresGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal postgres guard, service:%s", serviceName))
+		return
+	}
+
+	connPtr, connErr := dialPostgres(guardPtr.GetAddress(), guardPtr.GetAccount(), guardPtr.Password, guardPtr.GetDatabase(), postgresTimeOut)
+	if connErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("connect postgres %s failed, %s", guardPtr.GetAddress(), connErr.Error()))
+		return
+	}
+	defer connPtr.Close()
+
+	rows, rowsErr := connPtr.Query(queryOverview)
+	if rowsErr != nil || len(rows) != 1 || len(rows[0]) != 3 {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("query postgres overview failed, %v", rowsErr))
+		return
+	}
+
+	statusPtr := &common.PostgresStatus{Role: common.PostgresPrimary, InRecovery: rows[0][0] == "t"}
+	statusPtr.Connections, _ = strconv.Atoi(rows[0][1])
+	statusPtr.MaxConnections, _ = strconv.Atoi(rows[0][2])
+	if statusPtr.InRecovery {
+		statusPtr.Role = common.PostgresStandby
+		rows, rowsErr = connPtr.Query(queryReceiver)
+		if rowsErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("query pg_stat_wal_receiver failed, %s", rowsErr.Error()))
+			return
+		}
+		statusPtr.Receiver = parseReceiver(rows)
+	} else {
+		rows, rowsErr = connPtr.Query(queryReplicas)
+		if rowsErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("query pg_stat_replication failed, %s", rowsErr.Error()))
+			return
+		}
+		statusPtr.Replicas = parseReplicas(rows)
+
+		rows, rowsErr = connPtr.Query(querySlots)
+		if rowsErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("query pg_replication_slots failed, %s", rowsErr.Error()))
+			return
+		}
+		statusPtr.Slots = parseSlots(rows)
+	}
+
+	ret = statusPtr
+	if config.EnableTrace() {
+		log.Infof("query postgres status, service:%s, role:%s, replicas:%d, connections:%d/%d",
+			serviceName, ret.Role, len(ret.Replicas), ret.Connections, ret.MaxConnections)
+	}
+	return
+}
+
+func parseReplicas(rows [][]string) (ret []*common.PostgresReplica) {
+	for _, row := range rows {
+		if len(row) != 6 {
+			continue
+		}
+
+		replicaPtr := &common.PostgresReplica{ApplicationName: row[0], ClientAddr: row[1], State: row[2], SyncState: row[3]}
+		replicaPtr.LagBytes, _ = strconv.ParseInt(row[4], 10, 64)
+		replicaPtr.ReplayLag, _ = strconv.ParseFloat(row[5], 64)
+		ret = append(ret, replicaPtr)
+	}
+
+	return
+}
+
+func parseSlots(rows [][]string) (ret []*common.ReplicationSlot) {
+	for _, row := range rows {
+		if len(row) != 4 {
+			continue
+		}
+
+		slotPtr := &common.ReplicationSlot{Name: row[0], Type: row[1], Active: row[2] == "t"}
+		slotPtr.RetainedBytes, _ = strconv.ParseInt(row[3], 10, 64)
+		ret = append(ret, slotPtr)
+	}
+
+	return
+}
+
+// parseReceiver 没有记录说明WAL接收进程未运行
+func parseReceiver(rows [][]string) *common.WalReceiver {
+	if len(rows) == 0 || len(rows[0]) != 3 {
+		return nil
+	}
+
+	receiverPtr := &common.WalReceiver{Status: rows[0][0], SenderHost: rows[0][1]}
+	receiverPtr.SenderPort, _ = strconv.Atoi(rows[0][2])
+	return receiverPtr
+}
@@ -0,0 +1,58 @@
+package biz
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestParseReplicas(t *testing.T) {
+	rows := [][]string{
+		{"s1", "10.0.0.2", "streaming", "async", "4096", "1.5"},
+		{"short"},
+		{"s2", "", "catchup", "sync", "", ""},
+	}
+	expect := []*common.PostgresReplica{
+		{ApplicationName: "s1", ClientAddr: "10.0.0.2", State: "streaming", SyncState: "async", LagBytes: 4096, ReplayLag: 1.5},
+		{ApplicationName: "s2", State: "catchup", SyncState: "sync"},
+	}
+	if ret := parseReplicas(rows); !reflect.DeepEqual(ret, expect) {
+		t.Errorf("replicas %+v, expect %+v", ret, expect)
+	}
+}
+
+func TestParseSlots(t *testing.T) {
+	rows := [][]string{
+		{"slot1", "physical", "t", "100"},
+		{"slot2", "logical", "f", "2048"},
+		{"bad", "physical"},
+	}
+	expect := []*common.ReplicationSlot{
+		{Name: "slot1", Type: "physical", Active: true, RetainedBytes: 100},
+		{Name: "slot2", Type: "logical", RetainedBytes: 2048},
+	}
+	if ret := parseSlots(rows); !reflect.DeepEqual(ret, expect) {
+		t.Errorf("slots %+v, expect %+v", ret, expect)
+	}
+}
+
+func TestParseReceiver(t *testing.T) {
+	cases := []struct {
+		name   string
+		rows   [][]string
+		expect *common.WalReceiver
+	}{
+		{name: "streaming", rows: [][]string{{"streaming", "10.0.0.1", "5432"}}, expect: &common.WalReceiver{Status: "streaming", SenderHost: "10.0.0.1", SenderPort: 5432}},
+		{name: "not running", rows: [][]string{}},
+		{name: "illegal row", rows: [][]string{{"streaming"}}},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := parseReceiver(val.rows); !reflect.DeepEqual(ret, val.expect) {
+				t.Errorf("receiver %+v, expect %+v", ret, val.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package biz
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// protocolVersion 协议版本3.0
+const protocolVersion = 196608
+
+// maxMessageSize 单个消息的最大长度
+const maxMessageSize = 16 * 1024 * 1024
+
+// 认证请求类型
+const (
+	authOK           = 0
+	authCleartext    = 3
+	authMD5          = 5
+	authSASL         = 10
+	authSASLContinue = 11
+	authSASLFinal    = 12
+)
+
+// pgError 服务端返回的ErrorResponse
+type pgError struct {
+	Code    string
+	Message string
+}
+
+func (s *pgError) Error() string {
+	return fmt.Sprintf("%s (SQLSTATE %s)", s.Message, s.Code)
+}
+
+// pgConn 使用简单查询协议，不支持SSL，满足状态查询
+type pgConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeOut time.Duration
+}
+
+// dialPostgres 建立连接并完成认证，支持trust、明文、md5和SCRAM-SHA-256
+func dialPostgres(address, user, password, database string, timeOut time.Duration) (ret *pgConn, err error) {
+	conn, err := net.DialTimeout("tcp", address, timeOut)
+	if err != nil {
+		return
+	}
+
+	connPtr := &pgConn{conn: conn, reader: bufio.NewReader(conn), timeOut: timeOut}
+	err = connPtr.startup(user, password, database)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	ret = connPtr
+	return
+}
+
+func (s *pgConn) Close() error {
+	_ = s.writeMessage('X', nil)
+	return s.conn.Close()
+}
+
+func (s *pgConn) startup(user, password, database string) (err error) {
+	_ = s.conn.SetDeadline(time.Now().Add(s.timeOut))
+
+	body := binary.BigEndian.AppendUint32(nil, protocolVersion)
+	for _, val := range []string{"user", user, "database", database, "application_name", "magicAgent"} {
+		body = append(body, val...)
+		body = append(body, 0)
+	}
+	body = append(body, 0)
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err = s.conn.Write(append(buf, body...)); err != nil {
+		return
+	}
+
+	var scramPtr *scramClient
+	for {
+		msgType, payload, msgErr := s.readMessage()
+		if msgErr != nil {
+			err = msgErr
+			return
+		}
+
+		switch msgType {
+		case 'R':
+			if len(payload) < 4 {
+				err = fmt.Errorf("illegal authentication message")
+				return
+			}
+			code := binary.BigEndian.Uint32(payload)
+			switch code {
+			case authOK:
+			case authCleartext:
+				err = s.writeMessage('p', append([]byte(password), 0))
+			case authMD5:
+				if len(payload) < 8 {
+					err = fmt.Errorf("illegal md5 authentication message")
+					return
+				}
+				err = s.writeMessage('p', append([]byte(md5Password(user, password, payload[4:8])), 0))
+			case authSASL:
+				if !strings.Contains(string(payload[4:]), "SCRAM-SHA-256\x00") {
+					err = fmt.Errorf("unsupported SASL mechanism:%q", payload[4:])
+					return
+				}
+				scramPtr = newScramClient(password)
+				first := scramPtr.clientFirst()
+				data := append([]byte("SCRAM-SHA-256\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+				err = s.writeMessage('p', append(data, first...))
+			case authSASLContinue:
+				if scramPtr == nil {
+					err = fmt.Errorf("unexpected SASL continue")
+					return
+				}
+				final, finalErr := scramPtr.clientFinal(string(payload[4:]))
+				if finalErr != nil {
+					err = finalErr
+					return
+				}
+				err = s.writeMessage('p', []byte(final))
+			case authSASLFinal:
+				if scramPtr == nil || !scramPtr.verifyServer(string(payload[4:])) {
+					err = fmt.Errorf("illegal SCRAM server signature")
+					return
+				}
+			default:
+				err = fmt.Errorf("unsupported authentication method:%d", code)
+			}
+			if err != nil {
+				return
+			}
+		case 'E':
+			err = parseError(payload)
+			return
+		case 'Z':
+			return
+		}
+	}
+}
+
+// Query 执行简单查询，返回最后一个结果集，NULL值返回空字符串
+func (s *pgConn) Query(sql string) (ret [][]string, err error) {
+	_ = s.conn.SetDeadline(time.Now().Add(s.timeOut))
+	if err = s.writeMessage('Q', append([]byte(sql), 0)); err != nil {
+		return
+	}
+
+	for {
+		msgType, payload, msgErr := s.readMessage()
+		if msgErr != nil {
+			err = msgErr
+			return
+		}
+
+		switch msgType {
+		case 'T':
+			ret = [][]string{}
+		case 'D':
+			row, rowErr := parseDataRow(payload)
+			if rowErr != nil {
+				err = rowErr
+				return
+			}
+			ret = append(ret, row)
+		case 'E':
+			// 出错后服务端仍会发送ReadyForQuery
+			err = parseError(payload)
+		case 'Z':
+			return
+		}
+	}
+}
+
+func (s *pgConn) writeMessage(msgType byte, payload []byte) error {
+	buf := append([]byte{msgType}, binary.BigEndian.AppendUint32(nil, uint32(len(payload)+4))...)
+	_, err := s.conn.Write(append(buf, payload...))
+	return err
+}
+
+func (s *pgConn) readMessage() (msgType byte, payload []byte, err error) {
+	header := make([]byte, 5)
+	if _, err = io.ReadFull(s.reader, header); err != nil {
+		return
+	}
+
+	msgType = header[0]
+	size := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if size < 0 || size > maxMessageSize {
+		err = fmt.Errorf("illegal message size:%d", size)
+		return
+	}
+
+	payload = make([]byte, size)
+	_, err = io.ReadFull(s.reader, payload)
+	return
+}
+
+func parseDataRow(payload []byte) (ret []string, err error) {
+	if len(payload) < 2 {
+		err = fmt.Errorf("illegal data row")
+		return
+	}
+
+	count := int(binary.BigEndian.Uint16(payload))
+	offset := 2
+	for idx := 0; idx < count; idx++ {
+		if offset+4 > len(payload) {
+			err = fmt.Errorf("illegal data row")
+			return
+		}
+		size := int(int32(binary.BigEndian.Uint32(payload[offset:])))
+		offset += 4
+		if size < 0 {
+			ret = append(ret, "")
+			continue
+		}
+		if offset+size > len(payload) {
+			err = fmt.Errorf("illegal data row")
+			return
+		}
+		ret = append(ret, string(payload[offset:offset+size]))
+		offset += size
+	}
+
+	return
+}
+
+// parseError 解析ErrorResponse中的SQLSTATE和消息
+func parseError(payload []byte) error {
+	errPtr := &pgError{}
+	for _, field := range strings.Split(string(payload), "\x00") {
+		if field == "" {
+			continue
+		}
+		switch field[0] {
+		case 'C':
+			errPtr.Code = field[1:]
+		case 'M':
+			errPtr.Message = field[1:]
+		}
+	}
+
+	return errPtr
+}
+
+// md5Password md5(md5(password+user)+salt)
+func md5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramClient SCRAM-SHA-256认证，用户名由启动消息提供，这里为空
+type scramClient struct {
+	password    string
+	clientNonce string
+	firstBare   string
+	authMessage string
+	salted      []byte
+}
+
+func newScramClient(password string) *scramClient {
+	nonce := make([]byte, 18)
+	_, _ = rand.Read(nonce)
+	return &scramClient{password: password, clientNonce: base64.StdEncoding.EncodeToString(nonce)}
+}
+
+func (s *scramClient) clientFirst() string {
+	s.firstBare = "n=,r=" + s.clientNonce
+	return "n,," + s.firstBare
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (ret string, err error) {
+	attrs := map[string]string{}
+	for _, item := range strings.Split(serverFirst, ",") {
+		if len(item) > 2 && item[1] == '=' {
+			attrs[item[:1]] = item[2:]
+		}
+	}
+
+	nonce := attrs["r"]
+	salt, saltErr := base64.StdEncoding.DecodeString(attrs["s"])
+	iterations, iterErr := strconv.Atoi(attrs["i"])
+	if !strings.HasPrefix(nonce, s.clientNonce) || saltErr != nil || iterErr != nil || iterations <= 0 {
+		err = fmt.Errorf("illegal SCRAM server message")
+		return
+	}
+
+	s.salted = pbkdf2SHA256([]byte(s.password), salt, iterations)
+	withoutProof := "c=biws,r=" + nonce
+	s.authMessage = s.firstBare + "," + serverFirst + "," + withoutProof
+
+	clientKey := hmacSHA256(s.salted, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	signature := hmacSHA256(storedKey[:], []byte(s.authMessage))
+	proof := make([]byte, len(clientKey))
+	for idx := range clientKey {
+		proof[idx] = clientKey[idx] ^ signature[idx]
+	}
+
+	ret = withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)
+	return
+}
+
+func (s *scramClient) verifyServer(serverFinal string) bool {
+	signature, signatureErr := base64.StdEncoding.DecodeString(strings.TrimPrefix(serverFinal, "v="))
+	if signatureErr != nil || s.salted == nil {
+		return false
+	}
+
+	serverKey := hmacSHA256(s.salted, []byte("Server Key"))
+	return hmac.Equal(signature, hmacSHA256(serverKey, []byte(s.authMessage)))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 SCRAM中的Hi函数，输出长度与SHA-256相同，只需要一个块
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	block := hmacSHA256(password, append(append([]byte{}, salt...), 0, 0, 0, 1))
+	ret := append([]byte{}, block...)
+	for idx := 1; idx < iterations; idx++ {
+		block = hmacSHA256(password, block)
+		for pos := range ret {
+			ret[pos] ^= block[pos]
+		}
+	}
+
+	return ret
+}
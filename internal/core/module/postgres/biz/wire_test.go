@@ -0,0 +1,245 @@
+package biz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dataRow(values ...interface{}) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, uint16(len(values)))
+	for _, val := range values {
+		str, ok := val.(string)
+		if !ok {
+			buf = binary.BigEndian.AppendUint32(buf, 0xffffffff)
+			continue
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(str)))
+		buf = append(buf, str...)
+	}
+	return buf
+}
+
+func message(msgType byte, payload []byte) []byte {
+	buf := append([]byte{msgType}, binary.BigEndian.AppendUint32(nil, uint32(len(payload)+4))...)
+	return append(buf, payload...)
+}
+
+func TestParseDataRow(t *testing.T) {
+	cases := []struct {
+		name      string
+		payload   []byte
+		expect    []string
+		expectErr bool
+	}{
+		{name: "values", payload: dataRow("1", "", "streaming"), expect: []string{"1", "", "streaming"}},
+		{name: "null", payload: dataRow("a", nil), expect: []string{"a", ""}},
+		{name: "no column", payload: dataRow()},
+		{name: "short header", payload: []byte{0}, expectErr: true},
+		{name: "missing size", payload: []byte{0, 1, 0, 0}, expectErr: true},
+		{name: "truncated value", payload: dataRow("abcdef")[:8], expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, err := parseDataRow(val.payload)
+			if (err != nil) != val.expectErr {
+				t.Fatalf("err %v, expect error %v", err, val.expectErr)
+			}
+			if !val.expectErr && !reflect.DeepEqual(ret, val.expect) {
+				t.Errorf("row %q, expect %q", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	err := parseError([]byte("SFATAL\x00VFATAL\x00C28P01\x00Mpassword authentication failed for user \"monitor\"\x00\x00"))
+	if err.Error() != `password authentication failed for user "monitor" (SQLSTATE 28P01)` {
+		t.Errorf("error %q", err.Error())
+	}
+}
+
+func TestMD5Password(t *testing.T) {
+	ret := md5Password("postgres", "secret", []byte{1, 2, 3, 4})
+	if ret != "md5bb41a296aab6baccb36ff243a562abff" {
+		t.Errorf("md5 password %s", ret)
+	}
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	cases := []struct {
+		iterations int
+		expect     string
+	}{
+		{iterations: 1, expect: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{iterations: 2, expect: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{iterations: 4096, expect: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+
+	for _, val := range cases {
+		if ret := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), val.iterations)); ret != val.expect {
+			t.Errorf("iterations %d, %s, expect %s", val.iterations, ret, val.expect)
+		}
+	}
+}
+
+// TestScramClient RFC 7677中的示例，用户名由启动消息提供时client-first-bare中的用户名为空，这里直接指定
+func TestScramClient(t *testing.T) {
+	clientPtr := &scramClient{password: "pencil", clientNonce: "rOprNGfwEbeRWgbNEkqO"}
+	if first := clientPtr.clientFirst(); first != "n,,n=,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Errorf("client first %s", first)
+	}
+	clientPtr.firstBare = "n=user,r=rOprNGfwEbeRWgbNEkqO"
+
+	serverFirst := "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	final, err := clientPtr.clientFinal(serverFirst)
+	if err != nil {
+		t.Fatalf("client final failed, %v", err)
+	}
+	if final != "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=" {
+		t.Errorf("client final %s", final)
+	}
+	if !clientPtr.verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=") {
+		t.Errorf("verify server signature failed")
+	}
+	if clientPtr.verifyServer("v=AAAA") || clientPtr.verifyServer("v=!") {
+		t.Errorf("illegal server signature accepted")
+	}
+
+	for _, val := range []string{
+		"r=other,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+		"r=rOprNGfwEbeRWgbNEkqOabc,s=!,i=4096",
+		"r=rOprNGfwEbeRWgbNEkqOabc,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=0",
+	} {
+		if _, err := (&scramClient{password: "pencil", clientNonce: "rOprNGfwEbeRWgbNEkqO"}).clientFinal(val); err == nil {
+			t.Errorf("server first %q accepted", val)
+		}
+	}
+	if (&scramClient{}).verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=") {
+		t.Errorf("server signature accepted before client final")
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	oversize := append([]byte{'D'}, binary.BigEndian.AppendUint32(nil, maxMessageSize+5)...)
+	cases := []struct {
+		name      string
+		content   []byte
+		expectErr string
+	}{
+		{name: "message", content: message('Z', []byte{'I'})},
+		{name: "size below header", content: []byte{'Z', 0, 0, 0, 3}, expectErr: "illegal message size"},
+		{name: "oversize", content: oversize, expectErr: "illegal message size"},
+		{name: "truncated", content: message('D', dataRow("abc"))[:8], expectErr: "unexpected EOF"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			connPtr := &pgConn{reader: bufio.NewReader(bytes.NewReader(val.content))}
+			msgType, payload, err := connPtr.readMessage()
+			if val.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), val.expectErr) {
+					t.Errorf("err %v, expect %s", err, val.expectErr)
+				}
+				return
+			}
+			if err != nil || msgType != 'Z' || string(payload) != "I" {
+				t.Errorf("message %c %q, err %v", msgType, payload, err)
+			}
+		})
+	}
+}
+
+// fakePostgres 要求md5认证后执行一次查询，查询语句包含error时返回ErrorResponse
+func fakePostgres(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return
+	}
+	startup := make([]byte, binary.BigEndian.Uint32(header)-4)
+	if _, err := io.ReadFull(reader, startup); err != nil {
+		return
+	}
+	if !bytes.Contains(startup, []byte("user\x00postgres\x00database\x00postgres\x00")) {
+		t.Errorf("startup %q", startup)
+	}
+
+	salt := []byte{1, 2, 3, 4}
+	_, _ = conn.Write(message('R', append(binary.BigEndian.AppendUint32(nil, authMD5), salt...)))
+	connPtr := &pgConn{reader: reader}
+	msgType, payload, err := connPtr.readMessage()
+	if err != nil || msgType != 'p' || string(payload) != md5Password("postgres", "secret", salt)+"\x00" {
+		_, _ = conn.Write(message('E', []byte("C28P01\x00Mpassword authentication failed\x00\x00")))
+		return
+	}
+	_, _ = conn.Write(append(message('R', binary.BigEndian.AppendUint32(nil, authOK)), message('Z', []byte{'I'})...))
+
+	for {
+		msgType, payload, err = connPtr.readMessage()
+		if err != nil || msgType != 'Q' {
+			return
+		}
+		if strings.Contains(string(payload), "error") {
+			_, _ = conn.Write(append(message('E', []byte("C42P01\x00Mrelation does not exist\x00\x00")), message('Z', []byte{'I'})...))
+			continue
+		}
+
+		reply := message('T', []byte{0, 2})
+		reply = append(reply, message('D', dataRow("1", nil))...)
+		reply = append(reply, message('D', dataRow("2", "b"))...)
+		reply = append(reply, message('C', []byte("SELECT 2\x00"))...)
+		_, _ = conn.Write(append(reply, message('Z', []byte{'I'})...))
+	}
+}
+
+func TestDialPostgres(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, connErr := listener.Accept()
+			if connErr != nil {
+				return
+			}
+			go fakePostgres(t, conn)
+		}
+	}()
+
+	if _, err := dialPostgres(listener.Addr().String(), "postgres", "wrong", "postgres", time.Second); err == nil ||
+		err.Error() != "password authentication failed (SQLSTATE 28P01)" {
+		t.Errorf("dial with wrong password, err %v", err)
+	}
+
+	connPtr, connErr := dialPostgres(listener.Addr().String(), "postgres", "secret", "postgres", time.Second)
+	if connErr != nil {
+		t.Fatalf("dial failed, %v", connErr)
+	}
+	defer connPtr.Close()
+
+	rows, rowsErr := connPtr.Query("select a, b from t")
+	if rowsErr != nil || !reflect.DeepEqual(rows, [][]string{{"1", ""}, {"2", "b"}}) {
+		t.Errorf("rows %q, err %v", rows, rowsErr)
+	}
+
+	// 出错后连接仍可继续使用
+	if _, rowsErr = connPtr.Query("select error"); rowsErr == nil || !strings.Contains(rowsErr.Error(), "42P01") {
+		t.Errorf("query err %v, expect 42P01", rowsErr)
+	}
+	if rows, rowsErr = connPtr.Query("select a, b from t"); rowsErr != nil || len(rows) != 2 {
+		t.Errorf("rows %q, err %v", rows, rowsErr)
+	}
+}
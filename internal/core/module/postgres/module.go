@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/postgres/biz"
+	"github.com/muidea/magicAgent/internal/core/module/postgres/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Postgres struct {
+	routeRegistry engine.Router
+
+	service *service.Postgres
+	biz     *biz.Postgres
+}
+
+func New() *Postgres {
+	return &Postgres{}
+}
+
+func (s *Postgres) ID() string {
+	return common.PostgresModule
+}
+
+func (s *Postgres) BindRegistry(routeRegistry engine.Router) {
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Postgres) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	cd "github.com/muidea/magicCommon/def"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicAgent/internal/core/module/postgres/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Postgres BaseService
+type Postgres struct {
+	routeRegistry engine.Router
+
+	bizPtr *biz.Postgres
+
+	endpointName string
+}
+
+// New create base
+func New(endpointName string, bizPtr *biz.Postgres) *Postgres {
+	ptr := &Postgres{
+		endpointName: endpointName,
+		bizPtr:       bizPtr,
+	}
+
+	return ptr
+}
+
+func (s *Postgres) BindRegistry(
+	routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+
+	s.routeRegistry.SetApiVersion(common.ApiVersion)
+}
+
+// RegisterRoute 注册路由
+func (s *Postgres) RegisterRoute() {
+	statusRoute := engine.CreateRoute(common.QueryPostgresStatus, engine.GET, s.QueryStatusHandle)
+	s.routeRegistry.AddRoute(statusRoute)
+}
+
+func (s *Postgres) QueryStatusHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryPostgresStatusResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		statusPtr, statusErr := s.bizPtr.QueryPostgresStatus(serviceName)
+		if statusErr != nil {
+			result.Result = *statusErr
+			break
+		}
+
+		result.Status = statusPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
@@ -0,0 +1,211 @@
+package biz
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// maxBodySize http探测读取的最大响应长度，BodyRegex只匹配该范围内的内容
+const maxBodySize = 1024 * 1024
+
+type Probe struct {
+	biz.Base
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Probe {
+	ptr := &Probe{
+		Base: biz.New(common.ProbeModule, eventHub, backgroundRoutine),
+	}
+
+	ptr.SubscribeFunc(common.QueryProbeStatus, ptr.queryStatus)
+
+	return ptr
+}
+
+func (s *Probe) queryStatus(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryStatus failed, illegal param")
+		return
+	}
+
+	statusPtr, statusErr := s.QueryProbeStatus(serviceVal)
+	if re != nil {
+		re.Set(statusPtr, statusErr)
+	}
+}
+
+// QueryProbeStatus 依次执行守护对象的全部探测
+func (s *Probe) QueryProbeStatus(serviceName string) (ret *common.ProbeStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.ProbeGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal probe guard, service:%s", serviceName))
+		return
+	}
+
+	ret = &common.ProbeStatus{Healthy: true, Results: []*common.ProbeResult{}}
+	for _, val := range guardPtr.Probes {
+		resultPtr := s.runProbe(serviceName, val)
+		if !resultPtr.Healthy {
+			ret.Healthy = false
+			if config.EnableTrace() {
+				log.Warnf("probe failed, service:%s, type:%s, target:%s, reason:%s", serviceName, resultPtr.Type, resultPtr.Target, resultPtr.Reason)
+			}
+		}
+		ret.Results = append(ret.Results, resultPtr)
+	}
+
+	return
+}
+
+func (s *Probe) runProbe(serviceName string, probePtr *config.ProbeItem) *common.ProbeResult {
+	resultPtr := &common.ProbeResult{Type: probePtr.Type, Target: probePtr.Target()}
+	timeOut := time.Duration(probePtr.GetTimeOut()) * time.Second
+
+	var probeErr error
+	startTime := time.Now()
+	switch probePtr.Type {
+	case config.TCPProbe:
+		probeErr = probeTCP(probePtr.Address, timeOut)
+	case config.HTTPProbe:
+		probeErr = probeHTTP(probePtr, timeOut, resultPtr)
+	case config.TLSProbe:
+		probeErr = probeTLS(probePtr, timeOut, resultPtr)
+	case config.CommandProbe:
+		probeErr = s.probeCommand(serviceName, probePtr)
+	default:
+		probeErr = fmt.Errorf("illegal probe type:%s", probePtr.Type)
+	}
+	resultPtr.Latency = time.Since(startTime).Milliseconds()
+
+	resultPtr.Healthy = probeErr == nil
+	if probeErr != nil {
+		resultPtr.Reason = probeErr.Error()
+	}
+	return resultPtr
+}
+
+func probeTCP(address string, timeOut time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeOut)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// probeHTTP 跟随重定向，检查最终响应的状态码和内容
+func probeHTTP(probePtr *config.ProbeItem, timeOut time.Duration, resultPtr *common.ProbeResult) error {
+	clientPtr := &http.Client{
+		Timeout: timeOut,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: probePtr.Insecure},
+			DisableKeepAlives: true,
+		},
+	}
+
+	res, err := clientPtr.Get(probePtr.URL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.TLS != nil {
+		setCertExpire(res.TLS.PeerCertificates, probePtr.GetExpireDays(), resultPtr)
+	}
+	if res.StatusCode != probePtr.GetExpectStatus() {
+		return fmt.Errorf("unexpected status %d, expect %d", res.StatusCode, probePtr.GetExpectStatus())
+	}
+	if probePtr.BodyRegex == "" {
+		return nil
+	}
+
+	regexPtr, regexErr := regexp.Compile(probePtr.BodyRegex)
+	if regexErr != nil {
+		return regexErr
+	}
+	body, bodyErr := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if bodyErr != nil {
+		return bodyErr
+	}
+	if !regexPtr.Match(body) {
+		return fmt.Errorf("body not match %q", probePtr.BodyRegex)
+	}
+
+	return nil
+}
+
+// probeTLS 完成握手即视为正常，证书即将过期只记录在结果中
+func probeTLS(probePtr *config.ProbeItem, timeOut time.Duration, resultPtr *common.ProbeResult) error {
+	host, _, _ := net.SplitHostPort(probePtr.Address)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeOut}, "tcp", probePtr.Address, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: probePtr.Insecure,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	setCertExpire(conn.ConnectionState().PeerCertificates, probePtr.GetExpireDays(), resultPtr)
+	return nil
+}
+
+// setCertExpire 取证书链中最早的过期时间
+func setCertExpire(certs []*x509.Certificate, expireDays int, resultPtr *common.ProbeResult) {
+	for _, val := range certs {
+		if resultPtr.CertExpire == nil || val.NotAfter.Before(*resultPtr.CertExpire) {
+			notAfter := val.NotAfter
+			resultPtr.CertExpire = &notAfter
+		}
+	}
+
+	if resultPtr.CertExpire != nil {
+		resultPtr.CertExpiring = time.Until(*resultPtr.CertExpire) < time.Duration(expireDays)*24*time.Hour
+	}
+}
+
+// probeCommand 在被守护容器内执行命令，退出码非0或超时视为失败
+func (s *Probe) probeCommand(serviceName string, probePtr *config.ProbeItem) error {
+	param := &common.ServiceParam{
+		Service:  serviceName,
+		CmdParam: probePtr.Command,
+		TimeOut:  probePtr.GetTimeOut(),
+	}
+
+	execEvent := event.NewEvent(common.ExecuteCommand, s.ID(), common.RuntimeModule, nil, param)
+	result := s.SendEvent(execEvent)
+	_, execErr := result.Get()
+	if execErr == nil {
+		return nil
+	}
+
+	reason := execErr.Error()
+	if exitCode, ok := result.GetVal("exitCode").(int); ok && exitCode > 0 {
+		reason = fmt.Sprintf("exit code %d", exitCode)
+	}
+	if stderr, ok := result.GetVal("stderr").([]byte); ok && len(stderr) > 0 {
+		reason = fmt.Sprintf("%s, %s", reason, strings.TrimSpace(string(stderr)))
+	}
+
+	return fmt.Errorf("%s", reason)
+}
@@ -0,0 +1,177 @@
+package biz
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+	address := listener.Addr().String()
+	if err = probeTCP(address, time.Second); err != nil {
+		t.Errorf("probe listening port failed, %v", err)
+	}
+
+	listener.Close()
+	if err = probeTCP(address, time.Second); err == nil {
+		t.Errorf("probe closed port succeeded")
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(res http.ResponseWriter, _ *http.Request) {
+		_, _ = res.Write([]byte(`{"status":"UP"}`))
+	})
+	mux.HandleFunc("/down", func(res http.ResponseWriter, _ *http.Request) {
+		res.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.Handle("/old", http.RedirectHandler("/health", http.StatusFound))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cases := []struct {
+		name      string
+		probe     *config.ProbeItem
+		expectErr string
+	}{
+		{name: "ok", probe: &config.ProbeItem{URL: server.URL + "/health"}},
+		{name: "body match", probe: &config.ProbeItem{URL: server.URL + "/health", BodyRegex: `"status":\s*"UP"`}},
+		{name: "body not match", probe: &config.ProbeItem{URL: server.URL + "/health", BodyRegex: "DOWN"}, expectErr: `body not match "DOWN"`},
+		{name: "unexpected status", probe: &config.ProbeItem{URL: server.URL + "/down"}, expectErr: "unexpected status 503, expect 200"},
+		{name: "expect status", probe: &config.ProbeItem{URL: server.URL + "/down", ExpectStatus: 503}},
+		{name: "follow redirect", probe: &config.ProbeItem{URL: server.URL + "/old", BodyRegex: "UP"}},
+		{name: "not found", probe: &config.ProbeItem{URL: server.URL + "/none"}, expectErr: "unexpected status 404"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			resultPtr := &common.ProbeResult{}
+			err := probeHTTP(val.probe, time.Second, resultPtr)
+			if val.expectErr == "" && err != nil {
+				t.Errorf("probe failed, %v", err)
+			}
+			if val.expectErr != "" && (err == nil || !strings.Contains(err.Error(), val.expectErr)) {
+				t.Errorf("err %v, expect %s", err, val.expectErr)
+			}
+			if resultPtr.CertExpire != nil {
+				t.Errorf("plain http should not record certificate")
+			}
+		})
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	notAfter := server.Certificate().NotAfter
+
+	resultPtr := &common.ProbeResult{}
+	if err := probeHTTP(&config.ProbeItem{URL: server.URL}, time.Second, resultPtr); err == nil {
+		t.Errorf("untrusted certificate accepted")
+	}
+
+	resultPtr = &common.ProbeResult{}
+	if err := probeHTTP(&config.ProbeItem{URL: server.URL, Insecure: true}, time.Second, resultPtr); err != nil {
+		t.Errorf("insecure probe failed, %v", err)
+	}
+	if resultPtr.CertExpire == nil || !resultPtr.CertExpire.Equal(notAfter) || resultPtr.CertExpiring {
+		t.Errorf("cert expire %v, expiring %v, expect %v", resultPtr.CertExpire, resultPtr.CertExpiring, notAfter)
+	}
+
+	address := strings.TrimPrefix(server.URL, "https://")
+	resultPtr = &common.ProbeResult{}
+	if err := probeTLS(&config.ProbeItem{Address: address, Insecure: true}, time.Second, resultPtr); err != nil {
+		t.Errorf("tls probe failed, %v", err)
+	}
+	if resultPtr.CertExpire == nil || !resultPtr.CertExpire.Equal(notAfter) {
+		t.Errorf("cert expire %v, expect %v", resultPtr.CertExpire, notAfter)
+	}
+}
+
+func TestSetCertExpire(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name       string
+		notAfter   []time.Time
+		expireDays int
+		expect     *time.Time
+		expiring   bool
+	}{
+		{name: "no certificate", expireDays: 14},
+		{name: "far", notAfter: []time.Time{now.Add(30 * 24 * time.Hour)}, expireDays: 14, expect: timePtr(now.Add(30 * 24 * time.Hour))},
+		{name: "earliest in chain", notAfter: []time.Time{now.Add(90 * 24 * time.Hour), now.Add(10 * 24 * time.Hour)}, expireDays: 14, expect: timePtr(now.Add(10 * 24 * time.Hour)), expiring: true},
+		{name: "expired", notAfter: []time.Time{now.Add(-time.Hour)}, expireDays: 1, expect: timePtr(now.Add(-time.Hour)), expiring: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			certs := []*x509.Certificate{}
+			for _, notAfter := range val.notAfter {
+				certs = append(certs, &x509.Certificate{NotAfter: notAfter})
+			}
+
+			resultPtr := &common.ProbeResult{}
+			setCertExpire(certs, val.expireDays, resultPtr)
+			if (resultPtr.CertExpire == nil) != (val.expect == nil) || (val.expect != nil && !resultPtr.CertExpire.Equal(*val.expect)) {
+				t.Errorf("cert expire %v, expect %v", resultPtr.CertExpire, val.expect)
+			}
+			if resultPtr.CertExpiring != val.expiring {
+				t.Errorf("expiring %v, expect %v", resultPtr.CertExpiring, val.expiring)
+			}
+		})
+	}
+}
+
+func timePtr(val time.Time) *time.Time {
+	return &val
+}
+
+func TestQueryProbeStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	guardPtr := &config.GuardItem{Name: "app001", Type: config.ProbeGuard, Probes: []*config.ProbeItem{
+		{Type: config.TCPProbe, Address: strings.TrimPrefix(server.URL, "http://")},
+		{Type: config.HTTPProbe, URL: server.URL + "/health"},
+	}}
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.Guards = []*config.GuardItem{guardPtr} })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	statusPtr, statusErr := (&Probe{}).QueryProbeStatus("app001")
+	if statusErr != nil {
+		t.Fatalf("query probe status failed, %s", statusErr.Reason)
+	}
+	if statusPtr.Healthy || len(statusPtr.Results) != 2 {
+		t.Fatalf("status %+v, expect unhealthy with 2 results", statusPtr)
+	}
+	if !statusPtr.Results[0].Healthy || statusPtr.Results[0].Target != guardPtr.Probes[0].Address {
+		t.Errorf("tcp result %+v", statusPtr.Results[0])
+	}
+	if statusPtr.Results[1].Healthy || statusPtr.Results[1].Reason != "unexpected status 500, expect 200" {
+		t.Errorf("http result %+v", statusPtr.Results[1])
+	}
+
+	if resultPtr := (&Probe{}).runProbe("app001", &config.ProbeItem{Type: "udp"}); resultPtr.Healthy || resultPtr.Reason != "illegal probe type:udp" {
+		t.Errorf("illegal probe result %+v", resultPtr)
+	}
+	if _, statusErr = (&Probe{}).QueryProbeStatus("mariadb001"); statusErr == nil {
+		t.Errorf("expect illegal guard error")
+	}
+}
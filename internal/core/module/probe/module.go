@@ -0,0 +1,44 @@
+package probe
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/probe/biz"
+	"github.com/muidea/magicAgent/internal/core/module/probe/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Probe struct {
+	routeRegistry engine.Router
+
+	service *service.Probe
+	biz     *biz.Probe
+}
+
+func New() *Probe {
+	return &Probe{}
+}
+
+func (s *Probe) ID() string {
+	return common.ProbeModule
+}
+
+func (s *Probe) BindRegistry(routeRegistry engine.Router) {
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Probe) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
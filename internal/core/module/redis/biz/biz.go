@@ -0,0 +1,175 @@
+package biz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// redisTimeOut 连接redis及单个命令的超时时间
+const redisTimeOut = 5 * time.Second
+
+type Redis struct {
+	biz.Base
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Redis {
+	ptr := &Redis{
+		Base: biz.New(common.RedisModule, eventHub, backgroundRoutine),
+	}
+
+	ptr.SubscribeFunc(common.QueryRedisStatus, ptr.queryStatus)
+
+	return ptr
+}
+
+func (s *Redis) queryStatus(ev event.Event, re event.Result) {
+	serviceVal, serviceOK := ev.Data().(string)
+	if !serviceOK {
+		log.Warnf("queryStatus failed, illegal param")
+		return
+	}
+
+	statusPtr, statusErr := s.QueryRedisStatus(serviceVal)
+	if re != nil {
+		re.Set(statusPtr, statusErr)
+	}
+}
+
+// QueryRedisStatus 通过RESP协议读取INFO，Sentinel进程读取INFO sentinel
+func (s *Redis) QueryRedisStatus(serviceName string) (ret *common.RedisStatus, err *cd.Result) {
+	guardPtr := config.GetGuard(serviceName)
+	if guardPtr == nil || guardPtr.Type != config.RedisGuard {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal redis guard, service:%s", serviceName))
+		return
+	}
+
+	connPtr, connErr := dialRESP(guardPtr.GetAddress(), redisTimeOut)
+	if connErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("connect redis %s failed, %s", guardPtr.GetAddress(), connErr.Error()))
+		return
+	}
+	defer connPtr.Close()
+
+	if guardPtr.Password != "" {
+		args := []string{"AUTH", guardPtr.Password}
+		if guardPtr.Account != "" {
+			args = []string{"AUTH", guardPtr.Account, guardPtr.Password}
+		}
+		if _, authErr := connPtr.Do(args...); authErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("auth redis failed, %s", authErr.Error()))
+			return
+		}
+	}
+
+	sections := []string{"replication", "persistence", "memory"}
+	if guardPtr.IsSentinel() {
+		sections = []string{"sentinel"}
+	}
+
+	fields := map[string]string{}
+	for _, section := range sections {
+		replyVal, replyErr := connPtr.Do("INFO", section)
+		if replyErr != nil {
+			// 加载数据期间部分版本拒绝命令
+			if strings.HasPrefix(replyErr.Error(), "LOADING") {
+				ret = &common.RedisStatus{Loading: true}
+				return
+			}
+
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("query redis info %s failed, %s", section, replyErr.Error()))
+			return
+		}
+
+		infoVal, _ := replyVal.(string)
+		parseInfo(infoVal, fields)
+	}
+
+	ret = newRedisStatus(fields, guardPtr.IsSentinel())
+	if config.EnableTrace() {
+		log.Infof("query redis status, service:%s, role:%s, loading:%v, link:%s", serviceName, ret.Role, ret.Loading, ret.MasterLinkStatus)
+	}
+	return
+}
+
+// parseInfo 解析INFO输出，#开头为分组名称
+func parseInfo(content string, fields map[string]string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, ":")
+		if ok {
+			fields[key] = val
+		}
+	}
+}
+
+func newRedisStatus(fields map[string]string, sentinel bool) *common.RedisStatus {
+	statusPtr := &common.RedisStatus{Role: fields["role"]}
+	if sentinel {
+		statusPtr.Role = common.RedisSentinel
+		for idx := 0; ; idx++ {
+			masterVal, ok := fields[fmt.Sprintf("master%d", idx)]
+			if !ok {
+				break
+			}
+			statusPtr.SentinelMasters = append(statusPtr.SentinelMasters, parseSentinelMaster(masterVal))
+		}
+		return statusPtr
+	}
+
+	statusPtr.Loading = fields["loading"] == "1"
+	statusPtr.MasterHost = fields["master_host"]
+	statusPtr.MasterPort, _ = strconv.Atoi(fields["master_port"])
+	statusPtr.MasterLinkStatus = fields["master_link_status"]
+	statusPtr.MasterLastIOSeconds, _ = strconv.ParseInt(fields["master_last_io_seconds_ago"], 10, 64)
+	statusPtr.MasterSyncInProgress = fields["master_sync_in_progress"] == "1"
+	statusPtr.ConnectedSlaves, _ = strconv.Atoi(fields["connected_slaves"])
+	statusPtr.RDBLastBgsaveStatus = fields["rdb_last_bgsave_status"]
+	statusPtr.RDBChangesSinceSave, _ = strconv.ParseInt(fields["rdb_changes_since_last_save"], 10, 64)
+	statusPtr.AOFEnabled = fields["aof_enabled"] == "1"
+	statusPtr.AOFLastWriteStatus = fields["aof_last_write_status"]
+	statusPtr.AOFLastRewriteStatus = fields["aof_last_bgrewrite_status"]
+	statusPtr.UsedMemory, _ = strconv.ParseInt(fields["used_memory"], 10, 64)
+	statusPtr.MaxMemory, _ = strconv.ParseInt(fields["maxmemory"], 10, 64)
+	statusPtr.FragmentationRatio, _ = strconv.ParseFloat(fields["mem_fragmentation_ratio"], 64)
+	return statusPtr
+}
+
+// parseSentinelMaster 解析name=mymaster,status=ok,address=127.0.0.1:6379,slaves=2,sentinels=3
+func parseSentinelMaster(content string) *common.SentinelMaster {
+	masterPtr := &common.SentinelMaster{}
+	for _, item := range strings.Split(content, ",") {
+		key, val, _ := strings.Cut(item, "=")
+		switch key {
+		case "name":
+			masterPtr.Name = val
+		case "status":
+			masterPtr.Status = val
+		case "address":
+			masterPtr.Address = val
+		case "slaves":
+			masterPtr.Slaves, _ = strconv.Atoi(val)
+		case "sentinels":
+			masterPtr.Sentinels, _ = strconv.Atoi(val)
+		}
+	}
+
+	return masterPtr
+}
@@ -0,0 +1,183 @@
+package biz
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const replicaInfo = "# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6380\r\nmaster_link_status:down\r\n" +
+	"master_last_io_seconds_ago:-1\r\nmaster_sync_in_progress:1\r\nconnected_slaves:0\r\n"
+
+const persistenceInfo = "# Persistence\r\nloading:0\r\nrdb_changes_since_last_save:12\r\nrdb_last_bgsave_status:err\r\n" +
+	"aof_enabled:1\r\naof_last_bgrewrite_status:ok\r\naof_last_write_status:ok\r\n"
+
+const memoryInfo = "# Memory\r\nused_memory:1024\r\nmaxmemory:2048\r\nmem_fragmentation_ratio:1.25\r\n"
+
+const sentinelInfo = "# Sentinel\r\nsentinel_masters:2\r\n" +
+	"master0:name=mymaster,status=ok,address=127.0.0.1:6379,slaves=2,sentinels=3\r\n" +
+	"master1:name=cache,status=odown,address=10.0.0.2:6379,slaves=0,sentinels=1\r\n"
+
+func TestNewRedisStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		info     string
+		sentinel bool
+		expect   common.RedisStatus
+	}{
+		{
+			name: "replica",
+			info: replicaInfo + persistenceInfo + memoryInfo,
+			expect: common.RedisStatus{
+				Role:                 common.RedisSlave,
+				MasterHost:           "10.0.0.1",
+				MasterPort:           6380,
+				MasterLinkStatus:     "down",
+				MasterLastIOSeconds:  -1,
+				MasterSyncInProgress: true,
+				RDBLastBgsaveStatus:  "err",
+				RDBChangesSinceSave:  12,
+				AOFEnabled:           true,
+				AOFLastWriteStatus:   "ok",
+				AOFLastRewriteStatus: "ok",
+				UsedMemory:           1024,
+				MaxMemory:            2048,
+				FragmentationRatio:   1.25,
+			},
+		},
+		{
+			name:   "loading master",
+			info:   "role:master\nconnected_slaves:2\nloading:1\n",
+			expect: common.RedisStatus{Role: common.RedisMaster, ConnectedSlaves: 2, Loading: true},
+		},
+		{
+			name:     "sentinel",
+			info:     sentinelInfo,
+			sentinel: true,
+			expect: common.RedisStatus{
+				Role: common.RedisSentinel,
+				SentinelMasters: []*common.SentinelMaster{
+					{Name: "mymaster", Status: "ok", Address: "127.0.0.1:6379", Slaves: 2, Sentinels: 3},
+					{Name: "cache", Status: "odown", Address: "10.0.0.2:6379", Slaves: 0, Sentinels: 1},
+				},
+			},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			fields := map[string]string{}
+			parseInfo(val.info, fields)
+			ret := newRedisStatus(fields, val.sentinel)
+			if !reflect.DeepEqual(*ret, val.expect) {
+				t.Errorf("status %+v, expect %+v", *ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestParseSentinelMaster(t *testing.T) {
+	cases := []struct {
+		content string
+		expect  common.SentinelMaster
+	}{
+		{content: "name=mymaster,status=sdown,address=10.0.0.1:6379,slaves=1,sentinels=3", expect: common.SentinelMaster{Name: "mymaster", Status: "sdown", Address: "10.0.0.1:6379", Slaves: 1, Sentinels: 3}},
+		{content: "name=m,unknown=1,slaves=x", expect: common.SentinelMaster{Name: "m"}},
+		{content: "", expect: common.SentinelMaster{}},
+	}
+
+	for _, val := range cases {
+		if ret := parseSentinelMaster(val.content); *ret != val.expect {
+			t.Errorf("parse %q, %+v, expect %+v", val.content, *ret, val.expect)
+		}
+	}
+}
+
+func TestQueryRedisStatus(t *testing.T) {
+	cases := []struct {
+		name      string
+		guard     config.GuardItem
+		handler   func(args []string) string
+		expect    string
+		expectErr string
+		commands  []string
+	}{
+		{
+			name:  "replica with acl",
+			guard: config.GuardItem{Account: "monitor", Password: "secret"},
+			handler: func(args []string) string {
+				switch strings.Join(args, " ") {
+				case "AUTH monitor secret":
+					return "+OK\r\n"
+				case "INFO replication":
+					return fmt.Sprintf("$%d\r\n%s\r\n", len(replicaInfo), replicaInfo)
+				}
+				return "$0\r\n\r\n"
+			},
+			expect:   common.RedisSlave,
+			commands: []string{"AUTH monitor secret", "INFO replication", "INFO persistence", "INFO memory"},
+		},
+		{
+			name:  "sentinel",
+			guard: config.GuardItem{Mode: config.SentinelMode},
+			handler: func(args []string) string {
+				return fmt.Sprintf("$%d\r\n%s\r\n", len(sentinelInfo), sentinelInfo)
+			},
+			expect:   common.RedisSentinel,
+			commands: []string{"INFO sentinel"},
+		},
+		{
+			name:  "auth failed",
+			guard: config.GuardItem{Password: "wrong"},
+			handler: func(args []string) string {
+				return "-WRONGPASS invalid username-password pair\r\n"
+			},
+			expectErr: "auth redis failed, WRONGPASS",
+			commands:  []string{"AUTH wrong"},
+		},
+		{
+			name: "loading",
+			handler: func(args []string) string {
+				return "-LOADING Redis is loading the dataset in memory\r\n"
+			},
+			commands: []string{"INFO replication"},
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			serverPtr := newFakeRESPServer(t, val.handler)
+			guardPtr := val.guard
+			guardPtr.Name = "redis001"
+			guardPtr.Type = config.RedisGuard
+			guardPtr.Address = serverPtr.Address()
+			err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.Guards = []*config.GuardItem{&guardPtr} })
+			if err != nil {
+				t.Fatalf("load config failed, %v", err)
+			}
+			defer func() { _ = config.LoadConfig("") }()
+
+			ret, retErr := (&Redis{}).QueryRedisStatus("redis001")
+			if val.expectErr != "" {
+				if retErr == nil || !strings.Contains(retErr.Reason, val.expectErr) {
+					t.Errorf("err %v, expect %s", retErr, val.expectErr)
+				}
+			} else if retErr != nil {
+				t.Errorf("query failed, %s", retErr.Reason)
+			} else if ret.Role != val.expect || (val.expect == "" && !ret.Loading) {
+				t.Errorf("role %q, loading %v, expect %q", ret.Role, ret.Loading, val.expect)
+			}
+			if commands := serverPtr.Commands(); strings.Join(commands, ",") != strings.Join(val.commands, ",") {
+				t.Errorf("commands %q, expect %q", commands, val.commands)
+			}
+		})
+	}
+
+	if _, err := (&Redis{}).QueryRedisStatus("mariadb001"); err == nil {
+		t.Errorf("expect illegal guard error")
+	}
+}
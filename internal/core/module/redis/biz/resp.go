@@ -0,0 +1,133 @@
+package biz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// maxBulkSize 单个bulk string的最大长度，INFO输出远小于该值
+const maxBulkSize = 16 * 1024 * 1024
+
+// respError redis返回的错误回复
+type respError string
+
+func (s respError) Error() string {
+	return string(s)
+}
+
+// respConn 只实现请求-应答方式的RESP2协议，满足AUTH、PING、INFO等命令
+type respConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeOut time.Duration
+}
+
+func dialRESP(address string, timeOut time.Duration) (ret *respConn, err error) {
+	conn, err := net.DialTimeout("tcp", address, timeOut)
+	if err != nil {
+		return
+	}
+
+	ret = &respConn{conn: conn, reader: bufio.NewReader(conn), timeOut: timeOut}
+	return
+}
+
+func (s *respConn) Close() error {
+	return s.conn.Close()
+}
+
+// Do 发送命令并读取一个回复，错误回复以respError返回
+func (s *respConn) Do(args ...string) (ret interface{}, err error) {
+	_ = s.conn.SetDeadline(time.Now().Add(s.timeOut))
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, val := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(val))+"\r\n"...)
+		buf = append(buf, val...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err = s.conn.Write(buf); err != nil {
+		return
+	}
+
+	return s.readReply()
+}
+
+// readReply 简单字符串和bulk string返回string，整数返回int64，数组返回[]interface{}，空值返回nil
+func (s *respConn) readReply() (ret interface{}, err error) {
+	line, err := s.readLine()
+	if err != nil {
+		return
+	}
+	if len(line) == 0 {
+		err = fmt.Errorf("illegal RESP reply, empty line")
+		return
+	}
+
+	switch line[0] {
+	case '+':
+		ret = line[1:]
+	case '-':
+		err = respError(line[1:])
+	case ':':
+		ret, err = strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, sizeErr := strconv.Atoi(line[1:])
+		if sizeErr != nil || size > maxBulkSize {
+			err = fmt.Errorf("illegal RESP bulk size:%s", line[1:])
+			return
+		}
+		if size < 0 {
+			return
+		}
+
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(s.reader, data); err != nil {
+			return
+		}
+		ret = string(data[:size])
+	case '*':
+		count, countErr := strconv.Atoi(line[1:])
+		if countErr != nil {
+			err = fmt.Errorf("illegal RESP array size:%s", line[1:])
+			return
+		}
+		if count < 0 {
+			return
+		}
+
+		items := make([]interface{}, 0, count)
+		for idx := 0; idx < count; idx++ {
+			itemVal, itemErr := s.readReply()
+			if itemErr != nil {
+				if _, ok := itemErr.(respError); !ok {
+					err = itemErr
+					return
+				}
+				itemVal = itemErr
+			}
+			items = append(items, itemVal)
+		}
+		ret = items
+	default:
+		err = fmt.Errorf("illegal RESP reply type:%q", line[0])
+	}
+
+	return
+}
+
+func (s *respConn) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("illegal RESP line:%q", line)
+	}
+
+	return line[:len(line)-2], nil
+}
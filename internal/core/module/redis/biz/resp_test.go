@@ -0,0 +1,171 @@
+package biz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRESPServer 解析RESP命令并按handler返回原始回复，commands记录收到的命令
+type fakeRESPServer struct {
+	listener net.Listener
+	handler  func(args []string) string
+	lock     sync.Mutex
+	commands []string
+}
+
+func newFakeRESPServer(t *testing.T, handler func(args []string) string) *fakeRESPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed, %v", err)
+	}
+
+	serverPtr := &fakeRESPServer{listener: listener, handler: handler}
+	go serverPtr.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return serverPtr
+}
+
+func (s *fakeRESPServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRESPServer) Commands() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.commands...)
+}
+
+func (s *fakeRESPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			reader := bufio.NewReader(conn)
+			for {
+				args, argsErr := readCommand(reader)
+				if argsErr != nil {
+					return
+				}
+
+				s.lock.Lock()
+				s.commands = append(s.commands, strings.Join(args, " "))
+				s.lock.Unlock()
+				if _, writeErr := io.WriteString(conn, s.handler(args)); writeErr != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func readCommand(reader *bufio.Reader) (ret []string, err error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return
+	}
+
+	for idx := 0; idx < count; idx++ {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return
+		}
+		size, sizeErr := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if sizeErr != nil {
+			err = sizeErr
+			return
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return
+		}
+		ret = append(ret, string(data[:size]))
+	}
+	return
+}
+
+func TestRespConnDo(t *testing.T) {
+	cases := []struct {
+		name      string
+		reply     string
+		expect    interface{}
+		expectErr string
+	}{
+		{name: "simple string", reply: "+OK\r\n", expect: "OK"},
+		{name: "error", reply: "-ERR unknown command\r\n", expectErr: "ERR unknown command"},
+		{name: "integer", reply: ":42\r\n", expect: int64(42)},
+		{name: "bulk string", reply: "$11\r\nrole:master\r\n", expect: "role:master"},
+		{name: "empty bulk", reply: "$0\r\n\r\n", expect: ""},
+		{name: "null bulk", reply: "$-1\r\n", expect: nil},
+		{name: "null array", reply: "*-1\r\n", expect: nil},
+		{
+			name:   "nested array",
+			reply:  "*3\r\n+a\r\n*1\r\n:1\r\n-ERR item\r\n",
+			expect: []interface{}{"a", []interface{}{int64(1)}, respError("ERR item")},
+		},
+		{name: "bulk too large", reply: fmt.Sprintf("$%d\r\n", maxBulkSize+1), expectErr: "illegal RESP bulk size"},
+		{name: "illegal type", reply: "!3\r\n", expectErr: "illegal RESP reply type"},
+		{name: "missing CR", reply: "+OK\n", expectErr: "illegal RESP line"},
+		{name: "empty line", reply: "\r\n", expectErr: "empty line"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			serverPtr := newFakeRESPServer(t, func([]string) string { return val.reply })
+			connPtr, connErr := dialRESP(serverPtr.Address(), time.Second)
+			if connErr != nil {
+				t.Fatalf("dial failed, %v", connErr)
+			}
+			defer connPtr.Close()
+
+			ret, err := connPtr.Do("INFO", "replication")
+			if val.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), val.expectErr) {
+					t.Errorf("err %v, expect %s", err, val.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("do failed, %v", err)
+			}
+			if !reflect.DeepEqual(ret, val.expect) {
+				t.Errorf("reply %#v, expect %#v", ret, val.expect)
+			}
+			if commands := serverPtr.Commands(); len(commands) != 1 || commands[0] != "INFO replication" {
+				t.Errorf("commands %q", commands)
+			}
+		})
+	}
+}
+
+func TestRespConnTimeout(t *testing.T) {
+	serverPtr := newFakeRESPServer(t, func([]string) string {
+		time.Sleep(500 * time.Millisecond)
+		return "+OK\r\n"
+	})
+	connPtr, connErr := dialRESP(serverPtr.Address(), 100*time.Millisecond)
+	if connErr != nil {
+		t.Fatalf("dial failed, %v", connErr)
+	}
+	defer connPtr.Close()
+
+	if _, err := connPtr.Do("PING"); err == nil {
+		t.Errorf("expect timeout error")
+	}
+}
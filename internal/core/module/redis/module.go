@@ -0,0 +1,44 @@
+package redis
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/redis/biz"
+	"github.com/muidea/magicAgent/internal/core/module/redis/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Redis struct {
+	routeRegistry engine.Router
+
+	service *service.Redis
+	biz     *biz.Redis
+}
+
+func New() *Redis {
+	return &Redis{}
+}
+
+func (s *Redis) ID() string {
+	return common.RedisModule
+}
+
+func (s *Redis) BindRegistry(routeRegistry engine.Router) {
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Redis) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
@@ -0,0 +1,253 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+
+	_ "github.com/muidea/magicAgent/internal/runtime/docker"
+	_ "github.com/muidea/magicAgent/internal/runtime/kubernetes"
+	_ "github.com/muidea/magicAgent/internal/runtime/nerdctl"
+	_ "github.com/muidea/magicAgent/internal/runtime/podman"
+	_ "github.com/muidea/magicAgent/internal/runtime/systemd"
+)
+
+type Runtime struct {
+	biz.Base
+
+	runtimeLock sync.Mutex
+	runtimeMap  map[string]runtime.Runtime
+
+	watchCancel context.CancelFunc
+	eventLock   sync.RWMutex
+	eventList   []*common.ContainerEvent
+	logPath     string
+
+	terminalLock sync.Mutex
+	terminalMap  map[string]*TerminalSession
+	auditLock    sync.Mutex
+}
+
+func New(
+	eventHub event.Hub,
+	backgroundRoutine task.BackgroundRoutine,
+) *Runtime {
+	ptr := &Runtime{
+		Base:        biz.New(common.RuntimeModule, eventHub, backgroundRoutine),
+		runtimeMap:  map[string]runtime.Runtime{},
+		logPath:     path.Join(config.GetWorkPath(), "log"),
+		terminalMap: map[string]*TerminalSession{},
+	}
+
+	ptr.SubscribeFunc(common.ExecuteCommand, ptr.ExecuteCommand)
+	ptr.SubscribeFunc(common.StartService, ptr.StartService)
+	ptr.SubscribeFunc(common.StopService, ptr.StopService)
+	ptr.SubscribeFunc(common.RestartService, ptr.RestartService)
+	ptr.SubscribeFunc(common.InspectContainer, ptr.InspectContainer)
+	ptr.SubscribeFunc(common.QueryContainerLogs, ptr.QueryContainerLogs)
+	ptr.SubscribeFunc(common.StreamCommand, ptr.StreamCommand)
+	ptr.SubscribeFunc(common.RunCommand, ptr.RunCommand)
+	ptr.SubscribeFunc(common.AppendAudit, ptr.AppendAudit)
+	ptr.SubscribeJob(common.RestartJob, ptr.restartJob)
+	ptr.SubscribeJob(common.UpgradeJob, ptr.upgradeJob)
+	ptr.SubscribeJob(common.RollingUpgradeJob, ptr.rollingUpgradeJob)
+	return ptr
+}
+
+// getRuntime 根据守护对象配置选择运行时，未配置的服务使用docker
+func (s *Runtime) getRuntime(serviceName string) (ret runtime.Runtime, err *cd.Result) {
+	return s.guardRuntime(config.GetGuard(serviceName))
+}
+
+func (s *Runtime) guardRuntime(guardPtr *config.GuardItem) (ret runtime.Runtime, err *cd.Result) {
+	option := &runtime.Option{
+		TimeOut:         time.Duration(config.GetTimeOut()) * time.Second,
+		Executor:        s.ExecuteContext,
+		StreamExecutor:  s.ExecuteStream,
+		TerminalStarter: s.StartTerminal,
+	}
+	runtimeName := common.DockerRuntime
+	if guardPtr != nil {
+		if guardPtr.Runtime != "" {
+			runtimeName = guardPtr.Runtime
+		}
+		option.Endpoint = guardPtr.Endpoint
+		option.Namespace = guardPtr.Namespace
+		option.Selector = guardPtr.Selector
+	}
+
+	runtimeKey := runtimeName + "|" + option.Endpoint + "|" + option.Namespace + "|" + option.Selector
+
+	s.runtimeLock.Lock()
+	defer s.runtimeLock.Unlock()
+	ret, ok := s.runtimeMap[runtimeKey]
+	if ok {
+		return
+	}
+
+	ret, err = runtime.New(runtimeName, option)
+	if err != nil {
+		return
+	}
+
+	s.runtimeMap[runtimeKey] = ret
+	return
+}
+
+func (s *Runtime) Start(serviceName string) (stdout, stderr string, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Start(serviceName)
+}
+
+func (s *Runtime) Stop(serviceName string) (stdout, stderr string, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Stop(serviceName)
+}
+
+func (s *Runtime) Restart(serviceName string) (stdout, stderr string, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Restart(serviceName)
+}
+
+// ExecOption 转换执行参数，TimeOut单位为秒
+func ExecOption(param *common.ServiceParam) *runtime.ExecOption {
+	return &runtime.ExecOption{
+		Env:     param.Env,
+		WorkDir: param.WorkDir,
+		TimeOut: time.Duration(param.TimeOut) * time.Second,
+	}
+}
+
+func (s *Runtime) Exec(serviceName, execParam string, option *runtime.ExecOption) (ret *runtime.ExecResult, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Exec(serviceName, execParam, option)
+}
+
+func (s *Runtime) ExecStream(param *runtime.StreamParam) (err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(param.Service)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	streamer, ok := runtimePtr.(runtime.Streamer)
+	if !ok {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support stream command", runtimePtr.Name()))
+		return
+	}
+
+	return streamer.ExecStream(param.Context, param.Service, param.Command, param.Option, param.Stdout, param.Stderr)
+}
+
+func (s *Runtime) RunOnce(param *runtime.RunParam) (err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(param.Service)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	runner, ok := runtimePtr.(runtime.Runner)
+	if !ok {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support run command", runtimePtr.Name()))
+		return
+	}
+
+	return runner.Run(param.Context, param.Service, param.Command, param.Option, param.Stdout, param.Stderr)
+}
+
+func (s *Runtime) Inspect(serviceName string) (ret *common.ContainerInfo, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Inspect(serviceName)
+}
+
+func (s *Runtime) Logs(ctx context.Context, serviceName string, option *runtime.LogOption, stdout, stderr io.Writer) (err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Logs(ctx, serviceName, option, stdout, stderr)
+}
+
+func (s *Runtime) Stats(serviceName string) (ret *common.ContainerStats, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	return runtimePtr.Stats(serviceName)
+}
+
+// List 列出守护对象所用运行时中的全部容器，runtimeName非空时只列出默认配置下的该运行时
+func (s *Runtime) List(runtimeName string) (ret []*common.ContainerInfo, err *cd.Result) {
+	guards := []*config.GuardItem{}
+	if runtimeName != "" {
+		guards = append(guards, &config.GuardItem{Runtime: runtimeName})
+	} else {
+		guards = append(guards, config.GetGuards()...)
+	}
+	if len(guards) == 0 {
+		guards = append(guards, nil)
+	}
+
+	listed := map[runtime.Runtime]bool{}
+	for _, val := range guards {
+		runtimePtr, runtimeErr := s.guardRuntime(val)
+		if runtimeErr != nil {
+			err = runtimeErr
+			return
+		}
+		if listed[runtimePtr] {
+			continue
+		}
+		listed[runtimePtr] = true
+
+		containers, listErr := runtimePtr.List()
+		if listErr != nil {
+			err = listErr
+			return
+		}
+		ret = append(ret, containers...)
+	}
+
+	return
+}
@@ -0,0 +1,179 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// maxEventSize 内存中保留的容器事件数量
+const maxEventSize = 500
+
+// watchRetryInterval 事件流中断后重新订阅的间隔
+const watchRetryInterval = 5 * time.Second
+
+// StartWatch 订阅守护对象所用运行时的容器事件，不支持事件的运行时忽略
+func (s *Runtime) StartWatch() {
+	s.loadEventHistory()
+
+	guards := config.GetGuards()
+	if len(guards) == 0 {
+		guards = append(guards, nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.watchCancel = cancel
+
+	watched := map[runtime.Runtime]bool{}
+	for _, val := range guards {
+		runtimePtr, runtimeErr := s.guardRuntime(val)
+		if runtimeErr != nil || watched[runtimePtr] {
+			continue
+		}
+		watched[runtimePtr] = true
+
+		watcher, ok := runtimePtr.(runtime.Watcher)
+		if !ok {
+			continue
+		}
+
+		go s.watch(ctx, runtimePtr.Name(), watcher)
+	}
+}
+
+// StopWatch 停止订阅容器事件
+func (s *Runtime) StopWatch() {
+	if s.watchCancel != nil {
+		s.watchCancel()
+	}
+}
+
+// watch 持续订阅容器事件，中断后从最后一个事件的时间重新订阅
+func (s *Runtime) watch(ctx context.Context, runtimeName string, watcher runtime.Watcher) {
+	since := time.Now()
+	for {
+		log.Infof("watch %s container events", runtimeName)
+		err := watcher.Watch(ctx, since, func(eventPtr *common.ContainerEvent) {
+			if eventPtr.TimeStamp.After(since) {
+				since = eventPtr.TimeStamp
+			}
+
+			s.notifyEvent(eventPtr)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Errorf("watch %s container events failed, error:%s", runtimeName, err.Error())
+			if err.ErrorCode == cd.IllegalParam {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// notifyEvent 记录容器事件并广播到事件中心
+func (s *Runtime) notifyEvent(eventPtr *common.ContainerEvent) {
+	log.Infof("container event, name:%s, action:%s, health:%s, exitCode:%d", eventPtr.Name, eventPtr.Action, eventPtr.Health, eventPtr.ExitCode)
+
+	s.appendEventHistory(eventPtr)
+	s.BroadCast(common.NotifyContainerEvent, nil, eventPtr)
+}
+
+// QueryEvents 查询容器事件，按时间倒序，count<=0时返回全部
+func (s *Runtime) QueryEvents(count int) []*common.ContainerEvent {
+	s.eventLock.RLock()
+	defer s.eventLock.RUnlock()
+
+	ret := []*common.ContainerEvent{}
+	for idx := len(s.eventList) - 1; idx >= 0; idx-- {
+		if count > 0 && len(ret) >= count {
+			break
+		}
+
+		ret = append(ret, s.eventList[idx])
+	}
+
+	return ret
+}
+
+func (s *Runtime) eventFilePath() string {
+	return path.Join(s.logPath, "event.json")
+}
+
+// loadEventHistory 启动时加载历史容器事件
+func (s *Runtime) loadEventHistory() {
+	fileHandle, fileErr := os.Open(s.eventFilePath())
+	if fileErr != nil {
+		return
+	}
+	defer fileHandle.Close()
+
+	eventList := []*common.ContainerEvent{}
+	scanner := bufio.NewScanner(fileHandle)
+	for scanner.Scan() {
+		eventPtr := &common.ContainerEvent{}
+		if json.Unmarshal(scanner.Bytes(), eventPtr) != nil {
+			continue
+		}
+
+		eventList = append(eventList, eventPtr)
+		if len(eventList) > maxEventSize {
+			eventList = eventList[1:]
+		}
+	}
+
+	s.eventLock.Lock()
+	defer s.eventLock.Unlock()
+	s.eventList = eventList
+}
+
+func (s *Runtime) appendEventHistory(eventPtr *common.ContainerEvent) {
+	func() {
+		s.eventLock.Lock()
+		defer s.eventLock.Unlock()
+
+		s.eventList = append(s.eventList, eventPtr)
+		if len(s.eventList) > maxEventSize {
+			s.eventList = s.eventList[len(s.eventList)-maxEventSize:]
+		}
+	}()
+
+	byteVal, byteErr := json.Marshal(eventPtr)
+	if byteErr != nil {
+		log.Errorf("appendEventHistory failed, marshal event failed, error:%s", byteErr.Error())
+		return
+	}
+
+	logFullPath := s.eventFilePath()
+	logPath, _ := path.Split(logFullPath)
+	_ = os.MkdirAll(logPath, os.ModePerm)
+
+	fileHandle, fileErr := os.OpenFile(logFullPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if fileErr != nil {
+		log.Errorf("appendEventHistory failed, open file %s failed, error:%s", logFullPath, fileErr.Error())
+		return
+	}
+	defer fileHandle.Close()
+
+	_, writeErr := fileHandle.Write(append(byteVal, '\n'))
+	if writeErr != nil {
+		log.Errorf("appendEventHistory failed, write event failed, error:%s", writeErr.Error())
+	}
+}
@@ -0,0 +1,129 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// fakeWatcher 依次上报预设的事件后返回预设的错误
+type fakeWatcher struct {
+	events []*common.ContainerEvent
+	err    *cd.Result
+	since  []time.Time
+}
+
+func (s *fakeWatcher) Watch(_ context.Context, since time.Time, handler runtime.EventHandler) *cd.Result {
+	s.since = append(s.since, since)
+	for _, val := range s.events {
+		handler(val)
+	}
+	return s.err
+}
+
+func newTestRuntime(logPath string) *Runtime {
+	// 广播过事件的事件中心Terminate时可能阻塞，测试中不终止
+	eventHub := event.NewHub(10)
+
+	ptr := New(eventHub, task.NewBackgroundRoutine(10))
+	ptr.logPath = logPath
+	return ptr
+}
+
+func TestEventHistory(t *testing.T) {
+	logPath := path.Join(t.TempDir(), "log")
+	runtimePtr := newTestRuntime(logPath)
+	for idx := 0; idx < 3; idx++ {
+		runtimePtr.notifyEvent(&common.ContainerEvent{Name: fmt.Sprintf("db%d", idx), Action: common.ContainerDie})
+	}
+
+	cases := []struct {
+		name   string
+		count  int
+		expect string
+	}{
+		{name: "all", count: 0, expect: "db2,db1,db0"},
+		{name: "latest", count: 2, expect: "db2,db1"},
+		{name: "more than total", count: 10, expect: "db2,db1,db0"},
+	}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := eventNames(runtimePtr.QueryEvents(val.count)); ret != val.expect {
+				t.Errorf("events %q, expect %q", ret, val.expect)
+			}
+		})
+	}
+
+	reloadPtr := newTestRuntime(logPath)
+	reloadPtr.loadEventHistory()
+	if ret := eventNames(reloadPtr.QueryEvents(0)); ret != "db2,db1,db0" {
+		t.Errorf("reload events %q, expect db2,db1,db0", ret)
+	}
+}
+
+func TestLoadEventHistoryLimit(t *testing.T) {
+	logPath := t.TempDir()
+	eventFile := path.Join(logPath, "event.json")
+	content := &strings.Builder{}
+	content.WriteString("illegal\n")
+	for idx := 0; idx < maxEventSize+5; idx++ {
+		fmt.Fprintf(content, "{\"name\":\"db%d\",\"action\":\"die\"}\n", idx)
+	}
+	if err := os.WriteFile(eventFile, []byte(content.String()), 0600); err != nil {
+		t.Fatalf("write event file failed, %v", err)
+	}
+
+	runtimePtr := newTestRuntime(logPath)
+	runtimePtr.loadEventHistory()
+	events := runtimePtr.QueryEvents(0)
+	if len(events) != maxEventSize {
+		t.Fatalf("event size %d, expect %d", len(events), maxEventSize)
+	}
+	if events[0].Name != fmt.Sprintf("db%d", maxEventSize+4) || events[len(events)-1].Name != "db5" {
+		t.Errorf("events from %s to %s", events[0].Name, events[len(events)-1].Name)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	timeStamp := time.Now().Add(time.Hour)
+	watcher := &fakeWatcher{
+		events: []*common.ContainerEvent{{Name: "db", Action: common.ContainerOOM, TimeStamp: timeStamp}},
+		err:    cd.NewError(cd.IllegalParam, "not support events"),
+	}
+
+	runtimePtr := newTestRuntime(t.TempDir())
+	runtimePtr.watch(context.Background(), "docker", watcher)
+	if len(watcher.since) != 1 {
+		t.Errorf("watch %d times, expect 1", len(watcher.since))
+	}
+	if ret := eventNames(runtimePtr.QueryEvents(0)); ret != "db" {
+		t.Errorf("events %q, expect db", ret)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelWatcher := &fakeWatcher{err: cd.NewError(cd.UnExpected, "stream closed")}
+	runtimePtr.watch(ctx, "docker", cancelWatcher)
+	if len(cancelWatcher.since) != 1 {
+		t.Errorf("watch %d times after cancel, expect 1", len(cancelWatcher.since))
+	}
+}
+
+func eventNames(events []*common.ContainerEvent) string {
+	names := []string{}
+	for _, val := range events {
+		names = append(names, val.Name)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,48 @@
+package biz
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+)
+
+// restartJob 重启服务并等待服务恢复运行，等待时间为配置的超时时间
+func (s *Runtime) restartJob(jobCtx biz.JobContext) *cd.Result {
+	serviceName := jobCtx.Param().Service
+	if serviceName == "" {
+		return cd.NewError(cd.IllegalParam, "illegal service name")
+	}
+
+	jobCtx.SetProgress(10, fmt.Sprintf("restarting %s", serviceName))
+	stdout, stderr, err := s.Restart(serviceName)
+	_, _ = io.WriteString(jobCtx, stdout)
+	_, _ = io.WriteString(jobCtx, stderr)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.SetProgress(50, fmt.Sprintf("waiting for %s running", serviceName))
+	timeOut := time.Duration(config.GetTimeOut()) * time.Second
+	deadline := time.Now().Add(timeOut)
+	for {
+		containerPtr, containerErr := s.Inspect(serviceName)
+		if containerErr == nil && containerPtr.IsRunning() {
+			jobCtx.SetProgress(100, fmt.Sprintf("%s is running", serviceName))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("%s not running after %v", serviceName, timeOut))
+		}
+
+		select {
+		case <-jobCtx.Done():
+			return cd.NewError(cd.UnExpected, "job canceled")
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/pkg/client"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// maxPeerQueryFailure 连续查询节点任务失败的最大次数
+const maxPeerQueryFailure = 10
+
+// nodeJobContext 单个节点的升级进度映射到整体进度的[begin, end]区间
+type nodeJobContext struct {
+	biz.JobContext
+	host       string
+	begin, end int
+}
+
+func (s *nodeJobContext) SetProgress(progress int, message string) {
+	s.JobContext.SetProgress(s.begin+(s.end-s.begin)*progress/100, s.host+": "+message)
+}
+
+// upgradeHosts 默认升级顺序为localHost、clusterHosts
+func upgradeHosts() []string {
+	hosts := []string{}
+	existed := map[string]bool{}
+	for _, val := range append([]string{config.GetLocalHost()}, config.GetClusterHosts()...) {
+		if val == "" {
+			continue
+		}
+
+		address := config.AgentAddress(val)
+		if !existed[address] {
+			existed[address] = true
+			hosts = append(hosts, address)
+		}
+	}
+
+	return hosts
+}
+
+// rollingUpgradeJob 按顺序逐个节点升级，上一节点通过健康检查后才升级下一节点，
+// 任一节点失败时中止，失败节点已回滚，之前的节点保持新版本
+func (s *Runtime) rollingUpgradeJob(jobCtx biz.JobContext) *cd.Result {
+	serviceName := jobCtx.Param().Service
+	if serviceName == "" {
+		return cd.NewError(cd.IllegalParam, "illegal service name")
+	}
+
+	param := &common.RollingUpgradeParam{}
+	if err := unmarshalJobParam(jobCtx, param); err != nil {
+		return err
+	}
+	if param.Image == "" {
+		return cd.NewError(cd.IllegalParam, "illegal image")
+	}
+
+	hosts := upgradeHosts()
+	if len(param.Hosts) > 0 {
+		hosts = []string{}
+		for _, val := range param.Hosts {
+			hosts = append(hosts, config.AgentAddress(val))
+		}
+	}
+	if len(hosts) == 0 {
+		return cd.NewError(cd.IllegalParam, "no host to upgrade")
+	}
+
+	localAddress := config.AgentAddress(config.GetLocalHost())
+	for idx, host := range hosts {
+		_, _ = fmt.Fprintf(jobCtx, "==> upgrade %s on %s (%d/%d)\n", serviceName, host, idx+1, len(hosts))
+		nodeCtx := &nodeJobContext{
+			JobContext: jobCtx,
+			host:       host,
+			begin:      idx * 100 / len(hosts),
+			end:        (idx + 1) * 100 / len(hosts),
+		}
+
+		var err *cd.Result
+		if host == localAddress {
+			err = s.upgradeService(nodeCtx, serviceName, &param.UpgradeParam)
+		} else {
+			err = s.upgradePeer(nodeCtx, host, serviceName, &param.UpgradeParam)
+		}
+		if err != nil {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("upgrade %s on %s failed, %s, %d of %d hosts upgraded", serviceName, host, err.Reason, idx, len(hosts)))
+		}
+	}
+
+	jobCtx.SetProgress(100, fmt.Sprintf("%s upgraded to %s on %d hosts", serviceName, param.Image, len(hosts)))
+	return nil
+}
+
+// upgradePeer 在其他节点上创建升级任务并等待其结束，任务取消时同时取消节点任务并等待其回滚
+func (s *Runtime) upgradePeer(nodeCtx *nodeJobContext, host, serviceName string, param *common.UpgradeParam) *cd.Result {
+	byteVal, _ := json.Marshal(param)
+	clnt := client.NewClient(host, client.WithToken(config.GetAdminToken()))
+	jobPtr, jobErr := clnt.CreateJob(nodeCtx, &common.JobParam{Type: common.UpgradeJob, Service: serviceName, Param: byteVal})
+	if jobErr != nil {
+		return jobErr
+	}
+	_, _ = fmt.Fprintf(nodeCtx, "created job %s on %s\n", jobPtr.ID, host)
+
+	infoPtr, err := waitPeerJob(nodeCtx, nodeCtx, clnt, jobPtr.ID)
+	if err != nil {
+		return err
+	}
+
+	canceled := infoPtr == nil
+	if canceled {
+		_, _ = clnt.CancelJob(context.Background(), jobPtr.ID)
+
+		// 节点任务回滚需要时间，单独设置等待期限
+		ctx, cancel := context.WithTimeout(context.Background(), upgradeTimeOut(param))
+		infoPtr, err = waitPeerJob(ctx, nodeCtx, clnt, jobPtr.ID)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if infoPtr == nil {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("job %s on %s not finished after cancel", jobPtr.ID, host))
+		}
+	}
+
+	_, _ = io.WriteString(nodeCtx, infoPtr.Output)
+	switch {
+	case canceled:
+		return cd.NewError(cd.UnExpected, "job canceled")
+	case infoPtr.Status == common.JobSucceeded:
+		return nil
+	case infoPtr.Error != nil:
+		return infoPtr.Error
+	default:
+		return cd.NewError(cd.UnExpected, fmt.Sprintf("job %s on %s %s", jobPtr.ID, host, infoPtr.Status))
+	}
+}
+
+// waitPeerJob 轮询节点任务直到结束，ctx结束时返回的任务信息为nil
+func waitPeerJob(ctx context.Context, nodeCtx *nodeJobContext, clnt *client.Client, jobID string) (ret *common.JobInfo, err *cd.Result) {
+	failureCount := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(upgradeCheckInterval):
+		}
+
+		infoPtr, infoErr := clnt.QueryJob(ctx, jobID)
+		if infoErr != nil {
+			failureCount++
+			if failureCount >= maxPeerQueryFailure {
+				err = cd.NewError(cd.UnExpected, fmt.Sprintf("query job %s on %s failed, %s", jobID, nodeCtx.host, infoErr.Reason))
+				return
+			}
+			continue
+		}
+		failureCount = 0
+
+		nodeCtx.SetProgress(infoPtr.Progress, infoPtr.Message)
+		if infoPtr.IsFinished() {
+			ret = infoPtr
+			return
+		}
+	}
+}
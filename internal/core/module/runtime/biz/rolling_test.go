@@ -0,0 +1,219 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// fakeJobContext 记录任务输出和进度
+type fakeJobContext struct {
+	context.Context
+	param *common.JobParam
+
+	lock     sync.Mutex
+	output   strings.Builder
+	progress int
+	message  string
+}
+
+func (s *fakeJobContext) Write(data []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.output.Write(data)
+}
+
+func (s *fakeJobContext) JobID() string {
+	return "job001"
+}
+
+func (s *fakeJobContext) Param() *common.JobParam {
+	return s.param
+}
+
+func (s *fakeJobContext) SetProgress(progress int, message string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.progress = progress
+	s.message = message
+}
+
+func TestNodeProgress(t *testing.T) {
+	cases := []struct {
+		name          string
+		begin, end    int
+		progress      int
+		expect        int
+		expectMessage string
+	}{
+		{name: "first node begin", begin: 0, end: 33, progress: 0, expect: 0},
+		{name: "first node half", begin: 0, end: 50, progress: 50, expect: 25},
+		{name: "last node done", begin: 66, end: 100, progress: 100, expect: 100},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			jobCtx := &fakeJobContext{Context: context.Background()}
+			nodeCtx := &nodeJobContext{JobContext: jobCtx, host: "10.0.0.2:8080", begin: val.begin, end: val.end}
+			nodeCtx.SetProgress(val.progress, "pull image")
+			if jobCtx.progress != val.expect || jobCtx.message != "10.0.0.2:8080: pull image" {
+				t.Errorf("progress %d, message %q, expect %d", jobCtx.progress, jobCtx.message, val.expect)
+			}
+		})
+	}
+}
+
+func TestUpgradeHosts(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) {
+		cfg.LocalHost = "10.0.0.1"
+		cfg.ClusterHosts = []string{"10.0.0.2", "10.0.0.1", "10.0.0.3:9000", "10.0.0.2:8080"}
+	})
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	hosts := upgradeHosts()
+	if strings.Join(hosts, ",") != "10.0.0.1:8080,10.0.0.2:8080,10.0.0.3:9000" {
+		t.Errorf("hosts %v", hosts)
+	}
+}
+
+func TestRollingUpgradeParam(t *testing.T) {
+	cases := []struct {
+		name   string
+		param  *common.JobParam
+		expect string
+	}{
+		{name: "no service", param: &common.JobParam{Param: json.RawMessage(`{"image":"db:2"}`)}, expect: "illegal service name"},
+		{name: "illegal param", param: &common.JobParam{Service: "db", Param: json.RawMessage(`[]`)}, expect: "illegal job param"},
+		{name: "no image", param: &common.JobParam{Service: "db", Param: json.RawMessage(`{}`)}, expect: "illegal image"},
+	}
+
+	runtimePtr := &Runtime{}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			err := runtimePtr.rollingUpgradeJob(&fakeJobContext{Context: context.Background(), param: val.param})
+			if err == nil || err.ErrorCode != cd.IllegalParam || !strings.HasPrefix(err.Reason, val.expect) {
+				t.Errorf("error %v, expect %q", err, val.expect)
+			}
+		})
+	}
+}
+
+// fakeAgent 模拟节点agent的任务接口，statuses为依次返回的任务状态，取消后返回canceled
+type fakeAgent struct {
+	lock     sync.Mutex
+	statuses []string
+	canceled bool
+	token    string
+}
+
+func (s *fakeAgent) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.token = req.Header.Get("Authorization")
+	result := &common.JobResult{Job: &common.JobInfo{ID: "j1", Type: common.UpgradeJob, Status: common.JobRunning}}
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/job"):
+		result.Job.Status = common.JobPending
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/job/j1/cancel"):
+		s.canceled = true
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/job/j1"):
+		switch {
+		case s.canceled:
+			result.Job.Status = common.JobCanceled
+			result.Job.Output = "rollback\n"
+		case len(s.statuses) > 0:
+			result.Job.Status = s.statuses[0]
+			result.Job.Progress = 50
+			if len(s.statuses) > 1 {
+				s.statuses = s.statuses[1:]
+			}
+			if result.Job.Status == common.JobFailed {
+				result.Job.Error = cd.NewError(cd.UnExpected, "health check failed")
+			}
+			if result.Job.IsFinished() {
+				result.Job.Output = "upgraded\n"
+			}
+		}
+	default:
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	byteVal, _ := json.Marshal(result)
+	_, _ = res.Write(byteVal)
+}
+
+func TestUpgradePeer(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	interval := upgradeCheckInterval
+	upgradeCheckInterval = 10 * time.Millisecond
+	defer func() { upgradeCheckInterval = interval }()
+
+	cases := []struct {
+		name         string
+		statuses     []string
+		cancel       bool
+		expectErr    string
+		expectOutput string
+	}{
+		{name: "succeeded", statuses: []string{common.JobRunning, common.JobSucceeded}, expectOutput: "upgraded\n"},
+		{name: "failed", statuses: []string{common.JobFailed}, expectErr: "health check failed", expectOutput: "upgraded\n"},
+		{name: "canceled", statuses: []string{common.JobRunning}, cancel: true, expectErr: "job canceled", expectOutput: "rollback\n"},
+	}
+
+	runtimePtr := &Runtime{}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			agent := &fakeAgent{statuses: val.statuses}
+			server := httptest.NewServer(agent)
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			jobCtx := &fakeJobContext{Context: ctx}
+			host := strings.TrimPrefix(server.URL, "http://")
+			nodeCtx := &nodeJobContext{JobContext: jobCtx, host: host, begin: 0, end: 100}
+			if val.cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			err := runtimePtr.upgradePeer(nodeCtx, host, "db", &common.UpgradeParam{Image: "db:2", TimeOut: 5})
+			if val.expectErr == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if val.expectErr != "" && (err == nil || err.Reason != val.expectErr) {
+				t.Fatalf("error %v, expect %q", err, val.expectErr)
+			}
+
+			expectOutput := fmt.Sprintf("created job j1 on %s\n%s", host, val.expectOutput)
+			if jobCtx.output.String() != expectOutput {
+				t.Errorf("output %q, expect %q", jobCtx.output.String(), expectOutput)
+			}
+			if agent.token != "Bearer admin-token" || agent.canceled != val.cancel {
+				t.Errorf("token %q, canceled %v", agent.token, agent.canceled)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func (s *Runtime) ExecuteCommand(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("ExecuteCommand failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(*common.ServiceParam)
+	if !paramOK {
+		log.Warnf("ExecuteCommand failed, illegal param")
+		return
+	}
+
+	resultPtr, resultErr := s.Exec(paramVal.Service, paramVal.CmdParam, ExecOption(paramVal))
+	if resultPtr == nil {
+		resultPtr = &runtime.ExecResult{ExitCode: -1}
+	}
+	if re != nil {
+		re.Set([]byte(resultPtr.Stdout), resultErr)
+		re.SetVal("stderr", []byte(resultPtr.Stderr))
+		re.SetVal("exitCode", resultPtr.ExitCode)
+	}
+}
+
+func (s *Runtime) StartService(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("StartService failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(string)
+	if !paramOK {
+		log.Warnf("StartService failed, illegal param")
+		return
+	}
+
+	resultVal, errorVal, resultErr := s.Start(paramVal)
+	if re != nil {
+		re.Set([]byte(resultVal), resultErr)
+		re.SetVal("stderr", []byte(errorVal))
+	}
+}
+
+func (s *Runtime) StopService(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("StopService failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(string)
+	if !paramOK {
+		log.Warnf("StopService failed, illegal param")
+		return
+	}
+
+	resultVal, errorVal, resultErr := s.Stop(paramVal)
+	if re != nil {
+		re.Set([]byte(resultVal), resultErr)
+		re.SetVal("stderr", []byte(errorVal))
+	}
+}
+
+func (s *Runtime) RestartService(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("RestartService failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(string)
+	if !paramOK {
+		log.Warnf("RestartService failed, illegal param")
+		return
+	}
+
+	resultVal, errorVal, resultErr := s.Restart(paramVal)
+	if re != nil {
+		re.Set([]byte(resultVal), resultErr)
+		re.SetVal("stderr", []byte(errorVal))
+	}
+}
+
+func (s *Runtime) InspectContainer(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("InspectContainer failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(string)
+	if !paramOK {
+		log.Warnf("InspectContainer failed, illegal param")
+		return
+	}
+
+	infoPtr, infoErr := s.Inspect(paramVal)
+	if re != nil {
+		re.Set(infoPtr, infoErr)
+	}
+}
+
+// QueryContainerLogs 读取最近的服务日志，stdout和stderr按输出顺序合并
+func (s *Runtime) QueryContainerLogs(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("QueryContainerLogs failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(*common.ContainerLogParam)
+	if !paramOK {
+		log.Warnf("QueryContainerLogs failed, illegal param")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GetTimeOut())*time.Second)
+	defer cancel()
+
+	logBuffer := &bytes.Buffer{}
+	logErr := s.Logs(ctx, paramVal.Service, &runtime.LogOption{Tail: paramVal.Tail}, logBuffer, logBuffer)
+	if re != nil {
+		re.Set(logBuffer.String(), logErr)
+	}
+}
+
+// StreamCommand 流式执行命令，命令输出直接写入参数中的stdout、stderr
+func (s *Runtime) StreamCommand(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("StreamCommand failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(*runtime.StreamParam)
+	if !paramOK {
+		log.Warnf("StreamCommand failed, illegal param")
+		return
+	}
+
+	streamErr := s.ExecStream(paramVal)
+	if re != nil {
+		re.Set(nil, streamErr)
+	}
+}
+
+// RunCommand 在临时容器中执行命令，命令输出直接写入参数中的stdout、stderr
+func (s *Runtime) RunCommand(ev event.Event, re event.Result) {
+	param := ev.Data()
+	if param == nil {
+		log.Warnf("RunCommand failed, nil param")
+		return
+	}
+
+	paramVal, paramOK := param.(*runtime.RunParam)
+	if !paramOK {
+		log.Warnf("RunCommand failed, illegal param")
+		return
+	}
+
+	runErr := s.RunOnce(paramVal)
+	if re != nil {
+		re.Set(nil, runErr)
+	}
+}
@@ -0,0 +1,327 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// TerminalSession 交互式会话，输入、输出和窗口调整按asciicast v2格式写入会话记录
+type TerminalSession struct {
+	terminal runtime.Terminal
+	owner    *Runtime
+	record   *common.AuditRecord
+
+	castLock  sync.Mutex
+	castFile  *os.File
+	startTime time.Time
+	waitOnce  sync.Once
+}
+
+func (s *TerminalSession) ID() string {
+	return s.record.ID
+}
+
+func (s *TerminalSession) Read(data []byte) (int, error) {
+	size, err := s.terminal.Read(data)
+	if size > 0 {
+		s.writeCast("o", string(data[:size]))
+	}
+
+	return size, err
+}
+
+func (s *TerminalSession) Write(data []byte) (int, error) {
+	s.writeCast("i", string(data))
+	return s.terminal.Write(data)
+}
+
+func (s *TerminalSession) Resize(rows, cols uint16) error {
+	s.writeCast("r", fmt.Sprintf("%dx%d", cols, rows))
+	return s.terminal.Resize(rows, cols)
+}
+
+func (s *TerminalSession) CloseWrite() error {
+	return s.terminal.CloseWrite()
+}
+
+// Close 结束会话中的命令，审计记录在Wait返回时完成
+func (s *TerminalSession) Close() error {
+	return s.terminal.Close()
+}
+
+// Wait 等待命令结束并完成审计记录
+func (s *TerminalSession) Wait() (exitCode int, err *cd.Result) {
+	exitCode, err = s.terminal.Wait()
+	s.waitOnce.Do(func() {
+		s.castLock.Lock()
+		s.castFile.Close()
+		s.castFile = nil
+		s.castLock.Unlock()
+
+		finishTime := time.Now()
+		s.record.FinishTime = &finishTime
+		s.record.ExitCode = exitCode
+		s.record.Error = err
+		s.owner.finishTerminal(s)
+	})
+
+	return
+}
+
+func (s *TerminalSession) writeCast(castType, data string) {
+	elapsed := math.Round(time.Since(s.startTime).Seconds()*1e6) / 1e6
+	byteVal, byteErr := json.Marshal([]interface{}{elapsed, castType, data})
+	if byteErr != nil {
+		return
+	}
+
+	s.castLock.Lock()
+	defer s.castLock.Unlock()
+	if s.castFile == nil {
+		return
+	}
+
+	_, writeErr := s.castFile.Write(append(byteVal, '\n'))
+	if writeErr != nil {
+		log.Errorf("write terminal transcript failed, id:%s, error:%s", s.record.ID, writeErr.Error())
+	}
+}
+
+func newSessionID() string {
+	randVal := make([]byte, 4)
+	_, _ = rand.Read(randVal)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(randVal)
+}
+
+func (s *Runtime) auditFilePath() string {
+	return path.Join(s.logPath, "audit.json")
+}
+
+func (s *Runtime) transcriptFilePath(id string) string {
+	return path.Join(s.logPath, "audit", id+".cast")
+}
+
+// OpenTerminal 在服务中启动交互式会话，会话记录无法写入时拒绝打开会话
+func (s *Runtime) OpenTerminal(ctx context.Context, param *common.TerminalParam, remoteAddr string) (ret *TerminalSession, err *cd.Result) {
+	runtimePtr, runtimeErr := s.getRuntime(param.Service)
+	if runtimeErr != nil {
+		err = runtimeErr
+		return
+	}
+
+	attacher, ok := runtimePtr.(runtime.Attacher)
+	if !ok {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support terminal", runtimePtr.Name()))
+		return
+	}
+
+	command := param.Command
+	if len(command) == 0 {
+		command = runtime.DefaultTerminalCommand
+	}
+	recordPtr := &common.AuditRecord{
+		ID:         newSessionID(),
+		Service:    param.Service,
+		Command:    command,
+		WorkDir:    param.WorkDir,
+		TTY:        param.TTY,
+		RemoteAddr: remoteAddr,
+		StartTime:  time.Now(),
+	}
+	recordPtr.Transcript = s.transcriptFilePath(recordPtr.ID)
+
+	castFile, castErr := createTranscript(recordPtr, param)
+	if castErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("create terminal transcript failed, %s", castErr.Error()))
+		return
+	}
+
+	option := &runtime.TerminalOption{
+		Env:     param.Env,
+		WorkDir: param.WorkDir,
+		TTY:     param.TTY,
+		Rows:    param.Rows,
+		Cols:    param.Cols,
+	}
+	terminalPtr, terminalErr := attacher.Attach(ctx, param.Service, command, option)
+	if terminalErr != nil {
+		castFile.Close()
+		_ = os.Remove(recordPtr.Transcript)
+		err = terminalErr
+		return
+	}
+
+	ret = &TerminalSession{
+		terminal:  terminalPtr,
+		owner:     s,
+		record:    recordPtr,
+		castFile:  castFile,
+		startTime: recordPtr.StartTime,
+	}
+
+	s.terminalLock.Lock()
+	s.terminalMap[recordPtr.ID] = ret
+	s.terminalLock.Unlock()
+
+	log.Warnf("open terminal, id:%s, service:%s, command:%s, remote:%s", recordPtr.ID, recordPtr.Service, strings.Join(command, " "), remoteAddr)
+	s.appendAuditRecord(recordPtr)
+	return
+}
+
+// createTranscript 创建会话记录文件并写入asciicast v2文件头
+func createTranscript(recordPtr *common.AuditRecord, param *common.TerminalParam) (ret *os.File, err error) {
+	err = os.MkdirAll(path.Dir(recordPtr.Transcript), os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	width, height := param.Cols, param.Rows
+	if width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+	header := map[string]interface{}{
+		"version":   2,
+		"width":     width,
+		"height":    height,
+		"timestamp": recordPtr.StartTime.Unix(),
+		"command":   strings.Join(recordPtr.Command, " "),
+		"title":     recordPtr.Service,
+	}
+	byteVal, _ := json.Marshal(header)
+
+	ret, err = os.OpenFile(recordPtr.Transcript, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return
+	}
+
+	_, err = ret.Write(append(byteVal, '\n'))
+	if err != nil {
+		ret.Close()
+		ret = nil
+	}
+	return
+}
+
+func (s *Runtime) finishTerminal(sessionPtr *TerminalSession) {
+	recordPtr := sessionPtr.record
+	log.Warnf("close terminal, id:%s, service:%s, exitCode:%d", recordPtr.ID, recordPtr.Service, recordPtr.ExitCode)
+	s.appendAuditRecord(recordPtr)
+
+	s.terminalLock.Lock()
+	delete(s.terminalMap, sessionPtr.ID())
+	s.terminalLock.Unlock()
+}
+
+// CloseTerminals 结束全部交互式会话
+func (s *Runtime) CloseTerminals() {
+	s.terminalLock.Lock()
+	defer s.terminalLock.Unlock()
+
+	for _, val := range s.terminalMap {
+		_ = val.Close()
+	}
+}
+
+// QueryAudit 查询交互式会话审计记录，按开始时间倒序，count<=0时返回全部
+// 同一会话以最后一条记录为准，未结束且不在当前会话中的记录标记为中断
+func (s *Runtime) QueryAudit(count int) (ret []*common.AuditRecord, err *cd.Result) {
+	ret = []*common.AuditRecord{}
+	fileHandle, fileErr := os.Open(s.auditFilePath())
+	if fileErr != nil {
+		if !os.IsNotExist(fileErr) {
+			err = cd.NewError(cd.UnExpected, fileErr.Error())
+		}
+		return
+	}
+	defer fileHandle.Close()
+
+	recordMap := map[string]*common.AuditRecord{}
+	scanner := bufio.NewScanner(fileHandle)
+	for scanner.Scan() {
+		recordPtr := &common.AuditRecord{}
+		if json.Unmarshal(scanner.Bytes(), recordPtr) != nil || recordPtr.ID == "" {
+			continue
+		}
+
+		recordMap[recordPtr.ID] = recordPtr
+	}
+
+	s.terminalLock.Lock()
+	for _, val := range recordMap {
+		if _, ok := s.terminalMap[val.ID]; !ok && val.FinishTime == nil {
+			val.Error = cd.NewError(cd.UnExpected, "interrupted by agent restart")
+		}
+		ret = append(ret, val)
+	}
+	s.terminalLock.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].StartTime.After(ret[j].StartTime)
+	})
+	if count > 0 && len(ret) > count {
+		ret = ret[:count]
+	}
+	return
+}
+
+// AppendAudit 记录其他模块的管理操作，未指定ID时自动生成
+func (s *Runtime) AppendAudit(ev event.Event, re event.Result) {
+	recordPtr, recordOK := ev.Data().(*common.AuditRecord)
+	if !recordOK {
+		log.Warnf("AppendAudit failed, illegal param")
+		return
+	}
+
+	if recordPtr.ID == "" {
+		recordPtr.ID = newSessionID()
+	}
+	s.appendAuditRecord(recordPtr)
+	if re != nil {
+		re.Set(recordPtr.ID, nil)
+	}
+}
+
+func (s *Runtime) appendAuditRecord(recordPtr *common.AuditRecord) {
+	byteVal, byteErr := json.Marshal(recordPtr)
+	if byteErr != nil {
+		log.Errorf("appendAuditRecord failed, marshal record failed, error:%s", byteErr.Error())
+		return
+	}
+
+	s.auditLock.Lock()
+	defer s.auditLock.Unlock()
+
+	logFullPath := s.auditFilePath()
+	logPath, _ := path.Split(logFullPath)
+	_ = os.MkdirAll(logPath, os.ModePerm)
+
+	fileHandle, fileErr := os.OpenFile(logFullPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0640)
+	if fileErr != nil {
+		log.Errorf("appendAuditRecord failed, open file %s failed, error:%s", logFullPath, fileErr.Error())
+		return
+	}
+	defer fileHandle.Close()
+
+	_, writeErr := fileHandle.Write(append(byteVal, '\n'))
+	if writeErr != nil {
+		log.Errorf("appendAuditRecord failed, write record failed, error:%s", writeErr.Error())
+	}
+}
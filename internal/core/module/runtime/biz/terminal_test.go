@@ -0,0 +1,264 @@
+package biz
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// fakeTerminal 回显输入，结束输入后命令正常退出，Close时命令被结束
+type fakeTerminal struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+	done   chan struct{}
+	once   sync.Once
+
+	exitCode int
+	resize   []uint16
+}
+
+func newFakeTerminal() *fakeTerminal {
+	reader, writer := io.Pipe()
+	return &fakeTerminal{reader: reader, writer: writer, done: make(chan struct{})}
+}
+
+func (s *fakeTerminal) Read(data []byte) (int, error) {
+	return s.reader.Read(data)
+}
+
+func (s *fakeTerminal) Write(data []byte) (int, error) {
+	return s.writer.Write(data)
+}
+
+func (s *fakeTerminal) Resize(rows, cols uint16) error {
+	s.resize = append(s.resize, rows, cols)
+	return nil
+}
+
+func (s *fakeTerminal) CloseWrite() error {
+	s.finish(0)
+	return nil
+}
+
+func (s *fakeTerminal) Close() error {
+	s.finish(-1)
+	return nil
+}
+
+func (s *fakeTerminal) finish(exitCode int) {
+	s.once.Do(func() {
+		s.exitCode = exitCode
+		_ = s.writer.Close()
+		close(s.done)
+	})
+}
+
+func (s *fakeTerminal) Wait() (int, *cd.Result) {
+	<-s.done
+	if s.exitCode != 0 {
+		return s.exitCode, cd.NewError(cd.UnExpected, "killed")
+	}
+	return 0, nil
+}
+
+// fakeAttacher 只实现Name和Attach，其他运行时方法不会被调用
+type fakeAttacher struct {
+	runtime.Runtime
+	terminal *fakeTerminal
+	cmd      []string
+	option   *runtime.TerminalOption
+}
+
+func (s *fakeAttacher) Name() string {
+	return "fake"
+}
+
+func (s *fakeAttacher) Attach(_ context.Context, _ string, cmd []string, option *runtime.TerminalOption) (runtime.Terminal, *cd.Result) {
+	s.cmd = cmd
+	s.option = option
+	return s.terminal, nil
+}
+
+type fakeRuntime struct {
+	runtime.Runtime
+}
+
+func (s *fakeRuntime) Name() string {
+	return "fake"
+}
+
+// setDefaultRuntime 替换未配置守护对象的服务所用的运行时
+func setDefaultRuntime(runtimePtr *Runtime, val runtime.Runtime) {
+	runtimePtr.runtimeLock.Lock()
+	defer runtimePtr.runtimeLock.Unlock()
+	runtimePtr.runtimeMap[common.DockerRuntime+"|||"] = val
+}
+
+func TestTerminalSession(t *testing.T) {
+	runtimePtr := newTestRuntime(t.TempDir())
+	attacher := &fakeAttacher{terminal: newFakeTerminal()}
+	setDefaultRuntime(runtimePtr, attacher)
+
+	param := &common.TerminalParam{Service: "term001", Env: []string{"A=1"}, TTY: true, Rows: 30, Cols: 100}
+	sessionPtr, sessionErr := runtimePtr.OpenTerminal(context.Background(), param, "127.0.0.1:1234")
+	if sessionErr != nil {
+		t.Fatalf("open terminal failed, %v", sessionErr)
+	}
+	if strings.Join(attacher.cmd, " ") != strings.Join(runtime.DefaultTerminalCommand, " ") || !attacher.option.TTY || attacher.option.Env[0] != "A=1" {
+		t.Errorf("attach command %v, option %+v", attacher.cmd, attacher.option)
+	}
+
+	outputChan := make(chan string)
+	go func() {
+		byteVal, _ := io.ReadAll(sessionPtr)
+		outputChan <- string(byteVal)
+	}()
+	_, _ = sessionPtr.Write([]byte("hello\n"))
+	_ = sessionPtr.Resize(40, 120)
+	_ = sessionPtr.CloseWrite()
+	if output := <-outputChan; output != "hello\n" {
+		t.Errorf("output %q, expect hello", output)
+	}
+	if exitCode, err := sessionPtr.Wait(); exitCode != 0 || err != nil {
+		t.Errorf("exit code %d, error %v", exitCode, err)
+	}
+	if len(attacher.terminal.resize) != 2 || attacher.terminal.resize[0] != 40 || attacher.terminal.resize[1] != 120 {
+		t.Errorf("resize %v, expect 40x120", attacher.terminal.resize)
+	}
+
+	records, recordErr := runtimePtr.QueryAudit(0)
+	if recordErr != nil || len(records) != 1 {
+		t.Fatalf("records %+v, error %v", records, recordErr)
+	}
+	recordPtr := records[0]
+	if recordPtr.ID != sessionPtr.ID() || recordPtr.FinishTime == nil || recordPtr.RemoteAddr != "127.0.0.1:1234" || recordPtr.Error != nil {
+		t.Errorf("unexpected record %+v", recordPtr)
+	}
+
+	lines := readLines(t, recordPtr.Transcript)
+	header := map[string]interface{}{}
+	if len(lines) != 4 || json.Unmarshal([]byte(lines[0]), &header) != nil {
+		t.Fatalf("illegal transcript %q", lines)
+	}
+	if header["version"] != float64(2) || header["width"] != float64(100) || header["height"] != float64(30) || header["title"] != "term001" {
+		t.Errorf("illegal transcript header %v", header)
+	}
+	events := map[string]string{}
+	for _, val := range lines[1:] {
+		item := []interface{}{}
+		if json.Unmarshal([]byte(val), &item) != nil || len(item) != 3 {
+			t.Fatalf("illegal transcript event %q", val)
+		}
+		events[item[1].(string)] = item[2].(string)
+	}
+	if events["i"] != "hello\n" || events["o"] != "hello\n" || events["r"] != "120x40" {
+		t.Errorf("transcript events %v", events)
+	}
+}
+
+func TestOpenTerminalUnsupported(t *testing.T) {
+	runtimePtr := newTestRuntime(t.TempDir())
+	setDefaultRuntime(runtimePtr, &fakeRuntime{})
+
+	_, err := runtimePtr.OpenTerminal(context.Background(), &common.TerminalParam{Service: "term001"}, "")
+	if err == nil || err.ErrorCode != cd.IllegalParam {
+		t.Errorf("error %v, expect illegal param", err)
+	}
+	if records, _ := runtimePtr.QueryAudit(0); len(records) != 0 {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestCloseTerminals(t *testing.T) {
+	runtimePtr := newTestRuntime(t.TempDir())
+	setDefaultRuntime(runtimePtr, &fakeAttacher{terminal: newFakeTerminal()})
+
+	sessionPtr, sessionErr := runtimePtr.OpenTerminal(context.Background(), &common.TerminalParam{Service: "term001", Command: []string{"top"}}, "")
+	if sessionErr != nil {
+		t.Fatalf("open terminal failed, %v", sessionErr)
+	}
+
+	runtimePtr.CloseTerminals()
+	exitCode, err := sessionPtr.Wait()
+	if exitCode != -1 || err == nil {
+		t.Errorf("exit code %d, error %v, expect killed", exitCode, err)
+	}
+	if len(runtimePtr.terminalMap) != 0 {
+		t.Errorf("terminal not removed after wait")
+	}
+
+	records, _ := runtimePtr.QueryAudit(0)
+	if len(records) != 1 || records[0].ExitCode != -1 || records[0].Command[0] != "top" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestQueryAudit(t *testing.T) {
+	runtimePtr := newTestRuntime(t.TempDir())
+	baseTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishTime := baseTime.Add(time.Minute)
+	for _, val := range []*common.AuditRecord{
+		{ID: "a1", Service: "db", StartTime: baseTime},
+		{ID: "a2", Service: "db", StartTime: baseTime.Add(time.Hour)},
+		{ID: "a1", Service: "db", StartTime: baseTime, FinishTime: &finishTime},
+		{ID: "a3", Service: "db", StartTime: baseTime.Add(2 * time.Hour), FinishTime: &finishTime, Reason: "pause remediation"},
+	} {
+		runtimePtr.appendAuditRecord(val)
+	}
+
+	cases := []struct {
+		name        string
+		count       int
+		expectIDs   string
+		interrupted string
+	}{
+		{name: "all", expectIDs: "a3,a2,a1", interrupted: "a2"},
+		{name: "latest", count: 1, expectIDs: "a3"},
+	}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			records, err := runtimePtr.QueryAudit(val.count)
+			if err != nil {
+				t.Fatalf("query audit failed, %v", err)
+			}
+
+			ids := []string{}
+			interrupted := []string{}
+			for _, item := range records {
+				ids = append(ids, item.ID)
+				if item.Error != nil {
+					interrupted = append(interrupted, item.ID)
+				}
+			}
+			if strings.Join(ids, ",") != val.expectIDs || strings.Join(interrupted, ",") != val.interrupted {
+				t.Errorf("records %v, interrupted %v, expect %s, %s", ids, interrupted, val.expectIDs, val.interrupted)
+			}
+		})
+	}
+}
+
+func readLines(t *testing.T, filePath string) []string {
+	fileHandle, fileErr := os.Open(filePath)
+	if fileErr != nil {
+		t.Fatalf("open %s failed, %v", filePath, fileErr)
+	}
+	defer fileHandle.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(fileHandle)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
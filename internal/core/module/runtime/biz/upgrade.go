@@ -0,0 +1,205 @@
+package biz
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/biz"
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// defaultUpgradeTimeOut 新容器通过健康检查的默认等待时间，mariadb节点需要完成状态同步
+const defaultUpgradeTimeOut = 600 * time.Second
+
+// upgradeCheckInterval 检查新容器和节点任务状态的间隔
+var upgradeCheckInterval = 2 * time.Second
+
+func upgradeTimeOut(param *common.UpgradeParam) time.Duration {
+	if param.TimeOut <= 0 {
+		return defaultUpgradeTimeOut
+	}
+
+	return time.Duration(param.TimeOut) * time.Second
+}
+
+// upgradeJob 使用新镜像重建本节点的服务容器
+func (s *Runtime) upgradeJob(jobCtx biz.JobContext) *cd.Result {
+	serviceName := jobCtx.Param().Service
+	if serviceName == "" {
+		return cd.NewError(cd.IllegalParam, "illegal service name")
+	}
+
+	param := &common.UpgradeParam{}
+	if err := unmarshalJobParam(jobCtx, param); err != nil {
+		return err
+	}
+
+	return s.upgradeService(jobCtx, serviceName, param)
+}
+
+func unmarshalJobParam(jobCtx biz.JobContext, param interface{}) *cd.Result {
+	byteErr := json.Unmarshal(jobCtx.Param().Param, param)
+	if byteErr != nil {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal job param, %s", byteErr.Error()))
+	}
+
+	return nil
+}
+
+// upgradeService 升级前检查服务状态，拉取镜像后暂停自动修复并重建容器，
+// 新容器在超时时间内未通过健康检查或任务被取消时回滚到原容器
+func (s *Runtime) upgradeService(jobCtx biz.JobContext, serviceName string, param *common.UpgradeParam) *cd.Result {
+	if param.Image == "" {
+		return cd.NewError(cd.IllegalParam, "illegal image")
+	}
+
+	runtimePtr, runtimeErr := s.getRuntime(serviceName)
+	if runtimeErr != nil {
+		return runtimeErr
+	}
+	upgrader, ok := runtimePtr.(runtime.Upgrader)
+	if !ok {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support upgrade", runtimePtr.Name()))
+	}
+
+	guardPtr := config.GetGuard(serviceName)
+	nodeSize := 0
+	if guardPtr != nil && guardPtr.Type == config.MariadbGuard {
+		jobCtx.SetProgress(5, fmt.Sprintf("checking %s before upgrade", serviceName))
+		statusPtr, statusErr := s.queryClusterStatus(serviceName)
+		if statusErr != nil {
+			return statusErr
+		}
+		if statusPtr.Status != common.Primary || statusPtr.LocalState != common.Synced {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("cluster not ready for upgrade, status:%s, localState:%s", statusPtr.Status, statusPtr.LocalState))
+		}
+		nodeSize = statusPtr.NodeSize
+	}
+
+	jobCtx.SetProgress(10, fmt.Sprintf("pulling image %s", param.Image))
+	if err := upgrader.PullImage(jobCtx, param.Image); err != nil {
+		return err
+	}
+
+	resume := s.PauseRemediation(serviceName, fmt.Sprintf("upgrade job %s", jobCtx.JobID()))
+	defer resume()
+
+	jobCtx.SetProgress(40, fmt.Sprintf("recreating %s", serviceName))
+	oldImage, recreateErr := upgrader.Recreate(serviceName, param.Image)
+	if recreateErr != nil {
+		return recreateErr
+	}
+	_, _ = fmt.Fprintf(jobCtx, "recreated %s, image %s -> %s\n", serviceName, oldImage, param.Image)
+
+	jobCtx.SetProgress(60, fmt.Sprintf("waiting for %s healthy", serviceName))
+	checkErr := s.waitUpgraded(jobCtx, serviceName, guardPtr, nodeSize, upgradeTimeOut(param))
+	if checkErr != nil {
+		_, _ = fmt.Fprintf(jobCtx, "%s, rolling back to %s\n", checkErr.Reason, oldImage)
+		jobCtx.SetProgress(80, fmt.Sprintf("rolling back %s", serviceName))
+		rollbackErr := upgrader.Rollback(serviceName)
+		if rollbackErr != nil {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("%s, rollback failed, %s", checkErr.Reason, rollbackErr.Reason))
+		}
+
+		return cd.NewError(cd.UnExpected, fmt.Sprintf("%s, rolled back to %s", checkErr.Reason, oldImage))
+	}
+
+	// 原容器删除失败不影响升级结果，需要人工清理
+	if commitErr := upgrader.Commit(serviceName); commitErr != nil {
+		log.Warnf("remove container %s failed, error:%s", runtime.BackupName(serviceName), commitErr.Reason)
+		_, _ = fmt.Fprintf(jobCtx, "remove container %s failed, %s\n", runtime.BackupName(serviceName), commitErr.Reason)
+	}
+
+	jobCtx.SetProgress(100, fmt.Sprintf("%s upgraded to %s", serviceName, param.Image))
+	return nil
+}
+
+// waitUpgraded 等待新容器通过健康检查，容器退出、重启或健康检查失败时立即返回错误
+func (s *Runtime) waitUpgraded(jobCtx biz.JobContext, serviceName string, guardPtr *config.GuardItem, nodeSize int, timeOut time.Duration) *cd.Result {
+	deadline := time.Now().Add(timeOut)
+	restartCount := -1
+	for {
+		select {
+		case <-jobCtx.Done():
+			return cd.NewError(cd.UnExpected, "job canceled")
+		case <-time.After(upgradeCheckInterval):
+		}
+
+		pending, checkErr := s.checkUpgraded(serviceName, guardPtr, nodeSize, &restartCount)
+		if checkErr != nil {
+			return checkErr
+		}
+		if pending == "" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return cd.NewError(cd.UnExpected, fmt.Sprintf("%s not healthy after %v, %s", serviceName, timeOut, pending))
+		}
+	}
+}
+
+// checkUpgraded 检查一次新容器状态，pending非空表示尚未就绪
+func (s *Runtime) checkUpgraded(serviceName string, guardPtr *config.GuardItem, nodeSize int, restartCount *int) (pending string, err *cd.Result) {
+	containerPtr, containerErr := s.Inspect(serviceName)
+	if containerErr != nil {
+		pending = fmt.Sprintf("inspect failed, %s", containerErr.Reason)
+		return
+	}
+
+	if *restartCount < 0 {
+		*restartCount = containerPtr.RestartCount
+	}
+	switch {
+	case containerPtr.OOMKilled:
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s oom killed", serviceName))
+	case containerPtr.State == common.ContainerExited:
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s exited with code %d", serviceName, containerPtr.ExitCode))
+	case containerPtr.State == common.ContainerRestarting || containerPtr.RestartCount > *restartCount:
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s restarted", serviceName))
+	case containerPtr.Health == "unhealthy":
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s unhealthy", serviceName))
+	case !containerPtr.IsRunning():
+		pending = fmt.Sprintf("state %s", containerPtr.State)
+	case containerPtr.Health != "" && containerPtr.Health != "healthy":
+		pending = fmt.Sprintf("health %s", containerPtr.Health)
+	}
+	if err != nil || pending != "" || guardPtr == nil || guardPtr.Type != config.MariadbGuard {
+		return
+	}
+
+	statusPtr, statusErr := s.queryClusterStatus(serviceName)
+	switch {
+	case statusErr != nil:
+		pending = fmt.Sprintf("query cluster status failed, %s", statusErr.Reason)
+	case statusPtr.LocalState != common.Synced:
+		pending = fmt.Sprintf("localState %s", statusPtr.LocalState)
+	case statusPtr.Status != common.Primary:
+		pending = fmt.Sprintf("cluster status %s", statusPtr.Status)
+	case statusPtr.NodeSize < nodeSize:
+		pending = fmt.Sprintf("cluster size %d, expect %d", statusPtr.NodeSize, nodeSize)
+	}
+	return
+}
+
+func (s *Runtime) queryClusterStatus(serviceName string) (ret *common.ClusterStatus, err *cd.Result) {
+	ev := event.NewEvent(common.QueryStatus, s.ID(), common.MariadbModule, nil, serviceName)
+	result := s.SendEvent(ev)
+	statusVal, statusErr := result.Get()
+	if statusErr != nil {
+		err = statusErr
+		return
+	}
+
+	ret, _ = statusVal.(*common.ClusterStatus)
+	if ret == nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("query cluster status failed, service:%s", serviceName))
+	}
+	return
+}
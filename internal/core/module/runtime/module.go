@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	engine "github.com/muidea/magicEngine"
+
+	"github.com/muidea/magicCommon/event"
+	"github.com/muidea/magicCommon/module"
+	"github.com/muidea/magicCommon/task"
+
+	"github.com/muidea/magicAgent/internal/core/module/runtime/biz"
+	"github.com/muidea/magicAgent/internal/core/module/runtime/service"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func init() {
+	module.Register(New())
+}
+
+type Runtime struct {
+	routeRegistry engine.Router
+
+	service *service.Runtime
+	biz     *biz.Runtime
+}
+
+func New() *Runtime {
+	return &Runtime{}
+}
+
+func (s *Runtime) ID() string {
+	return common.RuntimeModule
+}
+
+func (s *Runtime) BindRegistry(routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+}
+
+func (s *Runtime) Setup(endpointName string, eventHub event.Hub, backgroundRoutine task.BackgroundRoutine) {
+	s.biz = biz.New(eventHub, backgroundRoutine)
+
+	s.service = service.New(endpointName, s.biz)
+	s.service.BindRegistry(s.routeRegistry)
+	s.service.RegisterRoute()
+}
+
+func (s *Runtime) Run() {
+	s.biz.StartWatch()
+}
+
+func (s *Runtime) Teardown() {
+	s.biz.StopWatch()
+	s.biz.CloseTerminals()
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/internal/core/base/service"
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+const eventStreamType = "text/event-stream"
+
+// logWriter 将日志写入响应，首次写入时才发送响应头，以便在输出前的错误仍可按json返回
+// SSE格式下stdout、stderr分别作为同名事件发送
+type logWriter struct {
+	lock    *sync.Mutex
+	res     http.ResponseWriter
+	req     *http.Request
+	sse     bool
+	event   string
+	started *bool
+}
+
+func (s *logWriter) Write(data []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !*s.started {
+		*s.started = true
+		if s.sse {
+			s.res.Header().Set("Content-Type", eventStreamType)
+			s.res.Header().Set("Cache-Control", "no-cache")
+		} else {
+			s.res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		s.res.WriteHeader(http.StatusOK)
+	}
+
+	var err error
+	if s.sse {
+		content := "event: " + s.event + "\n"
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			content += "data: " + line + "\n"
+		}
+		_, err = s.res.Write([]byte(content + "\n"))
+	} else {
+		_, err = s.res.Write(data)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	service.Flush(s.req)
+	return len(data), nil
+}
+
+// parseSince 支持RFC3339时间、相对时长(如10m)以及unix时间戳
+func parseSince(sinceVal string) (ret time.Time, err error) {
+	if sinceVal == "" {
+		return
+	}
+
+	if ret, err = time.Parse(time.RFC3339, sinceVal); err == nil {
+		return
+	}
+	if duration, durationErr := time.ParseDuration(sinceVal); durationErr == nil {
+		ret, err = time.Now().Add(-duration), nil
+		return
+	}
+	if unixVal, unixErr := strconv.ParseInt(sinceVal, 10, 64); unixErr == nil {
+		ret, err = time.Unix(unixVal, 0), nil
+		return
+	}
+
+	err = fmt.Errorf("illegal since value:%s", sinceVal)
+	return
+}
+
+// LogsHandle 以chunked方式持续输出日志，请求头Accept为text/event-stream时使用SSE格式
+func (s *Runtime) LogsHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &cd.Result{}
+	for {
+		query := req.URL.Query()
+		serviceName := query.Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		option := &runtime.LogOption{}
+		if tailVal := query.Get("tail"); tailVal != "" && tailVal != "all" {
+			tail, tailErr := strconv.Atoi(tailVal)
+			if tailErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = fmt.Sprintf("illegal tail value:%s", tailVal)
+				break
+			}
+			option.Tail = tail
+		}
+
+		since, sinceErr := parseSince(query.Get("since"))
+		if sinceErr != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = sinceErr.Error()
+			break
+		}
+		option.Since = since
+
+		if followVal := query.Get("follow"); followVal != "" {
+			follow, followErr := strconv.ParseBool(followVal)
+			if followErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = fmt.Sprintf("illegal follow value:%s", followVal)
+				break
+			}
+			option.Follow = follow
+		}
+
+		// 非follow时限制读取时长，follow时持续到客户端断开
+		ctx := req.Context()
+		if !option.Follow {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(config.GetTimeOut())*time.Second)
+			defer cancel()
+		}
+
+		started := false
+		lock := &sync.Mutex{}
+		sse := strings.Contains(req.Header.Get("Accept"), eventStreamType)
+		stdout := &logWriter{lock: lock, res: res, req: req, sse: sse, event: "stdout", started: &started}
+		stderr := &logWriter{lock: lock, res: res, req: req, sse: sse, event: "stderr", started: &started}
+		logErr := s.bizPtr.Logs(ctx, serviceName, option, stdout, stderr)
+		if logErr != nil && !started {
+			result = logErr
+			break
+		}
+		if logErr != nil {
+			log.Errorf("stream logs failed, service:%s, error:%s", serviceName, logErr.Error())
+			if sse {
+				_, _ = res.Write([]byte("event: error\ndata: " + logErr.Reason + "\n\n"))
+			}
+		}
+		if !started {
+			// 没有日志输出时返回空内容
+			res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			res.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
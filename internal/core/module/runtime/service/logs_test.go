@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name      string
+		since     string
+		expect    time.Time
+		tolerance time.Duration
+		expectErr bool
+	}{
+		{name: "empty"},
+		{name: "rfc3339", since: "2026-01-02T03:04:05Z", expect: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{name: "duration", since: "10m", expect: now.Add(-10 * time.Minute), tolerance: time.Minute},
+		{name: "unix", since: "1767323045", expect: time.Unix(1767323045, 0)},
+		{name: "illegal", since: "yesterday", expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, err := parseSince(val.since)
+			if val.expectErr {
+				if err == nil {
+					t.Fatalf("expect error for %q", val.since)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			diff := ret.Sub(val.expect)
+			if diff < -val.tolerance || diff > val.tolerance {
+				t.Errorf("since %v, expect %v", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	cases := []struct {
+		name        string
+		sse         bool
+		expectType  string
+		expectBody  string
+		writeOutput []string
+	}{
+		{
+			name:        "plain",
+			expectType:  "text/plain; charset=utf-8",
+			writeOutput: []string{"line1\n", "line2\n"},
+			expectBody:  "line1\nline2\n",
+		},
+		{
+			name:        "sse",
+			sse:         true,
+			expectType:  eventStreamType,
+			writeOutput: []string{"line1\nline2\n"},
+			expectBody:  "event: stdout\ndata: line1\ndata: line2\n\n",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			started := false
+			writer := &logWriter{
+				lock:    &sync.Mutex{},
+				res:     recorder,
+				req:     httptest.NewRequest(http.MethodGet, "/", nil),
+				sse:     val.sse,
+				event:   "stdout",
+				started: &started,
+			}
+			for _, item := range val.writeOutput {
+				if _, err := writer.Write([]byte(item)); err != nil {
+					t.Fatalf("write failed, %v", err)
+				}
+			}
+
+			if recorder.Header().Get("Content-Type") != val.expectType {
+				t.Errorf("content type %q, expect %q", recorder.Header().Get("Content-Type"), val.expectType)
+			}
+			if recorder.Body.String() != val.expectBody {
+				t.Errorf("body %q, expect %q", recorder.Body.String(), val.expectBody)
+			}
+		})
+	}
+}
+
+func TestLogsHandleParam(t *testing.T) {
+	cases := []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{name: "no service", query: "", expect: "illegal service name"},
+		{name: "illegal tail", query: "service=db&tail=ten", expect: "illegal tail value:ten"},
+		{name: "illegal since", query: "service=db&since=yesterday", expect: "illegal since value:yesterday"},
+		{name: "illegal follow", query: "service=db&follow=maybe", expect: "illegal follow value:maybe"},
+	}
+
+	servicePtr := &Runtime{}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			servicePtr.LogsHandle(context.Background(), recorder, httptest.NewRequest(http.MethodGet, "/container/logs?"+val.query, nil))
+
+			result := &cd.Result{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("illegal response %q", recorder.Body.String())
+			}
+			if result.ErrorCode != cd.IllegalParam || !strings.Contains(result.Reason, val.expect) {
+				t.Errorf("result %+v, expect %q", result, val.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	engine "github.com/muidea/magicEngine"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	"github.com/muidea/magicAgent/internal/core/base/service"
+	"github.com/muidea/magicAgent/internal/core/module/runtime/biz"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Runtime BaseService
+type Runtime struct {
+	routeRegistry engine.Router
+
+	bizPtr *biz.Runtime
+
+	endpointName string
+}
+
+// New create base
+func New(endpointName string, bizPtr *biz.Runtime) *Runtime {
+	ptr := &Runtime{
+		endpointName: endpointName,
+		bizPtr:       bizPtr,
+	}
+
+	return ptr
+}
+
+func (s *Runtime) BindRegistry(
+	routeRegistry engine.Router) {
+
+	s.routeRegistry = routeRegistry
+
+	s.routeRegistry.SetApiVersion(common.ApiVersion)
+}
+
+// RegisterRoute 注册路由
+func (s *Runtime) RegisterRoute() {
+	startRoute := engine.CreateRoute(common.StartService, engine.GET, s.StartHandle)
+	s.routeRegistry.AddRoute(startRoute)
+
+	stopRoute := engine.CreateRoute(common.StopService, engine.GET, s.StopHandle)
+	s.routeRegistry.AddRoute(stopRoute)
+
+	restartRoute := engine.CreateRoute(common.RestartService, engine.GET, s.RestartHandle)
+	s.routeRegistry.AddRoute(restartRoute)
+
+	execRoute := engine.CreateRoute(common.ExecuteCommand, engine.POST, s.ExecHandle)
+	s.routeRegistry.AddRoute(execRoute)
+
+	listRoute := engine.CreateRoute(common.ListContainer, engine.GET, s.ListContainerHandle)
+	s.routeRegistry.AddRoute(listRoute)
+
+	inspectRoute := engine.CreateRoute(common.InspectContainer, engine.GET, s.InspectContainerHandle)
+	s.routeRegistry.AddRoute(inspectRoute)
+
+	statsRoute := engine.CreateRoute(common.QueryContainerStats, engine.GET, s.QueryContainerStatsHandle)
+	s.routeRegistry.AddRoute(statsRoute)
+
+	logsRoute := engine.CreateRoute(common.QueryContainerLogs, engine.GET, s.LogsHandle)
+	s.routeRegistry.AddRoute(logsRoute)
+
+	eventsRoute := engine.CreateRoute(common.QueryContainerEvents, engine.GET, s.QueryContainerEventsHandle)
+	s.routeRegistry.AddRoute(eventsRoute)
+
+	terminalRoute := engine.CreateRoute(common.ExecuteTerminal, engine.GET, s.TerminalHandle)
+	s.routeRegistry.AddRoute(terminalRoute)
+
+	auditRoute := engine.CreateRoute(common.QueryAudit, engine.GET, s.QueryAuditHandle)
+	s.routeRegistry.AddRoute(auditRoute)
+}
+
+// StartHandle 启动服务，需要管理员权限
+func (s *Runtime) StartHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.StartServiceResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject start, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		execStdout, execStderr, startErr := s.bizPtr.Start(serviceName)
+		if startErr != nil {
+			result.Result = *startErr
+			break
+		}
+
+		result.StdOut = execStdout
+		result.StdErr = execStderr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// StopHandle 停止服务，需要管理员权限
+func (s *Runtime) StopHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.StopServiceResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject stop, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		execStdout, execStderr, stopErr := s.bizPtr.Stop(serviceName)
+		if stopErr != nil {
+			result.Result = *stopErr
+			break
+		}
+
+		result.StdOut = execStdout
+		result.StdErr = execStderr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// RestartHandle 重启服务，需要管理员权限
+func (s *Runtime) RestartHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.RestartServiceResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject restart, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		execStdout, execStderr, restartErr := s.bizPtr.Restart(serviceName)
+		if restartErr != nil {
+			result.Result = *restartErr
+			break
+		}
+
+		result.StdOut = execStdout
+		result.StdErr = execStderr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// ExecHandle 在服务中执行命令，需要管理员权限
+func (s *Runtime) ExecHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ExecServiceResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject execute, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result.Result = *authErr
+			break
+		}
+
+		param := &common.ServiceParam{}
+		err := fn.ParseJSONBody(req, nil, param)
+		if err != nil {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "非法参数"
+			break
+		}
+
+		if param.Service == "" || param.TimeOut < 0 {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "非法参数"
+			break
+		}
+
+		execResult, execErr := s.bizPtr.Exec(param.Service, param.CmdParam, biz.ExecOption(param))
+		if execErr != nil {
+			result.Result = *execErr
+		}
+		if execResult != nil {
+			result.StdOut = execResult.Stdout
+			result.StdErr = execResult.Stderr
+			result.ExitCode = execResult.ExitCode
+		}
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+// ListContainerHandle 可通过runtime参数指定运行时
+func (s *Runtime) ListContainerHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.ListContainerResult{}
+	for {
+		containers, listErr := s.bizPtr.List(req.URL.Query().Get("runtime"))
+		if listErr != nil {
+			result.Result = *listErr
+			break
+		}
+
+		result.Containers = containers
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Runtime) InspectContainerHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.InspectContainerResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		infoPtr, inspectErr := s.bizPtr.Inspect(serviceName)
+		if inspectErr != nil {
+			result.Result = *inspectErr
+			break
+		}
+
+		result.Container = infoPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Runtime) QueryContainerStatsHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryContainerStatsResult{}
+	for {
+		serviceName := req.URL.Query().Get("service")
+		if serviceName == "" {
+			result.ErrorCode = cd.IllegalParam
+			result.Reason = "illegal service name"
+			break
+		}
+
+		statsPtr, statsErr := s.bizPtr.Stats(serviceName)
+		if statsErr != nil {
+			result.Result = *statsErr
+			break
+		}
+
+		result.Stats = statsPtr
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Runtime) QueryContainerEventsHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryContainerEventsResult{}
+	for {
+		count := 0
+		countVal := req.URL.Query().Get("count")
+		if countVal != "" {
+			val, valErr := strconv.Atoi(countVal)
+			if valErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = "illegal count"
+				break
+			}
+			count = val
+		}
+
+		result.Events = s.bizPtr.QueryEvents(count)
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestServiceHandleReject(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+	defer func() { _ = config.LoadConfig("") }()
+
+	servicePtr := &Runtime{}
+	handles := map[string]func(context.Context, http.ResponseWriter, *http.Request){
+		"start":   servicePtr.StartHandle,
+		"stop":    servicePtr.StopHandle,
+		"restart": servicePtr.RestartHandle,
+		"execute": servicePtr.ExecHandle,
+	}
+
+	cases := []struct {
+		name       string
+		handle     string
+		token      string
+		expectCode cd.ErrorCode
+	}{
+		{name: "start without token", handle: "start", expectCode: cd.InvalidAuthority},
+		{name: "stop wrong token", handle: "stop", token: "other", expectCode: cd.InvalidAuthority},
+		{name: "restart without token", handle: "restart", expectCode: cd.InvalidAuthority},
+		{name: "restart wrong token", handle: "restart", token: "other", expectCode: cd.InvalidAuthority},
+		{name: "restart illegal param", handle: "restart", token: "admin-token", expectCode: cd.IllegalParam},
+		{name: "execute without token", handle: "execute", expectCode: cd.InvalidAuthority},
+		{name: "execute wrong token", handle: "execute", token: "other", expectCode: cd.InvalidAuthority},
+		{name: "execute illegal param", handle: "execute", token: "admin-token", expectCode: cd.IllegalParam},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, common.ExecuteCommand, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			if val.token != "" {
+				req.Header.Set("Authorization", "Bearer "+val.token)
+			}
+			recorder := httptest.NewRecorder()
+			handles[val.handle](context.Background(), recorder, req)
+
+			result := &cd.Result{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("illegal response %q", recorder.Body.String())
+			}
+			if result.ErrorCode != val.expectCode {
+				t.Errorf("result %+v, expect code %d", result, val.expectCode)
+			}
+		})
+	}
+}
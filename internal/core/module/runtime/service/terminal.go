@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+	fn "github.com/muidea/magicCommon/foundation/net"
+
+	"github.com/muidea/magicAgent/internal/core/base/service"
+	"github.com/muidea/magicAgent/internal/core/module/runtime/biz"
+	"github.com/muidea/magicAgent/internal/websocket"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// terminalBufferSize 单次转发的输出字节数
+const terminalBufferSize = 32 * 1024
+
+// parseTerminalParam 解析会话参数，cmd、env可重复指定
+func parseTerminalParam(req *http.Request) (ret *common.TerminalParam, err *cd.Result) {
+	query := req.URL.Query()
+	param := &common.TerminalParam{
+		Service: query.Get("service"),
+		Command: query["cmd"],
+		Env:     query["env"],
+		WorkDir: query.Get("workDir"),
+	}
+	if param.Service == "" {
+		err = cd.NewError(cd.IllegalParam, "illegal service name")
+		return
+	}
+
+	if ttyVal := query.Get("tty"); ttyVal != "" {
+		tty, ttyErr := strconv.ParseBool(ttyVal)
+		if ttyErr != nil {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal tty value:%s", ttyVal))
+			return
+		}
+		param.TTY = tty
+	}
+
+	for _, name := range []string{"rows", "cols"} {
+		sizeVal := query.Get(name)
+		if sizeVal == "" {
+			continue
+		}
+
+		size, sizeErr := strconv.ParseUint(sizeVal, 10, 16)
+		if sizeErr != nil {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal %s value:%s", name, sizeVal))
+			return
+		}
+		if name == "rows" {
+			param.Rows = uint16(size)
+		} else {
+			param.Cols = uint16(size)
+		}
+	}
+
+	ret = param
+	return
+}
+
+// TerminalHandle 交互式会话，握手前的错误按json返回，会话开始后输入输出以二进制帧转发，
+// 命令结束时发送exit控制消息并关闭连接，客户端断开时结束命令
+func (s *Runtime) TerminalHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &cd.Result{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			log.Warnf("reject terminal, remote:%s, reason:%s", req.RemoteAddr, authErr.Reason)
+			result = authErr
+			break
+		}
+
+		param, paramErr := parseTerminalParam(req)
+		if paramErr != nil {
+			result = paramErr
+			break
+		}
+
+		upgradeErr := websocket.CheckUpgrade(req)
+		if upgradeErr != nil {
+			result = cd.NewError(cd.IllegalParam, upgradeErr.Error())
+			break
+		}
+
+		sessionPtr, sessionErr := s.bizPtr.OpenTerminal(req.Context(), param, req.RemoteAddr)
+		if sessionErr != nil {
+			result = sessionErr
+			break
+		}
+
+		conn, connErr := websocket.Upgrade(service.RawResponseWriter(req), req)
+		if connErr != nil {
+			_ = sessionPtr.Close()
+			_, _ = sessionPtr.Wait()
+			result = cd.NewError(cd.UnExpected, connErr.Error())
+			break
+		}
+
+		s.serveTerminal(conn, sessionPtr)
+		return
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
+
+func (s *Runtime) serveTerminal(conn *websocket.Conn, sessionPtr *biz.TerminalSession) {
+	defer conn.Close()
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+
+		buffer := make([]byte, terminalBufferSize)
+		for {
+			size, readErr := sessionPtr.Read(buffer)
+			if size > 0 && conn.WriteMessage(websocket.BinaryMessage, buffer[:size]) != nil {
+				break
+			}
+			if readErr != nil {
+				break
+			}
+		}
+
+		exitCode, waitErr := sessionPtr.Wait()
+		byteVal, _ := json.Marshal(&common.TerminalMessage{Type: common.TerminalExit, ExitCode: exitCode, Error: waitErr})
+		_ = conn.WriteMessage(websocket.TextMessage, byteVal)
+
+		closeVal := make([]byte, 2)
+		binary.BigEndian.PutUint16(closeVal, 1000)
+		_ = conn.WriteMessage(websocket.CloseMessage, closeVal)
+		_ = conn.Close()
+	}()
+
+	for {
+		messageType, data, readErr := conn.ReadMessage()
+		if readErr != nil {
+			break
+		}
+
+		if messageType == websocket.BinaryMessage {
+			if _, writeErr := sessionPtr.Write(data); writeErr != nil {
+				break
+			}
+			continue
+		}
+
+		messagePtr := &common.TerminalMessage{}
+		if json.Unmarshal(data, messagePtr) != nil {
+			log.Warnf("illegal terminal message, id:%s", sessionPtr.ID())
+			continue
+		}
+		switch messagePtr.Type {
+		case common.TerminalResize:
+			_ = sessionPtr.Resize(messagePtr.Rows, messagePtr.Cols)
+		case common.TerminalEOF:
+			_ = sessionPtr.CloseWrite()
+		}
+	}
+
+	// 客户端断开或命令已结束，确保命令退出后等待输出转发完成
+	_ = sessionPtr.Close()
+	<-outputDone
+}
+
+func (s *Runtime) QueryAuditHandle(_ context.Context, res http.ResponseWriter, req *http.Request) {
+	result := &common.QueryAuditResult{}
+	for {
+		authErr := service.CheckAdmin(req)
+		if authErr != nil {
+			result.Result = *authErr
+			break
+		}
+
+		count := 0
+		countVal := req.URL.Query().Get("count")
+		if countVal != "" {
+			val, valErr := strconv.Atoi(countVal)
+			if valErr != nil {
+				result.ErrorCode = cd.IllegalParam
+				result.Reason = "illegal count"
+				break
+			}
+			count = val
+		}
+
+		records, queryErr := s.bizPtr.QueryAudit(count)
+		if queryErr != nil {
+			result.Result = *queryErr
+			break
+		}
+
+		result.Records = records
+		break
+	}
+
+	fn.PackageHTTPResponse(res, result)
+}
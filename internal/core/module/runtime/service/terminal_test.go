@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/config"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestParseTerminalParam(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		expect    *common.TerminalParam
+		expectErr string
+	}{
+		{
+			name:   "default",
+			query:  "service=db",
+			expect: &common.TerminalParam{Service: "db"},
+		},
+		{
+			name:   "full",
+			query:  "service=db&cmd=mysql&cmd=-uroot&env=A%3D1&env=B%3D2&workDir=%2Ftmp&tty=true&rows=30&cols=100",
+			expect: &common.TerminalParam{Service: "db", Command: []string{"mysql", "-uroot"}, Env: []string{"A=1", "B=2"}, WorkDir: "/tmp", TTY: true, Rows: 30, Cols: 100},
+		},
+		{name: "no service", query: "cmd=sh", expectErr: "illegal service name"},
+		{name: "illegal tty", query: "service=db&tty=yes", expectErr: "illegal tty value:yes"},
+		{name: "illegal rows", query: "service=db&rows=-1", expectErr: "illegal rows value:-1"},
+		{name: "cols overflow", query: "service=db&cols=70000", expectErr: "illegal cols value:70000"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, err := parseTerminalParam(httptest.NewRequest(http.MethodGet, "/command/terminal?"+val.query, nil))
+			if val.expectErr != "" {
+				if err == nil || err.ErrorCode != cd.IllegalParam || err.Reason != val.expectErr {
+					t.Errorf("error %v, expect %q", err, val.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			retVal, _ := json.Marshal(ret)
+			expectVal, _ := json.Marshal(val.expect)
+			if string(retVal) != string(expectVal) {
+				t.Errorf("param %s, expect %s", retVal, expectVal)
+			}
+		})
+	}
+}
+
+func TestTerminalHandleReject(t *testing.T) {
+	t.Setenv("MARIADB_ROOT_PASSWORD", "secret")
+	err := config.LoadConfig("", func(cfg *config.CfgItem) { cfg.AdminToken = "admin-token" })
+	if err != nil {
+		t.Fatalf("load config failed, %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		query      string
+		token      string
+		expectCode cd.ErrorCode
+		expect     string
+	}{
+		{name: "without token", query: "service=db", expectCode: cd.InvalidAuthority},
+		{name: "wrong token", query: "service=db", token: "other", expectCode: cd.InvalidAuthority},
+		{name: "illegal param", query: "tty=1", token: "admin-token", expectCode: cd.IllegalParam, expect: "illegal service name"},
+		{name: "not websocket", query: "service=db", token: "admin-token", expectCode: cd.IllegalParam},
+	}
+
+	servicePtr := &Runtime{}
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, common.ExecuteTerminal+"?"+val.query, nil)
+			if val.token != "" {
+				req.Header.Set("Authorization", "Bearer "+val.token)
+			}
+			recorder := httptest.NewRecorder()
+			servicePtr.TerminalHandle(context.Background(), recorder, req)
+
+			result := &cd.Result{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("illegal response %q", recorder.Body.String())
+			}
+			if result.ErrorCode != val.expectCode || !strings.Contains(result.Reason, val.expect) {
+				t.Errorf("result %+v, expect code %d", result, val.expectCode)
+			}
+		})
+	}
+}
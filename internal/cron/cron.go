@@ -0,0 +1,175 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 5段cron表达式：分 时 日 月 周，支持 * , - / 以及月份、星期的英文缩写，
+// 日和周均不以*开头时满足其一即可，与crontab一致
+type Schedule struct {
+	minute, hour, day, month, week uint64
+
+	dayAny, weekAny bool
+}
+
+type fieldRange struct {
+	min, max int
+	names    []string
+}
+
+var (
+	minuteRange = fieldRange{min: 0, max: 59}
+	hourRange   = fieldRange{min: 0, max: 23}
+	dayRange    = fieldRange{min: 1, max: 31}
+	monthRange  = fieldRange{min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// 周日可以写作0或7
+	weekRange = fieldRange{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse 解析cron表达式
+func Parse(spec string) (ret *Schedule, err error) {
+	spec = strings.TrimSpace(spec)
+	if val, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = val
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		err = fmt.Errorf("illegal cron expression %q, expect 5 fields", spec)
+		return
+	}
+
+	schedule := &Schedule{dayAny: strings.HasPrefix(fields[2], "*"), weekAny: strings.HasPrefix(fields[4], "*")}
+	items := []struct {
+		field   string
+		bits    *uint64
+		rangeIn fieldRange
+	}{
+		{fields[0], &schedule.minute, minuteRange},
+		{fields[1], &schedule.hour, hourRange},
+		{fields[2], &schedule.day, dayRange},
+		{fields[3], &schedule.month, monthRange},
+		{fields[4], &schedule.week, weekRange},
+	}
+	for _, val := range items {
+		bits, bitsErr := parseField(val.field, val.rangeIn)
+		if bitsErr != nil {
+			err = fmt.Errorf("illegal cron expression %q, %s", spec, bitsErr.Error())
+			return
+		}
+		*val.bits = bits
+	}
+	// 7与0均表示周日
+	if schedule.week&(1<<7) != 0 {
+		schedule.week |= 1
+	}
+
+	ret = schedule
+	return
+}
+
+func parseField(field string, rangeIn fieldRange) (ret uint64, err error) {
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if rangeVal, stepVal, ok := strings.Cut(item, "/"); ok {
+			step, err = strconv.Atoi(stepVal)
+			if err != nil || step <= 0 {
+				err = fmt.Errorf("illegal step %q", item)
+				return
+			}
+			item = rangeVal
+		}
+
+		begin, end := rangeIn.min, rangeIn.max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			beginVal, endVal, _ := strings.Cut(item, "-")
+			if begin, err = rangeIn.value(beginVal); err != nil {
+				return
+			}
+			if end, err = rangeIn.value(endVal); err != nil {
+				return
+			}
+		default:
+			if begin, err = rangeIn.value(item); err != nil {
+				return
+			}
+			// a/n 表示从a开始到最大值
+			end = begin
+			if step > 1 {
+				end = rangeIn.max
+			}
+		}
+		if begin > end {
+			err = fmt.Errorf("illegal range %q", item)
+			return
+		}
+
+		for idx := begin; idx <= end; idx += step {
+			ret |= 1 << uint(idx)
+		}
+	}
+
+	return
+}
+
+func (s fieldRange) value(val string) (ret int, err error) {
+	for idx, name := range s.names {
+		if name != "" && strings.EqualFold(name, val) {
+			ret = idx
+			return
+		}
+	}
+
+	ret, err = strconv.Atoi(val)
+	if err != nil || ret < s.min || ret > s.max {
+		err = fmt.Errorf("value %q out of range [%d, %d]", val, s.min, s.max)
+	}
+	return
+}
+
+// Match 判断指定时间所在的分钟是否满足表达式
+func (s *Schedule) Match(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dayMatch := s.day&(1<<uint(t.Day())) != 0
+	weekMatch := s.week&(1<<uint(t.Weekday())) != 0
+	if s.dayAny || s.weekAny {
+		return dayMatch && weekMatch
+	}
+
+	return dayMatch || weekMatch
+}
+
+// Due 判断(preTime, curTime]之间是否有满足表达式的整分钟时刻，用于定时器按间隔检查
+func (s *Schedule) Due(preTime, curTime time.Time) bool {
+	minuteTime := preTime.Truncate(time.Minute).Add(time.Minute)
+	// 间隔过长时只检查最近一天，避免系统时间跳变后长时间循环
+	if earliest := curTime.Add(-24 * time.Hour).Truncate(time.Minute); minuteTime.Before(earliest) {
+		minuteTime = earliest
+	}
+
+	for ; !minuteTime.After(curTime); minuteTime = minuteTime.Add(time.Minute) {
+		if s.Match(minuteTime) {
+			return true
+		}
+	}
+
+	return false
+}
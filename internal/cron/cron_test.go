@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name      string
+		spec      string
+		expectErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "descriptor", spec: "@daily"},
+		{name: "list range step", spec: "0,30 1-5/2 */10 jan-mar mon-fri"},
+		{name: "sunday as 7", spec: "0 0 * * 7"},
+		{name: "too few fields", spec: "0 0 * *", expectErr: true},
+		{name: "minute out of range", spec: "60 * * * *", expectErr: true},
+		{name: "day zero", spec: "0 0 0 * *", expectErr: true},
+		{name: "illegal step", spec: "*/0 * * * *", expectErr: true},
+		{name: "reverse range", spec: "0 5-1 * * *", expectErr: true},
+		{name: "illegal name", spec: "0 0 * foo *", expectErr: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			_, err := Parse(val.spec)
+			if (err != nil) != val.expectErr {
+				t.Errorf("error %v, expect error %v", err, val.expectErr)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	// 2026-01-04为周日
+	sunday := time.Date(2026, 1, 4, 2, 0, 0, 0, time.Local)
+	cases := []struct {
+		name   string
+		spec   string
+		time   time.Time
+		expect bool
+	}{
+		{name: "daily", spec: "@daily", time: sunday.Add(-2 * time.Hour), expect: true},
+		{name: "daily other hour", spec: "@daily", time: sunday, expect: false},
+		{name: "hour range step", spec: "0 0-6/2 * * *", time: sunday, expect: true},
+		{name: "hour range step miss", spec: "0 1-6/2 * * *", time: sunday, expect: false},
+		{name: "start with step", spec: "30 1/2 * * *", time: sunday.Add(time.Hour + 30*time.Minute), expect: true},
+		{name: "sunday as 7", spec: "0 2 * * 7", time: sunday, expect: true},
+		{name: "sunday name", spec: "0 2 * * SUN", time: sunday, expect: true},
+		{name: "month name", spec: "0 2 * feb *", time: sunday, expect: false},
+		{name: "day or week", spec: "0 2 15 * sun", time: sunday, expect: true},
+		{name: "day or week by day", spec: "0 2 4 * mon", time: sunday, expect: true},
+		{name: "day and any week", spec: "0 2 15 * *", time: sunday, expect: false},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			schedule, err := Parse(val.spec)
+			if err != nil {
+				t.Fatalf("parse failed, %v", err)
+			}
+			if ret := schedule.Match(val.time); ret != val.expect {
+				t.Errorf("match %v, expect %v", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	schedule, _ := Parse("0 3 * * *")
+	base := time.Date(2026, 1, 4, 2, 59, 30, 0, time.Local)
+	cases := []struct {
+		name    string
+		preTime time.Time
+		curTime time.Time
+		expect  bool
+	}{
+		{name: "cross minute", preTime: base, curTime: base.Add(time.Minute), expect: true},
+		{name: "before minute", preTime: base, curTime: base.Add(20 * time.Second), expect: false},
+		{name: "already checked", preTime: base.Add(31 * time.Second), curTime: base.Add(90 * time.Second), expect: false},
+		{name: "long gap", preTime: base.AddDate(0, 0, -10), curTime: base.Add(-time.Hour), expect: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := schedule.Due(val.preTime, val.curTime); ret != val.expect {
+				t.Errorf("due %v, expect %v", ret, val.expect)
+			}
+		})
+	}
+}
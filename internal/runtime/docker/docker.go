@@ -0,0 +1,279 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const cmdName = "docker"
+
+func init() {
+	runtime.Register(common.DockerRuntime, func(option *runtime.Option) runtime.Runtime {
+		globalArgs := []string{}
+		if option.Endpoint != "" {
+			globalArgs = append(globalArgs, "--host", option.Endpoint)
+		}
+
+		return NewCLI(common.DockerRuntime, cmdName, globalArgs, option)
+	})
+}
+
+// CLI 通过docker兼容的命令行管理容器，nerdctl复用该实现
+type CLI struct {
+	name            string
+	cmdName         string
+	globalArgs      []string
+	executor        runtime.Executor
+	streamExecutor  runtime.StreamExecutor
+	terminalStarter runtime.TerminalStarter
+}
+
+// NewCLI 新建命令行运行时，globalArgs放在子命令之前，例如 --namespace k8s.io
+func NewCLI(name, cmdName string, globalArgs []string, option *runtime.Option) *CLI {
+	return &CLI{
+		name:            name,
+		cmdName:         cmdName,
+		globalArgs:      globalArgs,
+		executor:        option.Executor,
+		streamExecutor:  option.StreamExecutor,
+		terminalStarter: option.TerminalStarter,
+	}
+}
+
+func (s *CLI) Name() string {
+	return s.name
+}
+
+func (s *CLI) Start(name string) (stdout, stderr string, err *cd.Result) {
+	return s.run("start", name)
+}
+
+func (s *CLI) Stop(name string) (stdout, stderr string, err *cd.Result) {
+	return s.run("stop", name)
+}
+
+func (s *CLI) Restart(name string) (stdout, stderr string, err *cd.Result) {
+	return s.run("restart", name)
+}
+
+// envArgs 环境变量的值通过本地命令的环境传递，命令行中只出现变量名，避免密码等出现在进程列表中
+func envArgs(env []string) (args, localEnv []string) {
+	for _, val := range env {
+		key := val
+		if idx := strings.Index(val, "="); idx > 0 {
+			key = val[:idx]
+			localEnv = append(localEnv, val)
+		}
+		args = append(args, "--env", key)
+	}
+
+	return
+}
+
+// Exec 在容器中执行命令，退出码为docker exec的退出码，超时只结束本地命令，不结束容器中的进程
+func (s *CLI) Exec(name, cmd string, option *runtime.ExecOption) (ret *runtime.ExecResult, err *cd.Result) {
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "exec")
+	execOption := &runtime.ExecOption{}
+	if option != nil {
+		args, localEnv := envArgs(option.Env)
+		cmdArgs = append(cmdArgs, args...)
+		execOption.Env = localEnv
+		if option.WorkDir != "" {
+			cmdArgs = append(cmdArgs, "--workdir", option.WorkDir)
+		}
+		execOption.TimeOut = option.TimeOut
+		execOption.MaxOutput = option.MaxOutput
+	}
+	cmdArgs = append(cmdArgs, name, "sh", "-c", cmd)
+
+	return s.executor(context.Background(), execOption, s.cmdName, cmdArgs...)
+}
+
+// ExecStream 通过exec执行命令，不经过shell
+func (s *CLI) ExecStream(ctx context.Context, name string, cmd []string, option *runtime.ExecOption, stdout, stderr io.Writer) (err *cd.Result) {
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "exec")
+	var localEnv []string
+	if option != nil {
+		var args []string
+		args, localEnv = envArgs(option.Env)
+		cmdArgs = append(cmdArgs, args...)
+		if option.WorkDir != "" {
+			cmdArgs = append(cmdArgs, "--workdir", option.WorkDir)
+		}
+	}
+	cmdArgs = append(cmdArgs, name)
+	cmdArgs = append(cmdArgs, cmd...)
+
+	err = s.streamExecutor(ctx, localEnv, stdout, stderr, s.cmdName, cmdArgs...)
+	if err == nil && ctx.Err() != nil {
+		err = cd.NewError(cd.UnExpected, ctx.Err().Error())
+	}
+	return
+}
+
+// Attach 通过exec -i启动交互式命令，TTY时增加-t，窗口大小由本地伪终端传递给命令行
+func (s *CLI) Attach(ctx context.Context, name string, cmd []string, option *runtime.TerminalOption) (ret runtime.Terminal, err *cd.Result) {
+	if s.terminalStarter == nil {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support terminal", s.name))
+		return
+	}
+
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "exec", "--interactive")
+	if option.TTY {
+		cmdArgs = append(cmdArgs, "--tty")
+	}
+	args, localEnv := envArgs(option.Env)
+	cmdArgs = append(cmdArgs, args...)
+	if option.WorkDir != "" {
+		cmdArgs = append(cmdArgs, "--workdir", option.WorkDir)
+	}
+	cmdArgs = append(cmdArgs, name)
+	if len(cmd) == 0 {
+		cmd = runtime.DefaultTerminalCommand
+	}
+	cmdArgs = append(cmdArgs, cmd...)
+
+	// 环境变量的值和工作目录已传给容器，本地命令使用默认工作目录
+	localOption := &runtime.TerminalOption{TTY: option.TTY, Rows: option.Rows, Cols: option.Cols, Env: localEnv}
+	return s.terminalStarter(ctx, localOption, s.cmdName, cmdArgs...)
+}
+
+func (s *CLI) Logs(ctx context.Context, name string, option *runtime.LogOption, stdout, stderr io.Writer) (err *cd.Result) {
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "logs", "--tail", "all")
+	if option.Tail > 0 {
+		cmdArgs[len(cmdArgs)-1] = strconv.Itoa(option.Tail)
+	}
+	if !option.Since.IsZero() {
+		cmdArgs = append(cmdArgs, "--since", option.Since.Format(time.RFC3339))
+	}
+	if option.Follow {
+		cmdArgs = append(cmdArgs, "--follow")
+	}
+	cmdArgs = append(cmdArgs, name)
+
+	return s.streamExecutor(ctx, nil, stdout, stderr, s.cmdName, cmdArgs...)
+}
+
+func (s *CLI) Inspect(name string) (ret *common.ContainerInfo, err *cd.Result) {
+	stdout, _, stdErr := s.run("inspect", "--type", "container", name)
+	if stdErr != nil {
+		err = stdErr
+		return
+	}
+
+	infos := []*InspectInfo{}
+	byteErr := json.Unmarshal([]byte(stdout), &infos)
+	if byteErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal inspect result, %s", byteErr.Error()))
+		return
+	}
+	if len(infos) == 0 {
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("container %s not exist", name))
+		return
+	}
+
+	ret = infos[0].ContainerInfo(s.name)
+	return
+}
+
+type statsInfo struct {
+	Name     string `json:"Name"`
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	MemPerc  string `json:"MemPerc"`
+	PIDs     string `json:"PIDs"`
+}
+
+func (s *CLI) Stats(name string) (ret *common.ContainerStats, err *cd.Result) {
+	stdout, _, stdErr := s.run("stats", "--no-stream", "--no-trunc", "--format", "{{json .}}", name)
+	if stdErr != nil {
+		err = stdErr
+		return
+	}
+
+	infoPtr := &statsInfo{}
+	byteErr := json.Unmarshal([]byte(strings.TrimSpace(stdout)), infoPtr)
+	if byteErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal stats result, %s", byteErr.Error()))
+		return
+	}
+
+	// MemUsage格式为 "1.5MiB / 7.6GiB"
+	ret = &common.ContainerStats{Name: infoPtr.Name, Runtime: s.name}
+	ret.CPUPercent, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(infoPtr.CPUPerc), "%"), 64)
+	ret.MemoryPercent, _ = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(infoPtr.MemPerc), "%"), 64)
+	ret.PIDs, _ = strconv.ParseUint(strings.TrimSpace(infoPtr.PIDs), 10, 64)
+	memItems := strings.SplitN(infoPtr.MemUsage, "/", 2)
+	ret.MemoryUsage = ParseSize(memItems[0])
+	if len(memItems) == 2 {
+		ret.MemoryLimit = ParseSize(memItems[1])
+	}
+	return
+}
+
+type psInfo struct {
+	ID     string `json:"ID"`
+	Names  string `json:"Names"`
+	Image  string `json:"Image"`
+	State  string `json:"State"`
+	Status string `json:"Status"`
+}
+
+func (s *CLI) List() (ret []*common.ContainerInfo, err *cd.Result) {
+	stdout, _, stdErr := s.run("ps", "--all", "--no-trunc", "--format", "{{json .}}")
+	if stdErr != nil {
+		err = stdErr
+		return
+	}
+
+	// 每行一个json对象
+	decoder := json.NewDecoder(bytes.NewReader([]byte(stdout)))
+	for decoder.More() {
+		infoPtr := &psInfo{}
+		byteErr := decoder.Decode(infoPtr)
+		if byteErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal ps result, %s", byteErr.Error()))
+			return
+		}
+
+		ret = append(ret, &common.ContainerInfo{
+			ID:      infoPtr.ID,
+			Name:    infoPtr.Names,
+			Image:   infoPtr.Image,
+			State:   infoPtr.State,
+			Status:  infoPtr.Status,
+			Runtime: s.name,
+		})
+	}
+
+	return
+}
+
+func (s *CLI) run(args ...string) (stdout, stderr string, err *cd.Result) {
+	cmdArgs := append(append([]string{}, s.globalArgs...), args...)
+	// 抽取返回值检查是否出错
+	resultPtr, resultErr := s.executor(context.Background(), nil, s.cmdName, cmdArgs...)
+	if resultErr != nil {
+		err = resultErr
+		return
+	}
+
+	stdout = resultPtr.Stdout
+	stderr = resultPtr.Stderr
+	return
+}
@@ -0,0 +1,245 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+// fakeExecutor 记录命令参数并返回预设的结果
+type fakeExecutor struct {
+	option *runtime.ExecOption
+	args   []string
+	result *runtime.ExecResult
+	err    *cd.Result
+}
+
+func (s *fakeExecutor) execute(_ context.Context, option *runtime.ExecOption, _ string, args ...string) (*runtime.ExecResult, *cd.Result) {
+	s.option = option
+	s.args = args
+	if s.result == nil {
+		s.result = &runtime.ExecResult{}
+	}
+	return s.result, s.err
+}
+
+func TestOperate(t *testing.T) {
+	cases := []struct {
+		name   string
+		global []string
+		run    func(cliPtr *CLI) *cd.Result
+		expect string
+	}{
+		{name: "start", run: func(cliPtr *CLI) *cd.Result { _, _, err := cliPtr.Start("db"); return err }, expect: "start db"},
+		{name: "stop", run: func(cliPtr *CLI) *cd.Result { _, _, err := cliPtr.Stop("db"); return err }, expect: "stop db"},
+		{
+			name:   "nerdctl restart",
+			global: []string{"--namespace", "k8s.io"},
+			run:    func(cliPtr *CLI) *cd.Result { _, _, err := cliPtr.Restart("db"); return err },
+			expect: "--namespace k8s.io restart db",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			cliPtr := NewCLI("docker", "docker", val.global, &runtime.Option{Executor: executor.execute})
+			if err := val.run(cliPtr); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if cmdLine := strings.Join(executor.args, " "); cmdLine != val.expect {
+				t.Errorf("command line %q, expect %q", cmdLine, val.expect)
+			}
+		})
+	}
+}
+
+func TestExecEnv(t *testing.T) {
+	executor := &fakeExecutor{}
+	cliPtr := NewCLI("docker", "docker", nil, &runtime.Option{Executor: executor.execute})
+	_, err := cliPtr.Exec("mariadb001", "mysql -e'select 1'", &runtime.ExecOption{Env: []string{"MYSQL_PWD=secret", "TERM"}, WorkDir: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cmdLine := strings.Join(executor.args, " ")
+	if strings.Contains(cmdLine, "secret") {
+		t.Errorf("env value leaked into command line %q", cmdLine)
+	}
+	if !strings.HasPrefix(cmdLine, "exec --env MYSQL_PWD --env TERM --workdir /tmp mariadb001 sh -c") {
+		t.Errorf("unexpected command line %q", cmdLine)
+	}
+	if len(executor.option.Env) != 1 || executor.option.Env[0] != "MYSQL_PWD=secret" {
+		t.Errorf("local env %v, expect [MYSQL_PWD=secret]", executor.option.Env)
+	}
+}
+
+func TestExecStreamEnv(t *testing.T) {
+	var localEnv, localArgs []string
+	streamExecutor := func(_ context.Context, env []string, _, _ io.Writer, _ string, args ...string) *cd.Result {
+		localEnv, localArgs = env, args
+		return nil
+	}
+
+	cliPtr := NewCLI("docker", "docker", nil, &runtime.Option{StreamExecutor: streamExecutor})
+	option := &runtime.ExecOption{Env: []string{"MYSQL_PWD=secret"}}
+	if err := cliPtr.ExecStream(context.Background(), "mariadb001", []string{"mariabackup", "--backup"}, option, io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cmdLine := strings.Join(localArgs, " "); cmdLine != "exec --env MYSQL_PWD mariadb001 mariabackup --backup" {
+		t.Errorf("unexpected command line %q", cmdLine)
+	}
+	if len(localEnv) != 1 || localEnv[0] != "MYSQL_PWD=secret" {
+		t.Errorf("local env %v, expect [MYSQL_PWD=secret]", localEnv)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	cases := []struct {
+		name       string
+		stdout     string
+		expectCode cd.ErrorCode
+		expectInfo string
+	}{
+		{
+			name:       "running",
+			stdout:     `[{"Id":"abc","Name":"/db","RestartCount":2,"Config":{"Image":"mariadb:11"},"State":{"Status":"running","Health":{"Status":"healthy"}}}]`,
+			expectInfo: "db mariadb:11 running (healthy) 2",
+		},
+		{name: "empty", stdout: `[]`, expectCode: cd.NoExist},
+		{name: "illegal", stdout: `{`, expectCode: cd.UnExpected},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			executor := &fakeExecutor{result: &runtime.ExecResult{Stdout: val.stdout}}
+			cliPtr := NewCLI("docker", "docker", nil, &runtime.Option{Executor: executor.execute})
+			infoPtr, err := cliPtr.Inspect("db")
+			if val.expectCode != cd.Succeeded {
+				if err == nil || err.ErrorCode != val.expectCode {
+					t.Fatalf("error %v, expect code %d", err, val.expectCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			info := fmt.Sprintf("%s %s %s %d", infoPtr.Name, infoPtr.Image, infoPtr.Status, infoPtr.RestartCount)
+			if info != val.expectInfo || infoPtr.Runtime != "docker" {
+				t.Errorf("info %q, expect %q", info, val.expectInfo)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	executor := &fakeExecutor{result: &runtime.ExecResult{Stdout: "{\"ID\":\"1\",\"Names\":\"a\",\"State\":\"running\"}\n{\"ID\":\"2\",\"Names\":\"b\",\"State\":\"exited\"}\n"}}
+	cliPtr := NewCLI("podman", "podman", nil, &runtime.Option{Executor: executor.execute})
+	infos, err := cliPtr.List()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "a" || infos[1].State != "exited" || infos[1].Runtime != "podman" {
+		t.Errorf("unexpected list %+v", infos)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]uint64{
+		"1.5MiB":  1572864,
+		" 2GB ":   2000000000,
+		"512B":    512,
+		"100":     100,
+		"7.5 GiB": 8053063680,
+		"abc":     0,
+		"1XB":     0,
+	}
+
+	for key, val := range cases {
+		if ret := ParseSize(key); ret != val {
+			t.Errorf("ParseSize(%q) = %d, expect %d", key, ret, val)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	cases := []struct {
+		name       string
+		stdout     string
+		expectCode cd.ErrorCode
+		expect     string
+	}{
+		{
+			name:   "running",
+			stdout: `{"Name":"db","CPUPerc":"12.50%","MemUsage":"512MiB / 2GiB","MemPerc":"25.00%","PIDs":"31"}`,
+			expect: "db cpu:12.50 mem:536870912/2147483648 25.00% pids:31",
+		},
+		{
+			name:   "stopped",
+			stdout: `{"Name":"db","CPUPerc":"--","MemUsage":"-- / --","MemPerc":"--","PIDs":"--"}`,
+			expect: "db cpu:0.00 mem:0/0 0.00% pids:0",
+		},
+		{name: "illegal", stdout: `not json`, expectCode: cd.UnExpected},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			executor := &fakeExecutor{result: &runtime.ExecResult{Stdout: val.stdout + "\n"}}
+			statsPtr, err := NewCLI("docker", "docker", nil, &runtime.Option{Executor: executor.execute}).Stats("db")
+			if val.expectCode != cd.Succeeded {
+				if err == nil || err.ErrorCode != val.expectCode {
+					t.Fatalf("error %v, expect code %d", err, val.expectCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			stats := fmt.Sprintf("%s cpu:%.2f mem:%d/%d %.2f%% pids:%d", statsPtr.Name, statsPtr.CPUPercent,
+				statsPtr.MemoryUsage, statsPtr.MemoryLimit, statsPtr.MemoryPercent, statsPtr.PIDs)
+			if stats != val.expect {
+				t.Errorf("stats %q, expect %q", stats, val.expect)
+			}
+		})
+	}
+}
+
+func TestLogs(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		name   string
+		option *runtime.LogOption
+		expect string
+	}{
+		{name: "all", option: &runtime.LogOption{}, expect: "logs --tail all db"},
+		{name: "tail", option: &runtime.LogOption{Tail: 20}, expect: "logs --tail 20 db"},
+		{name: "since follow", option: &runtime.LogOption{Since: since, Follow: true}, expect: "logs --tail all --since " + since.Format(time.RFC3339) + " --follow db"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			var cmdLine string
+			streamExecutor := func(_ context.Context, _ []string, _, _ io.Writer, _ string, args ...string) *cd.Result {
+				cmdLine = strings.Join(args, " ")
+				return nil
+			}
+
+			cliPtr := NewCLI("docker", "docker", nil, &runtime.Option{StreamExecutor: streamExecutor})
+			if err := cliPtr.Logs(context.Background(), "db", val.option, io.Discard, io.Discard); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if cmdLine != val.expect {
+				t.Errorf("command line %q, expect %q", cmdLine, val.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// watchEvents 订阅的容器事件
+var watchEvents = []string{
+	common.ContainerDie,
+	common.ContainerOOM,
+	common.ContainerHealthStatus,
+	common.ContainerRestart,
+}
+
+// EventMessage docker events输出的事件，podman兼容接口使用相同格式
+type EventMessage struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time     int64 `json:"time"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+// ContainerEvent 转换为容器事件，非容器事件或不关注的事件返回nil
+func (s *EventMessage) ContainerEvent(runtimeName string) *common.ContainerEvent {
+	if s.Type != "" && s.Type != "container" {
+		return nil
+	}
+
+	// 健康检查事件格式为 "health_status: unhealthy"
+	action, health, _ := strings.Cut(s.Action, ":")
+	action = strings.TrimSpace(action)
+	health = strings.TrimSpace(health)
+	if health == "" {
+		health = s.Actor.Attributes["health_status"]
+	}
+
+	eventPtr := &common.ContainerEvent{
+		ID:      s.Actor.ID,
+		Name:    s.Actor.Attributes["name"],
+		Action:  action,
+		Runtime: runtimeName,
+	}
+	switch action {
+	case common.ContainerDie:
+		eventPtr.ExitCode, _ = strconv.Atoi(s.Actor.Attributes["exitCode"])
+	case common.ContainerHealthStatus:
+		eventPtr.Health = health
+	case common.ContainerOOM, common.ContainerRestart:
+	default:
+		return nil
+	}
+
+	eventPtr.TimeStamp = time.Unix(s.Time, 0)
+	if s.TimeNano > 0 {
+		eventPtr.TimeStamp = time.Unix(0, s.TimeNano)
+	}
+
+	return eventPtr
+}
+
+// Watch 通过docker events订阅容器事件，nerdctl的事件格式不同，暂不支持
+func (s *CLI) Watch(ctx context.Context, since time.Time, handler runtime.EventHandler) *cd.Result {
+	if s.name != common.DockerRuntime {
+		return cd.NewError(cd.IllegalParam, fmt.Sprintf("runtime %s not support events", s.name))
+	}
+
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "events", "--format", "{{json .}}", "--filter", "type=container")
+	for _, val := range watchEvents {
+		cmdArgs = append(cmdArgs, "--filter", "event="+val)
+	}
+	if !since.IsZero() {
+		cmdArgs = append(cmdArgs, "--since", since.Format(time.RFC3339Nano))
+	}
+
+	writer := &lineWriter{handler: func(line []byte) {
+		messagePtr := &EventMessage{}
+		if err := json.Unmarshal(line, messagePtr); err != nil {
+			log.Warnf("illegal %s event, %s", s.name, err.Error())
+			return
+		}
+
+		if eventPtr := messagePtr.ContainerEvent(s.name); eventPtr != nil {
+			handler(eventPtr)
+		}
+	}}
+	errput := &bytes.Buffer{}
+	err := s.streamExecutor(ctx, nil, writer, errput, s.cmdName, cmdArgs...)
+	if err != nil && errput.Len() > 0 {
+		err = cd.NewError(err.ErrorCode, strings.TrimSpace(errput.String()))
+	}
+
+	return err
+}
+
+// lineWriter 按行回调输出内容
+type lineWriter struct {
+	buffer  []byte
+	handler func(line []byte)
+}
+
+func (s *lineWriter) Write(data []byte) (int, error) {
+	s.buffer = append(s.buffer, data...)
+	for {
+		idx := bytes.IndexByte(s.buffer, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := bytes.TrimSpace(s.buffer[:idx])
+		s.buffer = s.buffer[idx+1:]
+		if len(line) > 0 {
+			s.handler(line)
+		}
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestContainerEvent(t *testing.T) {
+	cases := []struct {
+		name   string
+		msg    EventMessage
+		expect *common.ContainerEvent
+	}{
+		{
+			name:   "die",
+			msg:    newMessage("container", "die", map[string]string{"name": "db", "exitCode": "137"}, 1767323045, 0),
+			expect: &common.ContainerEvent{ID: "c1", Name: "db", Action: common.ContainerDie, ExitCode: 137, Runtime: "docker", TimeStamp: time.Unix(1767323045, 0)},
+		},
+		{
+			name:   "health in action",
+			msg:    newMessage("container", "health_status: unhealthy", map[string]string{"name": "db"}, 0, 1767323045000000123),
+			expect: &common.ContainerEvent{ID: "c1", Name: "db", Action: common.ContainerHealthStatus, Health: "unhealthy", Runtime: "docker", TimeStamp: time.Unix(0, 1767323045000000123)},
+		},
+		{
+			name:   "health in attributes",
+			msg:    newMessage("", "health_status", map[string]string{"name": "db", "health_status": "healthy"}, 1767323045, 0),
+			expect: &common.ContainerEvent{ID: "c1", Name: "db", Action: common.ContainerHealthStatus, Health: "healthy", Runtime: "docker", TimeStamp: time.Unix(1767323045, 0)},
+		},
+		{
+			name:   "oom",
+			msg:    newMessage("container", "oom", map[string]string{"name": "db"}, 1767323045, 0),
+			expect: &common.ContainerEvent{ID: "c1", Name: "db", Action: common.ContainerOOM, Runtime: "docker", TimeStamp: time.Unix(1767323045, 0)},
+		},
+		{name: "ignored action", msg: newMessage("container", "start", map[string]string{"name": "db"}, 1767323045, 0)},
+		{name: "not container", msg: newMessage("network", "die", nil, 1767323045, 0)},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret := val.msg.ContainerEvent("docker")
+			if val.expect == nil {
+				if ret != nil {
+					t.Errorf("event %+v, expect nil", ret)
+				}
+				return
+			}
+			if ret == nil {
+				t.Fatalf("event nil, expect %+v", val.expect)
+			}
+			if !ret.TimeStamp.Equal(val.expect.TimeStamp) {
+				t.Errorf("time stamp %v, expect %v", ret.TimeStamp, val.expect.TimeStamp)
+			}
+			ret.TimeStamp = val.expect.TimeStamp
+			if *ret != *val.expect {
+				t.Errorf("event %+v, expect %+v", ret, val.expect)
+			}
+		})
+	}
+}
+
+func newMessage(eventType, action string, attributes map[string]string, timeVal, timeNano int64) EventMessage {
+	msg := EventMessage{Type: eventType, Action: action, Time: timeVal, TimeNano: timeNano}
+	msg.Actor.ID = "c1"
+	msg.Actor.Attributes = attributes
+	return msg
+}
+
+func TestLineWriter(t *testing.T) {
+	lines := []string{}
+	writer := &lineWriter{handler: func(line []byte) {
+		lines = append(lines, string(line))
+	}}
+
+	for _, val := range []string{"first\nsec", "ond\n\n  \nthi", "rd"} {
+		if size, err := writer.Write([]byte(val)); err != nil || size != len(val) {
+			t.Fatalf("write %q, size %d, error %v", val, size, err)
+		}
+	}
+	if strings.Join(lines, ",") != "first,second" {
+		t.Errorf("lines %q, expect first,second", lines)
+	}
+	if string(writer.buffer) != "third" {
+		t.Errorf("buffer %q, expect third", writer.buffer)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var cmdLine string
+	streamExecutor := func(_ context.Context, _ []string, stdout, _ io.Writer, _ string, args ...string) *cd.Result {
+		cmdLine = strings.Join(args, " ")
+		_, _ = io.WriteString(stdout, `{"Type":"container","Action":"die","Actor":{"ID":"c1","Attributes":{"name":"db","exitCode":"1"}},"time":1767323045}`+"\n")
+		_, _ = io.WriteString(stdout, "illegal\n")
+		_, _ = io.WriteString(stdout, `{"Type":"container","Action":"start","Actor":{"ID":"c1","Attributes":{"name":"db"}},"time":1767323046}`+"\n")
+		return nil
+	}
+
+	events := []*common.ContainerEvent{}
+	cliPtr := NewCLI(common.DockerRuntime, "docker", nil, &runtime.Option{StreamExecutor: streamExecutor})
+	err := cliPtr.Watch(context.Background(), since, func(eventPtr *common.ContainerEvent) {
+		events = append(events, eventPtr)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := "events --format {{json .}} --filter type=container --filter event=die --filter event=oom --filter event=health_status --filter event=restart --since " + since.Format(time.RFC3339Nano)
+	if cmdLine != expect {
+		t.Errorf("command line %q, expect %q", cmdLine, expect)
+	}
+	if len(events) != 1 || events[0].Name != "db" || events[0].ExitCode != 1 {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	nerdctlPtr := NewCLI("nerdctl", "nerdctl", nil, &runtime.Option{StreamExecutor: streamExecutor})
+	err = nerdctlPtr.Watch(context.Background(), since, func(*common.ContainerEvent) {})
+	if err == nil || err.ErrorCode != cd.IllegalParam {
+		t.Errorf("error %v, expect illegal param", err)
+	}
+}
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// InspectInfo docker inspect结果，podman兼容API返回相同结构
+type InspectInfo struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	RestartCount int    `json:"RestartCount"`
+	Config       struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	State struct {
+		Status     string `json:"Status"`
+		OOMKilled  bool   `json:"OOMKilled"`
+		ExitCode   int    `json:"ExitCode"`
+		StartedAt  string `json:"StartedAt"`
+		FinishedAt string `json:"FinishedAt"`
+		Health     *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	Mounts []struct {
+		Type        string `json:"Type"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+}
+
+// ContainerInfo 转换为通用容器信息
+func (s *InspectInfo) ContainerInfo(runtimeName string) *common.ContainerInfo {
+	ret := &common.ContainerInfo{
+		ID:           s.ID,
+		Name:         strings.TrimPrefix(s.Name, "/"),
+		Image:        s.Config.Image,
+		State:        s.State.Status,
+		Status:       s.State.Status,
+		RestartCount: s.RestartCount,
+		ExitCode:     s.State.ExitCode,
+		OOMKilled:    s.State.OOMKilled,
+		StartedAt:    s.State.StartedAt,
+		FinishedAt:   s.State.FinishedAt,
+		Runtime:      runtimeName,
+	}
+	if s.State.Health != nil {
+		ret.Health = s.State.Health.Status
+		ret.Status = s.State.Status + " (" + s.State.Health.Status + ")"
+	}
+
+	for _, val := range s.Mounts {
+		ret.Mounts = append(ret.Mounts, &common.MountInfo{
+			Type:        val.Type,
+			Source:      val.Source,
+			Destination: val.Destination,
+			ReadOnly:    !val.RW,
+		})
+	}
+
+	return ret
+}
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1024,
+	"mib": 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+	"tib": 1024 * 1024 * 1024 * 1024,
+}
+
+// ParseSize 解析docker stats输出的容量，例如 1.5MiB、2GB，无法解析时返回0
+func ParseSize(val string) uint64 {
+	val = strings.TrimSpace(val)
+	idx := strings.IndexFunc(val, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if idx < 0 {
+		idx = len(val)
+	}
+
+	number, numberErr := strconv.ParseFloat(val[:idx], 64)
+	unit, unitOK := sizeUnits[strings.ToLower(strings.TrimSpace(val[idx:]))]
+	if numberErr != nil || !unitOK {
+		return 0
+	}
+
+	return uint64(number * unit)
+}
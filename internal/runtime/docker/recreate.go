@@ -0,0 +1,498 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type healthConfig struct {
+	Test        []string `json:"Test,omitempty"`
+	Interval    int64    `json:"Interval,omitempty"`
+	Timeout     int64    `json:"Timeout,omitempty"`
+	StartPeriod int64    `json:"StartPeriod,omitempty"`
+	Retries     int      `json:"Retries,omitempty"`
+}
+
+// containerConfig 容器配置，字段与docker兼容API的Config一致
+type containerConfig struct {
+	Hostname     string              `json:"Hostname,omitempty"`
+	Domainname   string              `json:"Domainname,omitempty"`
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Tty          bool                `json:"Tty,omitempty"`
+	OpenStdin    bool                `json:"OpenStdin,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Healthcheck  *healthConfig       `json:"Healthcheck,omitempty"`
+	Image        string              `json:"Image,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+	StopTimeout  *int                `json:"StopTimeout,omitempty"`
+}
+
+type portBinding struct {
+	HostIP   string `json:"HostIp,omitempty"`
+	HostPort string `json:"HostPort,omitempty"`
+}
+
+type ulimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+type mountConfig struct {
+	Type     string `json:"Type"`
+	Source   string `json:"Source,omitempty"`
+	Target   string `json:"Target"`
+	ReadOnly bool   `json:"ReadOnly,omitempty"`
+}
+
+// hostConfig 宿主机相关配置，字段与docker兼容API的HostConfig一致，Binds统一转换为Mounts
+type hostConfig struct {
+	NetworkMode   string                   `json:"NetworkMode,omitempty"`
+	PortBindings  map[string][]portBinding `json:"PortBindings,omitempty"`
+	RestartPolicy struct {
+		Name              string `json:"Name,omitempty"`
+		MaximumRetryCount int    `json:"MaximumRetryCount,omitempty"`
+	} `json:"RestartPolicy"`
+	Privileged        bool      `json:"Privileged,omitempty"`
+	CapAdd            []string  `json:"CapAdd,omitempty"`
+	CapDrop           []string  `json:"CapDrop,omitempty"`
+	ExtraHosts        []string  `json:"ExtraHosts,omitempty"`
+	DNS               []string  `json:"Dns,omitempty"`
+	DNSSearch         []string  `json:"DnsSearch,omitempty"`
+	DNSOptions        []string  `json:"DnsOptions,omitempty"`
+	Memory            int64     `json:"Memory,omitempty"`
+	MemoryReservation int64     `json:"MemoryReservation,omitempty"`
+	MemorySwap        int64     `json:"MemorySwap,omitempty"`
+	NanoCPUs          int64     `json:"NanoCpus,omitempty"`
+	CPUShares         int64     `json:"CpuShares,omitempty"`
+	CpusetCpus        string    `json:"CpusetCpus,omitempty"`
+	ShmSize           int64     `json:"ShmSize,omitempty"`
+	Ulimits           []*ulimit `json:"Ulimits,omitempty"`
+	LogConfig         struct {
+		Type   string            `json:"Type,omitempty"`
+		Config map[string]string `json:"Config,omitempty"`
+	} `json:"LogConfig"`
+	SecurityOpt []string          `json:"SecurityOpt,omitempty"`
+	Sysctls     map[string]string `json:"Sysctls,omitempty"`
+	Tmpfs       map[string]string `json:"Tmpfs,omitempty"`
+	PidMode     string            `json:"PidMode,omitempty"`
+	IpcMode     string            `json:"IpcMode,omitempty"`
+	Init        *bool             `json:"Init,omitempty"`
+	Mounts      []*mountConfig    `json:"Mounts,omitempty"`
+}
+
+type ipamConfig struct {
+	IPv4Address string `json:"IPv4Address,omitempty"`
+	IPv6Address string `json:"IPv6Address,omitempty"`
+}
+
+// EndpointConfig 容器在网络中的配置
+type EndpointConfig struct {
+	IPAMConfig *ipamConfig `json:"IPAMConfig,omitempty"`
+	Aliases    []string    `json:"Aliases,omitempty"`
+}
+
+// containerSpec 重建所需的inspect信息
+type containerSpec struct {
+	ID         string          `json:"Id"`
+	Name       string          `json:"Name"`
+	Image      string          `json:"Image"`
+	Config     containerConfig `json:"Config"`
+	HostConfig hostConfig      `json:"HostConfig"`
+	Mounts     []struct {
+		Type        string `json:"Type"`
+		Name        string `json:"Name"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Networks map[string]*EndpointConfig `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+type imageSpec struct {
+	Config containerConfig `json:"Config"`
+}
+
+// CreateSpec 使用新镜像重建容器的参数，由原容器和原镜像的inspect结果生成
+// 与原镜像默认值相同的Env、Labels、Cmd等配置不再保留，由新镜像提供；
+// 全部挂载(包括匿名卷)按原卷名或路径挂载，保证数据沿用
+type CreateSpec struct {
+	Name       string
+	Config     containerConfig
+	HostConfig hostConfig
+	// Networks NetworkMode以外的网络，创建后逐个连接
+	Networks map[string]*EndpointConfig
+
+	network *EndpointConfig
+}
+
+// OldImageID 解析原容器使用的镜像ID，用于查询原镜像配置
+func OldImageID(containerVal []byte) (ret string, err error) {
+	specPtr := &containerSpec{}
+	err = json.Unmarshal(containerVal, specPtr)
+	if err == nil {
+		ret = specPtr.Image
+	}
+	return
+}
+
+// NewCreateSpec containerVal、imageVal为单个容器和镜像的inspect结果
+func NewCreateSpec(containerVal, imageVal []byte, image string) (ret *CreateSpec, err error) {
+	specPtr := &containerSpec{}
+	err = json.Unmarshal(containerVal, specPtr)
+	if err != nil {
+		err = fmt.Errorf("illegal container inspect result, %s", err.Error())
+		return
+	}
+	imagePtr := &imageSpec{}
+	err = json.Unmarshal(imageVal, imagePtr)
+	if err != nil {
+		err = fmt.Errorf("illegal image inspect result, %s", err.Error())
+		return
+	}
+
+	oldConfig, imageConfig := specPtr.Config, imagePtr.Config
+	shortID := specPtr.ID
+	if len(shortID) > 12 {
+		shortID = shortID[:12]
+	}
+
+	newConfig := containerConfig{
+		Domainname:  oldConfig.Domainname,
+		Tty:         oldConfig.Tty,
+		OpenStdin:   oldConfig.OpenStdin,
+		Image:       image,
+		StopTimeout: oldConfig.StopTimeout,
+	}
+	if oldConfig.Hostname != shortID {
+		newConfig.Hostname = oldConfig.Hostname
+	}
+	if oldConfig.User != imageConfig.User {
+		newConfig.User = oldConfig.User
+	}
+	if oldConfig.WorkingDir != imageConfig.WorkingDir {
+		newConfig.WorkingDir = oldConfig.WorkingDir
+	}
+	if oldConfig.StopSignal != imageConfig.StopSignal {
+		newConfig.StopSignal = oldConfig.StopSignal
+	}
+	if !reflect.DeepEqual(oldConfig.Healthcheck, imageConfig.Healthcheck) {
+		newConfig.Healthcheck = oldConfig.Healthcheck
+	}
+	for _, val := range oldConfig.Env {
+		if !containsString(imageConfig.Env, val) {
+			newConfig.Env = append(newConfig.Env, val)
+		}
+	}
+	for key, val := range oldConfig.Labels {
+		if imageVal, ok := imageConfig.Labels[key]; !ok || imageVal != val {
+			if newConfig.Labels == nil {
+				newConfig.Labels = map[string]string{}
+			}
+			newConfig.Labels[key] = val
+		}
+	}
+	for key := range oldConfig.ExposedPorts {
+		if _, ok := imageConfig.ExposedPorts[key]; !ok {
+			if newConfig.ExposedPorts == nil {
+				newConfig.ExposedPorts = map[string]struct{}{}
+			}
+			newConfig.ExposedPorts[key] = struct{}{}
+		}
+	}
+	// 指定Entrypoint时镜像的Cmd失效，需要同时保留Cmd
+	if !reflect.DeepEqual(oldConfig.Entrypoint, imageConfig.Entrypoint) {
+		newConfig.Entrypoint = oldConfig.Entrypoint
+		newConfig.Cmd = oldConfig.Cmd
+	} else if !reflect.DeepEqual(oldConfig.Cmd, imageConfig.Cmd) {
+		newConfig.Cmd = oldConfig.Cmd
+	}
+
+	newHost := specPtr.HostConfig
+	newHost.Mounts = nil
+	for _, val := range specPtr.Mounts {
+		mountPtr := &mountConfig{Type: val.Type, Source: val.Source, Target: val.Destination, ReadOnly: !val.RW}
+		switch val.Type {
+		case "bind":
+		case "volume":
+			mountPtr.Source = val.Name
+		default:
+			continue
+		}
+		newHost.Mounts = append(newHost.Mounts, mountPtr)
+	}
+
+	ret = &CreateSpec{
+		Name:       strings.TrimPrefix(specPtr.Name, "/"),
+		Config:     newConfig,
+		HostConfig: newHost,
+		Networks:   map[string]*EndpointConfig{},
+	}
+	for key, val := range specPtr.NetworkSettings.Networks {
+		endpointPtr := &EndpointConfig{IPAMConfig: val.IPAMConfig}
+		// 容器ID和名称由运行时自动加入别名
+		for _, alias := range val.Aliases {
+			if alias != shortID && alias != ret.Name {
+				endpointPtr.Aliases = append(endpointPtr.Aliases, alias)
+			}
+		}
+
+		if key == newHost.NetworkMode || (ret.network == nil && isDefaultNetwork(newHost.NetworkMode) && isDefaultNetwork(key)) {
+			ret.network = endpointPtr
+			continue
+		}
+		ret.Networks[key] = endpointPtr
+	}
+
+	return
+}
+
+func isDefaultNetwork(name string) bool {
+	return name == "" || name == "default" || name == "bridge"
+}
+
+func containsString(items []string, val string) bool {
+	for _, item := range items {
+		if item == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIBody docker兼容API创建容器的请求内容
+func (s *CreateSpec) APIBody() interface{} {
+	body := struct {
+		containerConfig
+		HostConfig       hostConfig `json:"HostConfig"`
+		NetworkingConfig struct {
+			EndpointsConfig map[string]*EndpointConfig `json:"EndpointsConfig,omitempty"`
+		} `json:"NetworkingConfig"`
+	}{containerConfig: s.Config, HostConfig: s.HostConfig}
+	if s.network != nil && !isDefaultNetwork(s.HostConfig.NetworkMode) {
+		body.NetworkingConfig.EndpointsConfig = map[string]*EndpointConfig{s.HostConfig.NetworkMode: s.network}
+	}
+
+	return body
+}
+
+func durationArg(val int64) string {
+	return time.Duration(val).String()
+}
+
+func sortedKeys(items map[string]string) []string {
+	keys := []string{}
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CreateArgs docker create的参数，不含全局参数
+func (s *CreateSpec) CreateArgs() []string {
+	cfg, host := s.Config, s.HostConfig
+	args := []string{"create", "--name", s.Name}
+	appendArg := func(name, val string) {
+		if val != "" {
+			args = append(args, name, val)
+		}
+	}
+	appendInt := func(name string, val int64) {
+		if val != 0 {
+			args = append(args, name, strconv.FormatInt(val, 10))
+		}
+	}
+
+	appendArg("--hostname", cfg.Hostname)
+	appendArg("--domainname", cfg.Domainname)
+	appendArg("--user", cfg.User)
+	appendArg("--workdir", cfg.WorkingDir)
+	appendArg("--stop-signal", cfg.StopSignal)
+	if cfg.StopTimeout != nil {
+		args = append(args, "--stop-timeout", strconv.Itoa(*cfg.StopTimeout))
+	}
+	if cfg.Tty {
+		args = append(args, "--tty")
+	}
+	if cfg.OpenStdin {
+		args = append(args, "--interactive")
+	}
+	for _, val := range cfg.Env {
+		args = append(args, "--env", val)
+	}
+	for _, key := range sortedKeys(cfg.Labels) {
+		args = append(args, "--label", key+"="+cfg.Labels[key])
+	}
+	for key := range cfg.ExposedPorts {
+		args = append(args, "--expose", key)
+	}
+	if healthPtr := cfg.Healthcheck; healthPtr != nil {
+		switch {
+		case len(healthPtr.Test) > 0 && healthPtr.Test[0] == "NONE":
+			args = append(args, "--no-healthcheck")
+		case len(healthPtr.Test) > 1 && healthPtr.Test[0] == "CMD-SHELL":
+			args = append(args, "--health-cmd", healthPtr.Test[1])
+		case len(healthPtr.Test) > 1 && healthPtr.Test[0] == "CMD":
+			args = append(args, "--health-cmd", shellJoin(healthPtr.Test[1:]))
+		}
+		if healthPtr.Interval > 0 {
+			args = append(args, "--health-interval", durationArg(healthPtr.Interval))
+		}
+		if healthPtr.Timeout > 0 {
+			args = append(args, "--health-timeout", durationArg(healthPtr.Timeout))
+		}
+		if healthPtr.StartPeriod > 0 {
+			args = append(args, "--health-start-period", durationArg(healthPtr.StartPeriod))
+		}
+		appendInt("--health-retries", int64(healthPtr.Retries))
+	}
+
+	if !isDefaultNetwork(host.NetworkMode) {
+		args = append(args, "--network", host.NetworkMode)
+	}
+	if s.network != nil && !strings.HasPrefix(host.NetworkMode, "container:") && host.NetworkMode != "host" {
+		if s.network.IPAMConfig != nil {
+			appendArg("--ip", s.network.IPAMConfig.IPv4Address)
+			appendArg("--ip6", s.network.IPAMConfig.IPv6Address)
+		}
+		for _, val := range s.network.Aliases {
+			args = append(args, "--network-alias", val)
+		}
+	}
+	for port, bindings := range host.PortBindings {
+		for _, val := range bindings {
+			publish := port
+			if val.HostPort != "" || val.HostIP != "" {
+				publish = val.HostPort + ":" + port
+				if val.HostIP != "" {
+					publish = val.HostIP + ":" + publish
+				}
+			}
+			args = append(args, "--publish", publish)
+		}
+	}
+	if policy := host.RestartPolicy.Name; policy != "" && policy != "no" {
+		if host.RestartPolicy.MaximumRetryCount > 0 {
+			policy += ":" + strconv.Itoa(host.RestartPolicy.MaximumRetryCount)
+		}
+		args = append(args, "--restart", policy)
+	}
+	if host.Privileged {
+		args = append(args, "--privileged")
+	}
+	for _, val := range host.CapAdd {
+		args = append(args, "--cap-add", val)
+	}
+	for _, val := range host.CapDrop {
+		args = append(args, "--cap-drop", val)
+	}
+	for _, val := range host.ExtraHosts {
+		args = append(args, "--add-host", val)
+	}
+	for _, val := range host.DNS {
+		args = append(args, "--dns", val)
+	}
+	for _, val := range host.DNSSearch {
+		args = append(args, "--dns-search", val)
+	}
+	for _, val := range host.DNSOptions {
+		args = append(args, "--dns-option", val)
+	}
+	appendInt("--memory", host.Memory)
+	appendInt("--memory-reservation", host.MemoryReservation)
+	appendInt("--memory-swap", host.MemorySwap)
+	if host.NanoCPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(host.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	appendInt("--cpu-shares", host.CPUShares)
+	appendArg("--cpuset-cpus", host.CpusetCpus)
+	appendInt("--shm-size", host.ShmSize)
+	for _, val := range host.Ulimits {
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%d:%d", val.Name, val.Soft, val.Hard))
+	}
+	if host.LogConfig.Type != "" {
+		args = append(args, "--log-driver", host.LogConfig.Type)
+		for _, key := range sortedKeys(host.LogConfig.Config) {
+			args = append(args, "--log-opt", key+"="+host.LogConfig.Config[key])
+		}
+	}
+	for _, val := range host.SecurityOpt {
+		args = append(args, "--security-opt", val)
+	}
+	for _, key := range sortedKeys(host.Sysctls) {
+		args = append(args, "--sysctl", key+"="+host.Sysctls[key])
+	}
+	for _, key := range sortedKeys(host.Tmpfs) {
+		tmpfs := key
+		if host.Tmpfs[key] != "" {
+			tmpfs += ":" + host.Tmpfs[key]
+		}
+		args = append(args, "--tmpfs", tmpfs)
+	}
+	appendArg("--pid", host.PidMode)
+	if host.IpcMode != "" && host.IpcMode != "private" && host.IpcMode != "shareable" {
+		args = append(args, "--ipc", host.IpcMode)
+	}
+	if host.Init != nil && *host.Init {
+		args = append(args, "--init")
+	}
+	for _, val := range host.Mounts {
+		mount := fmt.Sprintf("type=%s,source=%s,target=%s", val.Type, val.Source, val.Target)
+		if val.ReadOnly {
+			mount += ",readonly"
+		}
+		args = append(args, "--mount", mount)
+	}
+
+	// --entrypoint只接受单个值，其余部分放在命令参数之前
+	cmd := cfg.Cmd
+	if len(cfg.Entrypoint) > 0 {
+		args = append(args, "--entrypoint", cfg.Entrypoint[0])
+		cmd = append(append([]string{}, cfg.Entrypoint[1:]...), cmd...)
+	}
+	args = append(args, cfg.Image)
+	return append(args, cmd...)
+}
+
+// ConnectArgs 将容器连接到NetworkMode以外网络的参数
+func (s *CreateSpec) ConnectArgs(network string) []string {
+	args := []string{"network", "connect"}
+	endpointPtr := s.Networks[network]
+	if endpointPtr != nil {
+		if endpointPtr.IPAMConfig != nil && endpointPtr.IPAMConfig.IPv4Address != "" {
+			args = append(args, "--ip", endpointPtr.IPAMConfig.IPv4Address)
+		}
+		if endpointPtr.IPAMConfig != nil && endpointPtr.IPAMConfig.IPv6Address != "" {
+			args = append(args, "--ip6", endpointPtr.IPAMConfig.IPv6Address)
+		}
+		for _, val := range endpointPtr.Aliases {
+			args = append(args, "--alias", val)
+		}
+	}
+
+	return append(args, network, s.Name)
+}
+
+// shellJoin 按shell规则拼接参数，用于将exec形式的健康检查转换为--health-cmd
+func shellJoin(items []string) string {
+	quoted := []string{}
+	for _, val := range items {
+		quoted = append(quoted, "'"+strings.ReplaceAll(val, "'", "'\\''")+"'")
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+// Run 通过run --rm运行临时容器，ctx取消时命令行进程被结束但容器仍在运行，需要强制删除
+func (s *CLI) Run(ctx context.Context, name string, cmd []string, option *runtime.RunOption, stdout, stderr io.Writer) (err *cd.Result) {
+	if len(cmd) == 0 {
+		err = cd.NewError(cd.IllegalParam, "illegal command")
+		return
+	}
+
+	containerVal, containerErr := s.inspectRaw("container", name)
+	if containerErr != nil {
+		err = containerErr
+		return
+	}
+	imageID, imageErr := OldImageID(containerVal)
+	if imageErr != nil {
+		err = cd.NewError(cd.UnExpected, imageErr.Error())
+		return
+	}
+
+	// 清理上次异常退出时残留的临时容器
+	runName := runtime.RunName(name)
+	_, _, _ = s.run("rm", "--force", runName)
+
+	cmdArgs := append([]string{}, s.globalArgs...)
+	cmdArgs = append(cmdArgs, "run", "--rm", "--name", runName, "--volumes-from", name, "--entrypoint", cmd[0])
+	var localEnv []string
+	if option != nil {
+		for _, val := range option.Mounts {
+			cmdArgs = append(cmdArgs, "--volume", val+":"+val)
+		}
+		var args []string
+		args, localEnv = envArgs(option.Env)
+		cmdArgs = append(cmdArgs, args...)
+		if option.ShareNetwork {
+			cmdArgs = append(cmdArgs, "--network", "container:"+name)
+		}
+	}
+	cmdArgs = append(cmdArgs, imageID)
+	cmdArgs = append(cmdArgs, cmd[1:]...)
+
+	err = s.streamExecutor(ctx, localEnv, stdout, stderr, s.cmdName, cmdArgs...)
+	if ctx.Err() != nil {
+		_, _, _ = s.run("rm", "--force", runName)
+		if err == nil {
+			err = cd.NewError(cd.UnExpected, ctx.Err().Error())
+		}
+	}
+	return
+}
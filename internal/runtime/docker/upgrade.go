@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+func (s *CLI) PullImage(ctx context.Context, image string) (err *cd.Result) {
+	cmdArgs := append(append([]string{}, s.globalArgs...), "pull", image)
+	_, err = s.executor(ctx, &runtime.ExecOption{TimeOut: runtime.PullTimeOut}, s.cmdName, cmdArgs...)
+	return
+}
+
+// inspectRaw 返回单个对象的inspect结果
+func (s *CLI) inspectRaw(objType, name string) (ret []byte, err *cd.Result) {
+	stdout, _, stdErr := s.run("inspect", "--type", objType, name)
+	if stdErr != nil {
+		err = stdErr
+		return
+	}
+
+	items := []json.RawMessage{}
+	byteErr := json.Unmarshal([]byte(stdout), &items)
+	if byteErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal inspect result, %s", byteErr.Error()))
+		return
+	}
+	if len(items) == 0 {
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("%s %s not exist", objType, name))
+		return
+	}
+
+	ret = items[0]
+	return
+}
+
+// Recreate 停止原容器并改名保留，使用新镜像和原配置创建同名容器并启动，失败时恢复原容器
+func (s *CLI) Recreate(name, image string) (oldImage string, err *cd.Result) {
+	backupName := runtime.BackupName(name)
+	if _, _, backupErr := s.run("inspect", "--type", "container", backupName); backupErr == nil {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("container %s exist, commit or rollback previous upgrade first", backupName))
+		return
+	}
+
+	containerVal, containerErr := s.inspectRaw("container", name)
+	if containerErr != nil {
+		err = containerErr
+		return
+	}
+	infoPtr := &InspectInfo{}
+	_ = json.Unmarshal(containerVal, infoPtr)
+	oldImage = infoPtr.Config.Image
+
+	imageID, _ := OldImageID(containerVal)
+	imageVal, imageErr := s.inspectRaw("image", imageID)
+	if imageErr != nil {
+		err = imageErr
+		return
+	}
+	specPtr, specErr := NewCreateSpec(containerVal, imageVal, image)
+	if specErr != nil {
+		err = cd.NewError(cd.UnExpected, specErr.Error())
+		return
+	}
+
+	if _, _, err = s.Stop(name); err != nil {
+		return
+	}
+	if _, _, err = s.run("rename", name, backupName); err != nil {
+		_, _, _ = s.Start(name)
+		return
+	}
+
+	err = s.createContainer(specPtr)
+	if err != nil {
+		if restoreErr := s.Rollback(name); restoreErr != nil {
+			log.Errorf("restore container %s failed, error:%s", name, restoreErr.Reason)
+		}
+	}
+	return
+}
+
+func (s *CLI) createContainer(specPtr *CreateSpec) (err *cd.Result) {
+	if _, _, err = s.run(specPtr.CreateArgs()...); err != nil {
+		return
+	}
+	for network := range specPtr.Networks {
+		if _, _, err = s.run(specPtr.ConnectArgs(network)...); err != nil {
+			return
+		}
+	}
+
+	_, _, err = s.Start(specPtr.Name)
+	return
+}
+
+// Rollback 删除新容器，恢复并启动原容器
+func (s *CLI) Rollback(name string) (err *cd.Result) {
+	backupName := runtime.BackupName(name)
+	if _, _, err = s.run("inspect", "--type", "container", backupName); err != nil {
+		return
+	}
+
+	_, _, _ = s.run("rm", "--force", name)
+	if _, _, err = s.run("rename", backupName, name); err != nil {
+		return
+	}
+
+	_, _, err = s.Start(name)
+	return
+}
+
+// Commit 删除原容器，保留其数据卷
+func (s *CLI) Commit(name string) (err *cd.Result) {
+	_, _, err = s.run("rm", runtime.BackupName(name))
+	return
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"bytes"
+	"time"
+)
+
+// DefaultMaxOutput stdout、stderr各自保留的默认最大字节数
+const DefaultMaxOutput = 1024 * 1024
+
+// ExecOption 命令执行参数，Env格式为KEY=VALUE，TimeOut<=0时使用默认超时，MaxOutput<=0时使用DefaultMaxOutput
+type ExecOption struct {
+	Env       []string
+	WorkDir   string
+	TimeOut   time.Duration
+	MaxOutput int
+}
+
+// GetMaxOutput 输出上限
+func (s *ExecOption) GetMaxOutput() int {
+	if s == nil || s.MaxOutput <= 0 {
+		return DefaultMaxOutput
+	}
+
+	return s.MaxOutput
+}
+
+// ExecResult 命令执行结果，进程未正常退出时ExitCode为-1
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// LimitBuffer 只保留前Limit字节的输出，超出部分丢弃，Write始终成功避免阻塞命令
+type LimitBuffer struct {
+	Limit     int
+	Truncated bool
+	buffer    bytes.Buffer
+}
+
+func (s *LimitBuffer) Write(data []byte) (int, error) {
+	remain := s.Limit - s.buffer.Len()
+	if remain < len(data) {
+		s.Truncated = true
+		if remain > 0 {
+			s.buffer.Write(data[:remain])
+		}
+		return len(data), nil
+	}
+
+	return s.buffer.Write(data)
+}
+
+func (s *LimitBuffer) String() string {
+	return s.buffer.String()
+}
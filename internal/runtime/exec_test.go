@@ -0,0 +1,58 @@
+package runtime
+
+import "testing"
+
+func TestGetMaxOutput(t *testing.T) {
+	cases := []struct {
+		name   string
+		option *ExecOption
+		expect int
+	}{
+		{name: "nil option", expect: DefaultMaxOutput},
+		{name: "zero", option: &ExecOption{}, expect: DefaultMaxOutput},
+		{name: "negative", option: &ExecOption{MaxOutput: -1}, expect: DefaultMaxOutput},
+		{name: "custom", option: &ExecOption{MaxOutput: 16}, expect: 16},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			if ret := val.option.GetMaxOutput(); ret != val.expect {
+				t.Errorf("max output %d, expect %d", ret, val.expect)
+			}
+		})
+	}
+}
+
+func TestLimitBuffer(t *testing.T) {
+	cases := []struct {
+		name            string
+		limit           int
+		writes          []string
+		expect          string
+		expectTruncated bool
+	}{
+		{name: "within limit", limit: 8, writes: []string{"abc", "def"}, expect: "abcdef"},
+		{name: "exact limit", limit: 6, writes: []string{"abc", "def"}, expect: "abcdef"},
+		{name: "cut in write", limit: 4, writes: []string{"abc", "def"}, expect: "abcd", expectTruncated: true},
+		{name: "drop after full", limit: 3, writes: []string{"abc", "def", "ghi"}, expect: "abc", expectTruncated: true},
+		{name: "zero limit", limit: 0, writes: []string{"abc"}, expect: "", expectTruncated: true},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			buffer := &LimitBuffer{Limit: val.limit}
+			for _, item := range val.writes {
+				size, err := buffer.Write([]byte(item))
+				if err != nil || size != len(item) {
+					t.Fatalf("write %q, size %d, error %v", item, size, err)
+				}
+			}
+			if buffer.String() != val.expect {
+				t.Errorf("content %q, expect %q", buffer.String(), val.expect)
+			}
+			if buffer.Truncated != val.expectTruncated {
+				t.Errorf("truncated %v, expect %v", buffer.Truncated, val.expectTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,764 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/websocket"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const (
+	serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+	defaultNamespace   = "default"
+	defaultTimeout     = 30 * time.Second
+	// nodeNameEnv 通过downward API注入的节点名，选择器匹配多个pod时优先选择本节点的pod
+	nodeNameEnv = "NODE_NAME"
+	// execProtocol exec子资源的websocket子协议，首字节为通道号
+	execProtocol           = "v4.channel.k8s.io"
+	defaultContainerAnnote = "kubectl.kubernetes.io/default-container"
+)
+
+func init() {
+	runtime.Register(common.KubernetesRuntime, func(option *runtime.Option) runtime.Runtime {
+		return New(option)
+	})
+}
+
+// Kubernetes 通过API Server管理pod，使用pod所在的service account认证
+// 守护对象通过标签选择器对应到pod，未配置选择器时服务名即pod名
+type Kubernetes struct {
+	apiServer  string
+	namespace  string
+	selector   string
+	tokenFile  string
+	timeOut    time.Duration
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+}
+
+// New 新建Kubernetes运行时，Endpoint为空时使用集群内API Server地址
+func New(option *runtime.Option) *Kubernetes {
+	ptr := &Kubernetes{
+		apiServer: strings.TrimRight(option.Endpoint, "/"),
+		namespace: option.Namespace,
+		selector:  option.Selector,
+		tokenFile: path.Join(serviceAccountPath, "token"),
+		timeOut:   option.TimeOut,
+		tlsConfig: &tls.Config{},
+	}
+	if ptr.timeOut <= 0 {
+		ptr.timeOut = defaultTimeout
+	}
+
+	if ptr.apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if port == "" {
+			port = "443"
+		}
+		ptr.apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	if ptr.namespace == "" {
+		byteVal, byteErr := os.ReadFile(path.Join(serviceAccountPath, "namespace"))
+		ptr.namespace = strings.TrimSpace(string(byteVal))
+		if byteErr != nil || ptr.namespace == "" {
+			ptr.namespace = defaultNamespace
+		}
+	}
+
+	caVal, caErr := os.ReadFile(path.Join(serviceAccountPath, "ca.crt"))
+	if caErr == nil {
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(caVal)
+		ptr.tlsConfig.RootCAs = certPool
+	}
+
+	// 超时由请求的ctx控制，日志流需要长时间读取
+	ptr.httpClient = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: ptr.tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	return ptr
+}
+
+func (s *Kubernetes) Name() string {
+	return common.KubernetesRuntime
+}
+
+type containerState struct {
+	Running *struct {
+		StartedAt string `json:"startedAt"`
+	} `json:"running"`
+	Waiting *struct {
+		Reason string `json:"reason"`
+	} `json:"waiting"`
+	Terminated *struct {
+		ExitCode   int    `json:"exitCode"`
+		Reason     string `json:"reason"`
+		StartedAt  string `json:"startedAt"`
+		FinishedAt string `json:"finishedAt"`
+	} `json:"terminated"`
+}
+
+type podInfo struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace"`
+		UID               string            `json:"uid"`
+		Annotations       map[string]string `json:"annotations"`
+		DeletionTimestamp *string           `json:"deletionTimestamp"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Name      string `json:"name"`
+			Image     string `json:"image"`
+			Resources struct {
+				Limits map[string]string `json:"limits"`
+			} `json:"resources"`
+			VolumeMounts []struct {
+				Name      string `json:"name"`
+				MountPath string `json:"mountPath"`
+				ReadOnly  bool   `json:"readOnly"`
+			} `json:"volumeMounts"`
+		} `json:"containers"`
+		Volumes []map[string]json.RawMessage `json:"volumes"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			Name         string         `json:"name"`
+			Ready        bool           `json:"ready"`
+			RestartCount int            `json:"restartCount"`
+			State        containerState `json:"state"`
+			LastState    containerState `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// defaultContainer 与kubectl一致，优先使用default-container注解，否则使用第一个容器
+func (s *podInfo) defaultContainer() (name, image string) {
+	name = s.Metadata.Annotations[defaultContainerAnnote]
+	for _, val := range s.Spec.Containers {
+		if name == "" || val.Name == name {
+			return val.Name, val.Image
+		}
+	}
+
+	return
+}
+
+// volumeSource 卷类型及来源，例如 hostPath:/data、persistentVolumeClaim:data-mariadb-0
+func (s *podInfo) volumeSource(name string) (volumeType, source string) {
+	for _, volume := range s.Spec.Volumes {
+		if string(volume["name"]) != strconv.Quote(name) {
+			continue
+		}
+
+		for key, val := range volume {
+			if key == "name" {
+				continue
+			}
+
+			sourceVal := struct {
+				Path      string `json:"path"`
+				ClaimName string `json:"claimName"`
+				Name      string `json:"name"`
+				Secret    string `json:"secretName"`
+			}{}
+			_ = json.Unmarshal(val, &sourceVal)
+			volumeType = key
+			for _, item := range []string{sourceVal.Path, sourceVal.ClaimName, sourceVal.Name, sourceVal.Secret} {
+				if item != "" {
+					source = item
+					break
+				}
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (s *podInfo) containerInfo() *common.ContainerInfo {
+	containerName, image := s.defaultContainer()
+	readyCount, restartCount := 0, 0
+	ret := &common.ContainerInfo{
+		ID:      s.Metadata.UID,
+		Name:    s.Metadata.Name,
+		Image:   image,
+		State:   s.Status.Phase,
+		Runtime: common.KubernetesRuntime,
+	}
+	if s.Metadata.DeletionTimestamp != nil {
+		ret.State = "Terminating"
+	}
+
+	for _, val := range s.Status.ContainerStatuses {
+		if val.Ready {
+			readyCount++
+		}
+		restartCount += val.RestartCount
+		if val.Name != containerName {
+			continue
+		}
+
+		ret.Health = "unhealthy"
+		if val.Ready {
+			ret.Health = "healthy"
+		}
+
+		// 容器重启后，退出原因记录在lastState中
+		terminated := val.State.Terminated
+		if terminated == nil {
+			terminated = val.LastState.Terminated
+		}
+		if terminated != nil {
+			ret.ExitCode = terminated.ExitCode
+			ret.OOMKilled = terminated.Reason == "OOMKilled"
+			ret.FinishedAt = terminated.FinishedAt
+			ret.StartedAt = terminated.StartedAt
+		}
+		if val.State.Running != nil {
+			ret.StartedAt = val.State.Running.StartedAt
+		}
+		if val.State.Waiting != nil && val.State.Waiting.Reason != "" {
+			ret.State = val.State.Waiting.Reason
+		}
+	}
+
+	ret.RestartCount = restartCount
+	ret.Status = fmt.Sprintf("ready %d/%d, restarts %d", readyCount, len(s.Spec.Containers), restartCount)
+	for _, container := range s.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+
+		for _, val := range container.VolumeMounts {
+			volumeType, source := s.volumeSource(val.Name)
+			ret.Mounts = append(ret.Mounts, &common.MountInfo{
+				Type:        volumeType,
+				Source:      source,
+				Destination: val.MountPath,
+				ReadOnly:    val.ReadOnly,
+			})
+		}
+	}
+
+	return ret
+}
+
+type podList struct {
+	Items []*podInfo `json:"items"`
+}
+
+func (s *Kubernetes) podPath(name string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", url.PathEscape(s.namespace), url.PathEscape(name))
+}
+
+func (s *Kubernetes) listPods() (ret []*podInfo, err *cd.Result) {
+	query := url.Values{}
+	if s.selector != "" {
+		query.Set("labelSelector", s.selector)
+	}
+
+	byteVal, byteErr := s.request(http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s/pods", url.PathEscape(s.namespace)), query)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	listPtr := &podList{}
+	unmarshalErr := json.Unmarshal(byteVal, listPtr)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal pod list, %s", unmarshalErr.Error()))
+		return
+	}
+
+	sort.Slice(listPtr.Items, func(i, j int) bool {
+		return listPtr.Items[i].Metadata.Name < listPtr.Items[j].Metadata.Name
+	})
+	ret = listPtr.Items
+	return
+}
+
+// resolvePod 选择器必须唯一确定一个pod，正在删除的pod不参与选择
+func (s *Kubernetes) resolvePod(name string) (ret *podInfo, err *cd.Result) {
+	if s.selector == "" {
+		byteVal, byteErr := s.request(http.MethodGet, s.podPath(name), nil)
+		if byteErr != nil {
+			err = byteErr
+			return
+		}
+
+		ret = &podInfo{}
+		unmarshalErr := json.Unmarshal(byteVal, ret)
+		if unmarshalErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal pod, %s", unmarshalErr.Error()))
+		}
+		return
+	}
+
+	pods, podsErr := s.listPods()
+	if podsErr != nil {
+		err = podsErr
+		return
+	}
+
+	candidates := []*podInfo{}
+	for _, val := range pods {
+		if val.Metadata.DeletionTimestamp == nil {
+			candidates = append(candidates, val)
+		}
+	}
+
+	if nodeName := os.Getenv(nodeNameEnv); nodeName != "" && len(candidates) > 1 {
+		localPods := []*podInfo{}
+		for _, val := range candidates {
+			if val.Spec.NodeName == nodeName {
+				localPods = append(localPods, val)
+			}
+		}
+		if len(localPods) > 0 {
+			candidates = localPods
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("no pod matches selector %s, service:%s", s.selector, name))
+	case 1:
+		ret = candidates[0]
+	default:
+		names := []string{}
+		for _, val := range candidates {
+			names = append(names, val.Metadata.Name)
+		}
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("selector %s matches multiple pods:%s, service:%s", s.selector, strings.Join(names, ","), name))
+	}
+	return
+}
+
+// Start pod生命周期由控制器管理，不支持单独启动
+func (s *Kubernetes) Start(name string) (stdout, stderr string, err *cd.Result) {
+	err = cd.NewError(cd.IllegalParam, "kubernetes runtime does not support start, pod lifecycle is managed by its controller")
+	return
+}
+
+// Stop pod生命周期由控制器管理，不支持单独停止
+func (s *Kubernetes) Stop(name string) (stdout, stderr string, err *cd.Result) {
+	err = cd.NewError(cd.IllegalParam, "kubernetes runtime does not support stop, pod lifecycle is managed by its controller")
+	return
+}
+
+// Restart 删除pod，由StatefulSet等控制器重建
+func (s *Kubernetes) Restart(name string) (stdout, stderr string, err *cd.Result) {
+	podPtr, podErr := s.resolvePod(name)
+	if podErr != nil {
+		err = podErr
+		return
+	}
+
+	_, err = s.request(http.MethodDelete, s.podPath(podPtr.Metadata.Name), nil)
+	if err != nil {
+		return
+	}
+
+	stdout = fmt.Sprintf("pod %s/%s deleted\n", s.namespace, podPtr.Metadata.Name)
+	return
+}
+
+type execStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Details struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details"`
+}
+
+// exitCode 命令非零退出时状态中携带ExitCode原因
+func (s *execStatus) exitCode() int {
+	if s.Status == "Success" {
+		return 0
+	}
+
+	for _, val := range s.Details.Causes {
+		if val.Reason == "ExitCode" {
+			if code, codeErr := strconv.Atoi(val.Message); codeErr == nil {
+				return code
+			}
+		}
+	}
+
+	return -1
+}
+
+// envPattern 环境变量名
+var envPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envScript 将环境变量转为export语句，只有变量名的项使用本地环境变量的值，本地未设置时忽略
+func envScript(env []string) (ret string, err *cd.Result) {
+	for _, val := range env {
+		key, value, ok := strings.Cut(val, "=")
+		if !ok {
+			if value, ok = os.LookupEnv(key); !ok {
+				continue
+			}
+		}
+		if !envPattern.MatchString(key) {
+			err = cd.NewError(cd.IllegalParam, fmt.Sprintf("illegal env name:%s", key))
+			return
+		}
+
+		ret += fmt.Sprintf("export %s='%s'\n", key, strings.ReplaceAll(value, "'", `'\''`))
+	}
+
+	return
+}
+
+// Exec 通过pods/exec子资源执行命令，命令退出码非0时同时返回执行结果和错误
+// exec接口不支持环境变量和工作目录，环境变量通过stdin传入后由shell读取，不出现在请求地址和进程参数中，工作目录通过cd命令实现
+func (s *Kubernetes) Exec(name, cmd string, option *runtime.ExecOption) (ret *runtime.ExecResult, err *cd.Result) {
+	podPtr, podErr := s.resolvePod(name)
+	if podErr != nil {
+		err = podErr
+		return
+	}
+
+	timeOut := s.timeOut
+	command := []string{"sh", "-c", cmd}
+	script := ""
+	if option != nil {
+		if option.WorkDir != "" {
+			command[2] = fmt.Sprintf("cd '%s' && %s", strings.ReplaceAll(option.WorkDir, "'", `'\''`), cmd)
+		}
+		script, err = envScript(option.Env)
+		if err != nil {
+			return
+		}
+		if script != "" {
+			// 只读取指定长度的stdin，读取后不再使用stdin，避免命令等待输入
+			command[2] = fmt.Sprintf("eval \"$(head -c %d)\" || exit 126\nexec </dev/null\n%s", len(script), command[2])
+		}
+		if option.TimeOut > 0 {
+			timeOut = option.TimeOut
+		}
+	}
+
+	containerName, _ := podPtr.defaultContainer()
+	query := url.Values{
+		"command": command,
+		"stdout":  {"true"},
+		"stderr":  {"true"},
+	}
+	if containerName != "" {
+		query.Set("container", containerName)
+	}
+	if script != "" {
+		query.Set("stdin", "true")
+	}
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", execProtocol)
+	s.authorize(header)
+
+	conn, connErr := websocket.Dial(s.apiServer+s.podPath(podPtr.Metadata.Name)+"/exec?"+query.Encode(), header, s.tlsConfig, s.timeOut)
+	if connErr != nil {
+		err = cd.NewError(cd.UnExpected, connErr.Error())
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeOut))
+	if script != "" {
+		if writeErr := conn.WriteMessage(websocket.BinaryMessage, append([]byte{0}, script...)); writeErr != nil {
+			err = cd.NewError(cd.UnExpected, writeErr.Error())
+			return
+		}
+	}
+
+	outBuffer := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
+	errBuffer := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
+	ret = &runtime.ExecResult{ExitCode: -1}
+	var statusPtr *execStatus
+	for {
+		_, data, readErr := conn.ReadMessage()
+		if readErr != nil {
+			if !errors.Is(readErr, websocket.ErrClosed) && !errors.Is(readErr, io.EOF) {
+				ret.Stdout, ret.Stderr = outBuffer.String(), errBuffer.String()
+				err = cd.NewError(cd.UnExpected, readErr.Error())
+				return
+			}
+			break
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case 1:
+			outBuffer.Write(data[1:])
+		case 2:
+			errBuffer.Write(data[1:])
+		case 3:
+			statusPtr = &execStatus{}
+			_ = json.Unmarshal(data[1:], statusPtr)
+		}
+	}
+
+	ret.Stdout, ret.Stderr = outBuffer.String(), errBuffer.String()
+	if statusPtr == nil {
+		err = cd.NewError(cd.UnExpected, "exec stream closed without status")
+		return
+	}
+
+	ret.ExitCode = statusPtr.exitCode()
+	if statusPtr.Status != "Success" {
+		err = cd.NewError(cd.UnExpected, statusPtr.Message)
+	}
+	return
+}
+
+// Logs 读取默认容器日志，pod日志不区分stdout和stderr，全部写入stdout
+func (s *Kubernetes) Logs(ctx context.Context, name string, option *runtime.LogOption, stdout, stderr io.Writer) (err *cd.Result) {
+	podPtr, podErr := s.resolvePod(name)
+	if podErr != nil {
+		err = podErr
+		return
+	}
+
+	query := url.Values{}
+	if containerName, _ := podPtr.defaultContainer(); containerName != "" {
+		query.Set("container", containerName)
+	}
+	if option.Tail > 0 {
+		query.Set("tailLines", strconv.Itoa(option.Tail))
+	}
+	if !option.Since.IsZero() {
+		query.Set("sinceTime", option.Since.UTC().Format(time.RFC3339))
+	}
+	if option.Follow {
+		query.Set("follow", "true")
+	}
+
+	res, resErr := s.send(ctx, http.MethodGet, s.podPath(podPtr.Metadata.Name)+"/log", query)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	_, copyErr := io.Copy(stdout, res.Body)
+	if copyErr != nil && ctx.Err() == nil {
+		err = cd.NewError(cd.UnExpected, copyErr.Error())
+	}
+	return
+}
+
+func (s *Kubernetes) Inspect(name string) (ret *common.ContainerInfo, err *cd.Result) {
+	podPtr, podErr := s.resolvePod(name)
+	if podErr != nil {
+		err = podErr
+		return
+	}
+
+	ret = podPtr.containerInfo()
+	return
+}
+
+type podMetrics struct {
+	Containers []struct {
+		Name  string            `json:"name"`
+		Usage map[string]string `json:"usage"`
+	} `json:"containers"`
+}
+
+// Stats 从metrics.k8s.io读取默认容器的资源使用量，需要集群部署metrics-server
+func (s *Kubernetes) Stats(name string) (ret *common.ContainerStats, err *cd.Result) {
+	podPtr, podErr := s.resolvePod(name)
+	if podErr != nil {
+		err = podErr
+		return
+	}
+
+	byteVal, byteErr := s.request(http.MethodGet, fmt.Sprintf("/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods/%s", url.PathEscape(s.namespace), url.PathEscape(podPtr.Metadata.Name)), nil)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	metricsPtr := &podMetrics{}
+	unmarshalErr := json.Unmarshal(byteVal, metricsPtr)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal pod metrics, %s", unmarshalErr.Error()))
+		return
+	}
+
+	containerName, _ := podPtr.defaultContainer()
+	ret = &common.ContainerStats{Name: podPtr.Metadata.Name, Runtime: common.KubernetesRuntime}
+	for _, val := range metricsPtr.Containers {
+		if val.Name == containerName {
+			ret.CPUPercent = parseQuantity(val.Usage["cpu"]) * 100
+			ret.MemoryUsage = uint64(parseQuantity(val.Usage["memory"]))
+		}
+	}
+	for _, val := range podPtr.Spec.Containers {
+		if val.Name == containerName {
+			ret.MemoryLimit = uint64(parseQuantity(val.Resources.Limits["memory"]))
+		}
+	}
+	if ret.MemoryLimit > 0 {
+		ret.MemoryPercent = float64(ret.MemoryUsage) / float64(ret.MemoryLimit) * 100
+	}
+	return
+}
+
+var quantitySuffixes = map[string]float64{
+	"n":  1e-9,
+	"u":  1e-6,
+	"m":  1e-3,
+	"":   1,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// parseQuantity 解析资源数量，例如 250m、128Mi、1e3，无法解析时返回0
+func parseQuantity(val string) float64 {
+	val = strings.TrimSpace(val)
+	idx := len(val)
+	for idx > 0 && strings.ContainsRune("numkMGTPEi", rune(val[idx-1])) {
+		idx--
+	}
+
+	unit, unitOK := quantitySuffixes[val[idx:]]
+	number, numberErr := strconv.ParseFloat(val[:idx], 64)
+	if !unitOK || numberErr != nil {
+		return 0
+	}
+
+	return number * unit
+}
+
+// List 列出命名空间中与选择器匹配的pod
+func (s *Kubernetes) List() (ret []*common.ContainerInfo, err *cd.Result) {
+	pods, podsErr := s.listPods()
+	if podsErr != nil {
+		err = podsErr
+		return
+	}
+
+	for _, val := range pods {
+		ret = append(ret, val.containerInfo())
+	}
+	return
+}
+
+// authorize service account令牌会定期轮换，每次请求重新读取
+func (s *Kubernetes) authorize(header http.Header) {
+	byteVal, byteErr := os.ReadFile(s.tokenFile)
+	if byteErr != nil {
+		return
+	}
+
+	if token := strings.TrimSpace(string(byteVal)); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (s *Kubernetes) request(method, apiPath string, query url.Values) (ret []byte, err *cd.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeOut)
+	defer cancel()
+
+	res, resErr := s.send(ctx, method, apiPath, query)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	content, contentErr := io.ReadAll(res.Body)
+	if contentErr != nil {
+		err = cd.NewError(cd.UnExpected, contentErr.Error())
+		return
+	}
+
+	ret = content
+	return
+}
+
+// send 发送API请求，成功时由调用方关闭应答
+func (s *Kubernetes) send(ctx context.Context, method, apiPath string, query url.Values) (ret *http.Response, err *cd.Result) {
+	reqURL := s.apiServer + apiPath
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, method, reqURL, nil)
+	if reqErr != nil {
+		err = cd.NewError(cd.IllegalParam, reqErr.Error())
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	s.authorize(req.Header)
+
+	res, resErr := s.httpClient.Do(req)
+	if resErr != nil {
+		err = cd.NewError(cd.UnExpected, resErr.Error())
+		return
+	}
+
+	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+		ret = res
+		return
+	}
+	defer res.Body.Close()
+
+	// 失败时API Server返回Status对象
+	content, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	statusPtr := &execStatus{}
+	_ = json.Unmarshal(content, statusPtr)
+	if statusPtr.Message == "" {
+		statusPtr.Message = fmt.Sprintf("%s %s, status:%s", method, apiPath, res.Status)
+	}
+
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		err = cd.NewWarn(cd.NoExist, statusPtr.Message)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		err = cd.NewError(cd.InvalidAuthority, statusPtr.Message)
+	default:
+		err = cd.NewError(cd.UnExpected, statusPtr.Message)
+	}
+	return
+}
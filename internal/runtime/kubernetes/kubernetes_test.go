@@ -0,0 +1,343 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/websocket"
+)
+
+const testToken = "service-account-token"
+
+// fakeAPIServer 模拟API Server的pod查询、删除和exec子资源
+type fakeAPIServer struct {
+	lock     sync.Mutex
+	pods     []map[string]interface{}
+	deleted  []string
+	commands [][]string
+	// stdin exec连接上收到的stdin内容
+	stdin []string
+	// execFrames exec连接上依次发送的消息，首字节为通道号
+	execFrames [][]byte
+}
+
+func newPod(name, nodeName string, terminating bool) map[string]interface{} {
+	metadata := map[string]interface{}{"name": name, "namespace": "db", "uid": "uid-" + name}
+	if terminating {
+		metadata["deletionTimestamp"] = "2026-01-01T00:00:00Z"
+	}
+
+	return map[string]interface{}{
+		"metadata": metadata,
+		"spec": map[string]interface{}{
+			"nodeName":   nodeName,
+			"containers": []interface{}{map[string]interface{}{"name": "mariadb", "image": "mariadb:11"}},
+		},
+		"status": map[string]interface{}{"phase": "Running"},
+	}
+}
+
+func (s *fakeAPIServer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if req.Header.Get("Authorization") != "Bearer "+testToken {
+		writeStatus(res, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	const podsPath = "/api/v1/namespaces/db/pods"
+	if req.URL.Path == podsPath {
+		_ = json.NewEncoder(res).Encode(map[string]interface{}{"items": s.pods})
+		return
+	}
+
+	items := strings.Split(strings.TrimPrefix(req.URL.Path, podsPath+"/"), "/")
+	var podPtr map[string]interface{}
+	for _, val := range s.pods {
+		if val["metadata"].(map[string]interface{})["name"] == items[0] {
+			podPtr = val
+		}
+	}
+	if podPtr == nil {
+		writeStatus(res, http.StatusNotFound, `pods "`+items[0]+`" not found`)
+		return
+	}
+
+	switch {
+	case len(items) == 1 && req.Method == http.MethodGet:
+		_ = json.NewEncoder(res).Encode(podPtr)
+	case len(items) == 1 && req.Method == http.MethodDelete:
+		s.deleted = append(s.deleted, items[0])
+		_ = json.NewEncoder(res).Encode(podPtr)
+	case len(items) == 2 && items[1] == "exec":
+		s.commands = append(s.commands, req.URL.Query()["command"])
+		if s.execFrames == nil {
+			writeStatus(res, http.StatusForbidden, "pods/exec is forbidden")
+			return
+		}
+
+		conn, connErr := websocket.Upgrade(res, req, execProtocol)
+		if connErr != nil {
+			return
+		}
+		defer conn.Close()
+		if req.URL.Query().Get("stdin") == "true" {
+			if _, data, readErr := conn.ReadMessage(); readErr == nil && len(data) > 0 && data[0] == 0 {
+				s.stdin = append(s.stdin, string(data[1:]))
+			}
+		}
+		for _, val := range s.execFrames {
+			_ = conn.WriteMessage(websocket.BinaryMessage, val)
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, nil)
+	default:
+		writeStatus(res, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeStatus(res http.ResponseWriter, code int, message string) {
+	res.WriteHeader(code)
+	_ = json.NewEncoder(res).Encode(map[string]interface{}{"kind": "Status", "status": "Failure", "message": message})
+}
+
+func newTestKubernetes(t *testing.T, serverPtr *fakeAPIServer, selector string) *Kubernetes {
+	server := httptest.NewServer(serverPtr)
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(testToken+"\n"), 0600); err != nil {
+		t.Fatalf("write token failed, %v", err)
+	}
+
+	ptr := New(&runtime.Option{Endpoint: server.URL, Namespace: "db", Selector: selector, TimeOut: 5 * time.Second})
+	ptr.tokenFile = tokenFile
+	return ptr
+}
+
+func TestResolvePod(t *testing.T) {
+	cases := []struct {
+		name       string
+		pods       []map[string]interface{}
+		selector   string
+		nodeName   string
+		service    string
+		expectPod  string
+		expectCode cd.ErrorCode
+	}{
+		{name: "by name", pods: []map[string]interface{}{newPod("mariadb-0", "n1", false)}, service: "mariadb-0", expectPod: "mariadb-0"},
+		{name: "name not found", pods: []map[string]interface{}{newPod("mariadb-0", "n1", false)}, service: "mariadb-1", expectCode: cd.NoExist},
+		{
+			name:      "selector skips terminating",
+			pods:      []map[string]interface{}{newPod("mariadb-0", "n1", true), newPod("mariadb-1", "n1", false)},
+			selector:  "app=mariadb",
+			expectPod: "mariadb-1",
+		},
+		{
+			name:      "selector prefers local node",
+			pods:      []map[string]interface{}{newPod("mariadb-0", "n1", false), newPod("mariadb-1", "n2", false)},
+			selector:  "app=mariadb",
+			nodeName:  "n2",
+			expectPod: "mariadb-1",
+		},
+		{
+			name:       "selector matches multiple",
+			pods:       []map[string]interface{}{newPod("mariadb-0", "n1", false), newPod("mariadb-1", "n2", false)},
+			selector:   "app=mariadb",
+			expectCode: cd.IllegalParam,
+		},
+		{name: "selector matches none", selector: "app=mariadb", expectCode: cd.NoExist},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			t.Setenv(nodeNameEnv, val.nodeName)
+			ptr := newTestKubernetes(t, &fakeAPIServer{pods: val.pods}, val.selector)
+			podPtr, err := ptr.resolvePod(val.service)
+			if val.expectCode != cd.Succeeded {
+				if err == nil || err.ErrorCode != val.expectCode {
+					t.Fatalf("error %v, expect code %d", err, val.expectCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if podPtr.Metadata.Name != val.expectPod {
+				t.Errorf("pod %s, expect %s", podPtr.Metadata.Name, val.expectPod)
+			}
+		})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	serverPtr := &fakeAPIServer{pods: []map[string]interface{}{newPod("mariadb-0", "n1", false)}}
+	ptr := newTestKubernetes(t, serverPtr, "app=mariadb")
+	stdout, _, err := ptr.Restart("mariadb001")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(serverPtr.deleted) != 1 || serverPtr.deleted[0] != "mariadb-0" || !strings.Contains(stdout, "db/mariadb-0 deleted") {
+		t.Errorf("deleted %v, stdout %q", serverPtr.deleted, stdout)
+	}
+
+	ptr.tokenFile = filepath.Join(t.TempDir(), "missing")
+	_, _, err = ptr.Restart("mariadb001")
+	if err == nil || err.ErrorCode != cd.InvalidAuthority {
+		t.Errorf("error %v, expect InvalidAuthority", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	failure := `{"status":"Failure","message":"command terminated with non-zero exit code","details":{"causes":[{"reason":"ExitCode","message":"2"}]}}`
+	cases := []struct {
+		name         string
+		frames       [][]byte
+		option       *runtime.ExecOption
+		expectErr    string
+		expectResult *runtime.ExecResult
+		expectCmd    string
+		expectStdin  string
+	}{
+		{
+			name:         "succeeded",
+			frames:       [][]byte{append([]byte{1}, "out\n"...), append([]byte{2}, "warn\n"...), append([]byte{3}, `{"status":"Success"}`...)},
+			expectResult: &runtime.ExecResult{Stdout: "out\n", Stderr: "warn\n", ExitCode: 0},
+			expectCmd:    "sh -c echo",
+		},
+		{
+			name:         "exit code",
+			frames:       [][]byte{append([]byte{3}, failure...)},
+			option:       &runtime.ExecOption{WorkDir: "/var/lib/mysql"},
+			expectErr:    "non-zero exit code",
+			expectResult: &runtime.ExecResult{ExitCode: 2},
+			expectCmd:    "sh -c cd '/var/lib/mysql' && echo",
+		},
+		{
+			name:         "env over stdin",
+			frames:       [][]byte{append([]byte{3}, `{"status":"Success"}`...)},
+			option:       &runtime.ExecOption{Env: []string{"MYSQL_PWD=p'ss", "A=1", "MAGICAGENT_TEST_UNSET"}},
+			expectResult: &runtime.ExecResult{},
+			expectCmd:    "sh -c eval \"$(head -c 40)\" || exit 126\nexec </dev/null\necho",
+			expectStdin:  "export MYSQL_PWD='p'\\''ss'\nexport A='1'\n",
+		},
+		{
+			name:         "no status",
+			frames:       [][]byte{append([]byte{1}, "partial"...)},
+			expectErr:    "closed without status",
+			expectResult: &runtime.ExecResult{Stdout: "partial", ExitCode: -1},
+			expectCmd:    "sh -c echo",
+		},
+		{
+			name:      "upgrade forbidden",
+			expectErr: "403",
+			expectCmd: "sh -c echo",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			serverPtr := &fakeAPIServer{pods: []map[string]interface{}{newPod("mariadb-0", "n1", false)}, execFrames: val.frames}
+			ret, err := newTestKubernetes(t, serverPtr, "").Exec("mariadb-0", "echo", val.option)
+			if val.expectErr == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if val.expectErr != "" && (err == nil || !strings.Contains(err.Reason, val.expectErr)) {
+				t.Fatalf("error %v, expect %q", err, val.expectErr)
+			}
+			if val.expectResult != nil && *ret != *val.expectResult {
+				t.Errorf("result %+v, expect %+v", ret, val.expectResult)
+			}
+			if len(serverPtr.commands) != 1 || strings.Join(serverPtr.commands[0], " ") != val.expectCmd {
+				t.Errorf("commands %q, expect %q", serverPtr.commands, val.expectCmd)
+			}
+			if strings.Join(serverPtr.stdin, "") != val.expectStdin {
+				t.Errorf("stdin %q, expect %q", serverPtr.stdin, val.expectStdin)
+			}
+			if val.option != nil && strings.Contains(strings.Join(serverPtr.commands[0], " "), "p'ss") {
+				t.Errorf("env value leaked into command %q", serverPtr.commands[0])
+			}
+		})
+	}
+}
+
+func TestEnvScript(t *testing.T) {
+	t.Setenv("MAGICAGENT_TEST_TERM", "xterm")
+	script, err := envScript([]string{"MAGICAGENT_TEST_TERM", "B=a=b"})
+	if err != nil || script != "export MAGICAGENT_TEST_TERM='xterm'\nexport B='a=b'\n" {
+		t.Errorf("script %q, err %v", script, err)
+	}
+
+	if _, err = envScript([]string{"A;rm -rf /=1"}); err == nil || err.ErrorCode != cd.IllegalParam {
+		t.Errorf("err %v, expect IllegalParam", err)
+	}
+}
+
+func TestContainerInfo(t *testing.T) {
+	content := `{
+		"metadata": {"name": "mariadb-0", "uid": "u1", "annotations": {"kubectl.kubernetes.io/default-container": "mariadb"}},
+		"spec": {
+			"containers": [
+				{"name": "exporter", "image": "exporter:1"},
+				{"name": "mariadb", "image": "mariadb:11", "volumeMounts": [{"name": "data", "mountPath": "/var/lib/mysql"}]}
+			],
+			"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "data-mariadb-0"}}]
+		},
+		"status": {
+			"phase": "Running",
+			"containerStatuses": [
+				{"name": "exporter", "ready": true, "restartCount": 1, "state": {"running": {"startedAt": "t0"}}},
+				{"name": "mariadb", "ready": false, "restartCount": 3,
+					"state": {"waiting": {"reason": "CrashLoopBackOff"}},
+					"lastState": {"terminated": {"exitCode": 137, "reason": "OOMKilled", "startedAt": "t1", "finishedAt": "t2"}}}
+			]
+		}
+	}`
+
+	podPtr := &podInfo{}
+	if err := json.Unmarshal([]byte(content), podPtr); err != nil {
+		t.Fatalf("illegal pod, %v", err)
+	}
+
+	infoPtr := podPtr.containerInfo()
+	if infoPtr.Image != "mariadb:11" || infoPtr.State != "CrashLoopBackOff" || infoPtr.Health != "unhealthy" {
+		t.Errorf("unexpected state %+v", infoPtr)
+	}
+	if infoPtr.ExitCode != 137 || !infoPtr.OOMKilled || infoPtr.FinishedAt != "t2" || infoPtr.RestartCount != 4 {
+		t.Errorf("unexpected termination %+v", infoPtr)
+	}
+	if infoPtr.Status != "ready 1/2, restarts 4" {
+		t.Errorf("status %q", infoPtr.Status)
+	}
+	if len(infoPtr.Mounts) != 1 || infoPtr.Mounts[0].Type != "persistentVolumeClaim" || infoPtr.Mounts[0].Source != "data-mariadb-0" {
+		t.Errorf("unexpected mounts %+v", infoPtr.Mounts)
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	cases := map[string]float64{
+		"250m":  0.25,
+		"128Mi": 128 * 1024 * 1024,
+		"1G":    1e9,
+		"2":     2,
+		"2k":    2000,
+		"1x":    0,
+		"":      0,
+	}
+
+	for key, val := range cases {
+		if ret := parseQuantity(key); ret != val {
+			t.Errorf("parseQuantity(%q) = %v, expect %v", key, ret, val)
+		}
+	}
+}
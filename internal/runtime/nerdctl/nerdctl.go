@@ -0,0 +1,29 @@
+package nerdctl
+
+import (
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/runtime/docker"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const (
+	cmdName          = "nerdctl"
+	defaultNamespace = "default"
+)
+
+// nerdctl命令行与docker兼容，通过 --address 和 --namespace 指定containerd地址和命名空间
+func init() {
+	runtime.Register(common.NerdctlRuntime, func(option *runtime.Option) runtime.Runtime {
+		namespace := option.Namespace
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+
+		globalArgs := []string{"--namespace", namespace}
+		if option.Endpoint != "" {
+			globalArgs = append(globalArgs, "--address", option.Endpoint)
+		}
+
+		return docker.NewCLI(common.NerdctlRuntime, cmdName, globalArgs, option)
+	})
+}
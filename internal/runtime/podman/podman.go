@@ -0,0 +1,509 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/runtime/docker"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const (
+	defaultEndpoint = "unix:///run/podman/podman.sock"
+	defaultTimeout  = 30 * time.Second
+	// apiHost unix socket请求时的占位主机名
+	apiHost = "d"
+)
+
+func init() {
+	runtime.Register(common.PodmanRuntime, func(option *runtime.Option) runtime.Runtime {
+		return New(option.Endpoint, option.TimeOut)
+	})
+}
+
+// Podman 通过podman提供的docker兼容API管理容器
+type Podman struct {
+	baseURL    string
+	timeOut    time.Duration
+	httpClient *http.Client
+}
+
+// New 新建Podman运行时，endpoint支持 unix:///path/podman.sock 和 tcp://host:port
+func New(endpoint string, timeOut time.Duration) *Podman {
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	if timeOut <= 0 {
+		timeOut = defaultTimeout
+	}
+
+	// 超时由请求的ctx控制，日志流需要长时间读取
+	ptr := &Podman{
+		timeOut:    timeOut,
+		httpClient: &http.Client{},
+	}
+
+	switch {
+	case strings.HasPrefix(endpoint, "unix://") || strings.HasPrefix(endpoint, "/"):
+		socketPath := strings.TrimPrefix(endpoint, "unix://")
+		ptr.baseURL = "http://" + apiHost
+		ptr.httpClient.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+	case strings.HasPrefix(endpoint, "tcp://"):
+		ptr.baseURL = "http://" + strings.TrimPrefix(endpoint, "tcp://")
+	default:
+		ptr.baseURL = strings.TrimRight(endpoint, "/")
+	}
+
+	return ptr
+}
+
+func (s *Podman) Name() string {
+	return common.PodmanRuntime
+}
+
+func (s *Podman) Start(name string) (stdout, stderr string, err *cd.Result) {
+	err = s.operate("start", name)
+	return
+}
+
+func (s *Podman) Stop(name string) (stdout, stderr string, err *cd.Result) {
+	err = s.operate("stop", name)
+	return
+}
+
+func (s *Podman) Restart(name string) (stdout, stderr string, err *cd.Result) {
+	err = s.operate("restart", name)
+	return
+}
+
+// operate 容器启停操作，304表示容器已处于目标状态
+func (s *Podman) operate(action, name string) *cd.Result {
+	_, err := s.request(http.MethodPost, fmt.Sprintf("/containers/%s/%s", url.PathEscape(name), action), nil, nil, http.StatusNoContent, http.StatusNotModified)
+	return err
+}
+
+type execCreateParam struct {
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+	Cmd          []string `json:"Cmd"`
+	Env          []string `json:"Env,omitempty"`
+	WorkingDir   string   `json:"WorkingDir,omitempty"`
+}
+
+type execStartParam struct {
+	Detach bool `json:"Detach"`
+	Tty    bool `json:"Tty"`
+}
+
+// Exec 创建并启动exec实例，命令退出码非0时同时返回执行结果和错误
+// 超时只断开输出流，不结束容器中的进程
+func (s *Podman) Exec(name, cmd string, option *runtime.ExecOption) (ret *runtime.ExecResult, err *cd.Result) {
+	timeOut := s.timeOut
+	if option != nil && option.TimeOut > 0 {
+		timeOut = option.TimeOut
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+
+	outBuffer := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
+	errBuffer := &runtime.LimitBuffer{Limit: option.GetMaxOutput()}
+	exitCode, execErr := s.execute(ctx, name, []string{"sh", "-c", cmd}, option, outBuffer, errBuffer)
+	ret = &runtime.ExecResult{Stdout: outBuffer.String(), Stderr: errBuffer.String(), ExitCode: exitCode}
+	if execErr != nil && ctx.Err() != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("command timeout after %v", timeOut))
+		return
+	}
+
+	err = execErr
+	return
+}
+
+// ExecStream 输出实时写入stdout、stderr，ctx取消时断开输出流
+func (s *Podman) ExecStream(ctx context.Context, name string, cmd []string, option *runtime.ExecOption, stdout, stderr io.Writer) (err *cd.Result) {
+	_, err = s.execute(ctx, name, cmd, option, stdout, stderr)
+	if err != nil && ctx.Err() != nil {
+		err = cd.NewError(cd.UnExpected, ctx.Err().Error())
+	}
+	return
+}
+
+// execute 创建并启动exec实例，输出结束后查询命令退出码，未能获取退出码时为-1
+func (s *Podman) execute(ctx context.Context, name string, cmd []string, option *runtime.ExecOption, stdout, stderr io.Writer) (exitCode int, err *cd.Result) {
+	exitCode = -1
+	createParam := &execCreateParam{AttachStdout: true, AttachStderr: true, Cmd: cmd}
+	if option != nil {
+		createParam.Env = option.Env
+		createParam.WorkingDir = option.WorkDir
+	}
+	createVal, createErr := s.request(http.MethodPost, fmt.Sprintf("/containers/%s/exec", url.PathEscape(name)), nil, createParam, http.StatusCreated)
+	if createErr != nil {
+		err = createErr
+		return
+	}
+
+	createResult := struct {
+		ID string `json:"Id"`
+	}{}
+	byteErr := json.Unmarshal(createVal, &createResult)
+	if byteErr != nil || createResult.ID == "" {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal exec create result, %s", string(createVal)))
+		return
+	}
+
+	res, startErr := s.send(ctx, http.MethodPost, fmt.Sprintf("/exec/%s/start", createResult.ID), nil, &execStartParam{}, http.StatusOK)
+	if startErr != nil {
+		err = startErr
+		return
+	}
+	defer res.Body.Close()
+
+	copyErr := demuxCopy(res.Body, stdout, stderr)
+	if copyErr != nil {
+		err = cd.NewError(cd.UnExpected, copyErr.Error())
+		return
+	}
+
+	inspectVal, inspectErr := s.request(http.MethodGet, fmt.Sprintf("/exec/%s/json", createResult.ID), nil, nil, http.StatusOK)
+	if inspectErr != nil {
+		err = inspectErr
+		return
+	}
+
+	inspectResult := struct {
+		ExitCode int `json:"ExitCode"`
+	}{}
+	byteErr = json.Unmarshal(inspectVal, &inspectResult)
+	if byteErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal exec inspect result, %s", byteErr.Error()))
+		return
+	}
+
+	exitCode = inspectResult.ExitCode
+	if exitCode != 0 {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("exit status %d", exitCode))
+	}
+	return
+}
+
+func (s *Podman) Logs(ctx context.Context, name string, option *runtime.LogOption, stdout, stderr io.Writer) (err *cd.Result) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "tail": {"all"}}
+	if option.Tail > 0 {
+		query.Set("tail", strconv.Itoa(option.Tail))
+	}
+	if !option.Since.IsZero() {
+		query.Set("since", strconv.FormatInt(option.Since.Unix(), 10))
+	}
+	if option.Follow {
+		query.Set("follow", "true")
+	}
+
+	res, resErr := s.send(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/logs", url.PathEscape(name)), query, nil, http.StatusOK)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	copyErr := demuxCopy(res.Body, stdout, stderr)
+	if copyErr != nil && ctx.Err() == nil {
+		err = cd.NewError(cd.UnExpected, copyErr.Error())
+	}
+	return
+}
+
+// Watch 通过兼容API的事件流订阅容器事件
+func (s *Podman) Watch(ctx context.Context, since time.Time, handler runtime.EventHandler) (err *cd.Result) {
+	filterVal, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": {common.ContainerDie, common.ContainerOOM, common.ContainerHealthStatus, common.ContainerRestart},
+	})
+	query := url.Values{"filters": {string(filterVal)}}
+	if !since.IsZero() {
+		query.Set("since", strconv.FormatInt(since.Unix(), 10))
+	}
+
+	res, resErr := s.send(ctx, http.MethodGet, "/events", query, nil, http.StatusOK)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		messagePtr := &docker.EventMessage{}
+		decodeErr := decoder.Decode(messagePtr)
+		if decodeErr != nil {
+			if decodeErr != io.EOF && ctx.Err() == nil {
+				err = cd.NewError(cd.UnExpected, decodeErr.Error())
+			}
+			return
+		}
+
+		if eventPtr := messagePtr.ContainerEvent(common.PodmanRuntime); eventPtr != nil {
+			handler(eventPtr)
+		}
+	}
+}
+
+func (s *Podman) Inspect(name string) (ret *common.ContainerInfo, err *cd.Result) {
+	byteVal, byteErr := s.request(http.MethodGet, fmt.Sprintf("/containers/%s/json", url.PathEscape(name)), nil, nil, http.StatusOK)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	infoPtr := &docker.InspectInfo{}
+	unmarshalErr := json.Unmarshal(byteVal, infoPtr)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal inspect result, %s", unmarshalErr.Error()))
+		return
+	}
+
+	ret = infoPtr.ContainerInfo(common.PodmanRuntime)
+	return
+}
+
+type cpuStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemCPUUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs     uint64 `json:"online_cpus"`
+}
+
+type statsInfo struct {
+	Name        string   `json:"name"`
+	CPUStats    cpuStats `json:"cpu_stats"`
+	PreCPUStats cpuStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+}
+
+// Stats 与docker stats的计算方式一致，内存使用量不含page cache
+func (s *Podman) Stats(name string) (ret *common.ContainerStats, err *cd.Result) {
+	byteVal, byteErr := s.request(http.MethodGet, fmt.Sprintf("/containers/%s/stats", url.PathEscape(name)), url.Values{"stream": {"false"}}, nil, http.StatusOK)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	infoPtr := &statsInfo{}
+	unmarshalErr := json.Unmarshal(byteVal, infoPtr)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal stats result, %s", unmarshalErr.Error()))
+		return
+	}
+
+	ret = &common.ContainerStats{
+		Name:        strings.TrimPrefix(infoPtr.Name, "/"),
+		MemoryUsage: infoPtr.MemoryStats.Usage,
+		MemoryLimit: infoPtr.MemoryStats.Limit,
+		PIDs:        infoPtr.PidsStats.Current,
+		Runtime:     common.PodmanRuntime,
+	}
+
+	onlineCPUs := infoPtr.CPUStats.OnlineCPUs
+	if onlineCPUs == 0 {
+		onlineCPUs = uint64(len(infoPtr.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpuDelta := float64(infoPtr.CPUStats.CPUUsage.TotalUsage) - float64(infoPtr.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(infoPtr.CPUStats.SystemCPUUsage) - float64(infoPtr.PreCPUStats.SystemCPUUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		ret.CPUPercent = cpuDelta / systemDelta * float64(onlineCPUs) * 100
+	}
+
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if cacheVal, ok := infoPtr.MemoryStats.Stats[key]; ok && cacheVal < ret.MemoryUsage {
+			ret.MemoryUsage -= cacheVal
+			break
+		}
+	}
+	if ret.MemoryLimit > 0 {
+		ret.MemoryPercent = float64(ret.MemoryUsage) / float64(ret.MemoryLimit) * 100
+	}
+	return
+}
+
+type listInfo struct {
+	ID     string   `json:"Id"`
+	Names  []string `json:"Names"`
+	Image  string   `json:"Image"`
+	State  string   `json:"State"`
+	Status string   `json:"Status"`
+}
+
+func (s *Podman) List() (ret []*common.ContainerInfo, err *cd.Result) {
+	byteVal, byteErr := s.request(http.MethodGet, "/containers/json", url.Values{"all": {"true"}}, nil, http.StatusOK)
+	if byteErr != nil {
+		err = byteErr
+		return
+	}
+
+	infos := []*listInfo{}
+	unmarshalErr := json.Unmarshal(byteVal, &infos)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal list result, %s", unmarshalErr.Error()))
+		return
+	}
+
+	for _, val := range infos {
+		names := []string{}
+		for _, name := range val.Names {
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+
+		ret = append(ret, &common.ContainerInfo{
+			ID:      val.ID,
+			Name:    strings.Join(names, ","),
+			Image:   val.Image,
+			State:   val.State,
+			Status:  val.Status,
+			Runtime: common.PodmanRuntime,
+		})
+	}
+
+	return
+}
+
+// request 发送API请求并读取全部应答，返回码不在expectCodes中时返回错误
+func (s *Podman) request(method, path string, query url.Values, param interface{}, expectCodes ...int) (ret []byte, err *cd.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeOut)
+	defer cancel()
+
+	res, resErr := s.send(ctx, method, path, query, param, expectCodes...)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	content, contentErr := io.ReadAll(res.Body)
+	if contentErr != nil {
+		err = cd.NewError(cd.UnExpected, contentErr.Error())
+		return
+	}
+
+	ret = content
+	return
+}
+
+// send 发送API请求，成功时由调用方关闭应答
+func (s *Podman) send(ctx context.Context, method, path string, query url.Values, param interface{}, expectCodes ...int) (ret *http.Response, err *cd.Result) {
+	var reader io.Reader
+	if param != nil {
+		byteVal, byteErr := json.Marshal(param)
+		if byteErr != nil {
+			err = cd.NewError(cd.IllegalParam, byteErr.Error())
+			return
+		}
+		reader = bytes.NewReader(byteVal)
+	}
+
+	reqURL := s.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, method, reqURL, reader)
+	if reqErr != nil {
+		err = cd.NewError(cd.IllegalParam, reqErr.Error())
+		return
+	}
+	if param != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, resErr := s.httpClient.Do(req)
+	if resErr != nil {
+		err = cd.NewError(cd.UnExpected, resErr.Error())
+		return
+	}
+
+	for _, val := range expectCodes {
+		if res.StatusCode == val {
+			ret = res
+			return
+		}
+	}
+	defer res.Body.Close()
+
+	content, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	message := struct {
+		Message string `json:"message"`
+	}{}
+	_ = json.Unmarshal(content, &message)
+	if message.Message == "" {
+		message.Message = res.Status
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		err = cd.NewWarn(cd.NoExist, message.Message)
+		return
+	}
+
+	err = cd.NewError(cd.UnExpected, message.Message)
+	return
+}
+
+// demuxCopy 拆分多路复用输出流，每帧8字节头，首字节1为stdout、2为stderr，后4字节为大端长度
+// 容器启用tty时输出未复用，直接写入stdout
+func demuxCopy(reader io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		size, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			_, err = stdout.Write(header[:size])
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if header[0] > 2 || header[1] != 0 || header[2] != 0 || header[3] != 0 {
+			_, err = stdout.Write(header)
+			if err == nil {
+				_, err = io.Copy(stdout, reader)
+			}
+			return err
+		}
+
+		writer := stdout
+		if header[0] == 2 {
+			writer = stderr
+		}
+		_, err = io.CopyN(writer, reader, int64(binary.BigEndian.Uint32(header[4:8])))
+		if err != nil {
+			return err
+		}
+	}
+}
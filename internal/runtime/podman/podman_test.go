@@ -0,0 +1,171 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+func frame(stream byte, data string) []byte {
+	header := []byte{stream, 0, 0, 0}
+	header = binary.BigEndian.AppendUint32(header, uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestOperate(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		body       string
+		expectCode cd.ErrorCode
+	}{
+		{name: "started", status: http.StatusNoContent},
+		{name: "already started", status: http.StatusNotModified},
+		{name: "not exist", status: http.StatusNotFound, body: `{"message":"no such container"}`, expectCode: cd.NoExist},
+		{name: "server error", status: http.StatusInternalServerError, expectCode: cd.UnExpected},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				path = req.Method + " " + req.URL.Path
+				res.WriteHeader(val.status)
+				_, _ = res.Write([]byte(val.body))
+			}))
+			defer server.Close()
+
+			_, _, err := New(server.URL, time.Second).Start("db")
+			if path != "POST /containers/db/start" {
+				t.Errorf("unexpected request %q", path)
+			}
+			if val.expectCode == cd.Succeeded {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || err.ErrorCode != val.expectCode {
+				t.Fatalf("error %v, expect code %d", err, val.expectCode)
+			}
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	var createParam execCreateParam
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/containers/db/exec":
+			_ = json.NewDecoder(req.Body).Decode(&createParam)
+			res.WriteHeader(http.StatusCreated)
+			_, _ = res.Write([]byte(`{"Id":"e1"}`))
+		case "/exec/e1/start":
+			_, _ = res.Write(append(frame(1, "out\n"), frame(2, "err\n")...))
+		case "/exec/e1/json":
+			_, _ = res.Write([]byte(`{"ExitCode":3}`))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ret, err := New(server.URL, time.Second).Exec("db", "exit 3", &runtime.ExecOption{Env: []string{"A=1"}, WorkDir: "/tmp"})
+	if err == nil {
+		t.Fatalf("expect error for non-zero exit")
+	}
+	if ret.Stdout != "out\n" || ret.Stderr != "err\n" || ret.ExitCode != 3 {
+		t.Errorf("unexpected result %+v", ret)
+	}
+	if len(createParam.Cmd) != 3 || createParam.Cmd[2] != "exit 3" || createParam.Env[0] != "A=1" || createParam.WorkingDir != "/tmp" {
+		t.Errorf("unexpected create param %+v", createParam)
+	}
+}
+
+func TestDemuxCopy(t *testing.T) {
+	cases := []struct {
+		name         string
+		data         []byte
+		expectStdout string
+		expectStderr string
+	}{
+		{name: "multiplexed", data: append(frame(1, "a"), frame(2, "b")...), expectStdout: "a", expectStderr: "b"},
+		{name: "tty", data: []byte("plain output without header"), expectStdout: "plain output without header"},
+		{name: "short", data: []byte("abc"), expectStdout: "abc"},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if err := demuxCopy(bytes.NewReader(val.data), stdout, stderr); err != nil && err != io.EOF {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if stdout.String() != val.expectStdout || stderr.String() != val.expectStderr {
+				t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/containers/db/stats" || req.URL.Query().Get("stream") != "false" {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = res.Write([]byte(`{
+			"name": "/db",
+			"cpu_stats": {"cpu_usage": {"total_usage": 3000}, "system_cpu_usage": 20000, "online_cpus": 2},
+			"precpu_stats": {"cpu_usage": {"total_usage": 1000}, "system_cpu_usage": 10000},
+			"memory_stats": {"usage": 1500, "limit": 4000, "stats": {"inactive_file": 500}},
+			"pids_stats": {"current": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	statsPtr, err := New(server.URL, time.Second).Stats("db")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if statsPtr.Name != "db" || statsPtr.CPUPercent != 40 || statsPtr.MemoryUsage != 1000 || statsPtr.MemoryPercent != 25 || statsPtr.PIDs != 7 {
+		t.Errorf("unexpected stats %+v", statsPtr)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/containers/db/logs" {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = req.URL.RawQuery
+		_, _ = res.Write(append(frame(1, "out\n"), frame(2, "err\n")...))
+	}))
+	defer server.Close()
+
+	since := time.Unix(1767323045, 0)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := New(server.URL, time.Second).Logs(context.Background(), "db", &runtime.LogOption{Tail: 5, Since: since, Follow: true}, stdout, stderr)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if query != "follow=true&since=1767323045&stderr=true&stdout=true&tail=5" {
+		t.Errorf("query %q", query)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+}
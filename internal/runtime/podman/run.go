@@ -0,0 +1,108 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/runtime/docker"
+)
+
+type runHostConfig struct {
+	VolumesFrom []string `json:"VolumesFrom"`
+	Binds       []string `json:"Binds,omitempty"`
+	NetworkMode string   `json:"NetworkMode,omitempty"`
+}
+
+type runCreateParam struct {
+	Image      string        `json:"Image"`
+	Entrypoint []string      `json:"Entrypoint"`
+	Cmd        []string      `json:"Cmd"`
+	Env        []string      `json:"Env,omitempty"`
+	HostConfig runHostConfig `json:"HostConfig"`
+}
+
+// Run 创建并启动临时容器，跟随日志输出直到容器退出，再查询退出码，结束后删除临时容器
+func (s *Podman) Run(ctx context.Context, name string, cmd []string, option *runtime.RunOption, stdout, stderr io.Writer) (err *cd.Result) {
+	if len(cmd) == 0 {
+		err = cd.NewError(cd.IllegalParam, "illegal command")
+		return
+	}
+
+	containerVal, containerErr := s.request(http.MethodGet, containerPath(name)+"/json", nil, nil, http.StatusOK)
+	if containerErr != nil {
+		err = containerErr
+		return
+	}
+	imageID, imageErr := docker.OldImageID(containerVal)
+	if imageErr != nil {
+		err = cd.NewError(cd.UnExpected, imageErr.Error())
+		return
+	}
+
+	runName := runtime.RunName(name)
+	removeRun := func() {
+		_, _ = s.request(http.MethodDelete, containerPath(runName), url.Values{"force": {"true"}}, nil, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+	}
+	removeRun()
+
+	createParam := &runCreateParam{
+		Image:      imageID,
+		Entrypoint: cmd[:1],
+		Cmd:        cmd[1:],
+		HostConfig: runHostConfig{VolumesFrom: []string{name}},
+	}
+	if option != nil {
+		createParam.Env = option.Env
+		for _, val := range option.Mounts {
+			createParam.HostConfig.Binds = append(createParam.HostConfig.Binds, val+":"+val)
+		}
+		if option.ShareNetwork {
+			createParam.HostConfig.NetworkMode = "container:" + name
+		}
+	}
+	_, err = s.request(http.MethodPost, "/containers/create", url.Values{"name": {runName}}, createParam, http.StatusCreated)
+	if err != nil {
+		return
+	}
+	defer removeRun()
+
+	if err = s.operate("start", runName); err != nil {
+		return
+	}
+
+	err = s.Logs(ctx, runName, &runtime.LogOption{Follow: true}, stdout, stderr)
+	if err != nil {
+		return
+	}
+	if ctx.Err() != nil {
+		err = cd.NewError(cd.UnExpected, ctx.Err().Error())
+		return
+	}
+
+	res, resErr := s.send(ctx, http.MethodPost, containerPath(runName)+"/wait", nil, nil, http.StatusOK)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	waitResult := struct {
+		StatusCode int `json:"StatusCode"`
+	}{}
+	byteErr := json.NewDecoder(res.Body).Decode(&waitResult)
+	if byteErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal wait result, %s", byteErr.Error()))
+		return
+	}
+	if waitResult.StatusCode != 0 {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("exit status %d", waitResult.StatusCode))
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/internal/runtime/docker"
+)
+
+// PullImage 拉取过程以json消息流返回，出错时消息中带有error字段
+func (s *Podman) PullImage(ctx context.Context, image string) (err *cd.Result) {
+	ctx, cancel := context.WithTimeout(ctx, runtime.PullTimeOut)
+	defer cancel()
+
+	res, resErr := s.send(ctx, http.MethodPost, "/images/create", url.Values{"fromImage": {image}}, nil, http.StatusOK)
+	if resErr != nil {
+		err = resErr
+		return
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		message := struct {
+			Error string `json:"error"`
+		}{}
+		decodeErr := decoder.Decode(&message)
+		if decodeErr == io.EOF {
+			return
+		}
+		if decodeErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("pull image %s failed, %s", image, decodeErr.Error()))
+			return
+		}
+		if message.Error != "" {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("pull image %s failed, %s", image, strings.TrimSpace(message.Error)))
+			return
+		}
+	}
+}
+
+func containerPath(name string) string {
+	return "/containers/" + url.PathEscape(name)
+}
+
+// Recreate 停止原容器并改名保留，使用新镜像和原配置创建同名容器并启动，失败时恢复原容器
+func (s *Podman) Recreate(name, image string) (oldImage string, err *cd.Result) {
+	backupName := runtime.BackupName(name)
+	if _, backupErr := s.request(http.MethodGet, containerPath(backupName)+"/json", nil, nil, http.StatusOK); backupErr == nil {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("container %s exist, commit or rollback previous upgrade first", backupName))
+		return
+	}
+
+	containerVal, containerErr := s.request(http.MethodGet, containerPath(name)+"/json", nil, nil, http.StatusOK)
+	if containerErr != nil {
+		err = containerErr
+		return
+	}
+	infoPtr := &docker.InspectInfo{}
+	_ = json.Unmarshal(containerVal, infoPtr)
+	oldImage = infoPtr.Config.Image
+
+	imageID, _ := docker.OldImageID(containerVal)
+	imageVal, imageErr := s.request(http.MethodGet, fmt.Sprintf("/images/%s/json", url.PathEscape(imageID)), nil, nil, http.StatusOK)
+	if imageErr != nil {
+		err = imageErr
+		return
+	}
+	specPtr, specErr := docker.NewCreateSpec(containerVal, imageVal, image)
+	if specErr != nil {
+		err = cd.NewError(cd.UnExpected, specErr.Error())
+		return
+	}
+
+	if err = s.operate("stop", name); err != nil {
+		return
+	}
+	if err = s.rename(name, backupName); err != nil {
+		_ = s.operate("start", name)
+		return
+	}
+
+	err = s.createContainer(specPtr)
+	if err != nil {
+		if restoreErr := s.Rollback(name); restoreErr != nil {
+			log.Errorf("restore container %s failed, error:%s", name, restoreErr.Reason)
+		}
+	}
+	return
+}
+
+func (s *Podman) rename(name, newName string) (err *cd.Result) {
+	_, err = s.request(http.MethodPost, containerPath(name)+"/rename", url.Values{"name": {newName}}, nil, http.StatusNoContent, http.StatusOK)
+	return
+}
+
+func (s *Podman) createContainer(specPtr *docker.CreateSpec) (err *cd.Result) {
+	_, err = s.request(http.MethodPost, "/containers/create", url.Values{"name": {specPtr.Name}}, specPtr.APIBody(), http.StatusCreated)
+	if err != nil {
+		return
+	}
+
+	for network, endpointPtr := range specPtr.Networks {
+		param := struct {
+			Container      string                 `json:"Container"`
+			EndpointConfig *docker.EndpointConfig `json:"EndpointConfig,omitempty"`
+		}{Container: specPtr.Name, EndpointConfig: endpointPtr}
+		_, err = s.request(http.MethodPost, fmt.Sprintf("/networks/%s/connect", url.PathEscape(network)), nil, param, http.StatusOK)
+		if err != nil {
+			return
+		}
+	}
+
+	err = s.operate("start", specPtr.Name)
+	return
+}
+
+// Rollback 删除新容器，恢复并启动原容器
+func (s *Podman) Rollback(name string) (err *cd.Result) {
+	backupName := runtime.BackupName(name)
+	if _, err = s.request(http.MethodGet, containerPath(backupName)+"/json", nil, nil, http.StatusOK); err != nil {
+		return
+	}
+
+	_, _ = s.request(http.MethodDelete, containerPath(name), url.Values{"force": {"true"}}, nil, http.StatusNoContent, http.StatusOK)
+	if err = s.rename(backupName, name); err != nil {
+		return
+	}
+
+	err = s.operate("start", name)
+	return
+}
+
+// Commit 删除原容器，保留其数据卷
+func (s *Podman) Commit(name string) (err *cd.Result) {
+	_, err = s.request(http.MethodDelete, containerPath(runtime.BackupName(name)), nil, nil, http.StatusNoContent, http.StatusOK)
+	return
+}
@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Executor 执行本地命令，option为nil时使用默认参数，命令非零退出时同时返回执行结果和错误
+type Executor func(ctx context.Context, option *ExecOption, cmdName string, args ...string) (ret *ExecResult, err *cd.Result)
+
+// StreamExecutor 执行本地命令并实时输出，ctx取消时结束命令，env为本地命令额外的环境变量
+type StreamExecutor func(ctx context.Context, env []string, stdout, stderr io.Writer, cmdName string, args ...string) *cd.Result
+
+// LogOption 日志参数，Tail<=0时返回全部日志，Since为零值时不限制开始时间，Follow为true时持续输出直到ctx取消
+type LogOption struct {
+	Tail   int
+	Since  time.Time
+	Follow bool
+}
+
+// Runtime 服务运行时，name为运行时中的服务名称
+type Runtime interface {
+	Name() string
+	Start(name string) (stdout, stderr string, err *cd.Result)
+	Stop(name string) (stdout, stderr string, err *cd.Result)
+	Restart(name string) (stdout, stderr string, err *cd.Result)
+	Exec(name, cmd string, option *ExecOption) (ret *ExecResult, err *cd.Result)
+	Inspect(name string) (ret *common.ContainerInfo, err *cd.Result)
+	Logs(ctx context.Context, name string, option *LogOption, stdout, stderr io.Writer) (err *cd.Result)
+	Stats(name string) (ret *common.ContainerStats, err *cd.Result)
+	List() (ret []*common.ContainerInfo, err *cd.Result)
+}
+
+// EventHandler 容器事件回调
+type EventHandler func(eventPtr *common.ContainerEvent)
+
+// Watcher 支持订阅容器事件的运行时实现该接口，Watch持续上报since之后的die、oom、health_status、restart事件，
+// 直到ctx取消或事件流中断
+type Watcher interface {
+	Watch(ctx context.Context, since time.Time, handler EventHandler) *cd.Result
+}
+
+// Streamer 支持流式执行命令的运行时实现该接口，命令输出实时写入stdout、stderr，ctx取消时结束命令输出，
+// 命令非零退出时返回错误
+type Streamer interface {
+	ExecStream(ctx context.Context, name string, cmd []string, option *ExecOption, stdout, stderr io.Writer) *cd.Result
+}
+
+// StreamParam 流式执行命令的事件参数，Option中的TimeOut不生效，由Context控制
+type StreamParam struct {
+	Context context.Context
+	Service string
+	Command []string
+	Option  *ExecOption
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// Upgrader 支持更换镜像重建容器的运行时实现该接口，Recreate时原容器停止并改名为BackupName保留，
+// 新容器确认正常后调用Commit删除原容器，否则调用Rollback恢复原容器
+type Upgrader interface {
+	PullImage(ctx context.Context, image string) *cd.Result
+	Recreate(name, image string) (oldImage string, err *cd.Result)
+	Rollback(name string) *cd.Result
+	Commit(name string) *cd.Result
+}
+
+// PullTimeOut 拉取镜像的超时时间
+const PullTimeOut = 30 * time.Minute
+
+// BackupName 重建期间原容器的名称
+func BackupName(name string) string {
+	return name + "-upgrade-backup"
+}
+
+// RunOption 临时容器参数，Mounts为宿主机目录，挂载到临时容器内的相同路径，
+// ShareNetwork为true时使用服务容器的网络，此时服务容器需处于运行状态
+type RunOption struct {
+	Mounts       []string
+	Env          []string
+	ShareNetwork bool
+}
+
+// Runner 支持运行临时容器的运行时实现该接口，临时容器使用服务容器的镜像和数据卷，服务容器停止时同样可以运行，
+// 命令结束或ctx取消后删除临时容器，命令非零退出时返回错误
+type Runner interface {
+	Run(ctx context.Context, name string, cmd []string, option *RunOption, stdout, stderr io.Writer) *cd.Result
+}
+
+// RunParam 运行临时容器的事件参数
+type RunParam struct {
+	Context context.Context
+	Service string
+	Command []string
+	Option  *RunOption
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// RunName 临时容器的名称
+func RunName(name string) string {
+	return name + "-run"
+}
+
+// Option 运行时参数，Endpoint为运行时API地址，Namespace为运行时命名空间，Selector为kubernetes标签选择器
+type Option struct {
+	Endpoint        string
+	Namespace       string
+	Selector        string
+	TimeOut         time.Duration
+	Executor        Executor
+	StreamExecutor  StreamExecutor
+	TerminalStarter TerminalStarter
+}
+
+// Creator 运行时构造函数
+type Creator func(option *Option) Runtime
+
+var creatorLock sync.RWMutex
+var creatorMap = map[string]Creator{}
+
+// Register 注册运行时，由各运行时包在init中调用
+func Register(name string, creator Creator) {
+	creatorLock.Lock()
+	defer creatorLock.Unlock()
+
+	if _, ok := creatorMap[name]; ok {
+		panic(fmt.Sprintf("duplicate runtime:%s", name))
+	}
+
+	creatorMap[name] = creator
+}
+
+// New 新建运行时
+func New(name string, option *Option) (ret Runtime, err *cd.Result) {
+	creatorLock.RLock()
+	defer creatorLock.RUnlock()
+
+	creator, ok := creatorMap[name]
+	if !ok {
+		err = cd.NewError(cd.IllegalParam, fmt.Sprintf("unknown runtime:%s", name))
+		return
+	}
+
+	ret = creator(option)
+	return
+}
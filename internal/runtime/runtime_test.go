@@ -0,0 +1,25 @@
+package runtime
+
+import (
+	"testing"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New("unknown", &Option{}); err == nil || err.ErrorCode != cd.IllegalParam {
+		t.Fatalf("error %v, expect IllegalParam", err)
+	}
+
+	Register("fake", func(option *Option) Runtime { return nil })
+	if _, err := New("fake", &Option{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expect panic for duplicate runtime")
+		}
+	}()
+	Register("fake", func(option *Option) Runtime { return nil })
+}
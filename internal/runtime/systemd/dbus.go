@@ -0,0 +1,503 @@
+package systemd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 最小化的D-Bus客户端，仅支持方法调用，参数类型限于s和o
+
+const (
+	defaultBusAddress = "unix:path=/run/dbus/system_bus_socket"
+
+	msgMethodCall   = 1
+	msgMethodReturn = 2
+	msgError        = 3
+
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSignature   = 8
+)
+
+// dbusError D-Bus返回的错误应答，与连接失败区分
+type dbusError struct {
+	Name    string
+	Message string
+}
+
+func (s *dbusError) Error() string {
+	return fmt.Sprintf("%s: %s", s.Name, s.Message)
+}
+
+type dbusConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	serial  uint32
+	timeOut time.Duration
+}
+
+// busAddress 解析总线地址，支持 unix:path=、unix:abstract= 以及直接的socket路径
+func busAddress(address string) (network, path string, err error) {
+	if address == "" {
+		address = os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	}
+	if address == "" {
+		address = defaultBusAddress
+	}
+	if strings.HasPrefix(address, "/") {
+		return "unix", address, nil
+	}
+
+	// 多个地址以分号分隔，使用第一个
+	address = strings.SplitN(address, ";", 2)[0]
+	if !strings.HasPrefix(address, "unix:") {
+		return "", "", fmt.Errorf("unsupported dbus address:%s", address)
+	}
+
+	for _, val := range strings.Split(strings.TrimPrefix(address, "unix:"), ",") {
+		items := strings.SplitN(val, "=", 2)
+		if len(items) != 2 {
+			continue
+		}
+
+		switch items[0] {
+		case "path":
+			return "unix", items[1], nil
+		case "abstract":
+			return "unix", "@" + items[1], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unsupported dbus address:%s", address)
+}
+
+func dialBus(address string, timeOut time.Duration) (ret *dbusConn, err error) {
+	network, path, err := busAddress(address)
+	if err != nil {
+		return
+	}
+
+	conn, err := net.DialTimeout(network, path, timeOut)
+	if err != nil {
+		return
+	}
+
+	ptr := &dbusConn{conn: conn, reader: bufio.NewReader(conn), timeOut: timeOut}
+	err = ptr.auth()
+	if err == nil {
+		_, err = ptr.call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello")
+	}
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	ret = ptr
+	return
+}
+
+// auth 使用EXTERNAL机制认证，凭据为当前用户uid
+func (s *dbusConn) auth() error {
+	s.conn.SetDeadline(time.Now().Add(s.timeOut))
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	_, err := s.conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n"))
+	if err != nil {
+		return err
+	}
+
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK") {
+		return fmt.Errorf("dbus auth failed, %s", strings.TrimSpace(line))
+	}
+
+	_, err = s.conn.Write([]byte("BEGIN\r\n"))
+	return err
+}
+
+func (s *dbusConn) close() {
+	s.conn.Close()
+}
+
+// call 调用方法并等待应答，args依次按s类型编码，以/开头的参数按o类型编码
+func (s *dbusConn) call(destination, path, iface, member string, args ...string) (ret []interface{}, err error) {
+	s.serial++
+	serial := s.serial
+
+	signature := ""
+	body := &encoder{order: binary.LittleEndian}
+	for _, val := range args {
+		if strings.HasPrefix(val, "/") {
+			signature += "o"
+		} else {
+			signature += "s"
+		}
+		body.string(val)
+	}
+
+	msg := &encoder{order: binary.LittleEndian}
+	msg.byte('l')
+	msg.byte(msgMethodCall)
+	msg.byte(0)
+	msg.byte(1)
+	msg.uint32(uint32(body.buf.Len()))
+	msg.uint32(serial)
+
+	fields := []struct {
+		code      byte
+		signature string
+		value     string
+	}{
+		{fieldPath, "o", path},
+		{fieldInterface, "s", iface},
+		{fieldMember, "s", member},
+		{fieldDestination, "s", destination},
+	}
+	if signature != "" {
+		fields = append(fields, struct {
+			code      byte
+			signature string
+			value     string
+		}{fieldSignature, "g", signature})
+	}
+
+	// 头部字段 a(yv)，数组长度不包含首元素前的对齐
+	msg.align(4)
+	lenPos := msg.buf.Len()
+	msg.uint32(0)
+	msg.align(8)
+	startPos := msg.buf.Len()
+	for _, val := range fields {
+		msg.align(8)
+		msg.byte(val.code)
+		msg.signature(val.signature)
+		if val.signature == "g" {
+			msg.signature(val.value)
+		} else {
+			msg.string(val.value)
+		}
+	}
+	msg.order.PutUint32(msg.buf.Bytes()[lenPos:], uint32(msg.buf.Len()-startPos))
+	msg.align(8)
+	msg.buf.Write(body.buf.Bytes())
+
+	s.conn.SetDeadline(time.Now().Add(s.timeOut))
+	_, err = s.conn.Write(msg.buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	// 跳过信号及其他应答，直到收到对应serial的应答
+	for {
+		msgType, header, bodyVal, readErr := s.readMessage()
+		if readErr != nil {
+			err = readErr
+			return
+		}
+
+		replySerial, _ := header[fieldReplySerial].(uint32)
+		if (msgType != msgMethodReturn && msgType != msgError) || replySerial != serial {
+			continue
+		}
+
+		if msgType == msgError {
+			errPtr := &dbusError{}
+			errPtr.Name, _ = header[fieldErrorName].(string)
+			if len(bodyVal) > 0 {
+				errPtr.Message, _ = bodyVal[0].(string)
+			}
+			err = errPtr
+			return
+		}
+
+		ret = bodyVal
+		return
+	}
+}
+
+func (s *dbusConn) readMessage() (msgType byte, header map[byte]interface{}, body []interface{}, err error) {
+	fixed := make([]byte, 16)
+	_, err = io.ReadFull(s.reader, fixed)
+	if err != nil {
+		return
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if fixed[0] == 'B' {
+		order = binary.BigEndian
+	}
+
+	msgType = fixed[1]
+	bodyLen := int(order.Uint32(fixed[4:8]))
+	fieldsLen := int(order.Uint32(fixed[12:16]))
+	headerLen := 16 + fieldsLen
+	if headerLen%8 != 0 {
+		headerLen += 8 - headerLen%8
+	}
+	if headerLen+bodyLen > 128*1024*1024 {
+		err = fmt.Errorf("dbus message too large")
+		return
+	}
+
+	data := make([]byte, headerLen+bodyLen)
+	copy(data, fixed)
+	_, err = io.ReadFull(s.reader, data[16:])
+	if err != nil {
+		return
+	}
+
+	headerDecoder := &decoder{order: order, data: data[:16+fieldsLen], pos: 12}
+	fieldsVal, _, fieldsErr := headerDecoder.value("a(yv)")
+	if fieldsErr != nil {
+		err = fieldsErr
+		return
+	}
+
+	header = map[byte]interface{}{}
+	for _, val := range fieldsVal.([]interface{}) {
+		items := val.([]interface{})
+		header[items[0].(byte)] = items[1]
+	}
+
+	signature, _ := header[fieldSignature].(string)
+	bodyDecoder := &decoder{order: order, data: data[headerLen:]}
+	for signature != "" {
+		var item interface{}
+		item, signature, err = bodyDecoder.value(signature)
+		if err != nil {
+			return
+		}
+		body = append(body, item)
+	}
+	return
+}
+
+type encoder struct {
+	order binary.ByteOrder
+	buf   bytes.Buffer
+}
+
+func (s *encoder) align(n int) {
+	for s.buf.Len()%n != 0 {
+		s.buf.WriteByte(0)
+	}
+}
+
+func (s *encoder) byte(val byte) {
+	s.buf.WriteByte(val)
+}
+
+func (s *encoder) uint32(val uint32) {
+	s.align(4)
+	byteVal := make([]byte, 4)
+	s.order.PutUint32(byteVal, val)
+	s.buf.Write(byteVal)
+}
+
+func (s *encoder) string(val string) {
+	s.uint32(uint32(len(val)))
+	s.buf.WriteString(val)
+	s.buf.WriteByte(0)
+}
+
+func (s *encoder) signature(val string) {
+	s.buf.WriteByte(byte(len(val)))
+	s.buf.WriteString(val)
+	s.buf.WriteByte(0)
+}
+
+type decoder struct {
+	order binary.ByteOrder
+	data  []byte
+	pos   int
+}
+
+func alignOf(typeCode byte) int {
+	switch typeCode {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+
+	return 4
+}
+
+// nextType 拆分出签名中的第一个完整类型
+func nextType(signature string) (string, string, error) {
+	if signature == "" {
+		return "", "", fmt.Errorf("empty signature")
+	}
+
+	switch signature[0] {
+	case 'a':
+		elem, rest, err := nextType(signature[1:])
+		return "a" + elem, rest, err
+	case '(', '{':
+		depth := 0
+		for idx := 0; idx < len(signature); idx++ {
+			switch signature[idx] {
+			case '(', '{':
+				depth++
+			case ')', '}':
+				depth--
+			}
+			if depth == 0 {
+				return signature[:idx+1], signature[idx+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("illegal signature:%s", signature)
+	}
+
+	return signature[:1], signature[1:], nil
+}
+
+func (s *decoder) align(n int) {
+	if s.pos%n != 0 {
+		s.pos += n - s.pos%n
+	}
+}
+
+func (s *decoder) take(n int) ([]byte, error) {
+	if n < 0 || s.pos+n > len(s.data) {
+		return nil, fmt.Errorf("dbus message truncated")
+	}
+
+	val := s.data[s.pos : s.pos+n]
+	s.pos += n
+	return val, nil
+}
+
+// fixed 解析定长类型，有符号整数按位转换
+func (s *decoder) fixed(typeCode byte) (ret interface{}, err error) {
+	size := alignOf(typeCode)
+	byteVal, err := s.take(size)
+	if err != nil {
+		return
+	}
+
+	switch typeCode {
+	case 'y':
+		ret = byteVal[0]
+	case 'n':
+		ret = int16(s.order.Uint16(byteVal))
+	case 'q':
+		ret = s.order.Uint16(byteVal)
+	case 'b':
+		ret = s.order.Uint32(byteVal) != 0
+	case 'i':
+		ret = int32(s.order.Uint32(byteVal))
+	case 'u', 'h':
+		ret = s.order.Uint32(byteVal)
+	case 'x':
+		ret = int64(s.order.Uint64(byteVal))
+	case 't':
+		ret = s.order.Uint64(byteVal)
+	case 'd':
+		ret = math.Float64frombits(s.order.Uint64(byteVal))
+	}
+	return
+}
+
+// value 解析签名中的第一个值，返回剩余签名
+func (s *decoder) value(signature string) (ret interface{}, rest string, err error) {
+	current, rest, err := nextType(signature)
+	if err != nil {
+		return
+	}
+
+	s.align(alignOf(current[0]))
+	switch current[0] {
+	case 'y', 'n', 'q', 'b', 'i', 'u', 'h', 'x', 't', 'd':
+		ret, err = s.fixed(current[0])
+	case 's', 'o':
+		lenVal, lenErr := s.take(4)
+		if lenErr != nil {
+			err = lenErr
+			return
+		}
+		strVal, strErr := s.take(int(s.order.Uint32(lenVal)) + 1)
+		if strErr != nil {
+			err = strErr
+			return
+		}
+		ret = string(strVal[:len(strVal)-1])
+	case 'g':
+		lenVal, lenErr := s.take(1)
+		if lenErr != nil {
+			err = lenErr
+			return
+		}
+		strVal, strErr := s.take(int(lenVal[0]) + 1)
+		if strErr != nil {
+			err = strErr
+			return
+		}
+		ret = string(strVal[:len(strVal)-1])
+	case 'v':
+		sigVal, _, sigErr := s.value("g")
+		if sigErr != nil {
+			err = sigErr
+			return
+		}
+		ret, _, err = s.value(sigVal.(string))
+	case 'a':
+		lenVal, lenErr := s.take(4)
+		if lenErr != nil {
+			err = lenErr
+			return
+		}
+		elemType := current[1:]
+		s.align(alignOf(elemType[0]))
+		end := s.pos + int(s.order.Uint32(lenVal))
+		if end > len(s.data) {
+			err = fmt.Errorf("dbus message truncated")
+			return
+		}
+
+		items := []interface{}{}
+		for s.pos < end {
+			item, _, itemErr := s.value(elemType)
+			if itemErr != nil {
+				err = itemErr
+				return
+			}
+			items = append(items, item)
+		}
+		ret = items
+	case '(', '{':
+		items := []interface{}{}
+		fieldsType := current[1 : len(current)-1]
+		for fieldsType != "" {
+			var item interface{}
+			item, fieldsType, err = s.value(fieldsType)
+			if err != nil {
+				return
+			}
+			items = append(items, item)
+		}
+		ret = items
+	default:
+		err = fmt.Errorf("unsupported dbus type:%c", current[0])
+	}
+
+	return
+}
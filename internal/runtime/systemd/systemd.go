@@ -0,0 +1,441 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+	"github.com/muidea/magicCommon/foundation/log"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const (
+	systemdService   = "org.freedesktop.systemd1"
+	systemdPath      = "/org/freedesktop/systemd1"
+	managerInterface = "org.freedesktop.systemd1.Manager"
+	unitInterface    = "org.freedesktop.systemd1.Unit"
+	serviceInterface = "org.freedesktop.systemd1.Service"
+	jobInterface     = "org.freedesktop.systemd1.Job"
+	propInterface    = "org.freedesktop.DBus.Properties"
+	noSuchUnitError  = "org.freedesktop.systemd1.NoSuchUnit"
+
+	defaultTimeout = 30 * time.Second
+	pollInterval   = 500 * time.Millisecond
+	statsInterval  = 500 * time.Millisecond
+)
+
+// showProperties systemctl show回退时读取的属性
+var showProperties = []string{
+	"Id", "LoadState", "ActiveState", "SubState", "FragmentPath", "NRestarts", "ExecMainStatus", "Result",
+	"ActiveEnterTimestamp", "InactiveEnterTimestamp", "CPUUsageNSec", "MemoryCurrent", "MemoryMax", "TasksCurrent",
+}
+
+func init() {
+	runtime.Register(common.SystemdRuntime, func(option *runtime.Option) runtime.Runtime {
+		return New(option)
+	})
+}
+
+// busConn systemd使用的D-Bus连接
+type busConn interface {
+	call(destination, path, iface, member string, args ...string) ([]interface{}, error)
+	close()
+}
+
+// busDialer 连接D-Bus系统总线
+type busDialer func(address string, timeOut time.Duration) (busConn, error)
+
+func dialSystemBus(address string, timeOut time.Duration) (busConn, error) {
+	connPtr, connErr := dialBus(address, timeOut)
+	if connErr != nil {
+		return nil, connErr
+	}
+
+	return connPtr, nil
+}
+
+// Systemd 通过D-Bus管理systemd单元，总线不可用时回退到systemctl
+// 服务名不含单元类型后缀时按.service处理
+type Systemd struct {
+	address        string
+	timeOut        time.Duration
+	dialer         busDialer
+	executor       runtime.Executor
+	streamExecutor runtime.StreamExecutor
+}
+
+// New 新建systemd运行时，Endpoint为D-Bus系统总线地址，为空时使用默认地址
+func New(option *runtime.Option) *Systemd {
+	ptr := &Systemd{
+		address:        option.Endpoint,
+		timeOut:        option.TimeOut,
+		dialer:         dialSystemBus,
+		executor:       option.Executor,
+		streamExecutor: option.StreamExecutor,
+	}
+	if ptr.timeOut <= 0 {
+		ptr.timeOut = defaultTimeout
+	}
+
+	return ptr
+}
+
+func unitName(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	return name + ".service"
+}
+
+func (s *Systemd) Name() string {
+	return common.SystemdRuntime
+}
+
+func (s *Systemd) Start(name string) (stdout, stderr string, err *cd.Result) {
+	return s.operate("StartUnit", "start", unitName(name))
+}
+
+func (s *Systemd) Stop(name string) (stdout, stderr string, err *cd.Result) {
+	return s.operate("StopUnit", "stop", unitName(name))
+}
+
+func (s *Systemd) Restart(name string) (stdout, stderr string, err *cd.Result) {
+	return s.operate("RestartUnit", "restart", unitName(name))
+}
+
+// Exec systemd服务运行在宿主机上，命令直接在本机执行
+func (s *Systemd) Exec(name, cmd string, option *runtime.ExecOption) (ret *runtime.ExecResult, err *cd.Result) {
+	return s.executor(context.Background(), option, "sh", "-c", cmd)
+}
+
+// Logs 读取单元的journal日志
+func (s *Systemd) Logs(ctx context.Context, name string, option *runtime.LogOption, stdout, stderr io.Writer) (err *cd.Result) {
+	args := []string{"--unit", unitName(name), "--no-pager"}
+	if option.Tail > 0 {
+		args = append(args, "--lines", strconv.Itoa(option.Tail))
+	}
+	if !option.Since.IsZero() {
+		args = append(args, "--since", option.Since.Local().Format("2006-01-02 15:04:05"))
+	}
+	if option.Follow {
+		args = append(args, "--follow")
+	}
+
+	return s.streamExecutor(ctx, nil, stdout, stderr, "journalctl", args...)
+}
+
+// operate 通过D-Bus提交任务并等待任务完成，连接总线失败时回退到systemctl
+func (s *Systemd) operate(method, action, unit string) (stdout, stderr string, err *cd.Result) {
+	connPtr, connErr := s.dialer(s.address, s.timeOut)
+	if connErr != nil {
+		log.Warnf("connect dbus failed, fallback to systemctl, error:%s", connErr.Error())
+		return s.run("systemctl", action, unit)
+	}
+	defer connPtr.close()
+
+	jobVal, jobErr := connPtr.call(systemdService, systemdPath, managerInterface, method, unit, "replace")
+	if jobErr != nil {
+		err = dbusResult(jobErr)
+		return
+	}
+
+	jobPath, _ := jobVal[0].(string)
+	err = s.waitJob(connPtr, jobPath)
+	if err != nil {
+		return
+	}
+
+	props, propsErr := s.properties(connPtr, unit, unitInterface)
+	if propsErr != nil {
+		err = propsErr
+		return
+	}
+
+	activeState := propString(props, "ActiveState")
+	stdout = fmt.Sprintf("%s %s, state:%s\n", action, unit, activeState)
+	switch action {
+	case "stop":
+		if activeState != "inactive" && activeState != "failed" {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("stop %s failed, state:%s", unit, activeState))
+		}
+	default:
+		if activeState != "active" {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("%s %s failed, state:%s", action, unit, activeState))
+		}
+	}
+	return
+}
+
+// waitJob 任务对象在任务完成后被移除
+func (s *Systemd) waitJob(connPtr busConn, jobPath string) *cd.Result {
+	deadline := time.Now().Add(s.timeOut)
+	for time.Now().Before(deadline) {
+		_, stateErr := connPtr.call(systemdService, jobPath, propInterface, "Get", jobInterface, "State")
+		if stateErr != nil {
+			if _, ok := stateErr.(*dbusError); ok {
+				return nil
+			}
+
+			return cd.NewError(cd.UnExpected, stateErr.Error())
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	return cd.NewError(cd.UnExpected, fmt.Sprintf("wait job %s timeout", jobPath))
+}
+
+// properties 读取单元属性，多个接口的属性合并返回，第一个接口之后的接口不存在时忽略
+func (s *Systemd) properties(connPtr busConn, unit string, ifaces ...string) (ret map[string]interface{}, err *cd.Result) {
+	pathVal, pathErr := connPtr.call(systemdService, systemdPath, managerInterface, "LoadUnit", unit)
+	if pathErr != nil {
+		err = dbusResult(pathErr)
+		return
+	}
+
+	ret = map[string]interface{}{}
+	unitPath, _ := pathVal[0].(string)
+	for idx, iface := range ifaces {
+		propsVal, propsErr := connPtr.call(systemdService, unitPath, propInterface, "GetAll", iface)
+		if propsErr != nil {
+			if idx == 0 {
+				err = dbusResult(propsErr)
+				return
+			}
+			continue
+		}
+
+		items, _ := propsVal[0].([]interface{})
+		for _, val := range items {
+			entry := val.([]interface{})
+			ret[entry[0].(string)] = entry[1]
+		}
+	}
+	return
+}
+
+// unitProperties 读取单元及服务属性，总线不可用时使用systemctl show
+func (s *Systemd) unitProperties(unit string) (ret map[string]interface{}, err *cd.Result) {
+	connPtr, connErr := s.dialer(s.address, s.timeOut)
+	if connErr == nil {
+		defer connPtr.close()
+		return s.properties(connPtr, unit, unitInterface, serviceInterface)
+	}
+
+	log.Warnf("connect dbus failed, fallback to systemctl, error:%s", connErr.Error())
+	stdout, _, showErr := s.run("systemctl", "show", unit, "--property="+strings.Join(showProperties, ","))
+	if showErr != nil {
+		err = showErr
+		return
+	}
+
+	ret = map[string]interface{}{}
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		items := strings.SplitN(scanner.Text(), "=", 2)
+		if len(items) == 2 {
+			ret[items[0]] = items[1]
+		}
+	}
+	return
+}
+
+func (s *Systemd) Inspect(name string) (ret *common.ContainerInfo, err *cd.Result) {
+	unit := unitName(name)
+	props, propsErr := s.unitProperties(unit)
+	if propsErr != nil {
+		err = propsErr
+		return
+	}
+
+	if propString(props, "LoadState") == "not-found" {
+		err = cd.NewWarn(cd.NoExist, fmt.Sprintf("unit %s not exist", unit))
+		return
+	}
+
+	restartCount, _ := propUint(props, "NRestarts")
+	exitCode, _ := propUint(props, "ExecMainStatus")
+	ret = &common.ContainerInfo{
+		ID:           propString(props, "Id"),
+		Name:         propString(props, "Id"),
+		Image:        propString(props, "FragmentPath"),
+		State:        propString(props, "ActiveState"),
+		Status:       propString(props, "SubState"),
+		RestartCount: int(restartCount),
+		ExitCode:     int(exitCode),
+		OOMKilled:    propString(props, "Result") == "oom-kill",
+		StartedAt:    propTime(props, "ActiveEnterTimestamp"),
+		FinishedAt:   propTime(props, "InactiveEnterTimestamp"),
+		Runtime:      common.SystemdRuntime,
+	}
+	return
+}
+
+// Stats CPU使用率按间隔内CPUUsageNSec的增量计算，需要开启CPUAccounting和MemoryAccounting
+func (s *Systemd) Stats(name string) (ret *common.ContainerStats, err *cd.Result) {
+	unit := unitName(name)
+	firstProps, firstErr := s.unitProperties(unit)
+	if firstErr != nil {
+		err = firstErr
+		return
+	}
+	firstTime := time.Now()
+
+	time.Sleep(statsInterval)
+	props, propsErr := s.unitProperties(unit)
+	if propsErr != nil {
+		err = propsErr
+		return
+	}
+
+	ret = &common.ContainerStats{Name: propString(props, "Id"), Runtime: common.SystemdRuntime}
+	ret.MemoryUsage, _ = propUint(props, "MemoryCurrent")
+	ret.MemoryLimit, _ = propUint(props, "MemoryMax")
+	ret.PIDs, _ = propUint(props, "TasksCurrent")
+	if ret.MemoryLimit > 0 {
+		ret.MemoryPercent = float64(ret.MemoryUsage) / float64(ret.MemoryLimit) * 100
+	}
+
+	firstCPU, firstOK := propUint(firstProps, "CPUUsageNSec")
+	currentCPU, currentOK := propUint(props, "CPUUsageNSec")
+	if firstOK && currentOK && currentCPU > firstCPU {
+		ret.CPUPercent = float64(currentCPU-firstCPU) / float64(time.Since(firstTime).Nanoseconds()) * 100
+	}
+	return
+}
+
+func propString(props map[string]interface{}, key string) string {
+	switch val := props[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// propUint 未设置的属性D-Bus返回UINT64_MAX，systemctl返回[not set]或infinity
+func propUint(props map[string]interface{}, key string) (uint64, bool) {
+	switch val := props[key].(type) {
+	case uint64:
+		return val, val != math.MaxUint64
+	case uint32:
+		return uint64(val), true
+	case int32:
+		return uint64(val), val >= 0
+	case string:
+		intVal, intErr := strconv.ParseUint(val, 10, 64)
+		return intVal, intErr == nil && intVal != math.MaxUint64
+	}
+
+	return 0, false
+}
+
+// propTime D-Bus返回微秒时间戳，systemctl返回格式化后的时间
+func propTime(props map[string]interface{}, key string) string {
+	switch val := props[key].(type) {
+	case uint64:
+		if val == 0 {
+			return ""
+		}
+		return time.UnixMicro(int64(val)).Format(time.RFC3339)
+	case string:
+		return val
+	}
+
+	return ""
+}
+
+// List 列出已加载的service单元
+func (s *Systemd) List() (ret []*common.ContainerInfo, err *cd.Result) {
+	connPtr, connErr := s.dialer(s.address, s.timeOut)
+	if connErr != nil {
+		log.Warnf("connect dbus failed, fallback to systemctl, error:%s", connErr.Error())
+		return s.listUnits()
+	}
+	defer connPtr.close()
+
+	unitsVal, unitsErr := connPtr.call(systemdService, systemdPath, managerInterface, "ListUnits")
+	if unitsErr != nil {
+		err = dbusResult(unitsErr)
+		return
+	}
+
+	// a(ssssssouso): name, description, load state, active state, sub state, ...
+	units, _ := unitsVal[0].([]interface{})
+	for _, val := range units {
+		items := val.([]interface{})
+		name := items[0].(string)
+		if !strings.HasSuffix(name, ".service") {
+			continue
+		}
+
+		ret = append(ret, &common.ContainerInfo{
+			ID:      name,
+			Name:    name,
+			State:   items[3].(string),
+			Status:  items[4].(string),
+			Runtime: common.SystemdRuntime,
+		})
+	}
+
+	return
+}
+
+func (s *Systemd) listUnits() (ret []*common.ContainerInfo, err *cd.Result) {
+	stdout, _, listErr := s.run("systemctl", "list-units", "--type=service", "--all", "--no-legend", "--no-pager", "--plain")
+	if listErr != nil {
+		err = listErr
+		return
+	}
+
+	// UNIT LOAD ACTIVE SUB DESCRIPTION
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		items := strings.Fields(scanner.Text())
+		if len(items) < 4 {
+			continue
+		}
+
+		ret = append(ret, &common.ContainerInfo{
+			ID:      items[0],
+			Name:    items[0],
+			State:   items[2],
+			Status:  items[3],
+			Runtime: common.SystemdRuntime,
+		})
+	}
+
+	return
+}
+
+func (s *Systemd) run(cmdName string, args ...string) (stdout, stderr string, err *cd.Result) {
+	// 抽取返回值检查是否出错
+	resultPtr, resultErr := s.executor(context.Background(), nil, cmdName, args...)
+	if resultErr != nil {
+		err = resultErr
+		return
+	}
+
+	stdout = resultPtr.Stdout
+	stderr = resultPtr.Stderr
+	return
+}
+
+func dbusResult(err error) *cd.Result {
+	if errPtr, ok := err.(*dbusError); ok && errPtr.Name == noSuchUnitError {
+		return cd.NewWarn(cd.NoExist, errPtr.Message)
+	}
+
+	return cd.NewError(cd.UnExpected, err.Error())
+}
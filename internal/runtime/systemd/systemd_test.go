@@ -0,0 +1,268 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/runtime"
+)
+
+// fakeBus 按member返回预设的应答，GetAll按接口返回属性
+type fakeBus struct {
+	unitErr error
+	props   map[string]map[string]interface{}
+	calls   []string
+	closed  bool
+}
+
+func (s *fakeBus) call(_, path, iface, member string, args ...string) ([]interface{}, error) {
+	s.calls = append(s.calls, strings.Join(append([]string{member}, args...), " "))
+	switch member {
+	case "StartUnit", "StopUnit", "RestartUnit":
+		if s.unitErr != nil {
+			return nil, s.unitErr
+		}
+		return []interface{}{"/org/freedesktop/systemd1/job/1"}, nil
+	case "Get":
+		// 任务完成后任务对象被移除
+		return nil, &dbusError{Name: "org.freedesktop.DBus.Error.UnknownObject"}
+	case "LoadUnit":
+		return []interface{}{"/org/freedesktop/systemd1/unit/" + args[0]}, nil
+	case "GetAll":
+		props, ok := s.props[args[0]]
+		if !ok {
+			return nil, &dbusError{Name: "org.freedesktop.DBus.Error.UnknownInterface"}
+		}
+		items := []interface{}{}
+		for key, val := range props {
+			items = append(items, []interface{}{key, val})
+		}
+		return []interface{}{items}, nil
+	case "ListUnits":
+		return []interface{}{[]interface{}{
+			[]interface{}{"mariadb.service", "MariaDB", "loaded", "active", "running"},
+			[]interface{}{"dbus.socket", "D-Bus", "loaded", "active", "listening"},
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected call %s %s.%s", path, iface, member)
+}
+
+func (s *fakeBus) close() {
+	s.closed = true
+}
+
+// fakeExecutor 记录命令并返回预设的输出
+type fakeExecutor struct {
+	stdout string
+	err    *cd.Result
+	cmds   []string
+}
+
+func (s *fakeExecutor) execute(_ context.Context, _ *runtime.ExecOption, cmdName string, args ...string) (*runtime.ExecResult, *cd.Result) {
+	s.cmds = append(s.cmds, strings.Join(append([]string{cmdName}, args...), " "))
+	return &runtime.ExecResult{Stdout: s.stdout}, s.err
+}
+
+func newTestSystemd(busPtr *fakeBus, executor *fakeExecutor) *Systemd {
+	ptr := New(&runtime.Option{TimeOut: time.Second, Executor: executor.execute})
+	ptr.dialer = func(string, time.Duration) (busConn, error) {
+		if busPtr == nil {
+			return nil, fmt.Errorf("no bus")
+		}
+		return busPtr, nil
+	}
+	return ptr
+}
+
+func TestOperate(t *testing.T) {
+	cases := []struct {
+		name        string
+		operate     func(ptr *Systemd) *cd.Result
+		unitErr     error
+		activeState string
+		expectCode  cd.ErrorCode
+		expectCall  string
+	}{
+		{name: "start", operate: func(ptr *Systemd) *cd.Result { _, _, err := ptr.Start("mariadb"); return err }, activeState: "active", expectCall: "StartUnit mariadb.service replace"},
+		{name: "start failed", operate: func(ptr *Systemd) *cd.Result { _, _, err := ptr.Start("mariadb"); return err }, activeState: "failed", expectCode: cd.UnExpected},
+		{name: "stop", operate: func(ptr *Systemd) *cd.Result { _, _, err := ptr.Stop("mariadb"); return err }, activeState: "inactive", expectCall: "StopUnit mariadb.service replace"},
+		{name: "stop still active", operate: func(ptr *Systemd) *cd.Result { _, _, err := ptr.Stop("mariadb"); return err }, activeState: "deactivating", expectCode: cd.UnExpected},
+		{name: "restart socket", operate: func(ptr *Systemd) *cd.Result { _, _, err := ptr.Restart("dbus.socket"); return err }, activeState: "active", expectCall: "RestartUnit dbus.socket replace"},
+		{
+			name:       "no such unit",
+			operate:    func(ptr *Systemd) *cd.Result { _, _, err := ptr.Start("missing"); return err },
+			unitErr:    &dbusError{Name: noSuchUnitError, Message: "Unit missing.service not found."},
+			expectCode: cd.NoExist,
+		},
+		{
+			name:       "access denied",
+			operate:    func(ptr *Systemd) *cd.Result { _, _, err := ptr.Start("mariadb"); return err },
+			unitErr:    &dbusError{Name: "org.freedesktop.DBus.Error.AccessDenied", Message: "denied"},
+			expectCode: cd.UnExpected,
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			busPtr := &fakeBus{unitErr: val.unitErr, props: map[string]map[string]interface{}{unitInterface: {"ActiveState": val.activeState}}}
+			err := val.operate(newTestSystemd(busPtr, &fakeExecutor{}))
+			if val.expectCode == cd.Succeeded {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			} else if err == nil || err.ErrorCode != val.expectCode {
+				t.Fatalf("error %v, expect code %d", err, val.expectCode)
+			}
+			if val.expectCall != "" && busPtr.calls[0] != val.expectCall {
+				t.Errorf("call %q, expect %q", busPtr.calls[0], val.expectCall)
+			}
+			if !busPtr.closed {
+				t.Errorf("bus not closed")
+			}
+		})
+	}
+}
+
+func TestOperateFallback(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        *cd.Result
+		expectCode cd.ErrorCode
+	}{
+		{name: "succeeded"},
+		{name: "failed", err: cd.NewError(cd.UnExpected, "exit status 5"), expectCode: cd.UnExpected},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			executor := &fakeExecutor{err: val.err}
+			_, _, err := newTestSystemd(nil, executor).Restart("mariadb")
+			if (err == nil) != (val.expectCode == cd.Succeeded) || (err != nil && err.ErrorCode != val.expectCode) {
+				t.Fatalf("error %v, expect code %d", err, val.expectCode)
+			}
+			if len(executor.cmds) != 1 || executor.cmds[0] != "systemctl restart mariadb.service" {
+				t.Errorf("unexpected commands %v", executor.cmds)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	startTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		name       string
+		busPtr     *fakeBus
+		stdout     string
+		expectCode cd.ErrorCode
+		expect     string
+	}{
+		{
+			name: "dbus",
+			busPtr: &fakeBus{props: map[string]map[string]interface{}{
+				unitInterface: {
+					"Id": "mariadb.service", "LoadState": "loaded", "ActiveState": "failed", "SubState": "failed",
+					"ActiveEnterTimestamp": uint64(startTime.UnixMicro()), "InactiveEnterTimestamp": uint64(0),
+				},
+				serviceInterface: {"NRestarts": uint32(3), "ExecMainStatus": int32(137), "Result": "oom-kill"},
+			}},
+			expect: "mariadb.service failed failed restarts:3 exit:137 oom:true started:" + startTime.Local().Format(time.RFC3339) + " finished:",
+		},
+		{
+			name:   "dbus without service interface",
+			busPtr: &fakeBus{props: map[string]map[string]interface{}{unitInterface: {"Id": "dbus.socket", "LoadState": "loaded", "ActiveState": "active", "SubState": "listening"}}},
+			expect: "dbus.socket active listening restarts:0 exit:0 oom:false started: finished:",
+		},
+		{
+			name:       "not found",
+			busPtr:     &fakeBus{props: map[string]map[string]interface{}{unitInterface: {"LoadState": "not-found"}}},
+			expectCode: cd.NoExist,
+		},
+		{
+			name:   "systemctl show",
+			stdout: "Id=mariadb.service\nLoadState=loaded\nActiveState=active\nSubState=running\nNRestarts=1\nExecMainStatus=0\nResult=success\nActiveEnterTimestamp=Fri 2026-01-02 03:04:05 UTC\n",
+			expect: "mariadb.service active running restarts:1 exit:0 oom:false started:Fri 2026-01-02 03:04:05 UTC finished:",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			infoPtr, err := newTestSystemd(val.busPtr, &fakeExecutor{stdout: val.stdout}).Inspect("mariadb")
+			if val.expectCode != cd.Succeeded {
+				if err == nil || err.ErrorCode != val.expectCode {
+					t.Fatalf("error %v, expect code %d", err, val.expectCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			info := fmt.Sprintf("%s %s %s restarts:%d exit:%d oom:%v started:%s finished:%s",
+				infoPtr.Name, infoPtr.State, infoPtr.Status, infoPtr.RestartCount, infoPtr.ExitCode, infoPtr.OOMKilled, infoPtr.StartedAt, infoPtr.FinishedAt)
+			if info != val.expect {
+				t.Errorf("info %q, expect %q", info, val.expect)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	infos, err := newTestSystemd(&fakeBus{}, &fakeExecutor{}).List()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "mariadb.service" || infos[0].Status != "running" {
+		t.Errorf("unexpected list %+v", infos)
+	}
+
+	executor := &fakeExecutor{stdout: "mariadb.service loaded active running MariaDB\nbroken.service not-found inactive dead broken\n"}
+	infos, err = newTestSystemd(nil, executor).List()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(infos) != 2 || infos[1].State != "inactive" || infos[1].Status != "dead" {
+		t.Errorf("unexpected list %+v", infos)
+	}
+}
+
+func TestPropUint(t *testing.T) {
+	cases := []struct {
+		name     string
+		val      interface{}
+		expect   uint64
+		expectOK bool
+	}{
+		{name: "uint64", val: uint64(10), expect: 10, expectOK: true},
+		{name: "not set", val: uint64(math.MaxUint64), expect: math.MaxUint64},
+		{name: "uint32", val: uint32(3), expect: 3, expectOK: true},
+		{name: "negative int32", val: int32(-1), expect: math.MaxUint64},
+		{name: "string", val: "42", expect: 42, expectOK: true},
+		{name: "infinity", val: "infinity"},
+		{name: "missing", val: nil},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			ret, ok := propUint(map[string]interface{}{"key": val.val}, "key")
+			if ok != val.expectOK || (ok && ret != val.expect) {
+				t.Errorf("propUint = %d, %v, expect %d, %v", ret, ok, val.expect, val.expectOK)
+			}
+		})
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	cases := map[string]string{"mariadb": "mariadb.service", "dbus.socket": "dbus.socket", "redis.service": "redis.service"}
+	for key, val := range cases {
+		if ret := unitName(key); ret != val {
+			t.Errorf("unitName(%q) = %q, expect %q", key, ret, val)
+		}
+	}
+}
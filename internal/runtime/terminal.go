@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"context"
+	"io"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+// DefaultTerminalCommand 未指定命令时启动的shell
+var DefaultTerminalCommand = []string{"sh"}
+
+// TerminalOption 交互式会话参数，Env格式为KEY=VALUE，TTY为true时分配伪终端，Rows、Cols为初始窗口大小
+type TerminalOption struct {
+	Env     []string
+	WorkDir string
+	TTY     bool
+	Rows    uint16
+	Cols    uint16
+}
+
+// Terminal 交互式会话，Read读取输出，非TTY时stdout、stderr合并输出，Write写入输入，
+// Close结束进程并释放资源
+type Terminal interface {
+	io.ReadWriteCloser
+	// Resize 调整窗口大小，非TTY时忽略
+	Resize(rows, cols uint16) error
+	// CloseWrite 结束输入，TTY时发送EOF字符
+	CloseWrite() error
+	// Wait 等待进程结束，进程未正常退出时ExitCode为-1
+	Wait() (exitCode int, err *cd.Result)
+}
+
+// TerminalStarter 在本地启动交互式命令，ctx取消时结束命令
+type TerminalStarter func(ctx context.Context, option *TerminalOption, cmdName string, args ...string) (Terminal, *cd.Result)
+
+// Attacher 支持交互式执行的运行时实现该接口，cmd为空时启动DefaultTerminalCommand
+type Attacher interface {
+	Attach(ctx context.Context, name string, cmd []string, option *TerminalOption) (Terminal, *cd.Result)
+}
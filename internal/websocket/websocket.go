@@ -0,0 +1,387 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 按RFC6455实现的最小化websocket连接，不支持扩展，客户端和服务端共用
+
+const (
+	ContinuationMessage = 0
+	TextMessage         = 1
+	BinaryMessage       = 2
+	CloseMessage        = 8
+	PingMessage         = 9
+	PongMessage         = 10
+
+	acceptGUID     = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxMessageSize = 64 * 1024 * 1024
+)
+
+// ErrClosed 对端已关闭连接
+var ErrClosed = errors.New("websocket closed")
+
+// HandshakeError 服务端未同意升级，Body为服务端返回的内容
+type HandshakeError struct {
+	Status     string
+	StatusCode int
+	Body       []byte
+}
+
+func (s *HandshakeError) Error() string {
+	return fmt.Sprintf("websocket handshake failed, status:%s, %s", s.Status, strings.TrimSpace(string(s.Body)))
+}
+
+// Conn websocket连接，client为true时发送的帧需要掩码
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	client    bool
+	protocol  string
+	writeLock sync.Mutex
+}
+
+// Protocol 协商后的子协议
+func (s *Conn) Protocol() string {
+	return s.protocol
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Dial 建立客户端连接，rawURL支持ws、wss、http、https
+func Dial(rawURL string, header http.Header, tlsConfig *tls.Config, timeOut time.Duration) (ret *Conn, err error) {
+	urlPtr, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	secure := false
+	switch urlPtr.Scheme {
+	case "wss", "https":
+		secure = true
+	case "ws", "http":
+	default:
+		err = fmt.Errorf("illegal websocket url:%s", rawURL)
+		return
+	}
+
+	address := urlPtr.Host
+	if urlPtr.Port() == "" {
+		if secure {
+			address = net.JoinHostPort(urlPtr.Hostname(), "443")
+		} else {
+			address = net.JoinHostPort(urlPtr.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: timeOut}
+	var conn net.Conn
+	if secure {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = urlPtr.Hostname()
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        urlPtr,
+		Host:       urlPtr.Host,
+		Header:     http.Header{},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if timeOut > 0 {
+		conn.SetDeadline(time.Now().Add(timeOut))
+	}
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		res.Body.Close()
+		conn.Close()
+		err = &HandshakeError{Status: res.Status, StatusCode: res.StatusCode, Body: body}
+		return
+	}
+	if res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		err = fmt.Errorf("websocket handshake failed, illegal accept key")
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	ret = &Conn{
+		conn:     conn,
+		reader:   reader,
+		client:   true,
+		protocol: res.Header.Get("Sec-WebSocket-Protocol"),
+	}
+	return
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, val := range header.Values(name) {
+		for _, item := range strings.Split(val, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// CheckUpgrade 检查请求是否为合法的websocket握手请求
+func CheckUpgrade(req *http.Request) error {
+	if req.Method != http.MethodGet {
+		return fmt.Errorf("websocket handshake requires GET method")
+	}
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return fmt.Errorf("websocket handshake requires upgrade header")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fmt.Errorf("unsupported websocket version:%s", req.Header.Get("Sec-WebSocket-Version"))
+	}
+	if req.Header.Get("Sec-WebSocket-Key") == "" {
+		return fmt.Errorf("websocket handshake requires key")
+	}
+
+	return nil
+}
+
+// Upgrade 将HTTP请求升级为服务端websocket连接，res需支持Hijack，
+// protocols为服务端支持的子协议，按protocols的顺序选取第一个客户端请求的子协议
+func Upgrade(res http.ResponseWriter, req *http.Request, protocols ...string) (ret *Conn, err error) {
+	err = CheckUpgrade(req)
+	if err != nil {
+		return
+	}
+
+	hijacker, ok := res.(http.Hijacker)
+	if !ok {
+		err = fmt.Errorf("response writer not support hijack")
+		return
+	}
+
+	protocol := ""
+	for _, val := range protocols {
+		if headerContains(req.Header, "Sec-WebSocket-Protocol", val) {
+			protocol = val
+			break
+		}
+	}
+
+	conn, bufRW, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	content := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(req.Header.Get("Sec-WebSocket-Key")) + "\r\n"
+	if protocol != "" {
+		content += "Sec-WebSocket-Protocol: " + protocol + "\r\n"
+	}
+	_, err = conn.Write([]byte(content + "\r\n"))
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	ret = &Conn{
+		conn:     conn,
+		reader:   bufRW.Reader,
+		protocol: protocol,
+	}
+	return
+}
+
+// SetDeadline 设置读写超时
+func (s *Conn) SetDeadline(deadline time.Time) error {
+	return s.conn.SetDeadline(deadline)
+}
+
+// ReadMessage 读取完整消息，自动应答ping，收到close时返回ErrClosed
+func (s *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		final, opcode, payload, frameErr := s.readFrame()
+		if frameErr != nil {
+			err = frameErr
+			return
+		}
+
+		switch opcode {
+		case PingMessage:
+			err = s.WriteMessage(PongMessage, payload)
+			if err != nil {
+				return
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			_ = s.WriteMessage(CloseMessage, payload)
+			err = ErrClosed
+			return
+		case ContinuationMessage:
+			if messageType == 0 {
+				err = fmt.Errorf("unexpected continuation frame")
+				return
+			}
+		default:
+			messageType = opcode
+		}
+
+		data = append(data, payload...)
+		if len(data) > maxMessageSize {
+			err = fmt.Errorf("websocket message too large")
+			return
+		}
+		if final {
+			return
+		}
+	}
+}
+
+func (s *Conn) readFrame() (final bool, opcode int, payload []byte, err error) {
+	header := make([]byte, 2)
+	_, err = io.ReadFull(s.reader, header)
+	if err != nil {
+		return
+	}
+
+	final = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	size := uint64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		extend := make([]byte, 2)
+		_, err = io.ReadFull(s.reader, extend)
+		size = uint64(binary.BigEndian.Uint16(extend))
+	case 127:
+		extend := make([]byte, 8)
+		_, err = io.ReadFull(s.reader, extend)
+		size = binary.BigEndian.Uint64(extend)
+	}
+	if err != nil {
+		return
+	}
+	if size > maxMessageSize {
+		err = fmt.Errorf("websocket frame too large")
+		return
+	}
+
+	maskKey := make([]byte, 4)
+	if masked {
+		_, err = io.ReadFull(s.reader, maskKey)
+		if err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, size)
+	_, err = io.ReadFull(s.reader, payload)
+	if err != nil {
+		return
+	}
+	if masked {
+		for idx := range payload {
+			payload[idx] ^= maskKey[idx%4]
+		}
+	}
+	return
+}
+
+// WriteMessage 以单帧发送消息
+func (s *Conn) WriteMessage(messageType int, data []byte) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	frame := []byte{0x80 | byte(messageType)}
+	maskBit := byte(0)
+	if s.client {
+		maskBit = 0x80
+	}
+
+	size := len(data)
+	switch {
+	case size < 126:
+		frame = append(frame, maskBit|byte(size))
+	case size <= 0xffff:
+		frame = append(frame, maskBit|126, byte(size>>8), byte(size))
+	default:
+		extend := make([]byte, 8)
+		binary.BigEndian.PutUint64(extend, uint64(size))
+		frame = append(append(frame, maskBit|127), extend...)
+	}
+
+	payload := data
+	if s.client {
+		maskKey := make([]byte, 4)
+		_, _ = rand.Read(maskKey)
+		frame = append(frame, maskKey...)
+		payload = make([]byte, size)
+		for idx := range data {
+			payload[idx] = data[idx] ^ maskKey[idx%4]
+		}
+	}
+
+	_, err := s.conn.Write(append(frame, payload...))
+	return err
+}
+
+// Close 关闭底层连接
+func (s *Conn) Close() error {
+	return s.conn.Close()
+}
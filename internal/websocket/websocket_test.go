@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEcho(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		conn, err := Upgrade(res, req, "v4.channel.k8s.io", "v5.channel.k8s.io")
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		// 先发送ping，客户端读取时自动应答
+		_ = conn.WriteMessage(PingMessage, []byte("p"))
+		for {
+			messageType, data, readErr := conn.ReadMessage()
+			if readErr != nil {
+				return
+			}
+			_ = conn.WriteMessage(messageType, data)
+		}
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", "v5.channel.k8s.io, v4.channel.k8s.io")
+	conn, err := Dial(server.URL, header, nil, time.Second)
+	if err != nil {
+		t.Fatalf("dial failed, %v", err)
+	}
+	defer conn.Close()
+
+	// 服务端按自身的顺序选择子协议
+	if conn.Protocol() != "v4.channel.k8s.io" {
+		t.Errorf("protocol %q", conn.Protocol())
+	}
+
+	messages := [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), 200), bytes.Repeat([]byte("b"), 70000)}
+	for _, val := range messages {
+		if err := conn.WriteMessage(BinaryMessage, val); err != nil {
+			t.Fatalf("write failed, %v", err)
+		}
+		messageType, data, readErr := conn.ReadMessage()
+		if readErr != nil {
+			t.Fatalf("read failed, %v", readErr)
+		}
+		if messageType != BinaryMessage || !bytes.Equal(data, val) {
+			t.Errorf("echo mismatch, size %d, expect %d", len(data), len(val))
+		}
+	}
+
+	if err := conn.WriteMessage(CloseMessage, nil); err != nil {
+		t.Fatalf("close failed, %v", err)
+	}
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("error %v, expect ErrClosed", err)
+	}
+}
+
+func TestHandshakeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.Error(res, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := Dial(server.URL, nil, nil, time.Second)
+	handshakeErr, ok := err.(*HandshakeError)
+	if !ok {
+		t.Fatalf("error %v, expect HandshakeError", err)
+	}
+	if handshakeErr.StatusCode != http.StatusForbidden || !strings.Contains(string(handshakeErr.Body), "forbidden") {
+		t.Errorf("unexpected handshake error %v", handshakeErr)
+	}
+
+	if _, err := Dial("ftp://127.0.0.1", nil, nil, time.Second); err == nil {
+		t.Errorf("expect error for illegal scheme")
+	}
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		header map[string]string
+		expect string
+	}{
+		{
+			name:   "valid",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "k"},
+		},
+		{name: "method", method: http.MethodPost, expect: "GET method"},
+		{name: "no upgrade", method: http.MethodGet, header: map[string]string{"Connection": "keep-alive"}, expect: "upgrade header"},
+		{
+			name:   "version",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8"},
+			expect: "unsupported websocket version",
+		},
+		{
+			name:   "no key",
+			method: http.MethodGet,
+			header: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13"},
+			expect: "requires key",
+		},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			req := httptest.NewRequest(val.method, "/", nil)
+			for key, headerVal := range val.header {
+				req.Header.Set(key, headerVal)
+			}
+
+			err := CheckUpgrade(req)
+			if val.expect == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if val.expect != "" && (err == nil || !strings.Contains(err.Error(), val.expect)) {
+				t.Errorf("error %v, expect %q", err, val.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,693 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+const (
+	defaultTimeout       = 30 * time.Second
+	defaultRetryInterval = 200 * time.Millisecond
+	maxRetryInterval     = 5 * time.Second
+)
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 使用Bearer Token认证
+func WithToken(token string) Option {
+	return func(s *Client) {
+		s.token = token
+	}
+}
+
+// WithBasicAuth 使用Basic认证
+func WithBasicAuth(account, password string) Option {
+	return func(s *Client) {
+		s.account = account
+		s.password = password
+	}
+}
+
+// WithRetry 设置失败重试次数及初始间隔，间隔按指数增长
+func WithRetry(retryCount int, retryInterval time.Duration) Option {
+	return func(s *Client) {
+		s.retryCount = retryCount
+		if retryInterval > 0 {
+			s.retryInterval = retryInterval
+		}
+	}
+}
+
+// WithHTTPClient 使用自定义的http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(s *Client) {
+		if httpClient != nil {
+			s.httpClient = httpClient
+		}
+	}
+}
+
+// WithTimeout 设置单次请求超时时间
+func WithTimeout(timeOut time.Duration) Option {
+	return func(s *Client) {
+		s.httpClient.Timeout = timeOut
+	}
+}
+
+// Client magicAgent客户端
+type Client struct {
+	serverURL     string
+	httpClient    *http.Client
+	token         string
+	account       string
+	password      string
+	retryCount    int
+	retryInterval time.Duration
+}
+
+// NewClient 新建客户端，serverURL为agent地址，例如 http://192.168.1.2:8080
+func NewClient(serverURL string, options ...Option) *Client {
+	if !strings.Contains(serverURL, "://") {
+		serverURL = "http://" + serverURL
+	}
+
+	ptr := &Client{
+		serverURL:     strings.TrimRight(serverURL, "/"),
+		httpClient:    &http.Client{Timeout: defaultTimeout},
+		retryInterval: defaultRetryInterval,
+	}
+	for _, val := range options {
+		val(ptr)
+	}
+
+	return ptr
+}
+
+// ServerURL agent地址
+func (s *Client) ServerURL() string {
+	return s.serverURL
+}
+
+func (s *Client) StartService(ctx context.Context, serviceName string) (ret *common.StartServiceResult, err *cd.Result) {
+	result := &common.StartServiceResult{}
+	err = s.action(ctx, common.StartService, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result
+	}
+	return
+}
+
+func (s *Client) StopService(ctx context.Context, serviceName string) (ret *common.StopServiceResult, err *cd.Result) {
+	result := &common.StopServiceResult{}
+	err = s.action(ctx, common.StopService, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result
+	}
+	return
+}
+
+func (s *Client) RestartService(ctx context.Context, serviceName string) (ret *common.RestartServiceResult, err *cd.Result) {
+	result := &common.RestartServiceResult{}
+	err = s.action(ctx, common.RestartService, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result
+	}
+	return
+}
+
+func (s *Client) ExecuteCommand(ctx context.Context, param *common.ServiceParam) (ret *common.ExecServiceResult, err *cd.Result) {
+	result := &common.ExecServiceResult{}
+	err = s.post(ctx, common.ExecuteCommand, param, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result
+	}
+	return
+}
+
+// ListContainer 列出容器，runtimeName为空时列出守护对象所用的全部运行时
+func (s *Client) ListContainer(ctx context.Context, runtimeName string) (ret []*common.ContainerInfo, err *cd.Result) {
+	query := url.Values{}
+	if runtimeName != "" {
+		query.Set("runtime", runtimeName)
+	}
+
+	result := &common.ListContainerResult{}
+	err = s.get(ctx, common.ListContainer, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Containers
+	}
+	return
+}
+
+func (s *Client) InspectContainer(ctx context.Context, serviceName string) (ret *common.ContainerInfo, err *cd.Result) {
+	result := &common.InspectContainerResult{}
+	err = s.get(ctx, common.InspectContainer, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Container
+	}
+	return
+}
+
+func (s *Client) QueryContainerStats(ctx context.Context, serviceName string) (ret *common.ContainerStats, err *cd.Result) {
+	result := &common.QueryContainerStatsResult{}
+	err = s.get(ctx, common.QueryContainerStats, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Stats
+	}
+	return
+}
+
+// QueryContainerEvents 查询容器事件，按时间倒序，count<=0时返回全部
+func (s *Client) QueryContainerEvents(ctx context.Context, count int) (ret []*common.ContainerEvent, err *cd.Result) {
+	query := url.Values{}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	result := &common.QueryContainerEventsResult{}
+	err = s.get(ctx, common.QueryContainerEvents, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Events
+	}
+	return
+}
+
+// ContainerLogs 将服务日志写入writer，follow为true时持续输出直到ctx取消
+// since支持RFC3339时间、相对时长(如10m)以及unix时间戳，tail<=0时返回全部日志
+func (s *Client) ContainerLogs(ctx context.Context, serviceName string, tail int, since string, follow bool, writer io.Writer) (err *cd.Result) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	query := url.Values{"service": {serviceName}}
+	if tail > 0 {
+		query.Set("tail", strconv.Itoa(tail))
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, s.serverURL+common.ApiVersion+common.QueryContainerLogs+"?"+query.Encode(), nil)
+	if reqErr != nil {
+		err = cd.NewError(cd.IllegalParam, reqErr.Error())
+		return
+	}
+	s.authorize(req)
+
+	// 日志流持续时间不受单次请求超时限制
+	streamClient := *s.httpClient
+	streamClient.Timeout = 0
+	res, resErr := streamClient.Do(req)
+	if resErr != nil {
+		err = cd.NewError(cd.UnExpected, resErr.Error())
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err = statusError(req.Method, req.URL.String(), res)
+		return
+	}
+
+	// 出错时服务端返回json格式的结果
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+		result := &cd.Result{}
+		content, _ := io.ReadAll(res.Body)
+		if unmarshalErr := json.Unmarshal(content, result); unmarshalErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal response, %s", unmarshalErr.Error()))
+			return
+		}
+		err = checkResult(*result)
+		return
+	}
+
+	_, copyErr := io.Copy(writer, res.Body)
+	if copyErr != nil && ctx.Err() == nil {
+		err = cd.NewError(cd.UnExpected, copyErr.Error())
+	}
+	return
+}
+
+func (s *Client) QueryStatus(ctx context.Context, serviceName string) (ret *common.ClusterStatus, err *cd.Result) {
+	result := &common.QueryClusterStatusResult{}
+	err = s.get(ctx, common.QueryStatus, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryReplication 查询主从复制状态
+func (s *Client) QueryReplication(ctx context.Context, serviceName string) (ret *common.ReplicationStatus, err *cd.Result) {
+	result := &common.QueryReplicationResult{}
+	err = s.get(ctx, common.QueryReplication, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryRedisStatus 查询redis复制、持久化和内存状态
+func (s *Client) QueryRedisStatus(ctx context.Context, serviceName string) (ret *common.RedisStatus, err *cd.Result) {
+	result := &common.QueryRedisStatusResult{}
+	err = s.get(ctx, common.QueryRedisStatus, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryPostgresStatus 查询postgres角色、流复制、复制槽和连接状态
+func (s *Client) QueryPostgresStatus(ctx context.Context, serviceName string) (ret *common.PostgresStatus, err *cd.Result) {
+	result := &common.QueryPostgresStatusResult{}
+	err = s.get(ctx, common.QueryPostgresStatus, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryProbeStatus 执行probe守护对象的探测
+func (s *Client) QueryProbeStatus(ctx context.Context, serviceName string) (ret *common.ProbeStatus, err *cd.Result) {
+	result := &common.QueryProbeStatusResult{}
+	err = s.get(ctx, common.QueryProbeStatus, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryHostStatus 查询主机磁盘、inode、内存、swap和负载
+func (s *Client) QueryHostStatus(ctx context.Context) (ret *common.HostStatus, err *cd.Result) {
+	result := &common.QueryHostStatusResult{}
+	err = s.get(ctx, common.QueryHostStatus, nil, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+// QueryDataVolume 查询守护对象数据卷的使用情况
+func (s *Client) QueryDataVolume(ctx context.Context, serviceName string) (ret *common.DataVolumeStatus, err *cd.Result) {
+	result := &common.QueryDataVolumeResult{}
+	err = s.get(ctx, common.QueryDataVolume, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Volume
+	}
+	return
+}
+
+// QueryComponent 查询Non-Primary组件的处理建议
+func (s *Client) QueryComponent(ctx context.Context, serviceName string) (ret *common.ComponentDecision, err *cd.Result) {
+	result := &common.QueryComponentResult{}
+	err = s.get(ctx, common.QueryComponent, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Decision
+	}
+	return
+}
+
+// Bootstrap 在节点上执行pc.bootstrap，需要管理员令牌
+func (s *Client) Bootstrap(ctx context.Context, serviceName string) (err *cd.Result) {
+	result := &common.BootstrapResult{}
+	err = s.post(ctx, common.BootstrapNode, &common.BootstrapParam{Service: serviceName}, result)
+	if err == nil {
+		err = checkResult(cd.Result(*result))
+	}
+	return
+}
+
+// Failover 主库不可用时执行故障切换，需要管理员令牌
+func (s *Client) Failover(ctx context.Context, serviceName string) (ret *common.FailoverDecision, err *cd.Result) {
+	result := &common.FailoverResult{}
+	err = s.post(ctx, common.Failover, &common.FailoverParam{Service: serviceName}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Decision
+	}
+	return
+}
+
+// VoteFailover 请求节点确认候选节点，需要管理员令牌
+func (s *Client) VoteFailover(ctx context.Context, param *common.FailoverParam) (err *cd.Result) {
+	result := &common.VoteFailoverResult{}
+	err = s.post(ctx, common.VoteFailover, param, result)
+	if err == nil {
+		err = checkResult(cd.Result(*result))
+	}
+	return
+}
+
+// RepointReplica 让从库切换到新主库，需要管理员令牌
+func (s *Client) RepointReplica(ctx context.Context, param *common.RepointParam) (err *cd.Result) {
+	result := &common.RepointResult{}
+	err = s.post(ctx, common.RepointReplica, param, result)
+	if err == nil {
+		err = checkResult(cd.Result(*result))
+	}
+	return
+}
+
+// QueryProcess 查询超过阈值的慢查询和长事务
+func (s *Client) QueryProcess(ctx context.Context, serviceName string) (ret *common.ProcessReport, err *cd.Result) {
+	result := &common.QueryProcessResult{}
+	err = s.get(ctx, common.QueryProcess, url.Values{"service": {serviceName}}, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Report
+	}
+	return
+}
+
+// KillProcess 结束指定线程或线程当前的查询，需要管理员令牌
+func (s *Client) KillProcess(ctx context.Context, param *common.KillProcessParam) (err *cd.Result) {
+	result := &common.KillProcessResult{}
+	err = s.post(ctx, common.KillProcess, param, result)
+	if err == nil {
+		err = checkResult(cd.Result(*result))
+	}
+	return
+}
+
+func (s *Client) SendAlarm(ctx context.Context, alarmInfo *common.AlarmInfo) (err *cd.Result) {
+	result := &common.SendAlarmResult{}
+	err = s.post(ctx, common.SendAlarm, alarmInfo, result)
+	if err == nil {
+		err = checkResult(cd.Result(*result))
+	}
+	return
+}
+
+// QueryAlarmHistory 查询告警记录，count<=0时返回全部
+func (s *Client) QueryAlarmHistory(ctx context.Context, count int) (ret []*common.AlarmRecord, err *cd.Result) {
+	query := url.Values{}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	result := &common.QueryAlarmHistoryResult{}
+	err = s.get(ctx, common.QueryAlarmHistory, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Alarms
+	}
+	return
+}
+
+// ListBackup 查询备份记录，serviceName为空时查询全部服务，count<=0时返回全部
+func (s *Client) ListBackup(ctx context.Context, serviceName string, count int) (ret []*common.BackupRecord, err *cd.Result) {
+	query := url.Values{}
+	if serviceName != "" {
+		query.Set("service", serviceName)
+	}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	result := &common.ListBackupResult{}
+	err = s.get(ctx, common.ListBackup, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Backups
+	}
+	return
+}
+
+func (s *Client) PauseRemediation(ctx context.Context, param *common.RemediationParam) (ret *common.RemediationStatus, err *cd.Result) {
+	result := &common.RemediationResult{}
+	err = s.post(ctx, common.PauseRemediation, param, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+func (s *Client) ResumeRemediation(ctx context.Context, param *common.RemediationParam) (ret *common.RemediationStatus, err *cd.Result) {
+	result := &common.RemediationResult{}
+	err = s.post(ctx, common.ResumeRemediation, param, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+func (s *Client) QueryRemediation(ctx context.Context) (ret *common.RemediationStatus, err *cd.Result) {
+	result := &common.RemediationResult{}
+	err = s.get(ctx, common.QueryRemediation, nil, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Status
+	}
+	return
+}
+
+func (s *Client) QueryConfig(ctx context.Context) (ret json.RawMessage, err *cd.Result) {
+	result := &common.QueryConfigResult{}
+	err = s.get(ctx, common.QueryConfig, nil, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Config
+	}
+	return
+}
+
+// CreateJob 创建异步任务，返回的任务处于等待状态
+func (s *Client) CreateJob(ctx context.Context, param *common.JobParam) (ret *common.JobInfo, err *cd.Result) {
+	result := &common.JobResult{}
+	err = s.post(ctx, common.CreateJob, param, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Job
+	}
+	return
+}
+
+// QueryJob 查询任务状态、进度和输出
+func (s *Client) QueryJob(ctx context.Context, jobID string) (ret *common.JobInfo, err *cd.Result) {
+	result := &common.JobResult{}
+	err = s.get(ctx, strings.ReplaceAll(common.QueryJob, ":id", url.PathEscape(jobID)), nil, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Job
+	}
+	return
+}
+
+// ListJob 查询任务列表，按创建时间倒序，count<=0时返回全部
+func (s *Client) ListJob(ctx context.Context, count int) (ret []*common.JobInfo, err *cd.Result) {
+	query := url.Values{}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	result := &common.ListJobResult{}
+	err = s.get(ctx, common.ListJob, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Jobs
+	}
+	return
+}
+
+func (s *Client) CancelJob(ctx context.Context, jobID string) (ret *common.JobInfo, err *cd.Result) {
+	result := &common.JobResult{}
+	err = s.post(ctx, strings.ReplaceAll(common.CancelJob, ":id", url.PathEscape(jobID)), nil, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Job
+	}
+	return
+}
+
+// get 只读查询，可以安全重试
+func (s *Client) get(ctx context.Context, route string, query url.Values, result interface{}) *cd.Result {
+	return s.invoke(ctx, http.MethodGet, route, query, nil, true, result)
+}
+
+// action 会改变服务状态的GET请求，如启停服务，与post一样不能重复执行
+func (s *Client) action(ctx context.Context, route string, query url.Values, result interface{}) *cd.Result {
+	return s.invoke(ctx, http.MethodGet, route, query, nil, false, result)
+}
+
+func (s *Client) post(ctx context.Context, route string, param interface{}, result interface{}) *cd.Result {
+	return s.invoke(ctx, http.MethodPost, route, nil, param, false, result)
+}
+
+// invoke 发送请求并解析结果，按指数退避重试
+// 只读查询在连接失败或服务暂不可用时重试，其他请求只在连接未建立、请求尚未发出时重试，避免操作被重复执行
+func (s *Client) invoke(ctx context.Context, method, route string, query url.Values, param interface{}, idempotent bool, result interface{}) *cd.Result {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var body []byte
+	if param != nil {
+		byteVal, byteErr := json.Marshal(param)
+		if byteErr != nil {
+			return cd.NewError(cd.IllegalParam, byteErr.Error())
+		}
+		body = byteVal
+	}
+
+	reqURL := s.serverURL + common.ApiVersion + route
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var err *cd.Result
+	for idx := 0; idx <= s.retryCount; idx++ {
+		if idx > 0 {
+			timer := time.NewTimer(s.backoff(idx))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return cd.NewError(cd.UnExpected, ctx.Err().Error())
+			case <-timer.C:
+			}
+		}
+
+		var retry bool
+		retry, err = s.request(ctx, method, reqURL, body, idempotent, result)
+		if !retry {
+			break
+		}
+	}
+
+	return err
+}
+
+func (s *Client) backoff(retryIdx int) time.Duration {
+	interval := s.retryInterval << (retryIdx - 1)
+	if interval <= 0 || interval > maxRetryInterval {
+		interval = maxRetryInterval
+	}
+
+	// 增加抖动，避免多个客户端同时重试
+	return interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+}
+
+func (s *Client) request(ctx context.Context, method, reqURL string, body []byte, idempotent bool, result interface{}) (retry bool, err *cd.Result) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, reqErr := http.NewRequestWithContext(ctx, method, reqURL, reader)
+	if reqErr != nil {
+		err = cd.NewError(cd.IllegalParam, reqErr.Error())
+		return
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.authorize(req)
+
+	res, resErr := s.httpClient.Do(req)
+	if resErr != nil {
+		err = cd.NewError(cd.UnExpected, resErr.Error())
+		retry = ctx.Err() == nil && (idempotent || isDialError(resErr))
+		return
+	}
+	defer res.Body.Close()
+
+	content, contentErr := io.ReadAll(res.Body)
+	if contentErr != nil {
+		err = cd.NewError(cd.UnExpected, contentErr.Error())
+		retry = ctx.Err() == nil && idempotent
+		return
+	}
+
+	if res.StatusCode != http.StatusOK {
+		err = statusError(method, reqURL, res)
+		retry = idempotent && (res.StatusCode == http.StatusBadGateway ||
+			res.StatusCode == http.StatusServiceUnavailable ||
+			res.StatusCode == http.StatusGatewayTimeout)
+		return
+	}
+
+	if result == nil {
+		return
+	}
+
+	unmarshalErr := json.Unmarshal(content, result)
+	if unmarshalErr != nil {
+		err = cd.NewError(cd.UnExpected, fmt.Sprintf("illegal response, %s", unmarshalErr.Error()))
+	}
+	return
+}
+
+func (s *Client) authorize(req *http.Request) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		return
+	}
+
+	if s.account != "" {
+		req.SetBasicAuth(s.account, s.password)
+	}
+}
+
+// isDialError 连接阶段失败，请求还没有发送到服务端
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// statusError 按HTTP状态码转换为错误
+func statusError(method, reqURL string, res *http.Response) *cd.Result {
+	reason := fmt.Sprintf("%s %s, status:%s", method, reqURL, res.Status)
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return cd.NewError(cd.InvalidAuthority, reason)
+	case http.StatusNotFound:
+		return cd.NewWarn(cd.NoExist, reason)
+	}
+
+	return cd.NewError(cd.UnExpected, reason)
+}
+
+// checkResult 服务端返回失败或警告时转换为错误
+func checkResult(result cd.Result) *cd.Result {
+	if result.Success() {
+		return nil
+	}
+
+	return &cd.Result{ErrorCode: result.ErrorCode, Reason: result.Reason}
+}
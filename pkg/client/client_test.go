@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+func TestStatusMapping(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		body       string
+		expectCode cd.ErrorCode
+		expectCall int32
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"errorCode":0,"state":"running"}`, expectCode: cd.Succeeded, expectCall: 1},
+		{name: "server warn", status: http.StatusOK, body: `{"errorCode":200001,"reason":"no guard"}`, expectCode: cd.NoExist, expectCall: 1},
+		{name: "not found", status: http.StatusNotFound, expectCode: cd.NoExist, expectCall: 1},
+		{name: "unauthorized", status: http.StatusUnauthorized, expectCode: cd.InvalidAuthority, expectCall: 1},
+		{name: "forbidden", status: http.StatusForbidden, expectCode: cd.InvalidAuthority, expectCall: 1},
+		{name: "internal error", status: http.StatusInternalServerError, expectCode: cd.UnExpected, expectCall: 1},
+		{name: "unavailable retry", status: http.StatusServiceUnavailable, expectCode: cd.UnExpected, expectCall: 3},
+		{name: "illegal body", status: http.StatusOK, body: `{`, expectCode: cd.UnExpected, expectCall: 1},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			var callCount int32
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&callCount, 1)
+				res.Header().Set("Content-Type", "application/json")
+				res.WriteHeader(val.status)
+				_, _ = res.Write([]byte(val.body))
+			}))
+			defer server.Close()
+
+			clientPtr := NewClient(server.URL, WithRetry(2, time.Millisecond))
+			_, err := clientPtr.QueryStatus(context.Background(), "mariadb001")
+			if val.expectCode == cd.Succeeded {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			} else if err == nil || err.ErrorCode != val.expectCode {
+				t.Fatalf("error %v, expect code %d", err, val.expectCode)
+			}
+			if val.status == http.StatusNotFound && !err.Warn() {
+				t.Errorf("expect warn for 404, got %v", err)
+			}
+			if callCount != val.expectCall {
+				t.Errorf("call count %d, expect %d", callCount, val.expectCall)
+			}
+		})
+	}
+}
+
+// TestRetry 只读查询可以重试，改变状态的请求只在连接未建立时重试
+func TestRetry(t *testing.T) {
+	invokes := map[string]func(*Client) *cd.Result{
+		"query": func(clientPtr *Client) *cd.Result {
+			_, err := clientPtr.QueryStatus(context.Background(), "mariadb001")
+			return err
+		},
+		"stop": func(clientPtr *Client) *cd.Result {
+			_, err := clientPtr.StopService(context.Background(), "mariadb001")
+			return err
+		},
+		"restart": func(clientPtr *Client) *cd.Result {
+			_, err := clientPtr.RestartService(context.Background(), "mariadb001")
+			return err
+		},
+		"post": func(clientPtr *Client) *cd.Result {
+			_, err := clientPtr.ExecuteCommand(context.Background(), &common.ServiceParam{Service: "mariadb001"})
+			return err
+		},
+	}
+
+	cases := []struct {
+		name       string
+		invoke     string
+		status     int
+		drop       bool
+		expectCall int32
+	}{
+		{name: "query unavailable", invoke: "query", status: http.StatusServiceUnavailable, expectCall: 3},
+		{name: "query connection dropped", invoke: "query", drop: true, expectCall: 3},
+		{name: "stop unavailable", invoke: "stop", status: http.StatusServiceUnavailable, expectCall: 1},
+		{name: "stop connection dropped", invoke: "stop", drop: true, expectCall: 1},
+		{name: "restart unavailable", invoke: "restart", status: http.StatusServiceUnavailable, expectCall: 1},
+		{name: "post bad gateway", invoke: "post", status: http.StatusBadGateway, expectCall: 1},
+		{name: "post connection dropped", invoke: "post", drop: true, expectCall: 1},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			var callCount int32
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&callCount, 1)
+				if val.drop {
+					conn, _, _ := res.(http.Hijacker).Hijack()
+					_ = conn.Close()
+					return
+				}
+				res.WriteHeader(val.status)
+			}))
+			defer server.Close()
+
+			clientPtr := NewClient(server.URL, WithRetry(2, time.Millisecond))
+			if err := invokes[val.invoke](clientPtr); err == nil {
+				t.Fatalf("expect error")
+			}
+			if count := atomic.LoadInt32(&callCount); count != val.expectCall {
+				t.Errorf("call count %d, expect %d", count, val.expectCall)
+			}
+		})
+	}
+}
+
+func TestRetryDialError(t *testing.T) {
+	var callCount int32
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	// 第一次连接失败后再启动服务，非幂等请求也能重试成功
+	time.AfterFunc(50*time.Millisecond, func() {
+		listener, listenErr := net.Listen("tcp", address)
+		if listenErr != nil {
+			return
+		}
+		server := &httptest.Server{
+			Listener: listener,
+			Config: &http.Server{Handler: http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&callCount, 1)
+				_, _ = res.Write([]byte(`{"errorCode":0}`))
+			})},
+		}
+		server.Start()
+		t.Cleanup(server.Close)
+	})
+
+	clientPtr := NewClient("http://"+address, WithRetry(5, 100*time.Millisecond))
+	if _, execErr := clientPtr.ExecuteCommand(context.Background(), &common.ServiceParam{Service: "mariadb001"}); execErr != nil {
+		t.Fatalf("unexpected error %v", execErr)
+	}
+	if count := atomic.LoadInt32(&callCount); count != 1 {
+		t.Errorf("call count %d, expect 1", count)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Option
+		expect string
+	}{
+		{name: "token", option: WithToken("abc"), expect: "Bearer abc"},
+		{name: "basic", option: WithBasicAuth("admin", "pwd"), expect: "Basic YWRtaW46cHdk"},
+		{name: "none", option: func(*Client) {}, expect: ""},
+	}
+
+	for _, val := range cases {
+		t.Run(val.name, func(t *testing.T) {
+			var header, path, contentType string
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				header = req.Header.Get("Authorization")
+				path = req.URL.Path
+				contentType = req.Header.Get("Content-Type")
+				_, _ = res.Write([]byte(`{"errorCode":0}`))
+			}))
+			defer server.Close()
+
+			clientPtr := NewClient(server.URL, val.option)
+			_, err := clientPtr.ExecuteCommand(context.Background(), &common.ServiceParam{Service: "mariadb001"})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if header != val.expect {
+				t.Errorf("authorization %q, expect %q", header, val.expect)
+			}
+			if path != common.ApiVersion+common.ExecuteCommand {
+				t.Errorf("path %q, expect %q", path, common.ApiVersion+common.ExecuteCommand)
+			}
+			if contentType != "application/json" {
+				t.Errorf("content type %q", contentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	cd "github.com/muidea/magicCommon/def"
+
+	"github.com/muidea/magicAgent/internal/websocket"
+	"github.com/muidea/magicAgent/pkg/common"
+)
+
+// Terminal 交互式会话，Write写入命令输入，Wait输出命令结果直到命令结束
+type Terminal struct {
+	conn *websocket.Conn
+}
+
+// OpenTerminal 打开交互式会话，需要管理员令牌，会话持续到命令结束或ctx取消
+func (s *Client) OpenTerminal(ctx context.Context, param *common.TerminalParam) (ret *Terminal, err *cd.Result) {
+	query := url.Values{"service": {param.Service}}
+	for _, val := range param.Command {
+		query.Add("cmd", val)
+	}
+	for _, val := range param.Env {
+		query.Add("env", val)
+	}
+	if param.WorkDir != "" {
+		query.Set("workDir", param.WorkDir)
+	}
+	query.Set("tty", strconv.FormatBool(param.TTY))
+	if param.Rows > 0 && param.Cols > 0 {
+		query.Set("rows", strconv.Itoa(int(param.Rows)))
+		query.Set("cols", strconv.Itoa(int(param.Cols)))
+	}
+	reqURL := s.serverURL + common.ApiVersion + common.ExecuteTerminal + "?" + query.Encode()
+
+	req := &http.Request{Header: http.Header{}}
+	s.authorize(req)
+
+	var tlsConfig *tls.Config
+	if transport, ok := s.httpClient.Transport.(*http.Transport); ok {
+		tlsConfig = transport.TLSClientConfig
+	}
+
+	conn, connErr := websocket.Dial(reqURL, req.Header, tlsConfig, s.httpClient.Timeout)
+	if connErr != nil {
+		err = handshakeError(connErr)
+		return
+	}
+
+	// ctx取消时关闭连接，服务端随即结束命令
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	ret = &Terminal{conn: conn}
+	return
+}
+
+// handshakeError 服务端拒绝升级时返回json格式的错误
+func handshakeError(err error) *cd.Result {
+	var handshakeErr *websocket.HandshakeError
+	if !errors.As(err, &handshakeErr) {
+		return cd.NewError(cd.UnExpected, err.Error())
+	}
+
+	result := &cd.Result{}
+	if handshakeErr.StatusCode == http.StatusOK && json.Unmarshal(handshakeErr.Body, result) == nil && !result.Success() {
+		return result
+	}
+	if handshakeErr.StatusCode == http.StatusUnauthorized || handshakeErr.StatusCode == http.StatusForbidden {
+		return cd.NewError(cd.InvalidAuthority, err.Error())
+	}
+	if handshakeErr.StatusCode == http.StatusNotFound {
+		return cd.NewWarn(cd.NoExist, err.Error())
+	}
+
+	return cd.NewError(cd.UnExpected, err.Error())
+}
+
+// QueryAudit 查询交互式会话审计记录，按开始时间倒序，count<=0时返回全部，需要管理员令牌
+func (s *Client) QueryAudit(ctx context.Context, count int) (ret []*common.AuditRecord, err *cd.Result) {
+	query := url.Values{}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	result := &common.QueryAuditResult{}
+	err = s.get(ctx, common.QueryAudit, query, result)
+	if err == nil {
+		err = checkResult(result.Result)
+		ret = result.Records
+	}
+	return
+}
+
+// Write 写入命令输入
+func (s *Terminal) Write(data []byte) (int, error) {
+	err := s.conn.WriteMessage(websocket.BinaryMessage, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Resize 调整窗口大小
+func (s *Terminal) Resize(rows, cols uint16) error {
+	return s.sendMessage(&common.TerminalMessage{Type: common.TerminalResize, Rows: rows, Cols: cols})
+}
+
+// CloseWrite 结束命令输入
+func (s *Terminal) CloseWrite() error {
+	return s.sendMessage(&common.TerminalMessage{Type: common.TerminalEOF})
+}
+
+func (s *Terminal) sendMessage(messagePtr *common.TerminalMessage) error {
+	byteVal, byteErr := json.Marshal(messagePtr)
+	if byteErr != nil {
+		return byteErr
+	}
+
+	return s.conn.WriteMessage(websocket.TextMessage, byteVal)
+}
+
+// Close 关闭会话，服务端随即结束命令
+func (s *Terminal) Close() error {
+	return s.conn.Close()
+}
+
+// Wait 将命令输出写入output，直到命令结束，返回命令退出码
+func (s *Terminal) Wait(output io.Writer) (exitCode int, err *cd.Result) {
+	defer s.conn.Close()
+
+	for {
+		messageType, data, readErr := s.conn.ReadMessage()
+		if readErr != nil {
+			err = cd.NewError(cd.UnExpected, fmt.Sprintf("terminal closed before command exit, %s", readErr.Error()))
+			return
+		}
+
+		if messageType == websocket.BinaryMessage {
+			if _, writeErr := output.Write(data); writeErr != nil {
+				err = cd.NewError(cd.UnExpected, writeErr.Error())
+				return
+			}
+			continue
+		}
+
+		messagePtr := &common.TerminalMessage{}
+		if json.Unmarshal(data, messagePtr) != nil || messagePtr.Type != common.TerminalExit {
+			continue
+		}
+
+		exitCode = messagePtr.ExitCode
+		err = messagePtr.Error
+		return
+	}
+}
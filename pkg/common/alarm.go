@@ -1,16 +1,35 @@
 package common
 
-import cd "github.com/muidea/magicCommon/def"
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
 
 const (
-	SendAlarm = "/alarm/send"
+	SendAlarm         = "/alarm/send"
+	QueryAlarmHistory = "/alarm/history"
 )
 
+// AlarmInfo 告警信息，Logs为告警时采集的服务日志
 type AlarmInfo struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
+	Logs    string `json:"logs,omitempty"`
+}
+
+// AlarmRecord 告警记录
+type AlarmRecord struct {
+	AlarmInfo
+	Host      string    `json:"host"`
+	TimeStamp time.Time `json:"timeStamp"`
 }
 
 type SendAlarmResult cd.Result
 
+type QueryAlarmHistoryResult struct {
+	cd.Result
+	Alarms []*AlarmRecord `json:"alarms"`
+}
+
 const AlarmModule = "/module/alarm"
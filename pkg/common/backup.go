@@ -0,0 +1,52 @@
+package common
+
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	ListBackup = "/backup/list"
+)
+
+// BackupParam BackupJob的参数，Method为空时使用配置的备份方式
+type BackupParam struct {
+	Method string `json:"method,omitempty"`
+}
+
+// 恢复后节点的启动方式
+const (
+	// RestoreBootstrap 以恢复的数据启动新集群，其他节点需重新加入
+	RestoreBootstrap = "bootstrap"
+	// RestoreJoiner 以恢复的数据作为joiner加入现有集群，可以通过IST追平数据
+	RestoreJoiner = "joiner"
+)
+
+// RestoreParam RestoreJob的参数，Backup为备份文件名，StopTime格式为"2006-01-02 15:04:05"，
+// 不为空时重放备份之后的binlog到该时间（服务容器时区），仅支持bootstrap方式，
+// TimeOut为节点恢复后达到Synced状态的最长等待秒数，0表示使用默认值，主从复制方式下Mode不生效
+type RestoreParam struct {
+	Backup   string `json:"backup"`
+	Mode     string `json:"mode"`
+	StopTime string `json:"stopTime,omitempty"`
+	TimeOut  int    `json:"timeOut,omitempty"`
+}
+
+// BackupRecord 备份文件信息，文件为gzip压缩，Checksum为压缩文件的sha256
+type BackupRecord struct {
+	Name       string    `json:"name"`
+	Service    string    `json:"service"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Checksum   string    `json:"checksum"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+type ListBackupResult struct {
+	cd.Result
+	Backups []*BackupRecord `json:"backups"`
+}
+
+const BackupModule = "/module/backup"
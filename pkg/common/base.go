@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"time"
 
 	cd "github.com/muidea/magicCommon/def"
@@ -11,9 +12,23 @@ const (
 	NotifyRunning = "/running/notify/"
 )
 
+const (
+	QueryConfig = "/config"
+)
+
+const (
+	PauseRemediation  = "/remediation/pause"
+	ResumeRemediation = "/remediation/resume"
+	QueryRemediation  = "/remediation/status"
+)
+
+// ServiceParam 服务命令参数，Env格式为KEY=VALUE，TimeOut为命令超时秒数，0表示使用默认超时
 type ServiceParam struct {
-	Service  string `json:"service"`
-	CmdParam string `json:"cmdParam"`
+	Service  string   `json:"service"`
+	CmdParam string   `json:"cmdParam"`
+	Env      []string `json:"env,omitempty"`
+	WorkDir  string   `json:"workDir,omitempty"`
+	TimeOut  int      `json:"timeOut,omitempty"`
 }
 
 type TimerNotify struct {
@@ -29,6 +44,38 @@ type StartServiceResult struct {
 
 type StopServiceResult StartServiceResult
 
-type ExecServiceResult StartServiceResult
+type RestartServiceResult StartServiceResult
+
+// ExecServiceResult 命令执行结果，命令非零退出时同时返回错误和输出，进程未正常退出时ExitCode为-1
+type ExecServiceResult struct {
+	cd.Result
+	StdOut   string `json:"stdout"`
+	StdErr   string `json:"stderr"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// QueryConfigResult 当前生效的配置，密文字段已隐藏
+type QueryConfigResult struct {
+	cd.Result
+	Config json.RawMessage `json:"config"`
+}
+
+// RemediationParam 暂停/恢复自动修复的参数，Service为空表示全部守护对象
+type RemediationParam struct {
+	Service string `json:"service"`
+	Reason  string `json:"reason"`
+}
+
+// RemediationStatus 自动修复状态，Paused表示全部暂停，Services为单独暂停的守护对象
+type RemediationStatus struct {
+	Paused   bool              `json:"paused"`
+	Reason   string            `json:"reason"`
+	Services map[string]string `json:"services"`
+}
+
+type RemediationResult struct {
+	cd.Result
+	Status *RemediationStatus `json:"status"`
+}
 
 const BaseModule = "/kernel/base"
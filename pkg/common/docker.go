@@ -1,9 +0,0 @@
-package common
-
-const (
-	ExecuteCommand = "/command/execute"
-	StartService   = "/service/start"
-	StopService    = "/service/stop"
-)
-
-const DockerModule = "/module/docker"
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	// QueryHostStatus 查询主机磁盘、inode、内存、swap和负载
+	QueryHostStatus = "/host/status"
+	// QueryDataVolume 查询守护对象数据卷的使用情况
+	QueryDataVolume = "/host/volume"
+)
+
+// MountUsage 挂载点使用情况，容量单位为字节，Usage、InodeUsage为使用率(百分比)，计算方式与df一致
+type MountUsage struct {
+	Path        string  `json:"path"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Available   uint64  `json:"available"`
+	Usage       float64 `json:"usage"`
+	Inodes      uint64  `json:"inodes"`
+	InodesUsed  uint64  `json:"inodesUsed"`
+	InodeUsage  float64 `json:"inodeUsage"`
+	Unavailable string  `json:"unavailable,omitempty"`
+}
+
+// HostStatus 主机资源，内存单位为字节，Warnings为超过阈值的项
+// 无法读取/proc时内存、swap和负载为0
+type HostStatus struct {
+	SampleTime      time.Time     `json:"sampleTime"`
+	Mounts          []*MountUsage `json:"mounts"`
+	MemoryTotal     uint64        `json:"memoryTotal"`
+	MemoryAvailable uint64        `json:"memoryAvailable"`
+	MemoryUsage     float64       `json:"memoryUsage"`
+	SwapTotal       uint64        `json:"swapTotal"`
+	SwapFree        uint64        `json:"swapFree"`
+	SwapUsage       float64       `json:"swapUsage"`
+	Load1           float64       `json:"load1"`
+	Load5           float64       `json:"load5"`
+	Load15          float64       `json:"load15"`
+	CPUCount        int           `json:"cpuCount"`
+	Warnings        []string      `json:"warnings,omitempty"`
+}
+
+// DataVolumeStatus 守护对象数据卷的使用情况，Full为true时重启无法恢复服务
+type DataVolumeStatus struct {
+	Service string      `json:"service"`
+	Path    string      `json:"path"`
+	Usage   *MountUsage `json:"usage"`
+	Full    bool        `json:"full"`
+}
+
+type QueryHostStatusResult struct {
+	cd.Result
+	Status *HostStatus `json:"status"`
+}
+
+type QueryDataVolumeResult struct {
+	cd.Result
+	Volume *DataVolumeStatus `json:"volume"`
+}
+
+const HostModule = "/module/host"
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"encoding/json"
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	CreateJob = "/job"
+	ListJob   = "/job"
+	QueryJob  = "/job/:id"
+	CancelJob = "/job/:id/cancel"
+)
+
+const (
+	// RunJob 任务执行事件前缀，任务处理方订阅 RunJob+任务类型
+	RunJob = "/job/run/"
+	// NotifyJob 任务结束后广播
+	NotifyJob = "/job/notify/"
+)
+
+// 任务类型
+const (
+	RestartJob = "restart"
+	// UpgradeJob 使用新镜像重建本节点服务容器，健康检查不通过时回滚
+	UpgradeJob = "upgrade"
+	// RollingUpgradeJob 在本节点和集群节点上逐个执行UpgradeJob，任一节点失败时中止
+	RollingUpgradeJob = "rolling-upgrade"
+	// BackupJob 备份mariadb服务，定时备份同样以该任务执行
+	BackupJob = "backup"
+	// RestoreJob 使用mariabackup备份恢复本节点数据，可重放binlog到指定时间
+	RestoreJob = "restore"
+)
+
+// 任务状态
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// JobParam 创建任务的参数，Param为任务类型相关的参数
+type JobParam struct {
+	Type    string          `json:"type"`
+	Service string          `json:"service"`
+	Param   json.RawMessage `json:"param,omitempty"`
+}
+
+// UpgradeParam UpgradeJob的参数，TimeOut为新容器通过健康检查的最长等待秒数，0表示使用默认值
+type UpgradeParam struct {
+	Image   string `json:"image"`
+	TimeOut int    `json:"timeOut,omitempty"`
+}
+
+// RollingUpgradeParam RollingUpgradeJob的参数，Hosts为按顺序升级的agent地址，为空时使用localHost和clusterHosts
+type RollingUpgradeParam struct {
+	UpgradeParam
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// JobInfo 任务信息，Progress取值0~100，Message为当前步骤说明，Output为任务输出
+type JobInfo struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Service    string          `json:"service"`
+	Param      json.RawMessage `json:"param,omitempty"`
+	Status     string          `json:"status"`
+	Progress   int             `json:"progress"`
+	Message    string          `json:"message"`
+	Output     string          `json:"output"`
+	Error      *cd.Result      `json:"error,omitempty"`
+	CreateTime time.Time       `json:"createTime"`
+	StartTime  *time.Time      `json:"startTime,omitempty"`
+	FinishTime *time.Time      `json:"finishTime,omitempty"`
+}
+
+// IsFinished 任务是否已结束
+func (s *JobInfo) IsFinished() bool {
+	switch s.Status {
+	case JobSucceeded, JobFailed, JobCanceled:
+		return true
+	}
+
+	return false
+}
+
+type JobResult struct {
+	cd.Result
+	Job *JobInfo `json:"job"`
+}
+
+type ListJobResult struct {
+	cd.Result
+	Jobs []*JobInfo `json:"jobs"`
+}
+
+const JobModule = "/module/job"
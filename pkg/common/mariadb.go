@@ -1,9 +1,31 @@
 package common
 
-import cd "github.com/muidea/magicCommon/def"
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
 
 const (
 	QueryStatus = "/status/query"
+	// DesyncNode 设置节点wsrep_desync，仅用于模块间事件
+	DesyncNode = "/mariadb/desync"
+	// BootstrapNode 将Non-Primary节点提升为Primary组件
+	BootstrapNode = "/mariadb/bootstrap"
+	// QueryComponent 汇总各节点视图，给出Non-Primary组件的处理建议
+	QueryComponent = "/mariadb/component"
+	// QueryReplication 查询主从复制状态
+	QueryReplication = "/mariadb/replication"
+	// Failover 主库故障时提升最新的从库，其他从库切换到新主库
+	Failover = "/mariadb/failover"
+	// VoteFailover 其他节点确认提升的从库
+	VoteFailover = "/mariadb/failover/vote"
+	// RepointReplica 从库切换到新主库
+	RepointReplica = "/mariadb/failover/repoint"
+	// QueryProcess 查询超过阈值的慢查询和长事务
+	QueryProcess = "/mariadb/process"
+	// KillProcess 结束指定线程或线程当前的查询
+	KillProcess = "/mariadb/process/kill"
 )
 
 /*
@@ -34,10 +56,15 @@ const (
 
 const MariadbModule = "/module/mariadb"
 
+// ClusterStatus 集群状态，Status为集群组件状态，LocalState为本节点同步状态，
+// StateUUID为集群状态UUID，LastCommitted为本节点最后提交的seqno
 type ClusterStatus struct {
-	Nodes    []string `json:"nodes"`
-	NodeSize int      `json:"nodeSize"`
-	Status   string   `json:"status"`
+	Nodes         []string `json:"nodes"`
+	NodeSize      int      `json:"nodeSize"`
+	Status        string   `json:"status"`
+	LocalState    string   `json:"localState,omitempty"`
+	StateUUID     string   `json:"stateUUID,omitempty"`
+	LastCommitted int64    `json:"lastCommitted"`
 }
 
 func (s *ClusterStatus) IsNormal() bool {
@@ -49,6 +76,212 @@ func (s *ClusterStatus) IsNormal() bool {
 	return false
 }
 
+// DesyncParam DesyncNode的参数
+type DesyncParam struct {
+	Service string `json:"service"`
+	Desync  bool   `json:"desync"`
+}
+
+// BootstrapParam BootstrapNode的参数
+type BootstrapParam struct {
+	Service string `json:"service"`
+}
+
+type BootstrapResult cd.Result
+
+// Non-Primary组件的处理方式
+const (
+	// ComponentNormal 本节点属于Primary组件，无需处理
+	ComponentNormal = "normal"
+	// ComponentRestart 其他节点属于Primary组件，本节点重启后重新加入
+	ComponentRestart = "restart"
+	// ComponentBootstrap 可达节点均不属于Primary组件，在Candidate节点上执行pc.bootstrap
+	ComponentBootstrap = "bootstrap"
+	// ComponentWait 无法确定是否存在Primary组件，需要人工处理
+	ComponentWait = "wait"
+)
+
+// NodeView 单个节点的集群视图，Host为节点agent地址，查询失败时Error非空
+type NodeView struct {
+	Host   string         `json:"host"`
+	Local  bool           `json:"local"`
+	Status *ClusterStatus `json:"status,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// ComponentDecision 组件处理建议，Candidate为需要执行pc.bootstrap的节点，Reason说明判断依据
+type ComponentDecision struct {
+	Action    string      `json:"action"`
+	Candidate string      `json:"candidate,omitempty"`
+	Reason    string      `json:"reason"`
+	Views     []*NodeView `json:"views"`
+}
+
+type QueryComponentResult struct {
+	cd.Result
+	Decision *ComponentDecision `json:"decision"`
+}
+
+// 主从复制中的角色
+const (
+	// ReplicationPrimary 未配置复制源，作为主库
+	ReplicationPrimary = "primary"
+	ReplicationReplica = "replica"
+)
+
+// ReplicationStatus SHOW REPLICA STATUS的主要字段，MySQL中的Replica_、Source_字段名已转换为Slave_、Master_对应的字段，
+// SecondsBehindMaster为空表示复制线程未运行无法计算延迟，GtidIOPos为MariaDB的GTID位置，
+// RetrievedGtidSet、ExecutedGtidSet为MySQL的GTID集合
+type ReplicationStatus struct {
+	Role                string `json:"role"`
+	Flavor              string `json:"flavor,omitempty"`
+	MasterHost          string `json:"masterHost,omitempty"`
+	MasterPort          int    `json:"masterPort,omitempty"`
+	IORunning           string `json:"ioRunning,omitempty"`
+	SQLRunning          string `json:"sqlRunning,omitempty"`
+	IOState             string `json:"ioState,omitempty"`
+	SecondsBehindMaster *int64 `json:"secondsBehindMaster,omitempty"`
+	MasterLogFile       string `json:"masterLogFile,omitempty"`
+	ReadMasterLogPos    int64  `json:"readMasterLogPos,omitempty"`
+	RelayMasterLogFile  string `json:"relayMasterLogFile,omitempty"`
+	ExecMasterLogPos    int64  `json:"execMasterLogPos,omitempty"`
+	UsingGtid           string `json:"usingGtid,omitempty"`
+	GtidIOPos           string `json:"gtidIOPos,omitempty"`
+	RetrievedGtidSet    string `json:"retrievedGtidSet,omitempty"`
+	ExecutedGtidSet     string `json:"executedGtidSet,omitempty"`
+	LastIOErrno         int    `json:"lastIOErrno,omitempty"`
+	LastIOError         string `json:"lastIOError,omitempty"`
+	LastSQLErrno        int    `json:"lastSQLErrno,omitempty"`
+	LastSQLError        string `json:"lastSQLError,omitempty"`
+}
+
+// 数据库类型，决定复制相关语句的语法
+const (
+	MariadbFlavor = "mariadb"
+	MysqlFlavor   = "mysql"
+)
+
+// IsBroken 复制线程未运行
+func (s *ReplicationStatus) IsBroken() bool {
+	return s.Role == ReplicationReplica && (s.IORunning != "Yes" || s.SQLRunning != "Yes")
+}
+
+// IsApplied 已接收的relay log全部执行完成
+func (s *ReplicationStatus) IsApplied() bool {
+	return s.RelayMasterLogFile == s.MasterLogFile && s.ExecMasterLogPos >= s.ReadMasterLogPos
+}
+
+// 故障切换结论
+const (
+	// FailoverNone 主库可用，无需切换
+	FailoverNone = "none"
+	// FailoverWait 无法确定主库故障或最新的从库，不做切换
+	FailoverWait = "wait"
+	// FailoverRemote 由Candidate节点执行切换
+	FailoverRemote = "remote"
+	// FailoverPromote 本节点为Candidate，应提升为主库
+	FailoverPromote = "promote"
+	// FailoverPromoted 本节点已提升为主库
+	FailoverPromoted = "promoted"
+)
+
+// ReplicaView 单个节点的复制视图，Host为节点agent地址，查询失败时Error非空
+type ReplicaView struct {
+	Host   string             `json:"host"`
+	Local  bool               `json:"local"`
+	Status *ReplicationStatus `json:"status,omitempty"`
+	Error  string             `json:"error,omitempty"`
+}
+
+// FailoverParam Failover、VoteFailover的参数，VoteFailover时Candidate为待确认的节点
+type FailoverParam struct {
+	Service   string `json:"service"`
+	Candidate string `json:"candidate,omitempty"`
+}
+
+// RepointParam RepointReplica的参数，Host、Port为新主库的数据库地址
+type RepointParam struct {
+	Service string `json:"service"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+}
+
+// FailoverDecision 故障切换结论，OldPrimary为原主库数据库地址，Reason说明判断依据及执行过程
+type FailoverDecision struct {
+	Action     string         `json:"action"`
+	Candidate  string         `json:"candidate,omitempty"`
+	OldPrimary string         `json:"oldPrimary,omitempty"`
+	Reason     string         `json:"reason"`
+	Views      []*ReplicaView `json:"views"`
+}
+
+type FailoverResult struct {
+	cd.Result
+	Decision *FailoverDecision `json:"decision"`
+}
+
+type VoteFailoverResult cd.Result
+
+type RepointResult cd.Result
+
+// ProcessInfo 执行时间超过阈值的查询，DigestText为去掉常量后的查询语句，Digest为其sha256前16位
+type ProcessInfo struct {
+	ID         int64  `json:"id"`
+	User       string `json:"user"`
+	Host       string `json:"host"`
+	DB         string `json:"db,omitempty"`
+	Command    string `json:"command"`
+	Time       int64  `json:"time"`
+	State      string `json:"state,omitempty"`
+	Query      string `json:"query"`
+	Digest     string `json:"digest"`
+	DigestText string `json:"digestText"`
+}
+
+// TransactionInfo 持续时间超过阈值的事务，Query为空表示事务中没有正在执行的语句
+type TransactionInfo struct {
+	ID           string `json:"id"`
+	ThreadID     int64  `json:"threadId"`
+	Started      string `json:"started"`
+	Time         int64  `json:"time"`
+	State        string `json:"state"`
+	RowsLocked   int64  `json:"rowsLocked"`
+	RowsModified int64  `json:"rowsModified"`
+	User         string `json:"user,omitempty"`
+	Host         string `json:"host,omitempty"`
+	DB           string `json:"db,omitempty"`
+	Query        string `json:"query,omitempty"`
+	Digest       string `json:"digest,omitempty"`
+	DigestText   string `json:"digestText,omitempty"`
+}
+
+// ProcessReport 慢查询和长事务采样结果
+type ProcessReport struct {
+	SampleTime       time.Time          `json:"sampleTime"`
+	SlowQueries      []*ProcessInfo     `json:"slowQueries"`
+	LongTransactions []*TransactionInfo `json:"longTransactions"`
+}
+
+type QueryProcessResult struct {
+	cd.Result
+	Report *ProcessReport `json:"report"`
+}
+
+// KillProcessParam Query为true时只结束线程当前的查询，Reason记录到审计日志
+type KillProcessParam struct {
+	Service  string `json:"service"`
+	ThreadID int64  `json:"threadId"`
+	Query    bool   `json:"query,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type KillProcessResult cd.Result
+
+type QueryReplicationResult struct {
+	cd.Result
+	Status *ReplicationStatus `json:"status"`
+}
+
 type QueryClusterStatusResult struct {
 	cd.Result
 	Status *ClusterStatus `json:"status"`
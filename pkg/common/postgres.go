@@ -0,0 +1,66 @@
+package common
+
+import (
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	// QueryPostgresStatus 查询postgres角色、流复制、复制槽和连接状态
+	QueryPostgresStatus = "/postgres/status"
+)
+
+// postgres角色，standby为处于恢复状态的备库
+const (
+	PostgresPrimary = "primary"
+	PostgresStandby = "standby"
+)
+
+// PostgresReplica 主库上的流复制连接，来自pg_stat_replication
+// LagBytes为主库当前WAL位置与备库回放位置的差值，ReplayLag为回放延迟(秒)
+type PostgresReplica struct {
+	ApplicationName string  `json:"applicationName"`
+	ClientAddr      string  `json:"clientAddr"`
+	State           string  `json:"state"`
+	SyncState       string  `json:"syncState"`
+	LagBytes        int64   `json:"lagBytes"`
+	ReplayLag       float64 `json:"replayLag"`
+}
+
+// WalReceiver 备库的WAL接收进程，来自pg_stat_wal_receiver
+type WalReceiver struct {
+	Status     string `json:"status"`
+	SenderHost string `json:"senderHost"`
+	SenderPort int    `json:"senderPort"`
+}
+
+// ReplicationSlot 主库上的复制槽，RetainedBytes为该复制槽保留的WAL大小
+type ReplicationSlot struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Active        bool   `json:"active"`
+	RetainedBytes int64  `json:"retainedBytes"`
+}
+
+// PostgresStatus postgres状态，主库包含Replicas和Slots，备库包含Receiver，Receiver为空表示WAL接收进程未运行
+// Connections为客户端连接数
+type PostgresStatus struct {
+	Role           string             `json:"role"`
+	InRecovery     bool               `json:"inRecovery"`
+	Connections    int                `json:"connections"`
+	MaxConnections int                `json:"maxConnections"`
+	Replicas       []*PostgresReplica `json:"replicas,omitempty"`
+	Slots          []*ReplicationSlot `json:"slots,omitempty"`
+	Receiver       *WalReceiver       `json:"receiver,omitempty"`
+}
+
+// IsReceiverDown 备库没有处于streaming状态的WAL接收进程
+func (s *PostgresStatus) IsReceiverDown() bool {
+	return s.InRecovery && (s.Receiver == nil || s.Receiver.Status != "streaming")
+}
+
+type QueryPostgresStatusResult struct {
+	cd.Result
+	Status *PostgresStatus `json:"status"`
+}
+
+const PostgresModule = "/module/postgres"
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	// QueryProbeStatus 执行probe守护对象的全部探测并返回结果
+	QueryProbeStatus = "/probe/status"
+)
+
+// ProbeResult 单个探测的结果，Latency为耗时(毫秒)
+// CertExpire为tls、https探测到的证书最早过期时间，CertExpiring表示剩余有效期低于告警阈值
+type ProbeResult struct {
+	Type         string     `json:"type"`
+	Target       string     `json:"target"`
+	Healthy      bool       `json:"healthy"`
+	Latency      int64      `json:"latency"`
+	Reason       string     `json:"reason,omitempty"`
+	CertExpire   *time.Time `json:"certExpire,omitempty"`
+	CertExpiring bool       `json:"certExpiring,omitempty"`
+}
+
+// ProbeStatus probe守护对象的探测结果，任一探测失败时Healthy为false
+type ProbeStatus struct {
+	Healthy bool           `json:"healthy"`
+	Results []*ProbeResult `json:"results"`
+}
+
+type QueryProbeStatusResult struct {
+	cd.Result
+	Status *ProbeStatus `json:"status"`
+}
+
+const ProbeModule = "/module/probe"
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"strings"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	// QueryRedisStatus 查询redis复制、持久化和内存状态
+	QueryRedisStatus = "/redis/status"
+)
+
+// redis角色，sentinel为Sentinel进程
+const (
+	RedisMaster   = "master"
+	RedisSlave    = "slave"
+	RedisSentinel = "sentinel"
+)
+
+// SentinelMaster Sentinel监控的主节点，Status为ok、sdown或odown
+type SentinelMaster struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Address   string `json:"address"`
+	Slaves    int    `json:"slaves"`
+	Sentinels int    `json:"sentinels"`
+}
+
+// RedisStatus redis状态，来自INFO replication、persistence、memory，Sentinel进程来自INFO sentinel
+// MaxMemory为0表示未限制内存
+type RedisStatus struct {
+	Role                 string            `json:"role"`
+	Loading              bool              `json:"loading"`
+	MasterHost           string            `json:"masterHost,omitempty"`
+	MasterPort           int               `json:"masterPort,omitempty"`
+	MasterLinkStatus     string            `json:"masterLinkStatus,omitempty"`
+	MasterLastIOSeconds  int64             `json:"masterLastIOSeconds,omitempty"`
+	MasterSyncInProgress bool              `json:"masterSyncInProgress,omitempty"`
+	ConnectedSlaves      int               `json:"connectedSlaves"`
+	RDBLastBgsaveStatus  string            `json:"rdbLastBgsaveStatus,omitempty"`
+	RDBChangesSinceSave  int64             `json:"rdbChangesSinceSave"`
+	AOFEnabled           bool              `json:"aofEnabled"`
+	AOFLastWriteStatus   string            `json:"aofLastWriteStatus,omitempty"`
+	AOFLastRewriteStatus string            `json:"aofLastRewriteStatus,omitempty"`
+	UsedMemory           int64             `json:"usedMemory"`
+	MaxMemory            int64             `json:"maxMemory"`
+	FragmentationRatio   float64           `json:"fragmentationRatio"`
+	SentinelMasters      []*SentinelMaster `json:"sentinelMasters,omitempty"`
+}
+
+// IsLinkDown 从节点与主节点的连接中断
+func (s *RedisStatus) IsLinkDown() bool {
+	return s.Role == RedisSlave && s.MasterLinkStatus != "up" && !s.MasterSyncInProgress
+}
+
+// PersistenceError 最近一次RDB、AOF写入失败时返回原因
+func (s *RedisStatus) PersistenceError() string {
+	problems := []string{}
+	if s.RDBLastBgsaveStatus != "" && s.RDBLastBgsaveStatus != "ok" {
+		problems = append(problems, "rdb_last_bgsave_status:"+s.RDBLastBgsaveStatus)
+	}
+	if s.AOFEnabled && s.AOFLastWriteStatus != "" && s.AOFLastWriteStatus != "ok" {
+		problems = append(problems, "aof_last_write_status:"+s.AOFLastWriteStatus)
+	}
+	if s.AOFEnabled && s.AOFLastRewriteStatus != "" && s.AOFLastRewriteStatus != "ok" {
+		problems = append(problems, "aof_last_bgrewrite_status:"+s.AOFLastRewriteStatus)
+	}
+
+	return strings.Join(problems, ", ")
+}
+
+type QueryRedisStatusResult struct {
+	cd.Result
+	Status *RedisStatus `json:"status"`
+}
+
+const RedisModule = "/module/redis"
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	ExecuteCommand = "/command/execute"
+	// StreamCommand 流式执行命令，仅用于模块间事件
+	StreamCommand = "/command/stream"
+	// RunCommand 基于服务容器运行临时容器，仅用于模块间事件
+	RunCommand     = "/command/run"
+	StartService   = "/service/start"
+	StopService    = "/service/stop"
+	RestartService = "/service/restart"
+
+	ListContainer       = "/container/list"
+	InspectContainer    = "/container/inspect"
+	QueryContainerStats = "/container/stats"
+	QueryContainerLogs  = "/container/logs"
+
+	QueryContainerEvents = "/container/events"
+)
+
+const (
+	NotifyContainerEvent = "/container/event/notify/"
+)
+
+// 运行时类型
+const (
+	DockerRuntime     = "docker"
+	PodmanRuntime     = "podman"
+	NerdctlRuntime    = "nerdctl"
+	SystemdRuntime    = "systemd"
+	KubernetesRuntime = "kubernetes"
+)
+
+// 容器状态
+const (
+	ContainerRunning    = "running"
+	ContainerExited     = "exited"
+	ContainerRestarting = "restarting"
+)
+
+// 容器事件
+const (
+	ContainerDie          = "die"
+	ContainerOOM          = "oom"
+	ContainerHealthStatus = "health_status"
+	ContainerRestart      = "restart"
+)
+
+// ContainerEvent 运行时上报的容器事件，Health仅在health_status事件中有效，ExitCode仅在die事件中有效
+type ContainerEvent struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Action    string    `json:"action"`
+	Health    string    `json:"health,omitempty"`
+	ExitCode  int       `json:"exitCode"`
+	Runtime   string    `json:"runtime"`
+	TimeStamp time.Time `json:"timeStamp"`
+}
+
+// ContainerInfo 容器信息，systemd运行时下对应service单元，kubernetes运行时下对应pod
+// State为运行状态，Status为运行时给出的状态描述，Health为健康检查状态
+type ContainerInfo struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Image        string       `json:"image"`
+	State        string       `json:"state"`
+	Status       string       `json:"status"`
+	Health       string       `json:"health,omitempty"`
+	RestartCount int          `json:"restartCount"`
+	ExitCode     int          `json:"exitCode"`
+	OOMKilled    bool         `json:"oomKilled"`
+	StartedAt    string       `json:"startedAt,omitempty"`
+	FinishedAt   string       `json:"finishedAt,omitempty"`
+	Mounts       []*MountInfo `json:"mounts,omitempty"`
+	Runtime      string       `json:"runtime"`
+}
+
+// IsRunning 容器是否处于运行状态，kubernetes运行时下pod状态为Running
+func (s *ContainerInfo) IsRunning() bool {
+	switch s.State {
+	case ContainerRunning, "active", "Running":
+		return true
+	}
+
+	return false
+}
+
+// MountInfo 挂载信息
+type MountInfo struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"readOnly"`
+}
+
+// ContainerStats 资源使用情况，CPUPercent按单核100%计算
+type ContainerStats struct {
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryUsage   uint64  `json:"memoryUsage"`
+	MemoryLimit   uint64  `json:"memoryLimit"`
+	MemoryPercent float64 `json:"memoryPercent"`
+	PIDs          uint64  `json:"pids"`
+	Runtime       string  `json:"runtime"`
+}
+
+// ContainerLogParam 读取服务最近Tail行日志
+type ContainerLogParam struct {
+	Service string `json:"service"`
+	Tail    int    `json:"tail"`
+}
+
+type ListContainerResult struct {
+	cd.Result
+	Containers []*ContainerInfo `json:"containers"`
+}
+
+type InspectContainerResult struct {
+	cd.Result
+	Container *ContainerInfo `json:"container"`
+}
+
+type QueryContainerStatsResult struct {
+	cd.Result
+	Stats *ContainerStats `json:"stats"`
+}
+
+type QueryContainerEventsResult struct {
+	cd.Result
+	Events []*ContainerEvent `json:"events"`
+}
+
+const RuntimeModule = "/module/runtime"
+
+// DockerModule 运行时模块的旧名称
+//
+// Deprecated: 使用RuntimeModule，运行时模块已支持docker以外的运行时
+const DockerModule = RuntimeModule
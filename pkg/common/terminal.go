@@ -0,0 +1,68 @@
+package common
+
+import (
+	"time"
+
+	cd "github.com/muidea/magicCommon/def"
+)
+
+const (
+	// ExecuteTerminal 交互式会话，websocket连接，需要管理员令牌
+	ExecuteTerminal = "/command/terminal"
+	// QueryAudit 查询交互式会话审计记录，需要管理员令牌
+	QueryAudit = "/command/audit"
+	// AppendAudit 记录其他模块的管理操作，仅用于模块间事件
+	AppendAudit = "/command/audit/append"
+)
+
+// 交互式会话的控制消息类型，控制消息以websocket文本帧发送，输入输出以二进制帧发送
+const (
+	// TerminalResize 客户端调整窗口大小
+	TerminalResize = "resize"
+	// TerminalEOF 客户端结束输入
+	TerminalEOF = "eof"
+	// TerminalExit 服务端通知命令已结束，随后关闭连接
+	TerminalExit = "exit"
+)
+
+// TerminalParam 交互式会话参数，Command为空时启动sh，Env格式为KEY=VALUE
+type TerminalParam struct {
+	Service string   `json:"service"`
+	Command []string `json:"command"`
+	Env     []string `json:"env,omitempty"`
+	WorkDir string   `json:"workDir,omitempty"`
+	TTY     bool     `json:"tty"`
+	Rows    uint16   `json:"rows,omitempty"`
+	Cols    uint16   `json:"cols,omitempty"`
+}
+
+// TerminalMessage 交互式会话控制消息
+type TerminalMessage struct {
+	Type     string     `json:"type"`
+	Rows     uint16     `json:"rows,omitempty"`
+	Cols     uint16     `json:"cols,omitempty"`
+	ExitCode int        `json:"exitCode,omitempty"`
+	Error    *cd.Result `json:"error,omitempty"`
+}
+
+// AuditRecord 交互式会话审计记录，FinishTime为空表示会话未结束，
+// Transcript为asciicast v2格式的完整会话记录文件，其他模块的管理操作没有会话记录，Reason为操作原因
+type AuditRecord struct {
+	ID         string     `json:"id"`
+	Service    string     `json:"service"`
+	Command    []string   `json:"command"`
+	WorkDir    string     `json:"workDir,omitempty"`
+	TTY        bool       `json:"tty"`
+	RemoteAddr string     `json:"remoteAddr"`
+	StartTime  time.Time  `json:"startTime"`
+	FinishTime *time.Time `json:"finishTime,omitempty"`
+	ExitCode   int        `json:"exitCode"`
+	Error      *cd.Result `json:"error,omitempty"`
+	Transcript string     `json:"transcript"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+type QueryAuditResult struct {
+	cd.Result
+	Records []*AuditRecord `json:"records"`
+}